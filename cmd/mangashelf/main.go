@@ -3,7 +3,6 @@ package main
 import (
 	"context"
 	"fmt"
-	"net/http"
 	"os"
 	"os/signal"
 	"path/filepath"
@@ -15,11 +14,15 @@ import (
 	"github.com/spf13/cobra"
 
 	"github.com/mangashelf/mangashelf/internal/api"
+	"github.com/mangashelf/mangashelf/internal/auth"
 	"github.com/mangashelf/mangashelf/internal/config"
 	"github.com/mangashelf/mangashelf/internal/database"
+	"github.com/mangashelf/mangashelf/internal/downloader"
+	"github.com/mangashelf/mangashelf/internal/events"
+	"github.com/mangashelf/mangashelf/internal/kosync"
 	"github.com/mangashelf/mangashelf/internal/library"
-	"github.com/mangashelf/mangashelf/internal/scraper"
-	"github.com/mangashelf/mangashelf/internal/scraper/mangadex"
+	"github.com/mangashelf/mangashelf/internal/notify"
+	"github.com/mangashelf/mangashelf/internal/webhook"
 )
 
 var (
@@ -56,6 +59,9 @@ func newRootCommand() *cobra.Command {
 	cmd.Flags().BoolVar(&disableUpdates, "no-update", false, "Disable automatic updates")
 	cmd.Flags().BoolVar(&skipScan, "no-scan", false, "Skip library scan on startup")
 
+	cmd.AddCommand(newScraperCommand())
+	cmd.AddCommand(newUserCommand())
+
 	return cmd
 }
 
@@ -84,39 +90,102 @@ func run(cmd *cobra.Command, _ []string) error {
 
 	logger.Info().Str("path", cfg.Database.Path).Msg("database initialized")
 
-	scraperMgr := scraper.NewManager(logger)
-	scraperMgr.Register(mangadex.New(cfg.Sources.Mangadex.Language))
-
 	queries := database.New(db)
-	libService := library.NewService(queries, scraperMgr, logger)
+	proxies := applyProxies(cmd.Context(), cfg, queries, logger)
+	applyResponseCache(cmd.Context(), cfg, queries, logger)
+
+	scraperMgr, closeScrapers := buildScrapers(cmd.Context(), cfg, proxies, logger)
+	defer closeScrapers()
+
+	bus := events.NewBus()
+	hooks := webhook.NewService(queries, webhook.Options{
+		MaxAttempts: cfg.Webhooks.MaxAttempts,
+		RetryDelay:  cfg.Webhooks.RetryDelay,
+		Timeout:     cfg.Webhooks.Timeout,
+		Retention:   cfg.Webhooks.Retention,
+	}, logger)
+	bus.Subscribe(hooks.Handle)
+
+	notifier, err := notify.NewService(queries, cfg.Notifications, logger)
+	if err != nil {
+		return fmt.Errorf("notifications: %w", err)
+	}
+	if cfg.Notifications.Enabled {
+		bus.Subscribe(notifier.Handle)
+		defer notifier.Flush(context.Background())
+	}
 
-	router := api.NewRouter(logger, scraperMgr, libService)
-	addr := fmt.Sprintf("%s:%d", cfg.Server.Host, cfg.Server.Port)
+	libService := library.NewService(queries, scraperMgr, library.Options{
+		LibraryPath:    cfg.Library.Path,
+		DownloadCovers: cfg.Metadata.DownloadCovers,
+		Events:         bus,
+	}, logger)
 
-	srv := &http.Server{ //nolint:exhaustruct
-		Addr:    addr,
-		Handler: router,
+	users := auth.NewService(queries, auth.Options{SessionTTL: cfg.Security.Auth.SessionTTL}, logger)
+	if err := bootstrapAdmin(cmd.Context(), cfg, users, logger); err != nil {
+		return err
+	}
+	if !cfg.Security.Auth.Enabled {
+		logger.Warn().Msg("authentication is disabled; anyone who can reach the server has admin access")
 	}
 
+	trustedProxies, err := api.ParseNetworks(cfg.Server.TrustedProxies)
+	if err != nil {
+		return fmt.Errorf("server.trustedProxies: %w", err)
+	}
+	var cors api.CORSOptions
+	if cfg.Server.CORS.Enabled {
+		cors = api.CORSOptions{
+			Origins:          cfg.Server.CORS.Origins,
+			AllowCredentials: cfg.Server.CORS.AllowCredentials,
+			MaxAge:           cfg.Server.CORS.MaxAge,
+		}
+	}
+
+	syncService := kosync.NewService(queries, libService, logger)
+
+	router := api.NewRouter(logger, scraperMgr, libService, users, syncService, hooks, notifier, api.Options{
+		DisableAuth:        !cfg.Security.Auth.Enabled,
+		SecureCookies:      cfg.Security.Auth.SecureCookies,
+		BaseURL:            cfg.Server.BaseURL,
+		CORS:               cors,
+		TrustedProxies:     trustedProxies,
+		KOSyncRegistration: cfg.Security.Auth.KOSyncRegistration,
+	})
 	ctx, stop := signal.NotifyContext(cmd.Context(), os.Interrupt, syscall.SIGTERM)
 	defer stop()
 
-	go func() {
-		<-ctx.Done()
-		shutdownCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
-		defer cancel()
-
-		if err := srv.Shutdown(shutdownCtx); err != nil && err != http.ErrServerClosed {
-			logger.Error().Err(err).Msg("failed to shutdown server")
-		}
-	}()
+	dl := downloader.New(queries, scraperMgr, cfg.Downloader, cfg.Library.Path, bus, logger)
+	go dl.Run(ctx)
+	go hooks.Run(ctx)
+	if cfg.Notifications.Enabled {
+		go notifier.Run(ctx)
+	}
+	go scraperMgr.RunHealthChecks(ctx)
 
-	logger.Info().Msgf("starting server on %s", addr)
+	return serve(ctx, cfg.Server, router, logger)
+}
 
-	if err := srv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
-		return fmt.Errorf("start server: %w", err)
+// bootstrapAdmin creates the admin account on first run and prunes expired
+// sessions.
+func bootstrapAdmin(ctx context.Context, cfg *config.Config, users *auth.Service, logger zerolog.Logger) error {
+	admin, password, err := users.Bootstrap(ctx, cfg.Security.Auth.Admin.Username, cfg.Security.Auth.Admin.Password)
+	if err != nil {
+		return fmt.Errorf("create admin account: %w", err)
+	}
+	switch {
+	case admin != nil && password != "":
+		logger.Warn().
+			Str("username", admin.Username).
+			Str("password", password).
+			Msg("created admin account with a generated password; change it after logging in")
+	case admin != nil:
+		logger.Info().Str("username", admin.Username).Msg("created admin account")
 	}
 
+	if err := users.PruneSessions(ctx); err != nil {
+		logger.Warn().Err(err).Msg("failed to prune expired sessions")
+	}
 	return nil
 }
 
@@ -124,6 +193,7 @@ func applyFlagOverrides(cmd *cobra.Command, cfg *config.Config) {
 	if cmd.Flags().Changed("data") {
 		cfg.Library.Path = filepath.Join(dataDir, "manga")
 		cfg.Sources.CustomPath = filepath.Join(dataDir, "scrapers")
+		cfg.Sources.Local.Path = filepath.Join(dataDir, "local")
 		cfg.Database.Path = filepath.Join(dataDir, "mangashelf.db")
 		cfg.Logging.File.Path = filepath.Join(dataDir, "logs", "mangashelf.log")
 	}
@@ -0,0 +1,204 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/rs/zerolog"
+
+	"github.com/mangashelf/mangashelf/internal/config"
+	"github.com/mangashelf/mangashelf/internal/database"
+	"github.com/mangashelf/mangashelf/internal/scraper"
+	"github.com/mangashelf/mangashelf/internal/scraper/declarative"
+	"github.com/mangashelf/mangashelf/internal/scraper/local"
+	"github.com/mangashelf/mangashelf/internal/scraper/mangadex"
+	"github.com/mangashelf/mangashelf/internal/scraper/plugin"
+)
+
+// buildScrapers registers every configured provider. proxies are the
+// per-provider proxies returned by applyProxies, passed on to plugins. The
+// returned function stops plugin processes.
+func buildScrapers(ctx context.Context, cfg *config.Config, proxies map[string]scraper.ProxyConfig, logger zerolog.Logger) (*scraper.Manager, func()) {
+	scraperMgr := scraper.NewManager(logger)
+	scraperMgr.SetHealthOptions(scraper.HealthOptions{
+		FailureThreshold: cfg.Sources.Health.FailureThreshold,
+		OpenDuration:     cfg.Sources.Health.OpenDuration,
+		ProbeInterval:    cfg.Sources.Health.ProbeInterval,
+		ProbeTimeout:     cfg.Sources.Health.ProbeTimeout,
+	})
+	scraperMgr.Register(mangadex.New(mangadex.Options{
+		Languages:       cfg.Sources.Mangadex.PreferredLanguages(),
+		NSFW:            cfg.Sources.Mangadex.NSFW,
+		ShowUnavailable: cfg.Sources.Mangadex.ShowUnavailable,
+		CoverSize:       cfg.Metadata.CoverSize,
+	}))
+	if cfg.Sources.Local.Enabled {
+		scraperMgr.Register(local.New(cfg.Sources.Local.Path))
+	}
+
+	sources, err := declarative.LoadDir(cfg.Sources.CustomPath)
+	if err != nil {
+		logger.Warn().Err(err).Str("path", cfg.Sources.CustomPath).Msg("skipped invalid source definitions")
+	}
+	for _, source := range sources {
+		scraperMgr.Register(source)
+	}
+
+	var plugins []*plugin.Plugin
+	if cfg.Sources.Plugins.Enabled {
+		plugins, err = plugin.LoadDir(ctx, cfg.Sources.CustomPath, plugin.Options{
+			Timeout:     cfg.Sources.Plugins.Timeout,
+			MaxRestarts: cfg.Sources.Plugins.MaxRestarts,
+			Env:         proxyConfig(cfg.Sources.Proxy).Env(),
+			EnvFor: func(id string) []string {
+				pc, ok := proxies[id]
+				if !ok {
+					return nil
+				}
+				return proxyEnv(pc)
+			},
+		}, logger)
+		if err != nil {
+			logger.Warn().Err(err).Str("path", cfg.Sources.CustomPath).Msg("skipped plugins that failed to start")
+		}
+		for _, p := range plugins {
+			scraperMgr.Register(p)
+		}
+	}
+
+	return scraperMgr, func() {
+		for _, p := range plugins {
+			p.Close()
+		}
+	}
+}
+
+// applyProxies installs the configured proxies: the global one as the
+// fallback transport and one per provider from the config file. Proxies in
+// the scraper table's config column replace the file entry for that
+// provider. queries may be nil when no database is open. The per-provider
+// proxies are returned for plugins, which make their own requests.
+func applyProxies(ctx context.Context, cfg *config.Config, queries *database.Queries, logger zerolog.Logger) map[string]scraper.ProxyConfig {
+	proxies := make(map[string]scraper.ProxyConfig, len(cfg.Sources.Proxies))
+	for id, pc := range cfg.Sources.Proxies {
+		proxies[id] = proxyConfig(pc)
+	}
+
+	if queries != nil {
+		rows, err := queries.ListScrapers(ctx)
+		if err != nil {
+			logger.Warn().Err(err).Msg("failed to load scraper proxy settings")
+		}
+		for _, row := range rows {
+			if !row.Config.Valid || row.Config.String == "" {
+				continue
+			}
+			var settings struct {
+				Proxy *scraper.ProxyConfig `json:"proxy"`
+			}
+			if err := json.Unmarshal([]byte(row.Config.String), &settings); err != nil {
+				logger.Warn().Err(err).Str("provider", row.Name).Msg("skipped invalid scraper config")
+				continue
+			}
+			if settings.Proxy != nil {
+				proxies[row.Name] = *settings.Proxy
+			}
+		}
+	}
+
+	if global := proxyConfig(cfg.Sources.Proxy); !global.IsZero() {
+		installProxy("", global, logger)
+	}
+	for id, pc := range proxies {
+		installProxy(id, pc, logger)
+	}
+	return proxies
+}
+
+// proxyEnv returns the environment routing a plugin through a proxy. A
+// provider entry without a URL clears the proxy variables, so the plugin
+// connects directly even when a global proxy is set.
+func proxyEnv(pc scraper.ProxyConfig) []string {
+	if pc.IsZero() {
+		return []string{"HTTP_PROXY=", "HTTPS_PROXY=", "ALL_PROXY=", "http_proxy=", "https_proxy=", "all_proxy="}
+	}
+	return pc.Env()
+}
+
+// installProxy routes a provider's traffic through the proxy. An empty
+// provider ID sets the proxy for every provider. A provider entry without a
+// URL connects directly, even when a global proxy is set.
+func installProxy(providerID string, pc scraper.ProxyConfig, logger zerolog.Logger) {
+	log := logger
+	if providerID != "" {
+		log = logger.With().Str("provider", providerID).Logger()
+	}
+
+	if pc.IsZero() {
+		scraper.SetTransport(providerID, http.DefaultTransport)
+		log.Info().Msg("provider connects without proxy")
+		return
+	}
+
+	rt, err := scraper.NewProxyTransport(pc)
+	if err != nil {
+		log.Warn().Err(err).Msg("skipped invalid proxy")
+		return
+	}
+	scraper.SetTransport(providerID, rt)
+	log.Info().Str("proxy", redactProxyURL(pc.URL)).Msg("proxy enabled")
+}
+
+func proxyConfig(pc config.ProxyConfig) scraper.ProxyConfig {
+	return scraper.ProxyConfig{
+		URL:      pc.URL,
+		Username: pc.Username,
+		Password: pc.Password,
+		NoProxy:  pc.NoProxy,
+	}
+}
+
+// redactProxyURL hides credentials embedded in a proxy URL for logging.
+func redactProxyURL(raw string) string {
+	u, err := url.Parse(raw)
+	if err != nil {
+		return "invalid"
+	}
+	return u.Redacted()
+}
+
+// cacheRetention is how long expired responses are kept in the database for
+// revalidation before they are pruned.
+const cacheRetention = 7 * 24 * time.Hour
+
+// applyResponseCache installs the provider response cache. queries may be
+// nil, in which case responses are kept in memory only.
+func applyResponseCache(ctx context.Context, cfg *config.Config, queries *database.Queries, logger zerolog.Logger) {
+	if !cfg.Sources.Cache.Enabled {
+		return
+	}
+
+	var store scraper.CacheStore
+	if queries != nil && cfg.Sources.Cache.Persist {
+		sqlStore := scraper.NewSQLCacheStore(queries)
+		if err := sqlStore.Prune(ctx, time.Now().Add(-cacheRetention)); err != nil {
+			logger.Warn().Err(err).Msg("failed to prune response cache")
+		}
+		store = sqlStore
+	}
+
+	ttl := cfg.Sources.Cache.TTL
+	scraper.SetResponseCache(scraper.NewResponseCache(scraper.CacheOptions{
+		MaxEntries:  cfg.Sources.Cache.MaxEntries,
+		MaxBodySize: cfg.Sources.Cache.MaxBodySize,
+		TTL: map[scraper.CallType]time.Duration{
+			scraper.CallSearch:   ttl.Search,
+			scraper.CallManga:    ttl.Manga,
+			scraper.CallChapters: ttl.Chapters,
+			scraper.CallPages:    ttl.Pages,
+		},
+	}, store, logger))
+}
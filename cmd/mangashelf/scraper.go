@@ -0,0 +1,150 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/spf13/cobra"
+
+	"github.com/mangashelf/mangashelf/internal/config"
+	"github.com/mangashelf/mangashelf/internal/scraper/fixture"
+	"github.com/mangashelf/mangashelf/internal/scraper/plugin"
+)
+
+var (
+	testQuery   string
+	testManga   string
+	testChapter string
+	testFixture string
+	testRecord  bool
+	testLive    bool
+)
+
+func newScraperCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "scraper",
+		Short: "Develop and check scrapers",
+	}
+
+	test := &cobra.Command{
+		Use:   "test <id>",
+		Short: "Run a scraper against recorded or live responses and check its results",
+		Long: `Runs search, manga, chapters and pages against a scraper and checks the
+results: IDs and titles are set, cover and page URLs are absolute, chapter
+numbers are valid and page indexes increase.
+
+Responses are replayed from the fixture file when it exists. Use --record to
+refresh it from the live source, or --live to skip fixtures entirely. Plugins
+make their own requests, so they are always tested live and cannot be
+recorded.`,
+		Args:         cobra.ExactArgs(1),
+		RunE:         runScraperTest,
+		SilenceUsage: true,
+	}
+	test.Flags().StringVarP(&testQuery, "query", "q", "one piece", "Search query")
+	test.Flags().StringVar(&testManga, "manga", "", "Manga ID to fetch instead of the first search result")
+	test.Flags().StringVar(&testChapter, "chapter", "", "Chapter ID to fetch instead of the first chapter")
+	test.Flags().StringVarP(&testFixture, "fixture", "f", "", "Fixture file (default <customPath>/fixtures/<id>.json)")
+	test.Flags().BoolVar(&testRecord, "record", false, "Record live responses into the fixture file")
+	test.Flags().BoolVar(&testLive, "live", false, "Use the live source without reading or writing fixtures")
+
+	cmd.AddCommand(test)
+	return cmd
+}
+
+func runScraperTest(cmd *cobra.Command, args []string) error {
+	cfg, err := config.Load(cfgFile)
+	if err != nil {
+		return err
+	}
+	logger := buildLogger(cfg)
+
+	id := args[0]
+	proxies := applyProxies(cmd.Context(), cfg, nil, logger)
+	scraperMgr, closeScrapers := buildScrapers(cmd.Context(), cfg, proxies, logger)
+	defer closeScrapers()
+
+	provider, err := scraperMgr.Get(id)
+	if err != nil {
+		return err
+	}
+
+	// Plugins run in their own process, out of reach of the recorder and
+	// replayer; a recording would come out empty.
+	_, isPlugin := provider.(*plugin.Plugin)
+	if isPlugin && testRecord {
+		return fmt.Errorf("cannot record %s: plugin traffic does not pass through MangaShelf", id)
+	}
+
+	path := testFixture
+	if path == "" {
+		path = filepath.Join(cfg.Sources.CustomPath, "fixtures", id+".json")
+	}
+
+	mode := "live"
+	var session *fixture.Session
+	switch {
+	case testRecord:
+		mode = "recording"
+		session = fixture.Record(id, path)
+	case !testLive && !isPlugin:
+		session, err = fixture.Replay(id, path)
+		switch {
+		case err == nil:
+			mode = "replaying"
+		case errors.Is(err, os.ErrNotExist):
+			session = nil
+		default:
+			return fmt.Errorf("load fixture: %w", err)
+		}
+	}
+
+	out := cmd.OutOrStdout()
+	fmt.Fprintf(out, "Testing %s (%s", id, mode)
+	if session != nil {
+		fmt.Fprintf(out, " %s", path)
+	}
+	fmt.Fprintln(out, ")")
+
+	report := fixture.Check(cmd.Context(), provider, fixture.Scenario{
+		Query:     testQuery,
+		MangaID:   testManga,
+		ChapterID: testChapter,
+	})
+
+	if session != nil {
+		if err := session.Stop(); err != nil {
+			return fmt.Errorf("save fixture: %w", err)
+		}
+	}
+
+	for _, step := range report.Steps {
+		status := "ok"
+		if step.Error != "" {
+			status = "FAIL " + step.Error
+		}
+		fmt.Fprintf(out, "  %-9s %4d  %8s  %s\n", step.Name, step.Count, step.Duration.Round(1e6), status)
+	}
+	for _, v := range report.Violations {
+		fmt.Fprintf(out, "  ✗ %s\n", v)
+	}
+
+	if !report.OK() {
+		return fmt.Errorf("scraper %s failed %d check(s)", id, len(report.Violations)+failedSteps(report))
+	}
+
+	fmt.Fprintln(out, "All checks passed")
+	return nil
+}
+
+func failedSteps(r *fixture.Report) int {
+	n := 0
+	for _, s := range r.Steps {
+		if s.Error != "" {
+			n++
+		}
+	}
+	return n
+}
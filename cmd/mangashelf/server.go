@@ -0,0 +1,277 @@
+package main
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog"
+
+	"github.com/mangashelf/mangashelf/internal/config"
+)
+
+// certCheckInterval is how often the certificate files are checked for changes.
+const certCheckInterval = time.Minute
+
+// serve runs the HTTP server on a TCP address or Unix socket until ctx is
+// cancelled. With TLS enabled it serves HTTPS and, when configured, redirects
+// plain HTTP requests from a second port.
+func serve(ctx context.Context, cfg config.ServerConfig, handler http.Handler, logger zerolog.Logger) error {
+	protocols := new(http.Protocols)
+	protocols.SetHTTP1(true)
+	protocols.SetHTTP2(cfg.HTTP2)
+	// Without TLS, HTTP/2 is only used by clients that ask for it directly,
+	// such as a reverse proxy configured for h2c.
+	protocols.SetUnencryptedHTTP2(cfg.HTTP2 && !cfg.TLS.Enabled)
+
+	srv := &http.Server{ //nolint:exhaustruct
+		Handler:   handler,
+		Protocols: protocols,
+	}
+
+	if cfg.TLS.Enabled {
+		tlsConfig, err := newTLSConfig(ctx, cfg.TLS, logger)
+		if err != nil {
+			return err
+		}
+		srv.TLSConfig = tlsConfig
+	}
+
+	ln, err := listen(cfg)
+	if err != nil {
+		return fmt.Errorf("start server: %w", err)
+	}
+
+	servers := []*http.Server{srv}
+	errc := make(chan error, 2)
+
+	go func() {
+		if cfg.TLS.Enabled {
+			errc <- srv.ServeTLS(ln, "", "")
+		} else {
+			errc <- srv.Serve(ln)
+		}
+	}()
+	addr := "http://" + ln.Addr().String()
+	switch {
+	case ln.Addr().Network() == "unix":
+		addr = "unix:" + ln.Addr().String()
+	case cfg.TLS.Enabled:
+		addr = "https://" + ln.Addr().String()
+	}
+	logger.Info().Msgf("starting server on %s", addr)
+
+	if cfg.TLS.Enabled && cfg.TLS.RedirectPort > 0 {
+		redirect := &http.Server{ //nolint:exhaustruct
+			Addr:    net.JoinHostPort(cfg.Host, strconv.Itoa(cfg.TLS.RedirectPort)),
+			Handler: redirectHTTPS(cfg.Port),
+		}
+		servers = append(servers, redirect)
+		go func() { errc <- redirect.ListenAndServe() }()
+		logger.Info().Msgf("redirecting http://%s to HTTPS", redirect.Addr)
+	}
+
+	var serveErr error
+	select {
+	case err := <-errc:
+		if !errors.Is(err, http.ErrServerClosed) {
+			serveErr = fmt.Errorf("start server: %w", err)
+		}
+	case <-ctx.Done():
+	}
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	for _, s := range servers {
+		if err := s.Shutdown(shutdownCtx); err != nil && err != http.ErrServerClosed {
+			logger.Error().Err(err).Msg("failed to shutdown server")
+		}
+	}
+	return serveErr
+}
+
+// listen opens the Unix socket when one is configured, or the TCP address.
+func listen(cfg config.ServerConfig) (net.Listener, error) {
+	if cfg.Socket == "" {
+		return net.Listen("tcp", net.JoinHostPort(cfg.Host, strconv.Itoa(cfg.Port)))
+	}
+
+	// A socket left behind by an unclean exit would make Listen fail.
+	if info, err := os.Lstat(cfg.Socket); err == nil && info.Mode()&os.ModeSocket != 0 {
+		if err := os.Remove(cfg.Socket); err != nil {
+			return nil, err
+		}
+	}
+	ln, err := net.Listen("unix", cfg.Socket)
+	if err != nil {
+		return nil, err
+	}
+	// Let a reverse proxy in the same group connect.
+	if err := os.Chmod(cfg.Socket, 0o660); err != nil {
+		_ = ln.Close()
+		return nil, err
+	}
+	return ln, nil
+}
+
+// newTLSConfig loads the certificate and reloads it whenever its files
+// change, until ctx is cancelled.
+func newTLSConfig(ctx context.Context, cfg config.TLSConfig, logger zerolog.Logger) (*tls.Config, error) {
+	if cfg.CertFile == "" || cfg.KeyFile == "" {
+		return nil, errors.New("server.tls: certFile and keyFile are required")
+	}
+	minVersion, err := tlsVersion(cfg.MinVersion)
+	if err != nil {
+		return nil, fmt.Errorf("server.tls.minVersion: %w", err)
+	}
+
+	certs := &certReloader{
+		certFile: cfg.CertFile,
+		keyFile:  cfg.KeyFile,
+		log:      logger.With().Str("component", "tls").Logger(),
+	}
+	if err := certs.load(); err != nil {
+		return nil, fmt.Errorf("load certificate: %w", err)
+	}
+	go certs.watch(ctx, certCheckInterval)
+
+	return &tls.Config{ //nolint:exhaustruct
+		MinVersion:     minVersion,
+		GetCertificate: certs.getCertificate,
+	}, nil
+}
+
+// tlsVersion parses a TLS version such as "1.2". An empty version means 1.2.
+func tlsVersion(version string) (uint16, error) {
+	switch strings.TrimPrefix(strings.ToLower(strings.TrimSpace(version)), "tls") {
+	case "1.0":
+		return tls.VersionTLS10, nil
+	case "1.1":
+		return tls.VersionTLS11, nil
+	case "", "1.2":
+		return tls.VersionTLS12, nil
+	case "1.3":
+		return tls.VersionTLS13, nil
+	default:
+		return 0, fmt.Errorf("unsupported TLS version %q", version)
+	}
+}
+
+// certReloader serves a certificate and key pair, reloading them when either
+// file changes so renewed certificates apply without a restart.
+type certReloader struct {
+	certFile string
+	keyFile  string
+	log      zerolog.Logger
+
+	mu      sync.RWMutex
+	cert    *tls.Certificate
+	modTime time.Time
+}
+
+func (c *certReloader) getCertificate(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.cert, nil
+}
+
+// load reads the certificate and key pair.
+func (c *certReloader) load() error {
+	modTime, err := c.latestModTime()
+	if err != nil {
+		return err
+	}
+	cert, err := tls.LoadX509KeyPair(c.certFile, c.keyFile)
+	if err != nil {
+		return err
+	}
+
+	c.mu.Lock()
+	c.cert = &cert
+	c.modTime = modTime
+	c.mu.Unlock()
+	return nil
+}
+
+// watch reloads the pair when either file's modification time changes. A
+// pair that fails to load keeps the previous certificate in use.
+func (c *certReloader) watch(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		modTime, err := c.latestModTime()
+		if err != nil {
+			c.log.Warn().Err(err).Msg("failed to check certificate")
+			continue
+		}
+		c.mu.RLock()
+		changed := !modTime.Equal(c.modTime)
+		c.mu.RUnlock()
+		if !changed {
+			continue
+		}
+
+		if err := c.load(); err != nil {
+			c.log.Warn().Err(err).Msg("failed to reload certificate; keeping the previous one")
+			continue
+		}
+		c.log.Info().Str("cert", c.certFile).Msg("reloaded certificate")
+	}
+}
+
+// latestModTime returns the later of the certificate and key modification times.
+func (c *certReloader) latestModTime() (time.Time, error) {
+	var latest time.Time
+	for _, path := range []string{c.certFile, c.keyFile} {
+		info, err := os.Stat(path)
+		if err != nil {
+			return time.Time{}, err
+		}
+		if info.ModTime().After(latest) {
+			latest = info.ModTime()
+		}
+	}
+	return latest, nil
+}
+
+// redirectHTTPS redirects every request to the same host and path over HTTPS
+// on port.
+func redirectHTTPS(port int) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		host := strings.Trim(req.Host, "[]")
+		if h, _, err := net.SplitHostPort(req.Host); err == nil {
+			host = h
+		}
+		if port != 443 {
+			host = net.JoinHostPort(host, strconv.Itoa(port))
+		} else if strings.Contains(host, ":") {
+			host = "[" + host + "]"
+		}
+
+		target := url.URL{ //nolint:exhaustruct
+			Scheme:   "https",
+			Host:     host,
+			Path:     req.URL.Path,
+			RawPath:  req.URL.RawPath,
+			RawQuery: req.URL.RawQuery,
+		}
+		http.Redirect(w, req, target.String(), http.StatusPermanentRedirect)
+	})
+}
@@ -0,0 +1,141 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/rs/zerolog"
+	"github.com/spf13/cobra"
+
+	"github.com/mangashelf/mangashelf/internal/auth"
+	"github.com/mangashelf/mangashelf/internal/config"
+	"github.com/mangashelf/mangashelf/internal/database"
+)
+
+var (
+	userPassword string
+	userRole     string
+)
+
+func newUserCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "user",
+		Short: "Manage user accounts",
+	}
+
+	create := &cobra.Command{
+		Use:          "create <username>",
+		Short:        "Create a user account",
+		Args:         cobra.ExactArgs(1),
+		RunE:         runUserCreate,
+		SilenceUsage: true,
+	}
+	create.Flags().StringVar(&userPassword, "password", "", "Password (read from stdin when omitted)")
+	create.Flags().StringVar(&userRole, "role", string(auth.RoleMember), "Role: admin, member or readonly")
+
+	passwd := &cobra.Command{
+		Use:   "passwd <username>",
+		Short: "Set a user's password",
+		Long: `Sets a user's password and signs out all of the user's sessions. Use it to
+regain access when the admin password is lost.`,
+		Args:         cobra.ExactArgs(1),
+		RunE:         runUserPasswd,
+		SilenceUsage: true,
+	}
+	passwd.Flags().StringVar(&userPassword, "password", "", "New password (read from stdin when omitted)")
+
+	cmd.AddCommand(create, passwd)
+	return cmd
+}
+
+func runUserCreate(cmd *cobra.Command, args []string) error {
+	users, closeDB, err := openUsers()
+	if err != nil {
+		return err
+	}
+	defer closeDB()
+
+	password, err := readPassword(cmd)
+	if err != nil {
+		return err
+	}
+
+	user, err := users.CreateUser(cmd.Context(), auth.CreateUserRequest{
+		Username: args[0],
+		Password: password,
+		Role:     auth.Role(userRole),
+	})
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintf(cmd.OutOrStdout(), "created user %s\n", user.Username)
+	return nil
+}
+
+func runUserPasswd(cmd *cobra.Command, args []string) error {
+	users, closeDB, err := openUsers()
+	if err != nil {
+		return err
+	}
+	defer closeDB()
+
+	password, err := readPassword(cmd)
+	if err != nil {
+		return err
+	}
+
+	if err := users.SetPasswordByUsername(cmd.Context(), args[0], password); err != nil {
+		return err
+	}
+
+	fmt.Fprintf(cmd.OutOrStdout(), "password updated for %s\n", args[0])
+	return nil
+}
+
+// openUsers opens the configured database for account management.
+func openUsers() (*auth.Service, func(), error) {
+	cfg, err := config.Load(cfgFile)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// The schema is only created for a new database; the server migrates
+	// existing ones on start.
+	_, statErr := os.Stat(cfg.Database.Path)
+	if err := os.MkdirAll(filepath.Dir(cfg.Database.Path), 0o755); err != nil {
+		return nil, nil, fmt.Errorf("create data directory: %w", err)
+	}
+	db, err := database.Open(cfg.Database.Path)
+	if err != nil {
+		return nil, nil, fmt.Errorf("open database: %w", err)
+	}
+	if errors.Is(statErr, fs.ErrNotExist) {
+		if err := database.Migrate(db); err != nil {
+			db.Close()
+			return nil, nil, fmt.Errorf("migrate database: %w", err)
+		}
+	}
+
+	users := auth.NewService(database.New(db), auth.Options{}, zerolog.Nop())
+	return users, func() { db.Close() }, nil
+}
+
+// readPassword returns the --password flag, or the first line of stdin.
+func readPassword(cmd *cobra.Command) (string, error) {
+	if userPassword != "" {
+		return userPassword, nil
+	}
+
+	fmt.Fprint(cmd.ErrOrStderr(), "Password: ")
+	line, err := bufio.NewReader(cmd.InOrStdin()).ReadString('\n')
+	if err != nil && line == "" {
+		return "", fmt.Errorf("read password: %w", err)
+	}
+	return strings.TrimRight(line, "\r\n"), nil
+}
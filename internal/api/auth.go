@@ -0,0 +1,444 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/rs/zerolog"
+
+	"github.com/mangashelf/mangashelf/internal/auth"
+	"github.com/mangashelf/mangashelf/internal/library"
+)
+
+// sessionCookie holds the session token of a logged-in browser.
+const sessionCookie = "mangashelf_session"
+
+// publicPaths are served without credentials.
+var publicPaths = map[string]bool{
+	"/api/health":     true,
+	"/api/auth/login": true,
+}
+
+// authenticate rejects requests without valid credentials and stores the
+// authenticated user and their visibility restrictions in the request
+// context. Credentials are read from, in order: an API key in
+// "Authorization: Bearer" or "X-Api-Key", Basic credentials with a password
+// or API key, and the session cookie. With auth disabled, every request is
+// made as the first admin.
+func authenticate(log zerolog.Logger, users *auth.Service, opts Options) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			// The KOReader sync server authenticates its own requests.
+			if publicPaths[req.URL.Path] || strings.HasPrefix(req.URL.Path, "/kosync/") {
+				next.ServeHTTP(w, req)
+				return
+			}
+
+			var user *auth.User
+			var err error
+			if opts.DisableAuth {
+				user, err = users.DefaultUser(req.Context())
+			} else {
+				user, err = authenticateRequest(req, users, opts.CORS)
+			}
+			if err != nil {
+				w.Header().Set("WWW-Authenticate", challenge(req))
+				writeServiceError(w, req, log, err, "UNAUTHENTICATED", "authentication required")
+				return
+			}
+
+			ctx := auth.WithUser(req.Context(), user)
+			ctx = library.WithRestrictions(ctx, user.Restrictions)
+			next.ServeHTTP(w, req.WithContext(ctx))
+		})
+	}
+}
+
+func authenticateRequest(req *http.Request, users *auth.Service, cors CORSOptions) (*auth.User, error) {
+	ctx := req.Context()
+
+	if key, ok := strings.CutPrefix(req.Header.Get("Authorization"), "Bearer "); ok {
+		return users.AuthenticateAPIKey(ctx, strings.TrimSpace(key))
+	}
+	if key := req.Header.Get("X-Api-Key"); key != "" {
+		return users.AuthenticateAPIKey(ctx, key)
+	}
+
+	if username, password, ok := req.BasicAuth(); ok {
+		if !auth.IsAPIKey(password) {
+			return users.CheckPassword(ctx, username, password)
+		}
+		user, err := users.AuthenticateAPIKey(ctx, password)
+		if err != nil {
+			return nil, err
+		}
+		if !strings.EqualFold(user.Username, username) {
+			return nil, auth.ErrUnauthenticated
+		}
+		return user, nil
+	}
+
+	cookie, err := req.Cookie(sessionCookie)
+	if err != nil {
+		return nil, auth.ErrUnauthenticated
+	}
+	// Cookies are sent with cross-site requests too, so changes must come
+	// from a page served by this host.
+	if !safeMethod(req.Method) && !sameOrigin(req, cors) {
+		return nil, auth.ErrUnauthenticated
+	}
+	session, err := users.AuthenticateSession(ctx, cookie.Value)
+	if err != nil {
+		return nil, err
+	}
+	return session.User, nil
+}
+
+// requireRole rejects requests from users whose role does not grant role.
+func requireRole(role auth.Role) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			if user, ok := auth.UserFromContext(req.Context()); !ok || !user.Role.Allows(role) {
+				writeError(w, http.StatusForbidden, "FORBIDDEN", fmt.Sprintf("%s access required", role))
+				return
+			}
+			next.ServeHTTP(w, req)
+		})
+	}
+}
+
+// currentUser returns the user authenticated for req.
+func currentUser(req *http.Request) *auth.User {
+	user, _ := auth.UserFromContext(req.Context())
+	return user
+}
+
+// registerAuthRoutes adds login, account and user management endpoints.
+func registerAuthRoutes(r chi.Router, log zerolog.Logger, users *auth.Service, opts Options) {
+	r.Post("/api/auth/login", func(w http.ResponseWriter, req *http.Request) {
+		var body struct {
+			Username string `json:"username"`
+			Password string `json:"password"`
+		}
+		if err := json.NewDecoder(req.Body).Decode(&body); err != nil || body.Username == "" {
+			writeError(w, http.StatusBadRequest, "INVALID_BODY", "username and password are required")
+			return
+		}
+
+		session, err := users.Login(req.Context(), body.Username, body.Password, auth.Client{
+			UserAgent: req.UserAgent(),
+			IPAddress: clientIP(req),
+		})
+		if err != nil {
+			writeServiceError(w, req, log, err, "LOGIN_FAILED", "failed to log in")
+			return
+		}
+
+		http.SetCookie(w, &http.Cookie{ //nolint:exhaustruct
+			Name:     sessionCookie,
+			Value:    session.Token,
+			Path:     cookiePath(opts),
+			Expires:  session.ExpiresAt,
+			HttpOnly: true,
+			Secure:   opts.SecureCookies || isHTTPS(req),
+			SameSite: http.SameSiteLaxMode,
+		})
+
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{"data": session.User})
+	})
+
+	r.Post("/api/auth/logout", func(w http.ResponseWriter, req *http.Request) {
+		if cookie, err := req.Cookie(sessionCookie); err == nil {
+			if err := users.Logout(req.Context(), cookie.Value); err != nil {
+				writeServiceError(w, req, log, err, "LOGOUT_FAILED", "failed to log out")
+				return
+			}
+		}
+
+		http.SetCookie(w, &http.Cookie{ //nolint:exhaustruct
+			Name:     sessionCookie,
+			Path:     cookiePath(opts),
+			Expires:  time.Unix(0, 0),
+			MaxAge:   -1,
+			HttpOnly: true,
+			Secure:   opts.SecureCookies || isHTTPS(req),
+			SameSite: http.SameSiteLaxMode,
+		})
+		w.WriteHeader(http.StatusNoContent)
+	})
+
+	r.Get("/api/auth/me", func(w http.ResponseWriter, req *http.Request) {
+		user := currentUser(req)
+
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{"data": user})
+	})
+
+	r.Put("/api/auth/password", func(w http.ResponseWriter, req *http.Request) {
+		user := currentUser(req)
+
+		var body struct {
+			CurrentPassword string `json:"currentPassword"`
+			NewPassword     string `json:"newPassword"`
+		}
+		if err := json.NewDecoder(req.Body).Decode(&body); err != nil {
+			writeError(w, http.StatusBadRequest, "INVALID_BODY", "invalid request body")
+			return
+		}
+
+		keep := ""
+		if cookie, err := req.Cookie(sessionCookie); err == nil {
+			keep = cookie.Value
+		}
+		if err := users.ChangePassword(req.Context(), user.ID, body.CurrentPassword, body.NewPassword, keep); err != nil {
+			writeServiceError(w, req, log, err, "PASSWORD_FAILED", "failed to change password")
+			return
+		}
+
+		w.WriteHeader(http.StatusNoContent)
+	})
+
+	r.Put("/api/auth/kosync", func(w http.ResponseWriter, req *http.Request) {
+		user := currentUser(req)
+
+		var body struct {
+			Password string `json:"password"`
+		}
+		if err := json.NewDecoder(req.Body).Decode(&body); err != nil {
+			writeError(w, http.StatusBadRequest, "INVALID_BODY", "invalid request body")
+			return
+		}
+
+		if err := users.SetSyncPassword(req.Context(), user.ID, body.Password); err != nil {
+			writeServiceError(w, req, log, err, "PASSWORD_FAILED", "failed to set sync password")
+			return
+		}
+
+		w.WriteHeader(http.StatusNoContent)
+	})
+
+	r.Delete("/api/auth/kosync", func(w http.ResponseWriter, req *http.Request) {
+		user := currentUser(req)
+
+		if err := users.ClearSyncPassword(req.Context(), user.ID); err != nil {
+			writeServiceError(w, req, log, err, "PASSWORD_FAILED", "failed to clear sync password")
+			return
+		}
+
+		w.WriteHeader(http.StatusNoContent)
+	})
+
+	r.Get("/api/auth/keys", func(w http.ResponseWriter, req *http.Request) {
+		user := currentUser(req)
+
+		keys, err := users.ListAPIKeys(req.Context(), user.ID)
+		if err != nil {
+			writeServiceError(w, req, log, err, "LIST_FAILED", "failed to list api keys")
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{"data": keys})
+	})
+
+	r.Post("/api/auth/keys", func(w http.ResponseWriter, req *http.Request) {
+		user := currentUser(req)
+
+		var body struct {
+			Name string `json:"name"`
+		}
+		if err := json.NewDecoder(req.Body).Decode(&body); err != nil {
+			writeError(w, http.StatusBadRequest, "INVALID_BODY", "invalid request body")
+			return
+		}
+
+		key, err := users.CreateAPIKey(req.Context(), user.ID, body.Name)
+		if err != nil {
+			writeServiceError(w, req, log, err, "CREATE_FAILED", "failed to create api key")
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{"data": key})
+	})
+
+	r.Delete("/api/auth/keys/{id}", func(w http.ResponseWriter, req *http.Request) {
+		user := currentUser(req)
+
+		id, err := idParam(req)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, "INVALID_ID", "invalid api key ID")
+			return
+		}
+
+		if err := users.DeleteAPIKey(req.Context(), user.ID, id); err != nil {
+			writeServiceError(w, req, log, err, "DELETE_FAILED", "failed to delete api key")
+			return
+		}
+
+		w.WriteHeader(http.StatusNoContent)
+	})
+
+	r.Group(func(r chi.Router) {
+		r.Use(requireRole(auth.RoleAdmin))
+
+		r.Get("/api/users", func(w http.ResponseWriter, req *http.Request) {
+			list, err := users.ListUsers(req.Context())
+			if err != nil {
+				writeServiceError(w, req, log, err, "LIST_FAILED", "failed to list users")
+				return
+			}
+
+			w.Header().Set("Content-Type", "application/json")
+			_ = json.NewEncoder(w).Encode(map[string]interface{}{"data": list})
+		})
+
+		r.Post("/api/users", func(w http.ResponseWriter, req *http.Request) {
+			var body auth.CreateUserRequest
+			if err := json.NewDecoder(req.Body).Decode(&body); err != nil {
+				writeError(w, http.StatusBadRequest, "INVALID_BODY", "invalid request body")
+				return
+			}
+
+			user, err := users.CreateUser(req.Context(), body)
+			if err != nil {
+				writeServiceError(w, req, log, err, "CREATE_FAILED", "failed to create user")
+				return
+			}
+
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusCreated)
+			_ = json.NewEncoder(w).Encode(map[string]interface{}{"data": user})
+		})
+
+		r.Patch("/api/users/{id}", func(w http.ResponseWriter, req *http.Request) {
+			id, err := idParam(req)
+			if err != nil {
+				writeError(w, http.StatusBadRequest, "INVALID_ID", "invalid user ID")
+				return
+			}
+
+			var body auth.UpdateUserRequest
+			if err := json.NewDecoder(req.Body).Decode(&body); err != nil {
+				writeError(w, http.StatusBadRequest, "INVALID_BODY", "invalid request body")
+				return
+			}
+
+			user, err := users.UpdateUser(req.Context(), id, body)
+			if err != nil {
+				writeServiceError(w, req, log, err, "UPDATE_FAILED", "failed to update user")
+				return
+			}
+
+			w.Header().Set("Content-Type", "application/json")
+			_ = json.NewEncoder(w).Encode(map[string]interface{}{"data": user})
+		})
+
+		r.Put("/api/users/{id}/password", func(w http.ResponseWriter, req *http.Request) {
+			id, err := idParam(req)
+			if err != nil {
+				writeError(w, http.StatusBadRequest, "INVALID_ID", "invalid user ID")
+				return
+			}
+
+			var body struct {
+				Password string `json:"password"`
+			}
+			if err := json.NewDecoder(req.Body).Decode(&body); err != nil {
+				writeError(w, http.StatusBadRequest, "INVALID_BODY", "invalid request body")
+				return
+			}
+
+			if err := users.SetPassword(req.Context(), id, body.Password); err != nil {
+				writeServiceError(w, req, log, err, "PASSWORD_FAILED", "failed to set password")
+				return
+			}
+
+			w.WriteHeader(http.StatusNoContent)
+		})
+
+		r.Delete("/api/users/{id}", func(w http.ResponseWriter, req *http.Request) {
+			id, err := idParam(req)
+			if err != nil {
+				writeError(w, http.StatusBadRequest, "INVALID_ID", "invalid user ID")
+				return
+			}
+
+			if err := users.DeleteUser(req.Context(), id); err != nil {
+				writeServiceError(w, req, log, err, "DELETE_FAILED", "failed to delete user")
+				return
+			}
+
+			w.WriteHeader(http.StatusNoContent)
+		})
+	})
+}
+
+// challenge returns the WWW-Authenticate header for a rejected request.
+// E-reader apps only prompt for credentials on a Basic challenge, so the
+// catalog and the Komga API ask for Basic credentials: a password, or an API
+// key as the password.
+func challenge(req *http.Request) string {
+	if req.URL.Path == "/opds" || strings.HasPrefix(req.URL.Path, "/opds/") || strings.HasPrefix(req.URL.Path, "/komga/") {
+		return `Basic realm="MangaShelf", charset="UTF-8"`
+	}
+	return `Bearer realm="MangaShelf"`
+}
+
+// safeMethod reports whether method does not change state.
+func safeMethod(method string) bool {
+	return method == http.MethodGet || method == http.MethodHead || method == http.MethodOptions
+}
+
+// sameOrigin reports whether a request was sent by a page on this host, or
+// by an origin allowed to send credentials through CORS. The Origin header
+// is preferred, with Referer as a fallback for older clients; requests
+// carrying neither come from non-browser clients and are allowed.
+func sameOrigin(req *http.Request, c CORSOptions) bool {
+	origin := req.Header.Get("Origin")
+	if c.AllowCredentials && c.allows(origin) {
+		return true
+	}
+	if origin == "" {
+		origin = req.Header.Get("Referer")
+	}
+	if origin == "" {
+		return true
+	}
+
+	u, err := url.Parse(origin)
+	if err != nil {
+		return false
+	}
+	return strings.EqualFold(u.Host, req.Host)
+}
+
+// isHTTPS reports whether the client connected over HTTPS, directly or
+// through a trusted proxy.
+func isHTTPS(req *http.Request) bool {
+	return req.TLS != nil || req.URL.Scheme == "https"
+}
+
+// cookiePath scopes the session cookie to the base URL.
+func cookiePath(opts Options) string {
+	if base := normalizeBaseURL(opts.BaseURL); base != "" {
+		return base
+	}
+	return "/"
+}
+
+// clientIP returns the request's remote address without the port.
+func clientIP(req *http.Request) string {
+	if host, _, err := net.SplitHostPort(req.RemoteAddr); err == nil {
+		return host
+	}
+	return req.RemoteAddr
+}
@@ -0,0 +1,98 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"github.com/rs/zerolog"
+
+	"github.com/mangashelf/mangashelf/internal/auth"
+	"github.com/mangashelf/mangashelf/internal/library"
+	"github.com/mangashelf/mangashelf/internal/notify"
+	"github.com/mangashelf/mangashelf/internal/scraper"
+	"github.com/mangashelf/mangashelf/internal/webhook"
+)
+
+// errorMapping is the response sent for a service error.
+type errorMapping struct {
+	err     error
+	status  int
+	code    string
+	message string
+}
+
+// errorMappings translates auth, library, scraper, webhook and notification
+// errors into responses. They are matched with errors.Is, in order.
+var errorMappings = []errorMapping{
+	{auth.ErrUnauthenticated, http.StatusUnauthorized, "UNAUTHENTICATED", "authentication required"},
+	{auth.ErrInvalidCredentials, http.StatusUnauthorized, "INVALID_CREDENTIALS", "invalid username or password"},
+
+	{library.ErrMangaNotFound, http.StatusNotFound, "NOT_FOUND", "manga not found"},
+	{library.ErrChapterNotFound, http.StatusNotFound, "CHAPTER_NOT_FOUND", "chapter not found"},
+	{library.ErrChapterNotDownloaded, http.StatusNotFound, "CHAPTER_NOT_DOWNLOADED", "chapter has not been downloaded"},
+	{library.ErrPageNotFound, http.StatusNotFound, "PAGE_NOT_FOUND", "page not found"},
+	{library.ErrCoverNotFound, http.StatusNotFound, "COVER_NOT_FOUND", "manga has no cover"},
+	{scraper.ErrProviderNotFound, http.StatusBadRequest, "UNKNOWN_SOURCE", "source not found"},
+	{scraper.ErrMangaNotFound, http.StatusNotFound, "SOURCE_MANGA_NOT_FOUND", "manga not found on source"},
+	{scraper.ErrChapterNotFound, http.StatusNotFound, "SOURCE_CHAPTER_NOT_FOUND", "chapter not found on source"},
+	{auth.ErrUserNotFound, http.StatusNotFound, "USER_NOT_FOUND", "user not found"},
+	{auth.ErrAPIKeyNotFound, http.StatusNotFound, "API_KEY_NOT_FOUND", "api key not found"},
+	{webhook.ErrWebhookNotFound, http.StatusNotFound, "WEBHOOK_NOT_FOUND", "webhook not found"},
+	{webhook.ErrDeliveryNotFound, http.StatusNotFound, "DELIVERY_NOT_FOUND", "delivery not found"},
+
+	{library.ErrMangaExists, http.StatusConflict, "MANGA_EXISTS", "manga already exists in library"},
+	{library.ErrSlugConflict, http.StatusConflict, "SLUG_CONFLICT", "no free slug for this title"},
+	{auth.ErrUserExists, http.StatusConflict, "USER_EXISTS", "username is already taken"},
+	{auth.ErrLastAdmin, http.StatusConflict, "LAST_ADMIN", "cannot remove the last admin"},
+
+	{library.ErrInvalidCoverSize, http.StatusBadRequest, "INVALID_SIZE", "size must be small, medium or large"},
+	{library.ErrInvalidImage, http.StatusBadRequest, "INVALID_IMAGE", "cover must be a JPEG, PNG, GIF or WebP image"},
+	{library.ErrImageTooLarge, http.StatusRequestEntityTooLarge, "IMAGE_TOO_LARGE", "cover has too many pixels"},
+	{library.ErrUnknownCover, http.StatusBadRequest, "UNKNOWN_COVER", "cover is not offered by the source"},
+	{scraper.ErrUnsupportedURL, http.StatusBadRequest, "UNSUPPORTED_URL", "url does not belong to a supported source"},
+	{scraper.ErrNotSupported, http.StatusBadRequest, "NOT_SUPPORTED", "source does not support this operation"},
+	{auth.ErrInvalidUsername, http.StatusBadRequest, "INVALID_USERNAME", "username may only contain letters, digits and . _ - @"},
+	{auth.ErrInvalidPassword, http.StatusBadRequest, "INVALID_PASSWORD", "password must be 8 to 72 characters"},
+	{auth.ErrInvalidRole, http.StatusBadRequest, "INVALID_ROLE", "role must be admin, member or readonly"},
+	{library.ErrInvalidContentRating, http.StatusBadRequest, "INVALID_CONTENT_RATING", "content rating must be safe, suggestive, erotica or pornographic"},
+	{webhook.ErrInvalidURL, http.StatusBadRequest, "INVALID_URL", "url must be an absolute http or https URL"},
+	{webhook.ErrInvalidEvent, http.StatusBadRequest, "INVALID_EVENT", "unknown event type"},
+	{notify.ErrInvalidEvent, http.StatusBadRequest, "INVALID_EVENT", "event must be chapter.new, download.completed, download.failed or digest"},
+	{notify.ErrNotConfigured, http.StatusBadRequest, "NOT_CONFIGURED", "no notification urls are configured"},
+	{webhook.ErrInvalidHeader, http.StatusBadRequest, "INVALID_HEADER", "headers must have valid names and values and may not replace Content-Type, Host or X-MangaShelf-* headers"},
+
+	{scraper.ErrRateLimited, http.StatusTooManyRequests, "RATE_LIMITED", "source rate limit reached, try again later"},
+	{scraper.ErrSourceUnavailable, http.StatusServiceUnavailable, "SOURCE_UNAVAILABLE", "source is unavailable"},
+	{context.DeadlineExceeded, http.StatusServiceUnavailable, "SOURCE_UNAVAILABLE", "source did not respond in time"},
+}
+
+// writeServiceError responds to a failed service call. Known errors get
+// their mapped status and code, other source failures a 502, and anything
+// else a 500 with the given code and message. Server-side failures are logged.
+func writeServiceError(w http.ResponseWriter, req *http.Request, log zerolog.Logger, err error, code, message string) {
+	status := http.StatusInternalServerError
+	var sourceErr *scraper.SourceError
+
+	switch mapping, ok := mapError(err); {
+	case ok:
+		status, code, message = mapping.status, mapping.code, mapping.message
+	case errors.As(err, &sourceErr):
+		status, code, message = http.StatusBadGateway, "SOURCE_ERROR", "source returned an invalid response"
+	}
+
+	if status >= http.StatusInternalServerError {
+		log.Error().Err(err).Str("path", req.URL.Path).Int("status", status).Msg(message)
+	}
+	writeError(w, status, code, message)
+}
+
+// mapError finds the mapping for err.
+func mapError(err error) (errorMapping, bool) {
+	for _, m := range errorMappings {
+		if errors.Is(err, m.err) {
+			return m, true
+		}
+	}
+	return errorMapping{}, false
+}
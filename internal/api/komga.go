@@ -0,0 +1,783 @@
+package api
+
+import (
+	"bytes"
+	"cmp"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/rs/zerolog"
+
+	"github.com/mangashelf/mangashelf/internal/auth"
+	"github.com/mangashelf/mangashelf/internal/database"
+	"github.com/mangashelf/mangashelf/internal/komga"
+	"github.com/mangashelf/mangashelf/internal/kosync"
+	"github.com/mangashelf/mangashelf/internal/library"
+)
+
+const (
+	// komgaLibraryID identifies the single Komga library holding every manga.
+	komgaLibraryID = "1"
+
+	// komgaPageSize is the number of results per page when a client does
+	// not ask for a size.
+	komgaPageSize = 20
+
+	// komgaThumbnailWidth is the width of book and page thumbnails.
+	komgaThumbnailWidth = 300
+)
+
+// registerKomgaRoutes serves the subset of Komga's REST API used by reader
+// apps such as Mihon under /komga/api/v1. Series are manga with downloaded
+// chapters, books are those chapters, and the whole library appears as a
+// single Komga library.
+func registerKomgaRoutes(r chi.Router, log zerolog.Logger, lib *library.Service, sync *kosync.Service) {
+	// shelf lists the manga that have books.
+	shelf := func(w http.ResponseWriter, req *http.Request) ([]*library.ShelfEntry, bool) {
+		entries, err := lib.ListShelf(req.Context(), currentUser(req).ID)
+		if err != nil {
+			writeServiceError(w, req, log, err, "LIST_FAILED", "failed to list manga")
+			return nil, false
+		}
+		return slices.DeleteFunc(entries, func(e *library.ShelfEntry) bool { return !e.Downloaded }), true
+	}
+
+	// books lists a manga's downloaded chapters in reading order.
+	books := func(w http.ResponseWriter, req *http.Request, mangaID int64) (*database.Manga, []*database.ListChaptersWithProgressRow, bool) {
+		manga, err := lib.GetManga(req.Context(), mangaID)
+		if err != nil {
+			writeServiceError(w, req, log, err, "GET_FAILED", "failed to get manga")
+			return nil, nil, false
+		}
+		chapters, err := lib.ListChapters(req.Context(), currentUser(req).ID, mangaID)
+		if err != nil {
+			writeServiceError(w, req, log, err, "LIST_CHAPTERS_FAILED", "failed to list chapters")
+			return nil, nil, false
+		}
+		chapters = slices.DeleteFunc(chapters, func(ch *database.ListChaptersWithProgressRow) bool {
+			return ch.Status.String != "completed"
+		})
+		slices.Reverse(chapters)
+		return manga, chapters, true
+	}
+
+	// book finds the requested book among the books of its series and
+	// returns them with its index.
+	book := func(w http.ResponseWriter, req *http.Request) (*database.Manga, []*database.ListChaptersWithProgressRow, int, bool) {
+		id, err := idParam(req)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, "INVALID_ID", "invalid book ID")
+			return nil, nil, 0, false
+		}
+		chapter, err := lib.GetChapter(req.Context(), id)
+		if err != nil {
+			writeServiceError(w, req, log, err, "GET_FAILED", "failed to get book")
+			return nil, nil, 0, false
+		}
+		manga, chapters, ok := books(w, req, chapter.MangaID)
+		if !ok {
+			return nil, nil, 0, false
+		}
+		i := slices.IndexFunc(chapters, func(ch *database.ListChaptersWithProgressRow) bool { return ch.ID == id })
+		if i < 0 {
+			writeServiceError(w, req, log, library.ErrChapterNotDownloaded, "GET_FAILED", "failed to get book")
+			return nil, nil, 0, false
+		}
+		return manga, chapters, i, true
+	}
+
+	listSeries := func(defaultSort string) http.HandlerFunc {
+		return func(w http.ResponseWriter, req *http.Request) {
+			entries, ok := shelf(w, req)
+			if !ok {
+				return
+			}
+			query := req.URL.Query()
+			entries = slices.DeleteFunc(entries, func(e *library.ShelfEntry) bool { return !komgaSeriesMatches(e, query) })
+
+			series := make([]komga.Series, 0, len(entries))
+			for _, e := range entries {
+				series = append(series, komgaSeries(e))
+			}
+			field, desc := komgaSort(req, defaultSort)
+			slices.SortStableFunc(series, sortOrder(compareKomgaSeries(field), desc))
+			writeKomgaPage(w, req, series, true)
+		}
+	}
+
+	r.Route("/komga/api/v1", func(r chi.Router) {
+		r.Get("/libraries", func(w http.ResponseWriter, _ *http.Request) {
+			writeKomgaJSON(w, []komga.Library{komgaLibrary()})
+		})
+
+		r.Get("/libraries/{libraryID}", func(w http.ResponseWriter, req *http.Request) {
+			if chi.URLParam(req, "libraryID") != komgaLibraryID {
+				writeError(w, http.StatusNotFound, "NOT_FOUND", "library not found")
+				return
+			}
+			writeKomgaJSON(w, komgaLibrary())
+		})
+
+		r.Get("/series", listSeries("metadata.titleSort,asc"))
+		r.Get("/series/new", listSeries("created,desc"))
+		r.Get("/series/updated", listSeries("lastModified,desc"))
+		r.Get("/series/latest", listSeries("lastModified,desc"))
+
+		r.Get("/series/{id}", func(w http.ResponseWriter, req *http.Request) {
+			id, err := idParam(req)
+			if err != nil {
+				writeError(w, http.StatusBadRequest, "INVALID_ID", "invalid series ID")
+				return
+			}
+			entry, err := lib.GetShelfEntry(req.Context(), currentUser(req).ID, id)
+			if err != nil {
+				writeServiceError(w, req, log, err, "GET_FAILED", "failed to get manga")
+				return
+			}
+			writeKomgaJSON(w, komgaSeries(entry))
+		})
+
+		r.Get("/series/{id}/thumbnail", func(w http.ResponseWriter, req *http.Request) {
+			id, err := idParam(req)
+			if err != nil {
+				writeError(w, http.StatusBadRequest, "INVALID_ID", "invalid series ID")
+				return
+			}
+			path, err := lib.CoverFile(req.Context(), id, "medium")
+			if err != nil {
+				writeServiceError(w, req, log, err, "COVER_FAILED", "failed to get cover")
+				return
+			}
+			w.Header().Set("Cache-Control", "private, max-age=86400, must-revalidate")
+			http.ServeFile(w, req, path)
+		})
+
+		r.Get("/series/{id}/books", func(w http.ResponseWriter, req *http.Request) {
+			id, err := idParam(req)
+			if err != nil {
+				writeError(w, http.StatusBadRequest, "INVALID_ID", "invalid series ID")
+				return
+			}
+			manga, chapters, ok := books(w, req, id)
+			if !ok {
+				return
+			}
+
+			statuses := queryList(req.URL.Query(), "read_status")
+			result := make([]komga.Book, 0, len(chapters))
+			for i, ch := range chapters {
+				b := komgaBook(manga, ch, i+1)
+				if len(statuses) == 0 || slices.Contains(statuses, komgaBookReadStatus(b)) {
+					result = append(result, b)
+				}
+			}
+			field, desc := komgaSort(req, "metadata.numberSort,asc")
+			slices.SortStableFunc(result, sortOrder(compareKomgaBooks(field), desc))
+			writeKomgaPage(w, req, result, true)
+		})
+
+		markSeries := func(read bool) http.HandlerFunc {
+			return func(w http.ResponseWriter, req *http.Request) {
+				id, err := idParam(req)
+				if err != nil {
+					writeError(w, http.StatusBadRequest, "INVALID_ID", "invalid series ID")
+					return
+				}
+				if _, err := lib.MarkRead(req.Context(), currentUser(req).ID, id, library.MarkReadRequest{Read: read}); err != nil {
+					writeServiceError(w, req, log, err, "PROGRESS_FAILED", "failed to update progress")
+					return
+				}
+				w.WriteHeader(http.StatusNoContent)
+			}
+		}
+		r.Post("/series/{id}/read-progress", markSeries(true))
+		r.Delete("/series/{id}/read-progress", markSeries(false))
+
+		r.Get("/books/{id}", func(w http.ResponseWriter, req *http.Request) {
+			manga, chapters, i, ok := book(w, req)
+			if !ok {
+				return
+			}
+			writeKomgaJSON(w, komgaBook(manga, chapters[i], i+1))
+		})
+
+		sibling := func(offset int) http.HandlerFunc {
+			return func(w http.ResponseWriter, req *http.Request) {
+				manga, chapters, i, ok := book(w, req)
+				if !ok {
+					return
+				}
+				i += offset
+				if i < 0 || i >= len(chapters) {
+					writeError(w, http.StatusNotFound, "CHAPTER_NOT_FOUND", "no more books in this series")
+					return
+				}
+				writeKomgaJSON(w, komgaBook(manga, chapters[i], i+1))
+			}
+		}
+		r.Get("/books/{id}/next", sibling(1))
+		r.Get("/books/{id}/previous", sibling(-1))
+
+		r.Get("/books/{id}/file", func(w http.ResponseWriter, req *http.Request) {
+			id, err := idParam(req)
+			if err != nil {
+				writeError(w, http.StatusBadRequest, "INVALID_ID", "invalid book ID")
+				return
+			}
+			chapter, filePath, err := lib.ChapterFile(req.Context(), id)
+			if err != nil {
+				writeServiceError(w, req, log, err, "DOWNLOAD_FAILED", "failed to get chapter file")
+				return
+			}
+			manga, err := lib.GetManga(req.Context(), chapter.MangaID)
+			if err != nil {
+				writeServiceError(w, req, log, err, "DOWNLOAD_FAILED", "failed to get chapter file")
+				return
+			}
+			name := chapterFileName(manga, chapter, ".cbz")
+			w.Header().Set("Content-Type", "application/zip")
+			w.Header().Set("Content-Disposition", attachment(name))
+			http.ServeFile(w, req, filePath)
+			recordFile(req, log, sync, chapter.ID, filePath, name)
+		})
+
+		r.Get("/books/{id}/thumbnail", func(w http.ResponseWriter, req *http.Request) {
+			id, err := idParam(req)
+			if err != nil {
+				writeError(w, http.StatusBadRequest, "INVALID_ID", "invalid book ID")
+				return
+			}
+			page, err := lib.ChapterPage(req.Context(), id, 1, komgaThumbnailWidth)
+			if err != nil {
+				writeServiceError(w, req, log, err, "PAGE_FAILED", "failed to get page")
+				return
+			}
+			writePage(w, req, page)
+		})
+
+		r.Get("/books/{id}/pages", func(w http.ResponseWriter, req *http.Request) {
+			id, err := idParam(req)
+			if err != nil {
+				writeError(w, http.StatusBadRequest, "INVALID_ID", "invalid book ID")
+				return
+			}
+			pages, err := lib.ChapterPages(req.Context(), id)
+			if err != nil {
+				writeServiceError(w, req, log, err, "PAGE_FAILED", "failed to list pages")
+				return
+			}
+
+			result := make([]komga.Page, 0, len(pages))
+			for _, p := range pages {
+				page := komga.Page{
+					Number:    p.Number,
+					FileName:  p.FileName,
+					MediaType: p.ContentType,
+					SizeBytes: p.Size,
+					Size:      formatSize(p.Size),
+				}
+				if p.Width > 0 && p.Height > 0 {
+					page.Width, page.Height = &p.Width, &p.Height
+				}
+				result = append(result, page)
+			}
+			writeKomgaJSON(w, result)
+		})
+
+		// Pages are numbered from 1 unless the client asks for zero_based.
+		// Clients report progress themselves, so fetching a page does not.
+		servePage := func(maxWidth int) http.HandlerFunc {
+			return func(w http.ResponseWriter, req *http.Request) {
+				id, err := idParam(req)
+				if err != nil {
+					writeError(w, http.StatusBadRequest, "INVALID_ID", "invalid book ID")
+					return
+				}
+				number, err := strconv.Atoi(chi.URLParam(req, "page"))
+				if err != nil {
+					writeError(w, http.StatusBadRequest, "INVALID_PAGE", "invalid page number")
+					return
+				}
+				if req.URL.Query().Get("zero_based") == "true" {
+					number++
+				}
+
+				page, err := lib.ChapterPage(req.Context(), id, number, maxWidth)
+				if err != nil {
+					writeServiceError(w, req, log, err, "PAGE_FAILED", "failed to get page")
+					return
+				}
+				writePage(w, req, page)
+			}
+		}
+		r.Get("/books/{id}/pages/{page}", servePage(0))
+		r.Get("/books/{id}/pages/{page}/thumbnail", servePage(komgaThumbnailWidth))
+
+		// Setting a page marks the book read when it is the last page and
+		// unread otherwise, as Komga does; completed overrides that.
+		r.Patch("/books/{id}/read-progress", func(w http.ResponseWriter, req *http.Request) {
+			id, err := idParam(req)
+			if err != nil {
+				writeError(w, http.StatusBadRequest, "INVALID_ID", "invalid book ID")
+				return
+			}
+			var body komga.ReadProgressUpdate
+			if err := json.NewDecoder(req.Body).Decode(&body); err != nil {
+				writeError(w, http.StatusBadRequest, "INVALID_BODY", "invalid request body")
+				return
+			}
+
+			var update library.ProgressUpdate
+			if body.Page != nil {
+				chapter, err := lib.GetChapter(req.Context(), id)
+				if err != nil {
+					writeServiceError(w, req, log, err, "PROGRESS_FAILED", "failed to update progress")
+					return
+				}
+				current := int64(max(*body.Page, 0))
+				pages := library.CountPages(chapter.PageCount.Int64, chapter.FilePath.String)
+				read := pages > 0 && int(current) >= pages
+				update.CurrentPage, update.Read = &current, &read
+			}
+			if body.Completed != nil {
+				update.Read = body.Completed
+			}
+
+			if _, err := lib.UpdateProgress(req.Context(), currentUser(req).ID, id, update); err != nil {
+				writeServiceError(w, req, log, err, "PROGRESS_FAILED", "failed to update progress")
+				return
+			}
+			w.WriteHeader(http.StatusNoContent)
+		})
+
+		r.Delete("/books/{id}/read-progress", func(w http.ResponseWriter, req *http.Request) {
+			id, err := idParam(req)
+			if err != nil {
+				writeError(w, http.StatusBadRequest, "INVALID_ID", "invalid book ID")
+				return
+			}
+			if err := lib.ResetProgress(req.Context(), currentUser(req).ID, id); err != nil {
+				writeServiceError(w, req, log, err, "PROGRESS_FAILED", "failed to reset progress")
+				return
+			}
+			w.WriteHeader(http.StatusNoContent)
+		})
+
+		// Filter values offered by the client's browse screen.
+		r.Get("/genres", func(w http.ResponseWriter, req *http.Request) {
+			entries, ok := shelf(w, req)
+			if !ok {
+				return
+			}
+			var genres []string
+			for _, e := range entries {
+				genres = append(genres, library.StringList(e.Genres)...)
+			}
+			writeKomgaJSON(w, uniqueSorted(genres))
+		})
+
+		r.Get("/tags", func(w http.ResponseWriter, req *http.Request) {
+			entries, ok := shelf(w, req)
+			if !ok {
+				return
+			}
+			var tags []string
+			for _, e := range entries {
+				tags = append(tags, library.StringList(e.Tags)...)
+			}
+			writeKomgaJSON(w, uniqueSorted(tags))
+		})
+
+		r.Get("/authors", func(w http.ResponseWriter, req *http.Request) {
+			entries, ok := shelf(w, req)
+			if !ok {
+				return
+			}
+			authors := []komga.Author{}
+			for _, e := range entries {
+				for _, a := range komgaAuthors(e.Manga) {
+					if !slices.Contains(authors, a) {
+						authors = append(authors, a)
+					}
+				}
+			}
+			slices.SortFunc(authors, func(a, b komga.Author) int {
+				return cmp.Or(strings.Compare(strings.ToLower(a.Name), strings.ToLower(b.Name)), strings.Compare(a.Role, b.Role))
+			})
+			writeKomgaJSON(w, authors)
+		})
+
+		r.Get("/publishers", func(w http.ResponseWriter, _ *http.Request) {
+			writeKomgaJSON(w, []string{})
+		})
+
+		// MangaShelf has no collections or read lists.
+		r.Get("/collections", func(w http.ResponseWriter, req *http.Request) {
+			writeKomgaPage(w, req, []any{}, false)
+		})
+		r.Get("/readlists", func(w http.ResponseWriter, req *http.Request) {
+			writeKomgaPage(w, req, []any{}, false)
+		})
+
+		r.Get("/users/me", func(w http.ResponseWriter, req *http.Request) {
+			writeKomgaJSON(w, komgaUser(currentUser(req)))
+		})
+	})
+
+	// Newer clients check the signed-in user through version 2 of the API.
+	r.Get("/komga/api/v2/users/me", func(w http.ResponseWriter, req *http.Request) {
+		writeKomgaJSON(w, komgaUser(currentUser(req)))
+	})
+}
+
+func komgaLibrary() komga.Library {
+	return komga.Library{ID: komgaLibraryID, Name: "MangaShelf", Root: "/"}
+}
+
+func komgaUser(u *auth.User) komga.User {
+	roles := []string{"USER", "FILE_DOWNLOAD", "PAGE_STREAMING"}
+	if u.Role == auth.RoleAdmin {
+		roles = append([]string{"ADMIN"}, roles...)
+	}
+	return komga.User{
+		ID:                 strconv.FormatInt(u.ID, 10),
+		Email:              u.Username,
+		Roles:              roles,
+		SharedAllLibraries: true,
+		SharedLibrariesIDs: []string{},
+		LabelsAllow:        []string{},
+		LabelsExclude:      []string{},
+	}
+}
+
+// komgaSeries converts a manga with the user's reading state.
+func komgaSeries(e *library.ShelfEntry) komga.Series {
+	created := parseSQLiteTime(e.CreatedAt.String)
+	modified := parseSQLiteTime(cmp.Or(e.LastDownloadedAt, e.UpdatedAt.String))
+
+	var language string
+	if languages := library.StringList(e.Languages); len(languages) > 0 {
+		language = languages[0]
+	}
+
+	return komga.Series{
+		ID:                   strconv.FormatInt(e.ID, 10),
+		LibraryID:            komgaLibraryID,
+		Name:                 e.Title,
+		URL:                  e.Url,
+		Created:              created,
+		LastModified:         modified,
+		FileLastModified:     modified,
+		BooksCount:           int(e.ChapterCount),
+		BooksReadCount:       int(e.ChapterCount - e.UnreadCount),
+		BooksUnreadCount:     int(e.UnreadCount - e.InProgressCount),
+		BooksInProgressCount: int(e.InProgressCount),
+		Metadata: komga.SeriesMetadata{
+			Status:          komgaStatus(e.Status.String),
+			Title:           e.Title,
+			TitleSort:       e.Title,
+			Summary:         e.Description.String,
+			AgeRating:       komgaAgeRating(e.ContentRating.String),
+			Language:        language,
+			Genres:          orEmpty(library.StringList(e.Genres)),
+			Tags:            orEmpty(library.StringList(e.Tags)),
+			SharingLabels:   []string{},
+			Links:           []komga.WebLink{{Label: e.Source, URL: e.Url}},
+			AlternateTitles: []komga.AltTitle{},
+			Created:         created,
+			LastModified:    parseSQLiteTime(e.UpdatedAt.String),
+		},
+		BooksMetadata: komga.BookMetadataAggregation{
+			Authors:      komgaAuthors(e.Manga),
+			Tags:         []string{},
+			Created:      created,
+			LastModified: modified,
+		},
+	}
+}
+
+// komgaBook converts a downloaded chapter at position number in its series.
+func komgaBook(m *database.Manga, ch *database.ListChaptersWithProgressRow, number int) komga.Book {
+	downloaded := parseSQLiteTime(ch.DownloadedAt.String)
+	pages := library.CountPages(ch.PageCount.Int64, ch.FilePath.String)
+	title := chapterTitle(ch.Number, ch.Volume.String, ch.Title)
+
+	b := komga.Book{
+		ID:               strconv.FormatInt(ch.ID, 10),
+		SeriesID:         strconv.FormatInt(m.ID, 10),
+		SeriesTitle:      m.Title,
+		LibraryID:        komgaLibraryID,
+		Name:             title,
+		URL:              ch.FilePath.String,
+		Number:           number,
+		Created:          parseSQLiteTime(ch.CreatedAt.String),
+		LastModified:     downloaded,
+		FileLastModified: downloaded,
+		SizeBytes:        ch.FileSize.Int64,
+		Size:             formatSize(ch.FileSize.Int64),
+		Media: komga.Media{
+			Status:       "READY",
+			MediaType:    "application/zip",
+			MediaProfile: "DIVINA",
+			PagesCount:   pages,
+		},
+		Metadata: komga.BookMetadata{
+			Title:        title,
+			Number:       strconv.FormatFloat(ch.Number, 'f', -1, 64),
+			NumberSort:   ch.Number,
+			Authors:      komgaAuthors(m),
+			Tags:         []string{},
+			Links:        []komga.WebLink{{Label: m.Source, URL: ch.Url}},
+			Created:      downloaded,
+			LastModified: downloaded,
+		},
+	}
+	if ch.PublishedAt.Valid {
+		released := parseSQLiteTime(ch.PublishedAt.String).Format(time.DateOnly)
+		b.Metadata.ReleaseDate = &released
+	}
+
+	read := ch.IsRead.Int64 == 1
+	if read || ch.CurrentPage.Int64 > 0 {
+		page := int(ch.CurrentPage.Int64)
+		switch {
+		case read && pages > 0:
+			page = pages
+		case pages > 0:
+			page = min(page, pages)
+		}
+		updated := parseSQLiteTime(ch.ProgressUpdatedAt.String)
+		b.ReadProgress = &komga.ReadProgress{
+			Page:         page,
+			Completed:    read,
+			ReadDate:     updated,
+			Created:      updated,
+			LastModified: updated,
+		}
+	}
+	return b
+}
+
+// komgaAuthors credits the manga's author as writer and its artist as
+// penciller.
+func komgaAuthors(m *database.Manga) []komga.Author {
+	authors := []komga.Author{}
+	if m.Author.String != "" {
+		authors = append(authors, komga.Author{Name: m.Author.String, Role: "writer"})
+	}
+	if m.Artist.String != "" {
+		authors = append(authors, komga.Author{Name: m.Artist.String, Role: "penciller"})
+	}
+	return authors
+}
+
+func komgaStatus(status string) string {
+	switch status {
+	case "completed":
+		return komga.StatusEnded
+	case "hiatus":
+		return komga.StatusHiatus
+	case "cancelled":
+		return komga.StatusAbandoned
+	default:
+		return komga.StatusOngoing
+	}
+}
+
+// komgaAgeRating maps a content rating to the minimum age Komga expects.
+func komgaAgeRating(rating string) *int {
+	var age int
+	switch rating {
+	case "suggestive":
+		age = 13
+	case "erotica":
+		age = 16
+	case "pornographic":
+		age = 18
+	default:
+		return nil
+	}
+	return &age
+}
+
+func komgaSeriesReadStatus(e *library.ShelfEntry) string {
+	switch {
+	case e.ChapterCount > 0 && e.UnreadCount == 0:
+		return komga.ReadStatusRead
+	case e.UnreadCount == e.ChapterCount && e.InProgressCount == 0:
+		return komga.ReadStatusUnread
+	default:
+		return komga.ReadStatusInProgress
+	}
+}
+
+func komgaBookReadStatus(b komga.Book) string {
+	switch {
+	case b.ReadProgress == nil:
+		return komga.ReadStatusUnread
+	case b.ReadProgress.Completed:
+		return komga.ReadStatusRead
+	default:
+		return komga.ReadStatusInProgress
+	}
+}
+
+// komgaSeriesMatches applies the series filters of the query: a search term
+// and lists of libraries, genres, tags, statuses and read statuses, each
+// matching any of its values.
+func komgaSeriesMatches(e *library.ShelfEntry, query url.Values) bool {
+	if search := strings.ToLower(strings.TrimSpace(query.Get("search"))); search != "" {
+		if !slices.ContainsFunc([]string{e.Title, e.Author.String, e.Artist.String}, func(field string) bool {
+			return strings.Contains(strings.ToLower(field), search)
+		}) {
+			return false
+		}
+	}
+	if ids := queryList(query, "library_id"); len(ids) > 0 && !slices.Contains(ids, komgaLibraryID) {
+		return false
+	}
+	if !matchesAnyFold(queryList(query, "genre"), library.StringList(e.Genres)) ||
+		!matchesAnyFold(queryList(query, "tag"), library.StringList(e.Tags)) {
+		return false
+	}
+	if statuses := queryList(query, "status"); len(statuses) > 0 && !slices.Contains(statuses, komgaStatus(e.Status.String)) {
+		return false
+	}
+	if statuses := queryList(query, "read_status"); len(statuses) > 0 && !slices.Contains(statuses, komgaSeriesReadStatus(e)) {
+		return false
+	}
+	return true
+}
+
+// matchesAnyFold reports whether values contains any of wanted, ignoring
+// case. An empty wanted list matches everything.
+func matchesAnyFold(wanted, values []string) bool {
+	if len(wanted) == 0 {
+		return true
+	}
+	return slices.ContainsFunc(values, func(v string) bool {
+		return slices.ContainsFunc(wanted, func(w string) bool { return strings.EqualFold(v, w) })
+	})
+}
+
+// queryList returns the values of a list parameter, which clients send
+// repeated or comma-separated.
+func queryList(query url.Values, key string) []string {
+	var values []string
+	for _, v := range query[key] {
+		for _, item := range strings.Split(v, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				values = append(values, item)
+			}
+		}
+	}
+	return values
+}
+
+// komgaSort parses the "sort" parameter, such as "metadata.titleSort,asc",
+// into a field and direction.
+func komgaSort(req *http.Request, fallback string) (field string, desc bool) {
+	field, direction, _ := strings.Cut(cmp.Or(req.URL.Query().Get("sort"), fallback), ",")
+	return field, strings.EqualFold(direction, "desc")
+}
+
+func compareKomgaSeries(field string) func(a, b komga.Series) int {
+	switch field {
+	case "created", "createdDate":
+		return func(a, b komga.Series) int { return a.Created.Compare(b.Created) }
+	case "lastModified", "lastModifiedDate":
+		return func(a, b komga.Series) int { return a.LastModified.Compare(b.LastModified) }
+	case "booksCount":
+		return func(a, b komga.Series) int { return cmp.Compare(a.BooksCount, b.BooksCount) }
+	default:
+		return func(a, b komga.Series) int {
+			return strings.Compare(strings.ToLower(a.Metadata.TitleSort), strings.ToLower(b.Metadata.TitleSort))
+		}
+	}
+}
+
+func compareKomgaBooks(field string) func(a, b komga.Book) int {
+	switch field {
+	case "created", "createdDate":
+		return func(a, b komga.Book) int { return a.Created.Compare(b.Created) }
+	case "lastModified", "lastModifiedDate", "fileLastModified":
+		return func(a, b komga.Book) int { return a.LastModified.Compare(b.LastModified) }
+	default:
+		return func(a, b komga.Book) int { return cmp.Compare(a.Number, b.Number) }
+	}
+}
+
+// sortOrder reverses compare when desc is set.
+func sortOrder[T any](compare func(a, b T) int, desc bool) func(a, b T) int {
+	if !desc {
+		return compare
+	}
+	return func(a, b T) int { return compare(b, a) }
+}
+
+// writeKomgaPage writes the page of items requested by the "page" (from 0),
+// "size" and "unpaged" parameters.
+func writeKomgaPage[T any](w http.ResponseWriter, req *http.Request, items []T, sorted bool) {
+	query := req.URL.Query()
+	number, _ := strconv.Atoi(query.Get("page"))
+	size, err := strconv.Atoi(query.Get("size"))
+	if err != nil || size <= 0 {
+		size = komgaPageSize
+	}
+	if query.Get("unpaged") == "true" {
+		size = 0
+	}
+	writeKomgaJSON(w, komga.NewPage(items, max(number, 0), size, sorted))
+}
+
+// writeKomgaJSON writes a Komga document. Unlike the rest of the API, Komga
+// responses are not wrapped in a data envelope.
+func writeKomgaJSON(w http.ResponseWriter, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+// writePage serves a page image, which clients may cache.
+func writePage(w http.ResponseWriter, req *http.Request, page *library.Page) {
+	w.Header().Set("Content-Type", page.ContentType)
+	w.Header().Set("Cache-Control", "private, max-age=86400")
+	http.ServeContent(w, req, "", page.ModTime, bytes.NewReader(page.Data))
+}
+
+// uniqueSorted removes duplicates from values, ignoring case, and sorts them.
+func uniqueSorted(values []string) []string {
+	slices.SortFunc(values, func(a, b string) int {
+		return cmp.Or(strings.Compare(strings.ToLower(a), strings.ToLower(b)), strings.Compare(a, b))
+	})
+	values = slices.CompactFunc(values, strings.EqualFold)
+	return orEmpty(values)
+}
+
+// formatSize formats a file size in binary units, such as "12.3 MiB".
+func formatSize(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	div, exp := int64(unit), 0
+	for m := n / unit; m >= unit; m /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(n)/float64(div), "KMGTPE"[exp])
+}
+
+// orEmpty returns an empty slice for nil, so it encodes as [] rather than null.
+func orEmpty[T any](s []T) []T {
+	if s == nil {
+		return []T{}
+	}
+	return s
+}
@@ -0,0 +1,174 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/rs/zerolog"
+
+	"github.com/mangashelf/mangashelf/internal/auth"
+	"github.com/mangashelf/mangashelf/internal/kosync"
+	"github.com/mangashelf/mangashelf/internal/library"
+)
+
+// Error codes of the KOReader sync protocol.
+const (
+	kosyncServerError        = 2000
+	kosyncUnauthorized       = 2001
+	kosyncUserExists         = 2002
+	kosyncInvalidRequest     = 2003
+	kosyncDocumentMissing    = 2004
+	kosyncRegistrationClosed = 2005
+)
+
+// registerKOSyncRoutes serves KOReader's progress sync protocol under
+// /kosync. Requests are authenticated with the X-Auth-User and X-Auth-Key
+// headers, the key being the MD5 of the user's sync password.
+func registerKOSyncRoutes(r chi.Router, log zerolog.Logger, users *auth.Service, sync *kosync.Service, opts Options) {
+	r.Route("/kosync", func(r chi.Router) {
+		r.Get("/healthcheck", func(w http.ResponseWriter, req *http.Request) {
+			writeKOSyncJSON(w, http.StatusOK, map[string]string{"state": "OK"})
+		})
+
+		r.Post("/users/create", func(w http.ResponseWriter, req *http.Request) {
+			if !opts.KOSyncRegistration {
+				writeKOSyncError(w, http.StatusPaymentRequired, kosyncRegistrationClosed, "User registration is disabled.")
+				return
+			}
+
+			var body struct {
+				Username string `json:"username"`
+				Password string `json:"password"`
+			}
+			if err := json.NewDecoder(req.Body).Decode(&body); err != nil || body.Username == "" || body.Password == "" {
+				writeKOSyncError(w, http.StatusForbidden, kosyncInvalidRequest, "Invalid request")
+				return
+			}
+
+			user, err := users.RegisterSyncUser(req.Context(), body.Username, body.Password)
+			switch {
+			case errors.Is(err, auth.ErrUserExists):
+				writeKOSyncError(w, http.StatusPaymentRequired, kosyncUserExists, "Username is already registered.")
+				return
+			case errors.Is(err, auth.ErrInvalidUsername), errors.Is(err, auth.ErrInvalidPassword):
+				writeKOSyncError(w, http.StatusForbidden, kosyncInvalidRequest, "Invalid request")
+				return
+			case err != nil:
+				log.Error().Err(err).Msg("failed to register sync user")
+				writeKOSyncError(w, http.StatusInternalServerError, kosyncServerError, "Unknown server error.")
+				return
+			}
+
+			log.Info().Str("username", user.Username).Msg("registered sync user")
+			writeKOSyncJSON(w, http.StatusCreated, map[string]string{"username": user.Username})
+		})
+
+		r.Group(func(r chi.Router) {
+			r.Use(authenticateKOSync(log, users, opts))
+
+			r.Get("/users/auth", func(w http.ResponseWriter, req *http.Request) {
+				writeKOSyncJSON(w, http.StatusOK, map[string]string{"authorized": "OK"})
+			})
+
+			r.Put("/syncs/progress", func(w http.ResponseWriter, req *http.Request) {
+				user := currentUser(req)
+
+				var body kosync.Progress
+				if err := json.NewDecoder(req.Body).Decode(&body); err != nil {
+					writeKOSyncError(w, http.StatusForbidden, kosyncInvalidRequest, "Invalid request")
+					return
+				}
+				if body.Document == "" {
+					writeKOSyncError(w, http.StatusForbidden, kosyncDocumentMissing, "Field 'document' not provided.")
+					return
+				}
+
+				saved, err := sync.UpdateProgress(req.Context(), user.ID, body)
+				if err != nil {
+					log.Error().Err(err).Str("document", body.Document).Msg("failed to save sync progress")
+					writeKOSyncError(w, http.StatusInternalServerError, kosyncServerError, "Unknown server error.")
+					return
+				}
+
+				writeKOSyncJSON(w, http.StatusOK, map[string]interface{}{
+					"document":  saved.Document,
+					"timestamp": saved.Timestamp,
+				})
+			})
+
+			r.Get("/syncs/progress/{document}", func(w http.ResponseWriter, req *http.Request) {
+				user := currentUser(req)
+				document := chi.URLParam(req, "document")
+
+				progress, err := sync.GetProgress(req.Context(), user.ID, document)
+				if err != nil {
+					log.Error().Err(err).Str("document", document).Msg("failed to get sync progress")
+					writeKOSyncError(w, http.StatusInternalServerError, kosyncServerError, "Unknown server error.")
+					return
+				}
+				if progress == nil {
+					writeKOSyncJSON(w, http.StatusOK, struct{}{})
+					return
+				}
+				writeKOSyncJSON(w, http.StatusOK, progress)
+			})
+		})
+	})
+}
+
+// authenticateKOSync checks the sync credentials KOReader sends and stores
+// the user and their visibility restrictions in the request context.
+func authenticateKOSync(log zerolog.Logger, users *auth.Service, opts Options) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			var user *auth.User
+			var err error
+			if opts.DisableAuth {
+				user, err = users.DefaultUser(req.Context())
+			} else {
+				user, err = users.CheckSyncKey(req.Context(), req.Header.Get("X-Auth-User"), req.Header.Get("X-Auth-Key"))
+			}
+			if err != nil {
+				if !errors.Is(err, auth.ErrInvalidCredentials) {
+					log.Error().Err(err).Msg("failed to check sync credentials")
+				}
+				writeKOSyncError(w, http.StatusUnauthorized, kosyncUnauthorized, "Unauthorized")
+				return
+			}
+
+			ctx := auth.WithUser(req.Context(), user)
+			ctx = library.WithRestrictions(ctx, user.Restrictions)
+			next.ServeHTTP(w, req.WithContext(ctx))
+		})
+	}
+}
+
+// recordFile records a CBZ handed to a reader so KOReader can sync progress
+// in it.
+func recordFile(req *http.Request, log zerolog.Logger, sync *kosync.Service, chapterID int64, filePath, name string) {
+	hash, err := kosync.FilePartialMD5(filePath)
+	if err != nil {
+		log.Warn().Err(err).Int64("chapter", chapterID).Msg("failed to hash chapter file")
+		return
+	}
+	recordDocument(req, log, sync, chapterID, kosync.FormatCBZ, name, hash)
+}
+
+// recordDocument records a chapter file handed to a reader under its hash.
+func recordDocument(req *http.Request, log zerolog.Logger, sync *kosync.Service, chapterID int64, format, name, hash string) {
+	if err := sync.RecordDocument(req.Context(), chapterID, format, name, hash); err != nil {
+		log.Warn().Err(err).Int64("chapter", chapterID).Msg("failed to record sync document")
+	}
+}
+
+func writeKOSyncJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+func writeKOSyncError(w http.ResponseWriter, status, code int, message string) {
+	writeKOSyncJSON(w, status, map[string]interface{}{"code": code, "message": message})
+}
@@ -0,0 +1,183 @@
+package api
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// CORSOptions configures cross-origin requests.
+type CORSOptions struct {
+	// Origins lists the allowed origins, such as "https://example.com".
+	// "*" allows any origin, but not together with AllowCredentials.
+	Origins []string
+	// AllowCredentials lets allowed origins send cookies and Authorization headers.
+	AllowCredentials bool
+	// MaxAge is how long browsers may cache preflight responses.
+	MaxAge time.Duration
+}
+
+// corsMethods and corsHeaders are allowed in cross-origin requests.
+var (
+	corsMethods = "GET, HEAD, POST, PUT, PATCH, DELETE"
+	corsHeaders = "Authorization, Content-Type, X-Api-Key"
+)
+
+// allows reports whether a cross-origin request from origin is allowed.
+func (c CORSOptions) allows(origin string) bool {
+	if origin == "" {
+		return false
+	}
+	for _, o := range c.Origins {
+		if o == "*" && !c.AllowCredentials || strings.EqualFold(strings.TrimSuffix(o, "/"), origin) {
+			return true
+		}
+	}
+	return false
+}
+
+// cors adds CORS headers for allowed origins and answers preflight requests,
+// which carry no credentials, before authentication.
+func cors(c CORSOptions) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			origin := req.Header.Get("Origin")
+			w.Header().Add("Vary", "Origin")
+			if !c.allows(origin) {
+				next.ServeHTTP(w, req)
+				return
+			}
+
+			h := w.Header()
+			h.Set("Access-Control-Allow-Origin", origin)
+			if c.AllowCredentials {
+				h.Set("Access-Control-Allow-Credentials", "true")
+			}
+
+			if req.Method != http.MethodOptions || req.Header.Get("Access-Control-Request-Method") == "" {
+				h.Set("Access-Control-Expose-Headers", "ETag")
+				next.ServeHTTP(w, req)
+				return
+			}
+
+			h.Add("Vary", "Access-Control-Request-Method")
+			h.Add("Vary", "Access-Control-Request-Headers")
+			h.Set("Access-Control-Allow-Methods", corsMethods)
+			h.Set("Access-Control-Allow-Headers", corsHeaders)
+			if c.MaxAge > 0 {
+				h.Set("Access-Control-Max-Age", strconv.Itoa(int(c.MaxAge.Seconds())))
+			}
+			w.WriteHeader(http.StatusNoContent)
+		})
+	}
+}
+
+// ParseNetworks parses trusted proxy networks given as CIDRs or single addresses.
+func ParseNetworks(values []string) ([]netip.Prefix, error) {
+	networks := make([]netip.Prefix, 0, len(values))
+	for _, v := range values {
+		v = strings.TrimSpace(v)
+		if v == "" {
+			continue
+		}
+		if prefix, err := netip.ParsePrefix(v); err == nil {
+			networks = append(networks, prefix.Masked())
+			continue
+		}
+		addr, err := netip.ParseAddr(v)
+		if err != nil {
+			return nil, fmt.Errorf("invalid network %q", v)
+		}
+		networks = append(networks, netip.PrefixFrom(addr, addr.BitLen()))
+	}
+	return networks, nil
+}
+
+// realIP replaces the remote address with the client address reported by a
+// trusted reverse proxy in X-Real-IP or X-Forwarded-For, and marks requests
+// the proxy received over HTTPS. Headers from other peers are ignored, so
+// clients cannot spoof their address. Peers on a Unix socket are trusted.
+func realIP(trusted []netip.Prefix) func(http.Handler) http.Handler {
+	isTrusted := func(addr netip.Addr) bool {
+		addr = addr.Unmap()
+		return slices.ContainsFunc(trusted, func(p netip.Prefix) bool { return p.Contains(addr) })
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			if !fromUnixSocket(req) {
+				peer, err := netip.ParseAddrPort(req.RemoteAddr)
+				if err != nil || !isTrusted(peer.Addr()) {
+					next.ServeHTTP(w, req)
+					return
+				}
+			}
+
+			if ip := forwardedClient(req.Header, isTrusted); ip.IsValid() {
+				req.RemoteAddr = net.JoinHostPort(ip.Unmap().String(), "0")
+			}
+			if strings.EqualFold(req.Header.Get("X-Forwarded-Proto"), "https") {
+				req.URL.Scheme = "https"
+			}
+			next.ServeHTTP(w, req)
+		})
+	}
+}
+
+// fromUnixSocket reports whether a request arrived over a Unix domain
+// socket. Only local processes with access to the socket file can connect,
+// so these peers are trusted like a local proxy.
+func fromUnixSocket(req *http.Request) bool {
+	return req.RemoteAddr == "" || req.RemoteAddr == "@"
+}
+
+// forwardedClient returns the client address from X-Real-IP, or the
+// right-most untrusted address in X-Forwarded-For. Proxies append to
+// X-Forwarded-For, so entries left of the first untrusted hop may be forged.
+func forwardedClient(h http.Header, isTrusted func(netip.Addr) bool) netip.Addr {
+	if ip, err := netip.ParseAddr(strings.TrimSpace(h.Get("X-Real-IP"))); err == nil {
+		return ip
+	}
+
+	hops := strings.Split(strings.Join(h.Values("X-Forwarded-For"), ","), ",")
+	for i := len(hops) - 1; i >= 0; i-- {
+		ip, err := netip.ParseAddr(strings.TrimSpace(hops[i]))
+		if err != nil {
+			return netip.Addr{}
+		}
+		if !isTrusted(ip) || i == 0 {
+			return ip
+		}
+	}
+	return netip.Addr{}
+}
+
+// normalizeBaseURL turns a configured base URL into a path prefix with a
+// leading slash and no trailing slash; "" and "/" mean no prefix.
+func normalizeBaseURL(base string) string {
+	base = strings.Trim(strings.TrimSpace(base), "/")
+	if base == "" {
+		return ""
+	}
+	return "/" + base
+}
+
+// stripBaseURL serves the router below base. The bare prefix redirects to
+// its trailing-slash form and paths outside it are not found.
+func stripBaseURL(base string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		stripped := http.StripPrefix(base, next)
+		return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			if req.URL.Path == base {
+				http.Redirect(w, req, base+"/", http.StatusMovedPermanently)
+				return
+			}
+			stripped.ServeHTTP(w, req)
+		})
+	}
+}
@@ -0,0 +1,286 @@
+package api
+
+import (
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestParseNetworks(t *testing.T) {
+	networks, err := ParseNetworks([]string{"10.0.0.0/8", " 192.168.1.7 ", "", "fd00::/8", "172.16.5.4/12"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"10.0.0.0/8", "192.168.1.7/32", "fd00::/8", "172.16.0.0/12"}
+	if len(networks) != len(want) {
+		t.Fatalf("got %v, want %v", networks, want)
+	}
+	for i, n := range networks {
+		if n.String() != want[i] {
+			t.Errorf("network %d = %s, want %s", i, n, want[i])
+		}
+	}
+
+	if _, err := ParseNetworks([]string{"not-an-ip"}); err == nil {
+		t.Error("invalid network accepted")
+	}
+}
+
+func TestRealIP(t *testing.T) {
+	trusted, err := ParseNetworks([]string{"10.0.0.0/8", "::1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name       string
+		remoteAddr string
+		headers    map[string][]string
+		wantIP     string
+		wantHTTPS  bool
+	}{
+		{
+			name:       "untrusted peer keeps its address",
+			remoteAddr: "203.0.113.5:1234",
+			headers:    map[string][]string{"X-Forwarded-For": {"198.51.100.1"}, "X-Forwarded-Proto": {"https"}},
+			wantIP:     "203.0.113.5",
+		},
+		{
+			name:       "trusted peer without headers",
+			remoteAddr: "10.0.0.2:1234",
+			wantIP:     "10.0.0.2",
+		},
+		{
+			name:       "X-Real-IP from a trusted peer",
+			remoteAddr: "10.0.0.2:1234",
+			headers:    map[string][]string{"X-Real-IP": {"198.51.100.1"}, "X-Forwarded-For": {"198.51.100.9"}},
+			wantIP:     "198.51.100.1",
+		},
+		{
+			name:       "single X-Forwarded-For hop",
+			remoteAddr: "10.0.0.2:1234",
+			headers:    map[string][]string{"X-Forwarded-For": {"198.51.100.1"}},
+			wantIP:     "198.51.100.1",
+		},
+		{
+			name:       "forged hops left of the client are ignored",
+			remoteAddr: "10.0.0.2:1234",
+			headers:    map[string][]string{"X-Forwarded-For": {"1.2.3.4, 198.51.100.1, 10.0.0.3"}},
+			wantIP:     "198.51.100.1",
+		},
+		{
+			name:       "repeated X-Forwarded-For headers are joined",
+			remoteAddr: "10.0.0.2:1234",
+			headers:    map[string][]string{"X-Forwarded-For": {"1.2.3.4", "198.51.100.1"}},
+			wantIP:     "198.51.100.1",
+		},
+		{
+			name:       "all hops trusted uses the left-most",
+			remoteAddr: "10.0.0.2:1234",
+			headers:    map[string][]string{"X-Forwarded-For": {"10.0.0.4, 10.0.0.3"}},
+			wantIP:     "10.0.0.4",
+		},
+		{
+			name:       "malformed hop is not used",
+			remoteAddr: "10.0.0.2:1234",
+			headers:    map[string][]string{"X-Forwarded-For": {"198.51.100.1, garbage"}},
+			wantIP:     "10.0.0.2",
+		},
+		{
+			name:       "IPv6 trusted peer",
+			remoteAddr: "[::1]:1234",
+			headers:    map[string][]string{"X-Forwarded-For": {"2001:db8::1"}},
+			wantIP:     "2001:db8::1",
+		},
+		{
+			name:       "IPv4-mapped client is unmapped",
+			remoteAddr: "10.0.0.2:1234",
+			headers:    map[string][]string{"X-Forwarded-For": {"::ffff:198.51.100.1"}},
+			wantIP:     "198.51.100.1",
+		},
+		{
+			name:       "HTTPS reported by a trusted peer",
+			remoteAddr: "10.0.0.2:1234",
+			headers:    map[string][]string{"X-Forwarded-Proto": {"HTTPS"}},
+			wantIP:     "10.0.0.2",
+			wantHTTPS:  true,
+		},
+		{
+			name:       "Unix socket peer is trusted",
+			remoteAddr: "@",
+			headers:    map[string][]string{"X-Forwarded-For": {"198.51.100.1"}},
+			wantIP:     "198.51.100.1",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got *http.Request
+			handler := realIP(trusted)(http.HandlerFunc(func(_ http.ResponseWriter, req *http.Request) {
+				got = req
+			}))
+
+			req := httptest.NewRequest(http.MethodGet, "/api/health", nil)
+			req.RemoteAddr = tt.remoteAddr
+			for key, values := range tt.headers {
+				for _, v := range values {
+					req.Header.Add(key, v)
+				}
+			}
+			handler.ServeHTTP(httptest.NewRecorder(), req)
+
+			host, _, err := net.SplitHostPort(got.RemoteAddr)
+			if err != nil {
+				t.Fatalf("RemoteAddr %q: %v", got.RemoteAddr, err)
+			}
+			if host != tt.wantIP {
+				t.Errorf("client = %s, want %s", host, tt.wantIP)
+			}
+			if https := got.URL.Scheme == "https"; https != tt.wantHTTPS {
+				t.Errorf("https = %v, want %v", https, tt.wantHTTPS)
+			}
+		})
+	}
+}
+
+func TestCORS(t *testing.T) {
+	tests := []struct {
+		name        string
+		opts        CORSOptions
+		method      string
+		origin      string
+		preflight   bool
+		wantStatus  int
+		wantNext    bool
+		wantOrigin  string
+		wantCreds   bool
+		wantMethods bool
+		wantMaxAge  string
+	}{
+		{
+			name:       "same-origin request passes through",
+			opts:       CORSOptions{Origins: []string{"https://app.example.com"}},
+			method:     http.MethodGet,
+			wantStatus: http.StatusOK,
+			wantNext:   true,
+		},
+		{
+			name:       "allowed origin",
+			opts:       CORSOptions{Origins: []string{"https://app.example.com/"}},
+			method:     http.MethodGet,
+			origin:     "https://APP.example.com",
+			wantStatus: http.StatusOK,
+			wantNext:   true,
+			wantOrigin: "https://APP.example.com",
+		},
+		{
+			name:       "other origin gets no CORS headers",
+			opts:       CORSOptions{Origins: []string{"https://app.example.com"}},
+			method:     http.MethodGet,
+			origin:     "https://evil.example.com",
+			wantStatus: http.StatusOK,
+			wantNext:   true,
+		},
+		{
+			name:       "credentials allowed",
+			opts:       CORSOptions{Origins: []string{"https://app.example.com"}, AllowCredentials: true},
+			method:     http.MethodGet,
+			origin:     "https://app.example.com",
+			wantStatus: http.StatusOK,
+			wantNext:   true,
+			wantOrigin: "https://app.example.com",
+			wantCreds:  true,
+		},
+		{
+			name:       "wildcard allows any origin",
+			opts:       CORSOptions{Origins: []string{"*"}},
+			method:     http.MethodGet,
+			origin:     "https://any.example.com",
+			wantStatus: http.StatusOK,
+			wantNext:   true,
+			wantOrigin: "https://any.example.com",
+		},
+		{
+			name:       "wildcard never allows credentials",
+			opts:       CORSOptions{Origins: []string{"*"}, AllowCredentials: true},
+			method:     http.MethodGet,
+			origin:     "https://any.example.com",
+			wantStatus: http.StatusOK,
+			wantNext:   true,
+		},
+		{
+			name:        "preflight is answered without the handler",
+			opts:        CORSOptions{Origins: []string{"https://app.example.com"}, MaxAge: time.Hour},
+			method:      http.MethodOptions,
+			origin:      "https://app.example.com",
+			preflight:   true,
+			wantStatus:  http.StatusNoContent,
+			wantOrigin:  "https://app.example.com",
+			wantMethods: true,
+			wantMaxAge:  "3600",
+		},
+		{
+			name:       "preflight from another origin reaches the handler",
+			opts:       CORSOptions{Origins: []string{"https://app.example.com"}},
+			method:     http.MethodOptions,
+			origin:     "https://evil.example.com",
+			preflight:  true,
+			wantStatus: http.StatusOK,
+			wantNext:   true,
+		},
+		{
+			name:       "OPTIONS without a requested method is not a preflight",
+			opts:       CORSOptions{Origins: []string{"https://app.example.com"}},
+			method:     http.MethodOptions,
+			origin:     "https://app.example.com",
+			wantStatus: http.StatusOK,
+			wantNext:   true,
+			wantOrigin: "https://app.example.com",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			called := false
+			handler := cors(tt.opts)(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+				called = true
+				w.WriteHeader(http.StatusOK)
+			}))
+
+			req := httptest.NewRequest(tt.method, "/api/manga", nil)
+			if tt.origin != "" {
+				req.Header.Set("Origin", tt.origin)
+			}
+			if tt.preflight {
+				req.Header.Set("Access-Control-Request-Method", http.MethodDelete)
+			}
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+
+			h := rec.Header()
+			if rec.Code != tt.wantStatus {
+				t.Errorf("status = %d, want %d", rec.Code, tt.wantStatus)
+			}
+			if called != tt.wantNext {
+				t.Errorf("handler called = %v, want %v", called, tt.wantNext)
+			}
+			if got := h.Get("Access-Control-Allow-Origin"); got != tt.wantOrigin {
+				t.Errorf("Allow-Origin = %q, want %q", got, tt.wantOrigin)
+			}
+			if got := h.Get("Access-Control-Allow-Credentials") == "true"; got != tt.wantCreds {
+				t.Errorf("Allow-Credentials = %v, want %v", got, tt.wantCreds)
+			}
+			if got := h.Get("Access-Control-Allow-Methods") != ""; got != tt.wantMethods {
+				t.Errorf("Allow-Methods set = %v, want %v", got, tt.wantMethods)
+			}
+			if got := h.Get("Access-Control-Max-Age"); got != tt.wantMaxAge {
+				t.Errorf("Max-Age = %q, want %q", got, tt.wantMaxAge)
+			}
+			if h.Get("Vary") != "Origin" {
+				t.Errorf("Vary = %q, want Origin first", h.Get("Vary"))
+			}
+		})
+	}
+}
@@ -0,0 +1,59 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/rs/zerolog"
+
+	"github.com/mangashelf/mangashelf/internal/auth"
+	"github.com/mangashelf/mangashelf/internal/events"
+	"github.com/mangashelf/mangashelf/internal/notify"
+)
+
+// registerNotificationRoutes serves notification checks and digests to admins.
+func registerNotificationRoutes(r chi.Router, log zerolog.Logger, notifier *notify.Service) {
+	r.Route("/api/notifications", func(r chi.Router) {
+		r.Use(requireRole(auth.RoleAdmin))
+
+		r.Post("/test", func(w http.ResponseWriter, req *http.Request) {
+			body := struct {
+				Event events.Type `json:"event"`
+			}{Event: events.NewChapters}
+			if err := json.NewDecoder(req.Body).Decode(&body); err != nil && !errors.Is(err, io.EOF) {
+				writeError(w, http.StatusBadRequest, "INVALID_BODY", "invalid request body")
+				return
+			}
+
+			if err := notifier.Test(req.Context(), body.Event); err != nil {
+				writeNotificationError(w, req, log, err, "failed to send test notification")
+				return
+			}
+
+			w.WriteHeader(http.StatusNoContent)
+		})
+
+		r.Post("/digest", func(w http.ResponseWriter, req *http.Request) {
+			if err := notifier.SendDigest(req.Context()); err != nil {
+				writeNotificationError(w, req, log, err, "failed to send digest")
+				return
+			}
+
+			w.WriteHeader(http.StatusNoContent)
+		})
+	})
+}
+
+// writeNotificationError writes a notification error. Send failures carry
+// the services' own errors, which admins need to fix their URLs.
+func writeNotificationError(w http.ResponseWriter, req *http.Request, log zerolog.Logger, err error, msg string) {
+	if errors.Is(err, notify.ErrSendFailed) {
+		log.Warn().Err(err).Msg(msg)
+		writeError(w, http.StatusBadGateway, "NOTIFICATION_FAILED", err.Error())
+		return
+	}
+	writeServiceError(w, req, log, err, "NOTIFICATION_FAILED", msg)
+}
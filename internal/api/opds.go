@@ -0,0 +1,590 @@
+package api
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"path"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/rs/zerolog"
+
+	"github.com/mangashelf/mangashelf/internal/database"
+	"github.com/mangashelf/mangashelf/internal/epub"
+	"github.com/mangashelf/mangashelf/internal/kosync"
+	"github.com/mangashelf/mangashelf/internal/library"
+	"github.com/mangashelf/mangashelf/internal/opds"
+)
+
+const (
+	// opdsPageSize is the number of entries per page of a catalog feed.
+	opdsPageSize = 50
+)
+
+// Media types of chapter downloads.
+const (
+	typeCBZ  = "application/vnd.comicbook+zip"
+	typeEPUB = "application/epub+zip"
+)
+
+// catalog builds the feeds of the OPDS catalog. Links are absolute paths
+// below the base URL.
+type catalog struct {
+	base string
+}
+
+func (c catalog) href(format string, args ...any) string {
+	return c.base + fmt.Sprintf(format, args...)
+}
+
+// newFeed returns a feed linking to itself, the catalog root and search.
+func (c catalog) newFeed(req *http.Request, id, title, kind string) *opds.Feed {
+	feed := opds.NewFeed("urn:mangashelf:opds:"+id, title, time.Now())
+	feed.Author = &opds.Author{Name: "MangaShelf"}
+	feed.Links = []opds.Link{
+		{Rel: opds.RelSelf, Href: c.base + req.URL.RequestURI(), Type: kind},
+		{Rel: opds.RelStart, Href: c.href("/opds"), Type: opds.TypeNavigation, Title: "MangaShelf"},
+		{Rel: opds.RelSearch, Href: c.href("/opds/search.xml"), Type: opds.TypeOpenSearch, Title: "Search"},
+	}
+	return feed
+}
+
+// navigationEntry links to another feed of the catalog.
+func (c catalog) navigationEntry(id, title, description, href, kind string, count int) opds.Entry {
+	return opds.Entry{
+		ID:      "urn:mangashelf:opds:" + id,
+		Title:   title,
+		Updated: opds.Time(time.Now()),
+		Content: opds.PlainText(description),
+		Links:   []opds.Link{{Rel: opds.RelSubsection, Href: href, Type: kind, Count: count}},
+	}
+}
+
+// seriesEntry links to a manga's acquisition feed.
+func (c catalog) seriesEntry(e *library.ShelfEntry) opds.Entry {
+	updated := cmp.Or(e.LastDownloadedAt, e.UpdatedAt.String)
+	entry := opds.Entry{
+		ID:      fmt.Sprintf("urn:mangashelf:manga:%d", e.ID),
+		Title:   e.Title,
+		Updated: opds.Time(parseSQLiteTime(updated)),
+		Authors: mangaAuthors(e.Manga),
+		Links: []opds.Link{{
+			Rel:   opds.RelSubsection,
+			Href:  c.href("/opds/series/%d", e.ID),
+			Type:  opds.TypeAcquisition,
+			Count: int(e.UnreadCount),
+		}},
+	}
+	for _, genre := range library.StringList(e.Genres) {
+		entry.Categories = append(entry.Categories, opds.Category{Term: genre, Label: genre})
+	}
+	if e.Description.String != "" {
+		entry.Content = opds.PlainText(e.Description.String)
+	}
+	entry.Links = append(entry.Links, c.coverLinks(e.Manga)...)
+	return entry
+}
+
+// chapterEntry offers a downloaded chapter as CBZ and EPUB.
+func (c catalog) chapterEntry(m *database.Manga, ch *database.ListChaptersWithProgressRow) opds.Entry {
+	entry := opds.Entry{
+		ID:       fmt.Sprintf("urn:mangashelf:chapter:%d", ch.ID),
+		Title:    chapterTitle(ch.Number, ch.Volume.String, ch.Title),
+		Updated:  opds.Time(parseSQLiteTime(ch.DownloadedAt.String)),
+		Authors:  mangaAuthors(m),
+		Language: ch.Language.String,
+		Links: []opds.Link{
+			{Rel: opds.RelAcquisition, Href: c.href("/opds/chapters/%d/cbz", ch.ID), Type: typeCBZ, Length: ch.FileSize.Int64},
+			{Rel: opds.RelAcquisition, Href: c.href("/opds/chapters/%d/epub", ch.ID), Type: typeEPUB},
+		},
+	}
+	if ch.PublishedAt.Valid {
+		entry.Issued = parseSQLiteTime(ch.PublishedAt.String).Format(time.DateOnly)
+	}
+	if stream := c.streamLink(ch); stream != nil {
+		entry.Links = append(entry.Links, *stream)
+	}
+
+	var details []string
+	if ch.PageCount.Int64 > 0 {
+		details = append(details, fmt.Sprintf("%d pages", ch.PageCount.Int64))
+	}
+	if ch.IsRead.Int64 == 1 {
+		details = append(details, "read")
+	} else if ch.CurrentPage.Int64 > 0 {
+		details = append(details, fmt.Sprintf("read up to page %d", ch.CurrentPage.Int64))
+	}
+	if len(details) > 0 {
+		entry.Content = opds.PlainText(strings.Join(details, ", "))
+	}
+
+	entry.Links = append(entry.Links, c.coverLinks(m)...)
+	return entry
+}
+
+// streamLink lets PSE readers fetch a chapter's pages one at a time. The
+// user's progress is reported as the 0-based page last read.
+func (c catalog) streamLink(ch *database.ListChaptersWithProgressRow) *opds.Link {
+	pages := library.CountPages(ch.PageCount.Int64, ch.FilePath.String)
+	if pages == 0 {
+		return nil
+	}
+
+	link := &opds.Link{
+		Rel:       opds.RelStream,
+		Href:      c.href("/opds/chapters/%d/pages/", ch.ID) + "{pageNumber}?width={maxWidth}",
+		Type:      "image/jpeg",
+		PageCount: pages,
+	}
+	lastRead := -1
+	switch {
+	case ch.IsRead.Int64 == 1:
+		lastRead = pages - 1
+	case ch.CurrentPage.Int64 > 0:
+		lastRead = min(int(ch.CurrentPage.Int64), pages) - 1
+	}
+	if lastRead >= 0 {
+		link.LastRead = &lastRead
+		if ch.ProgressUpdatedAt.Valid {
+			link.LastReadDate = opds.Time(parseSQLiteTime(ch.ProgressUpdatedAt.String))
+		}
+	}
+	return link
+}
+
+// coverLinks links to a manga's cover and thumbnail, when it has one.
+func (c catalog) coverLinks(m *database.Manga) []opds.Link {
+	if m.CoverPath.String == "" && m.CoverUrl.String == "" {
+		return nil
+	}
+	// Thumbnails are always JPEG; the full-size cover keeps its format.
+	imageType := mime.TypeByExtension(path.Ext(m.CoverPath.String))
+	if !strings.HasPrefix(imageType, "image/") {
+		imageType = "image/jpeg"
+	}
+	return []opds.Link{
+		{Rel: opds.RelImage, Href: c.href("/api/manga/%d/cover", m.ID), Type: imageType},
+		{Rel: opds.RelThumbnail, Href: c.href("/api/manga/%d/cover?size=medium", m.ID), Type: "image/jpeg"},
+	}
+}
+
+// seriesFeed writes a paged navigation feed of manga.
+func (c catalog) seriesFeed(w http.ResponseWriter, req *http.Request, id, title string, entries []*library.ShelfEntry) {
+	feed := c.newFeed(req, id, title, opds.TypeNavigation)
+	start, end := c.paginate(feed, req, len(entries), opds.TypeNavigation)
+	for _, e := range entries[start:end] {
+		feed.Entries = append(feed.Entries, c.seriesEntry(e))
+	}
+	writeFeed(w, feed, opds.TypeNavigation)
+}
+
+// paginate adds paging links for the page requested in the "page" query
+// parameter and returns the bounds of its entries.
+func (c catalog) paginate(feed *opds.Feed, req *http.Request, total int, kind string) (start, end int) {
+	pages := max((total+opdsPageSize-1)/opdsPageSize, 1)
+	page, err := strconv.Atoi(req.URL.Query().Get("page"))
+	if err != nil || page < 1 {
+		page = 1
+	}
+	page = min(page, pages)
+
+	feed.TotalResults = total
+	feed.ItemsPerPage = opdsPageSize
+	feed.StartIndex = (page-1)*opdsPageSize + 1
+
+	pageHref := func(n int) string {
+		query := req.URL.Query()
+		query.Set("page", strconv.Itoa(n))
+		return c.base + req.URL.Path + "?" + query.Encode()
+	}
+	if pages > 1 {
+		feed.Links = append(feed.Links,
+			opds.Link{Rel: opds.RelFirst, Href: pageHref(1), Type: kind},
+			opds.Link{Rel: opds.RelLast, Href: pageHref(pages), Type: kind},
+		)
+	}
+	if page > 1 {
+		feed.Links = append(feed.Links, opds.Link{Rel: opds.RelPrevious, Href: pageHref(page - 1), Type: kind})
+	}
+	if page < pages {
+		feed.Links = append(feed.Links, opds.Link{Rel: opds.RelNext, Href: pageHref(page + 1), Type: kind})
+	}
+
+	start = min((page-1)*opdsPageSize, total)
+	return start, min(start+opdsPageSize, total)
+}
+
+// registerOPDSRoutes serves an OPDS 1.2 catalog of the library under /opds
+// for e-reader apps, with navigation feeds for browsing and an acquisition
+// feed per manga offering its downloaded chapters as CBZ and EPUB.
+func registerOPDSRoutes(r chi.Router, log zerolog.Logger, lib *library.Service, sync *kosync.Service, opts Options) {
+	c := catalog{base: normalizeBaseURL(opts.BaseURL)}
+
+	shelf := func(w http.ResponseWriter, req *http.Request) ([]*library.ShelfEntry, bool) {
+		entries, err := lib.ListShelf(req.Context(), currentUser(req).ID)
+		if err != nil {
+			writeServiceError(w, req, log, err, "LIST_FAILED", "failed to list manga")
+			return nil, false
+		}
+		return entries, true
+	}
+
+	r.Get("/opds", func(w http.ResponseWriter, req *http.Request) {
+		feed := c.newFeed(req, "root", "MangaShelf", opds.TypeNavigation)
+		feed.Entries = []opds.Entry{
+			c.navigationEntry("series", "All series", "Every manga in the library, by title", c.href("/opds/series"), opds.TypeNavigation, 0),
+			c.navigationEntry("recent", "Recently updated", "Manga with newly downloaded chapters", c.href("/opds/recent"), opds.TypeNavigation, 0),
+			c.navigationEntry("unread", "Unread", "Manga with downloaded chapters you have not read", c.href("/opds/unread"), opds.TypeNavigation, 0),
+			c.navigationEntry("genres", "Genres", "Browse manga by genre", c.href("/opds/genres"), opds.TypeNavigation, 0),
+		}
+		// Readers use the "new" relation for a "what's new" shortcut.
+		feed.Entries[1].Links[0].Rel = opds.RelNew
+		writeFeed(w, feed, opds.TypeNavigation)
+	})
+
+	r.Get("/opds/series", func(w http.ResponseWriter, req *http.Request) {
+		entries, ok := shelf(w, req)
+		if !ok {
+			return
+		}
+		c.seriesFeed(w, req, "series", "All series", entries)
+	})
+
+	r.Get("/opds/recent", func(w http.ResponseWriter, req *http.Request) {
+		entries, ok := shelf(w, req)
+		if !ok {
+			return
+		}
+		entries = slices.DeleteFunc(entries, func(e *library.ShelfEntry) bool { return !e.Downloaded })
+		slices.SortStableFunc(entries, func(a, b *library.ShelfEntry) int {
+			return strings.Compare(b.LastDownloadedAt, a.LastDownloadedAt)
+		})
+		c.seriesFeed(w, req, "recent", "Recently updated", entries)
+	})
+
+	r.Get("/opds/unread", func(w http.ResponseWriter, req *http.Request) {
+		entries, ok := shelf(w, req)
+		if !ok {
+			return
+		}
+		entries = slices.DeleteFunc(entries, func(e *library.ShelfEntry) bool { return e.UnreadCount == 0 })
+		c.seriesFeed(w, req, "unread", "Unread", entries)
+	})
+
+	r.Get("/opds/genres", func(w http.ResponseWriter, req *http.Request) {
+		entries, ok := shelf(w, req)
+		if !ok {
+			return
+		}
+
+		counts := make(map[string]int)
+		for _, e := range entries {
+			for _, genre := range library.StringList(e.Genres) {
+				counts[genre]++
+			}
+		}
+		genres := make([]string, 0, len(counts))
+		for genre := range counts {
+			genres = append(genres, genre)
+		}
+		slices.SortFunc(genres, func(a, b string) int {
+			return strings.Compare(strings.ToLower(a), strings.ToLower(b))
+		})
+
+		feed := c.newFeed(req, "genres", "Genres", opds.TypeNavigation)
+		for _, genre := range genres {
+			feed.Entries = append(feed.Entries, c.navigationEntry(
+				"genre:"+url.PathEscape(genre),
+				genre,
+				fmt.Sprintf("%d series", counts[genre]),
+				c.href("/opds/genres/%s", url.PathEscape(genre)),
+				opds.TypeNavigation,
+				counts[genre],
+			))
+		}
+		writeFeed(w, feed, opds.TypeNavigation)
+	})
+
+	r.Get("/opds/genres/{genre}", func(w http.ResponseWriter, req *http.Request) {
+		genre, err := url.PathUnescape(chi.URLParam(req, "genre"))
+		if err != nil {
+			writeError(w, http.StatusBadRequest, "INVALID_GENRE", "invalid genre")
+			return
+		}
+		entries, ok := shelf(w, req)
+		if !ok {
+			return
+		}
+		entries = slices.DeleteFunc(entries, func(e *library.ShelfEntry) bool {
+			return !slices.ContainsFunc(library.StringList(e.Genres), func(g string) bool {
+				return strings.EqualFold(g, genre)
+			})
+		})
+		c.seriesFeed(w, req, "genre:"+url.PathEscape(genre), genre, entries)
+	})
+
+	r.Get("/opds/search.xml", func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", opds.TypeOpenSearch+"; charset=utf-8")
+		_ = opds.Write(w, opds.NewOpenSearchDescription(
+			"MangaShelf",
+			"Search the MangaShelf library by title, author or artist",
+			c.href("/opds/search?q={searchTerms}"),
+		))
+	})
+
+	r.Get("/opds/search", func(w http.ResponseWriter, req *http.Request) {
+		query := strings.ToLower(strings.TrimSpace(req.URL.Query().Get("q")))
+		if query == "" {
+			writeError(w, http.StatusBadRequest, "MISSING_QUERY", "query parameter 'q' is required")
+			return
+		}
+		entries, ok := shelf(w, req)
+		if !ok {
+			return
+		}
+		entries = slices.DeleteFunc(entries, func(e *library.ShelfEntry) bool {
+			for _, field := range []string{e.Title, e.Author.String, e.Artist.String} {
+				if strings.Contains(strings.ToLower(field), query) {
+					return false
+				}
+			}
+			return true
+		})
+		c.seriesFeed(w, req, "search", fmt.Sprintf("Search: %s", req.URL.Query().Get("q")), entries)
+	})
+
+	r.Get("/opds/series/{id}", func(w http.ResponseWriter, req *http.Request) {
+		id, err := idParam(req)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, "INVALID_ID", "invalid manga ID")
+			return
+		}
+
+		manga, err := lib.GetManga(req.Context(), id)
+		if err != nil {
+			writeServiceError(w, req, log, err, "GET_FAILED", "failed to get manga")
+			return
+		}
+		chapters, err := lib.ListChapters(req.Context(), currentUser(req).ID, id)
+		if err != nil {
+			writeServiceError(w, req, log, err, "LIST_CHAPTERS_FAILED", "failed to list chapters")
+			return
+		}
+		chapters = slices.DeleteFunc(chapters, func(ch *database.ListChaptersWithProgressRow) bool {
+			return ch.Status.String != "completed"
+		})
+		slices.Reverse(chapters)
+
+		feed := c.newFeed(req, fmt.Sprintf("series:%d", id), manga.Title, opds.TypeAcquisition)
+		feed.Links = append(feed.Links, opds.Link{Rel: opds.RelUp, Href: c.href("/opds/series"), Type: opds.TypeNavigation})
+		if covers := c.coverLinks(manga); covers != nil {
+			feed.Icon = covers[1].Href
+		}
+		start, end := c.paginate(feed, req, len(chapters), opds.TypeAcquisition)
+		for _, ch := range chapters[start:end] {
+			feed.Entries = append(feed.Entries, c.chapterEntry(manga, ch))
+		}
+		writeFeed(w, feed, opds.TypeAcquisition)
+	})
+
+	r.Get("/opds/chapters/{id}/cbz", func(w http.ResponseWriter, req *http.Request) {
+		id, err := idParam(req)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, "INVALID_ID", "invalid chapter ID")
+			return
+		}
+
+		chapter, filePath, err := lib.ChapterFile(req.Context(), id)
+		if err != nil {
+			writeServiceError(w, req, log, err, "DOWNLOAD_FAILED", "failed to get chapter file")
+			return
+		}
+		manga, err := lib.GetManga(req.Context(), chapter.MangaID)
+		if err != nil {
+			writeServiceError(w, req, log, err, "DOWNLOAD_FAILED", "failed to get chapter file")
+			return
+		}
+
+		name := chapterFileName(manga, chapter, ".cbz")
+		w.Header().Set("Content-Type", typeCBZ)
+		w.Header().Set("Content-Disposition", attachment(name))
+		http.ServeFile(w, req, filePath)
+		recordFile(req, log, sync, chapter.ID, filePath, name)
+	})
+
+	// Pages are numbered from 0 in PSE. Fetching a page past the user's
+	// saved page moves their progress forward, so progress made in a
+	// streaming reader shows up everywhere else. Readers prefetch pages and
+	// revisit earlier ones, so a fetch never moves progress back or marks the
+	// chapter read.
+	r.Get("/opds/chapters/{id}/pages/{page}", func(w http.ResponseWriter, req *http.Request) {
+		id, err := idParam(req)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, "INVALID_ID", "invalid chapter ID")
+			return
+		}
+		index, err := strconv.Atoi(chi.URLParam(req, "page"))
+		if err != nil || index < 0 {
+			writeError(w, http.StatusBadRequest, "INVALID_PAGE", "invalid page number")
+			return
+		}
+		// Readers that do not scale leave the template unfilled.
+		width, _ := strconv.Atoi(req.URL.Query().Get("width"))
+
+		page, err := lib.ChapterPage(req.Context(), id, index+1, max(width, 0))
+		if err != nil {
+			writeServiceError(w, req, log, err, "PAGE_FAILED", "failed to get page")
+			return
+		}
+
+		if err := advanceProgress(req.Context(), lib, currentUser(req).ID, id, int64(page.Number)); err != nil {
+			log.Warn().Err(err).Int64("chapter", id).Msg("failed to record streaming progress")
+		}
+
+		writePage(w, req, page)
+	})
+
+	r.Get("/opds/chapters/{id}/epub", func(w http.ResponseWriter, req *http.Request) {
+		id, err := idParam(req)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, "INVALID_ID", "invalid chapter ID")
+			return
+		}
+
+		chapter, filePath, err := lib.ChapterFile(req.Context(), id)
+		if err != nil {
+			writeServiceError(w, req, log, err, "DOWNLOAD_FAILED", "failed to get chapter file")
+			return
+		}
+		manga, err := lib.GetManga(req.Context(), chapter.MangaID)
+		if err != nil {
+			writeServiceError(w, req, log, err, "DOWNLOAD_FAILED", "failed to get chapter file")
+			return
+		}
+
+		archive, err := library.OpenArchive(filePath)
+		if err != nil {
+			writeServiceError(w, req, log, err, "DOWNLOAD_FAILED", "failed to read chapter file")
+			return
+		}
+		defer archive.Close()
+		if len(archive.Pages) == 0 {
+			writeServiceError(w, req, log, errors.New("chapter archive has no pages"), "DOWNLOAD_FAILED", "failed to read chapter file")
+			return
+		}
+
+		var authors []string
+		for _, a := range mangaAuthors(manga) {
+			authors = append(authors, a.Name)
+		}
+
+		// The EPUB is built while streaming, so failures after this point
+		// can only be logged.
+		name := chapterFileName(manga, chapter, ".epub")
+		hasher := kosync.NewHasher()
+		w.Header().Set("Content-Type", typeEPUB)
+		w.Header().Set("Content-Disposition", attachment(name))
+		err = epub.Write(io.MultiWriter(w, hasher), epub.Book{
+			ID:          fmt.Sprintf("urn:mangashelf:chapter:%d", chapter.ID),
+			Title:       manga.Title + " - " + chapterTitle(chapter.Number, chapter.Volume.String, chapter.Title),
+			Authors:     authors,
+			Language:    chapter.Language.String,
+			Series:      manga.Title,
+			SeriesIndex: chapter.Number,
+			Modified:    parseSQLiteTime(chapter.DownloadedAt.String),
+			Pages:       archive.Pages,
+		})
+		if err != nil {
+			log.Error().Err(err).Int64("chapter", id).Msg("failed to write epub")
+			return
+		}
+		recordDocument(req, log, sync, chapter.ID, kosync.FormatEPUB, name, hasher.Sum())
+	})
+}
+
+// writeFeed writes an OPDS feed with its media type.
+func writeFeed(w http.ResponseWriter, feed *opds.Feed, kind string) {
+	w.Header().Set("Content-Type", kind+";charset=utf-8")
+	_ = opds.Write(w, feed)
+}
+
+// mangaAuthors returns the manga's author and, when different, its artist.
+func mangaAuthors(m *database.Manga) []opds.Author {
+	var authors []opds.Author
+	for _, name := range []string{m.Author.String, m.Artist.String} {
+		if name != "" && !slices.ContainsFunc(authors, func(a opds.Author) bool { return a.Name == name }) {
+			authors = append(authors, opds.Author{Name: name})
+		}
+	}
+	return authors
+}
+
+// chapterTitle formats a chapter's title as "Vol. 2 Ch. 10: Title".
+func chapterTitle(number float64, volume, title string) string {
+	name := "Ch. " + strconv.FormatFloat(number, 'f', -1, 64)
+	if volume != "" {
+		name = "Vol. " + volume + " " + name
+	}
+	if title != "" && !strings.EqualFold(title, "Chapter "+strconv.FormatFloat(number, 'f', -1, 64)) {
+		name += ": " + title
+	}
+	return name
+}
+
+// advanceProgress saves page as the user's last page read in a chapter
+// when it is past the saved one.
+func advanceProgress(ctx context.Context, lib *library.Service, userID, chapterID, page int64) error {
+	progress, err := lib.GetProgress(ctx, userID, chapterID)
+	if err != nil {
+		return err
+	}
+	if progress.CurrentPage.Valid && progress.CurrentPage.Int64 >= page {
+		return nil
+	}
+	_, err = lib.UpdateProgress(ctx, userID, chapterID, library.ProgressUpdate{CurrentPage: &page})
+	return err
+}
+
+// chapterFileName names a chapter file after its manga and chapter.
+func chapterFileName(m *database.Manga, ch *database.Chapter, ext string) string {
+	name := library.FolderName(m.Title, m.Slug) + " - " + chapterTitle(ch.Number, ch.Volume.String, "")
+	return strings.ReplaceAll(name, `"`, "'") + ext
+}
+
+// attachment returns a Content-Disposition header for a download named name.
+func attachment(name string) string {
+	ascii := strings.Map(func(r rune) rune {
+		if r > 126 || r < 32 {
+			return '_'
+		}
+		return r
+	}, name)
+	var encoded strings.Builder
+	for _, b := range []byte(name) {
+		if 'a' <= b && b <= 'z' || 'A' <= b && b <= 'Z' || '0' <= b && b <= '9' || strings.IndexByte("!#$&+-.^_`|~", b) >= 0 {
+			encoded.WriteByte(b)
+		} else {
+			fmt.Fprintf(&encoded, "%%%02X", b)
+		}
+	}
+	return fmt.Sprintf(`attachment; filename="%s"; filename*=UTF-8''%s`, ascii, encoded.String())
+}
+
+// parseSQLiteTime parses a timestamp stored by SQLite, falling back to the
+// current time for empty or malformed values.
+func parseSQLiteTime(s string) time.Time {
+	t, err := time.Parse(database.TimeFormat, s)
+	if err != nil {
+		return time.Now()
+	}
+	return t
+}
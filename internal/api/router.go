@@ -1,35 +1,95 @@
 package api
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
 	"net/http"
+	"net/netip"
+	"os"
 	"strconv"
 
 	"github.com/go-chi/chi/v5"
 	"github.com/go-chi/chi/v5/middleware"
 	"github.com/rs/zerolog"
 
+	"github.com/mangashelf/mangashelf/internal/auth"
+	"github.com/mangashelf/mangashelf/internal/database"
+	"github.com/mangashelf/mangashelf/internal/kosync"
 	"github.com/mangashelf/mangashelf/internal/library"
+	"github.com/mangashelf/mangashelf/internal/notify"
 	"github.com/mangashelf/mangashelf/internal/scraper"
+	"github.com/mangashelf/mangashelf/internal/webhook"
 )
 
+// Options configures the HTTP API.
+type Options struct {
+	// DisableAuth serves every route without credentials, as the first
+	// admin, for deployments where a reverse proxy handles authentication.
+	DisableAuth bool
+	// SecureCookies marks session cookies Secure even when the request did
+	// not arrive over TLS, such as behind a TLS-terminating proxy.
+	SecureCookies bool
+	// BaseURL serves every route below a path prefix, such as "/manga".
+	BaseURL string
+	// CORS allows browsers on other origins to call the API.
+	CORS CORSOptions
+	// TrustedProxies lists the networks whose forwarded client address and
+	// scheme headers are honoured.
+	TrustedProxies []netip.Prefix
+	// KOSyncRegistration lets KOReader create read-only accounts through
+	// the sync server.
+	KOSyncRegistration bool
+}
+
 // NewRouter configures the HTTP routes for the API.
-func NewRouter(log zerolog.Logger, scrapers *scraper.Manager, lib *library.Service) *chi.Mux {
+func NewRouter(log zerolog.Logger, scrapers *scraper.Manager, lib *library.Service, users *auth.Service, sync *kosync.Service, hooks *webhook.Service, notifier *notify.Service, opts Options) *chi.Mux {
 	r := chi.NewRouter()
 	r.Use(middleware.RequestID)
-	r.Use(middleware.RealIP)
+	r.Use(realIP(opts.TrustedProxies))
 	r.Use(middleware.Logger)
 	r.Use(middleware.Recoverer)
+	if base := normalizeBaseURL(opts.BaseURL); base != "" {
+		r.Use(stripBaseURL(base))
+	}
+	r.Use(cors(opts.CORS))
+
+	r.Use(authenticate(log, users, opts))
+	registerAuthRoutes(r, log, users, opts)
+	registerOPDSRoutes(r, log, lib, sync, opts)
+	registerKomgaRoutes(r, log, lib, sync)
+	registerKOSyncRoutes(r, log, users, sync, opts)
+	registerWebhookRoutes(r, log, hooks)
+	registerNotificationRoutes(r, log, notifier)
+
+	// Members manage the library; read-only users browse it and track
+	// their own progress.
+	member := requireRole(auth.RoleMember)
 
 	r.Get("/api/health", func(w http.ResponseWriter, _ *http.Request) {
+		status := "ok"
+		sources := make(map[string]string)
+		for _, s := range scrapers.Statuses() {
+			sources[s.ID] = s.Health.Circuit
+			if !s.Health.Healthy {
+				status = "degraded"
+			}
+		}
+
 		w.Header().Set("Content-Type", "application/json")
-		_ = json.NewEncoder(w).Encode(map[string]string{"status": "ok"})
-		log.Debug().Msg("health check")
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{
+			"status":  status,
+			"sources": sources,
+		})
+		log.Debug().Str("status", status).Msg("health check")
 	})
 
 	r.Get("/api/sources", func(w http.ResponseWriter, _ *http.Request) {
 		w.Header().Set("Content-Type", "application/json")
-		sources := scrapers.List()
+		sources := scrapers.Statuses()
 		_ = json.NewEncoder(w).Encode(map[string]interface{}{
 			"data": sources,
 		})
@@ -47,16 +107,9 @@ func NewRouter(log zerolog.Logger, scrapers *scraper.Manager, lib *library.Servi
 			source = "mangadex"
 		}
 
-		results, err := scrapers.Search(r.Context(), source, query)
+		results, err := scrapers.Search(cacheContext(r), source, query)
 		if err != nil {
-			log.Error().Err(err).Str("source", source).Str("query", query).Msg("search failed")
-
-			if err.Error() == "provider not found: "+source {
-				writeError(w, http.StatusBadRequest, "UNKNOWN_SOURCE", "source '"+source+"' not found")
-				return
-			}
-
-			writeError(w, http.StatusInternalServerError, "SEARCH_FAILED", "failed to search manga")
+			writeServiceError(w, r, log, err, "SEARCH_FAILED", "failed to search manga")
 			return
 		}
 
@@ -69,8 +122,7 @@ func NewRouter(log zerolog.Logger, scrapers *scraper.Manager, lib *library.Servi
 	r.Get("/api/manga", func(w http.ResponseWriter, req *http.Request) {
 		manga, err := lib.ListManga(req.Context())
 		if err != nil {
-			log.Error().Err(err).Msg("failed to list manga")
-			writeError(w, http.StatusInternalServerError, "LIST_FAILED", "failed to list manga")
+			writeServiceError(w, req, log, err, "LIST_FAILED", "failed to list manga")
 			return
 		}
 
@@ -78,26 +130,21 @@ func NewRouter(log zerolog.Logger, scrapers *scraper.Manager, lib *library.Servi
 		_ = json.NewEncoder(w).Encode(map[string]interface{}{"data": manga})
 	})
 
-	r.Post("/api/manga", func(w http.ResponseWriter, req *http.Request) {
+	r.With(member).Post("/api/manga", func(w http.ResponseWriter, req *http.Request) {
 		var body library.AddMangaRequest
 		if err := json.NewDecoder(req.Body).Decode(&body); err != nil {
 			writeError(w, http.StatusBadRequest, "INVALID_BODY", "invalid request body")
 			return
 		}
 
-		if body.Source == "" || body.SourceID == "" {
-			writeError(w, http.StatusBadRequest, "MISSING_FIELDS", "source and sourceId are required")
+		if body.URL == "" && (body.Source == "" || body.SourceID == "") {
+			writeError(w, http.StatusBadRequest, "MISSING_FIELDS", "url, or source and sourceId, are required")
 			return
 		}
 
 		manga, err := lib.AddManga(req.Context(), body)
 		if err != nil {
-			log.Error().Err(err).Str("source", body.Source).Str("sourceId", body.SourceID).Msg("failed to add manga")
-			if err == library.ErrMangaExists {
-				writeError(w, http.StatusConflict, "MANGA_EXISTS", "manga already exists in library")
-				return
-			}
-			writeError(w, http.StatusInternalServerError, "ADD_FAILED", "failed to add manga")
+			writeServiceError(w, req, log, err, "ADD_FAILED", "failed to add manga")
 			return
 		}
 
@@ -107,8 +154,7 @@ func NewRouter(log zerolog.Logger, scrapers *scraper.Manager, lib *library.Servi
 	})
 
 	r.Get("/api/manga/{id}", func(w http.ResponseWriter, req *http.Request) {
-		idStr := chi.URLParam(req, "id")
-		id, err := strconv.ParseInt(idStr, 10, 64)
+		id, err := idParam(req)
 		if err != nil {
 			writeError(w, http.StatusBadRequest, "INVALID_ID", "invalid manga ID")
 			return
@@ -116,12 +162,115 @@ func NewRouter(log zerolog.Logger, scrapers *scraper.Manager, lib *library.Servi
 
 		manga, err := lib.GetManga(req.Context(), id)
 		if err != nil {
-			if err == library.ErrMangaNotFound {
-				writeError(w, http.StatusNotFound, "NOT_FOUND", "manga not found")
+			writeServiceError(w, req, log, err, "GET_FAILED", "failed to get manga")
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{"data": manga})
+	})
+
+	r.With(member).Patch("/api/manga/{id}", func(w http.ResponseWriter, req *http.Request) {
+		id, err := idParam(req)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, "INVALID_ID", "invalid manga ID")
+			return
+		}
+
+		var body library.UpdateMangaRequest
+		if err := json.NewDecoder(req.Body).Decode(&body); err != nil {
+			writeError(w, http.StatusBadRequest, "INVALID_BODY", "invalid request body")
+			return
+		}
+
+		manga, err := lib.UpdateManga(req.Context(), id, body)
+		if err != nil {
+			writeServiceError(w, req, log, err, "UPDATE_FAILED", "failed to update manga")
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{"data": manga})
+	})
+
+	r.Get("/api/manga/{id}/cover", func(w http.ResponseWriter, req *http.Request) {
+		id, err := idParam(req)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, "INVALID_ID", "invalid manga ID")
+			return
+		}
+
+		path, err := lib.CoverFile(req.Context(), id, req.URL.Query().Get("size"))
+		if err != nil {
+			writeServiceError(w, req, log, err, "COVER_FAILED", "failed to get cover")
+			return
+		}
+
+		f, err := os.Open(path)
+		if err != nil {
+			writeServiceError(w, req, log, err, "COVER_FAILED", "failed to get cover")
+			return
+		}
+		defer f.Close()
+
+		info, err := f.Stat()
+		if err != nil {
+			writeServiceError(w, req, log, err, "COVER_FAILED", "failed to get cover")
+			return
+		}
+
+		// The ETag changes whenever the file is replaced, so clients may
+		// cache covers but must revalidate them.
+		w.Header().Set("Cache-Control", "public, max-age=86400, must-revalidate")
+		w.Header().Set("ETag", fmt.Sprintf(`"%x-%x"`, info.ModTime().UnixNano(), info.Size()))
+		http.ServeContent(w, req, info.Name(), info.ModTime(), f)
+	})
+
+	r.With(member).Put("/api/manga/{id}/cover", func(w http.ResponseWriter, req *http.Request) {
+		id, err := idParam(req)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, "INVALID_ID", "invalid manga ID")
+			return
+		}
+
+		var manga *database.Manga
+		if mediaType, _, _ := mime.ParseMediaType(req.Header.Get("Content-Type")); mediaType == "application/json" {
+			var body struct {
+				URL string `json:"url"`
+			}
+			if err := json.NewDecoder(req.Body).Decode(&body); err != nil || body.URL == "" {
+				writeError(w, http.StatusBadRequest, "INVALID_BODY", "request body must contain a cover url")
+				return
+			}
+			manga, err = lib.SelectCover(req.Context(), id, body.URL)
+		} else {
+			var data []byte
+			data, err = readCoverUpload(w, req)
+			if err != nil {
+				writeError(w, http.StatusBadRequest, "INVALID_BODY", err.Error())
 				return
 			}
-			log.Error().Err(err).Int64("id", id).Msg("failed to get manga")
-			writeError(w, http.StatusInternalServerError, "GET_FAILED", "failed to get manga")
+			manga, err = lib.UploadCover(req.Context(), id, data)
+		}
+		if err != nil {
+			writeServiceError(w, req, log, err, "COVER_FAILED", "failed to set cover")
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{"data": manga})
+	})
+
+	r.With(member).Delete("/api/manga/{id}/cover", func(w http.ResponseWriter, req *http.Request) {
+		id, err := idParam(req)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, "INVALID_ID", "invalid manga ID")
+			return
+		}
+
+		manga, err := lib.ResetCover(req.Context(), id)
+		if err != nil {
+			writeServiceError(w, req, log, err, "COVER_FAILED", "failed to reset cover")
 			return
 		}
 
@@ -129,17 +278,175 @@ func NewRouter(log zerolog.Logger, scrapers *scraper.Manager, lib *library.Servi
 		_ = json.NewEncoder(w).Encode(map[string]interface{}{"data": manga})
 	})
 
-	r.Delete("/api/manga/{id}", func(w http.ResponseWriter, req *http.Request) {
-		idStr := chi.URLParam(req, "id")
-		id, err := strconv.ParseInt(idStr, 10, 64)
+	r.Get("/api/manga/{id}/covers", func(w http.ResponseWriter, req *http.Request) {
+		id, err := idParam(req)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, "INVALID_ID", "invalid manga ID")
+			return
+		}
+
+		covers, err := lib.ListCovers(req.Context(), id)
+		if err != nil {
+			writeServiceError(w, req, log, err, "LIST_FAILED", "failed to list covers")
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{"data": covers})
+	})
+
+	r.Get("/api/manga/{id}/chapters", func(w http.ResponseWriter, req *http.Request) {
+		id, err := idParam(req)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, "INVALID_ID", "invalid manga ID")
+			return
+		}
+
+		chapters, err := lib.ListChapters(req.Context(), currentUser(req).ID, id)
+		if err != nil {
+			writeServiceError(w, req, log, err, "LIST_FAILED", "failed to list chapters")
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{"data": chapters})
+	})
+
+	r.With(member).Post("/api/manga/{id}/refresh", func(w http.ResponseWriter, req *http.Request) {
+		id, err := idParam(req)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, "INVALID_ID", "invalid manga ID")
+			return
+		}
+
+		if _, err := lib.RefreshMetadata(cacheContext(req), id); err != nil {
+			if errors.Is(err, library.ErrMangaNotFound) {
+				writeServiceError(w, req, log, err, "REFRESH_FAILED", "failed to refresh manga")
+				return
+			}
+			log.Warn().Err(err).Int64("id", id).Msg("failed to refresh metadata")
+		}
+
+		if _, err := lib.SyncChapters(cacheContext(req), id); err != nil {
+			writeServiceError(w, req, log, err, "REFRESH_FAILED", "failed to refresh chapters")
+			return
+		}
+
+		chapters, err := lib.ListChapters(req.Context(), currentUser(req).ID, id)
+		if err != nil {
+			writeServiceError(w, req, log, err, "REFRESH_FAILED", "failed to refresh chapters")
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{"data": chapters})
+	})
+
+	r.Put("/api/manga/{id}/progress", func(w http.ResponseWriter, req *http.Request) {
+		id, err := idParam(req)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, "INVALID_ID", "invalid manga ID")
+			return
+		}
+
+		var body library.MarkReadRequest
+		if err := json.NewDecoder(req.Body).Decode(&body); err != nil {
+			writeError(w, http.StatusBadRequest, "INVALID_BODY", "invalid request body")
+			return
+		}
+
+		chapters, err := lib.MarkRead(req.Context(), currentUser(req).ID, id, body)
+		if err != nil {
+			writeServiceError(w, req, log, err, "PROGRESS_FAILED", "failed to update progress")
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{"data": chapters})
+	})
+
+	r.Get("/api/chapters/{id}/progress", func(w http.ResponseWriter, req *http.Request) {
+		id, err := idParam(req)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, "INVALID_ID", "invalid chapter ID")
+			return
+		}
+
+		progress, err := lib.GetProgress(req.Context(), currentUser(req).ID, id)
+		if err != nil {
+			writeServiceError(w, req, log, err, "PROGRESS_FAILED", "failed to get progress")
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{"data": progress})
+	})
+
+	r.Put("/api/chapters/{id}/progress", func(w http.ResponseWriter, req *http.Request) {
+		id, err := idParam(req)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, "INVALID_ID", "invalid chapter ID")
+			return
+		}
+
+		var body library.ProgressUpdate
+		if err := json.NewDecoder(req.Body).Decode(&body); err != nil {
+			writeError(w, http.StatusBadRequest, "INVALID_BODY", "invalid request body")
+			return
+		}
+
+		progress, err := lib.UpdateProgress(req.Context(), currentUser(req).ID, id, body)
+		if err != nil {
+			writeServiceError(w, req, log, err, "PROGRESS_FAILED", "failed to update progress")
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{"data": progress})
+	})
+
+	r.Delete("/api/chapters/{id}/progress", func(w http.ResponseWriter, req *http.Request) {
+		id, err := idParam(req)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, "INVALID_ID", "invalid chapter ID")
+			return
+		}
+
+		if err := lib.ResetProgress(req.Context(), currentUser(req).ID, id); err != nil {
+			writeServiceError(w, req, log, err, "PROGRESS_FAILED", "failed to reset progress")
+			return
+		}
+
+		w.WriteHeader(http.StatusNoContent)
+	})
+
+	r.With(member).Post("/api/manga/{id}/download", func(w http.ResponseWriter, req *http.Request) {
+		id, err := idParam(req)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, "INVALID_ID", "invalid manga ID")
+			return
+		}
+
+		queued, err := lib.QueueDownloads(req.Context(), id)
+		if err != nil {
+			writeServiceError(w, req, log, err, "QUEUE_FAILED", "failed to queue downloads")
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusAccepted)
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{"data": queued})
+	})
+
+	r.With(member).Delete("/api/manga/{id}", func(w http.ResponseWriter, req *http.Request) {
+		id, err := idParam(req)
 		if err != nil {
 			writeError(w, http.StatusBadRequest, "INVALID_ID", "invalid manga ID")
 			return
 		}
 
 		if err := lib.DeleteManga(req.Context(), id); err != nil {
-			log.Error().Err(err).Int64("id", id).Msg("failed to delete manga")
-			writeError(w, http.StatusInternalServerError, "DELETE_FAILED", "failed to delete manga")
+			writeServiceError(w, req, log, err, "DELETE_FAILED", "failed to delete manga")
 			return
 		}
 
@@ -149,6 +456,48 @@ func NewRouter(log zerolog.Logger, scrapers *scraper.Manager, lib *library.Servi
 	return r
 }
 
+// idParam parses the numeric {id} URL parameter.
+func idParam(req *http.Request) (int64, error) {
+	return strconv.ParseInt(chi.URLParam(req, "id"), 10, 64)
+}
+
+// maxCoverUpload is the largest cover image accepted by PUT /api/manga/{id}/cover.
+const maxCoverUpload = 20 << 20
+
+// readCoverUpload reads an uploaded cover image, sent either as the raw
+// request body or as the "file" field of a multipart form.
+func readCoverUpload(w http.ResponseWriter, req *http.Request) ([]byte, error) {
+	req.Body = http.MaxBytesReader(w, req.Body, maxCoverUpload)
+
+	body := io.Reader(req.Body)
+	if mediaType, _, _ := mime.ParseMediaType(req.Header.Get("Content-Type")); mediaType == "multipart/form-data" {
+		file, _, err := req.FormFile("file")
+		if err != nil {
+			return nil, errors.New("multipart upload must contain a file field")
+		}
+		defer file.Close()
+		body = file
+	}
+
+	data, err := io.ReadAll(body)
+	if err != nil {
+		return nil, errors.New("cover image is too large or incomplete")
+	}
+	if len(data) == 0 {
+		return nil, errors.New("cover image is empty")
+	}
+	return data, nil
+}
+
+// cacheContext returns the request context, bypassing cached source
+// responses when the request asks for it with force=true.
+func cacheContext(req *http.Request) context.Context {
+	if force, _ := strconv.ParseBool(req.URL.Query().Get("force")); force {
+		return scraper.WithoutCache(req.Context())
+	}
+	return req.Context()
+}
+
 func writeError(w http.ResponseWriter, status int, code, message string) {
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(status)
@@ -0,0 +1,211 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/rs/zerolog"
+
+	"github.com/mangashelf/mangashelf/internal/auth"
+	"github.com/mangashelf/mangashelf/internal/events"
+	"github.com/mangashelf/mangashelf/internal/webhook"
+)
+
+const (
+	// defaultDeliveryLimit is how many deliveries are listed when no limit is given.
+	defaultDeliveryLimit = 50
+
+	// maxDeliveryLimit is the most deliveries listed at once.
+	maxDeliveryLimit = 500
+)
+
+// registerWebhookRoutes serves webhook management to admins.
+func registerWebhookRoutes(r chi.Router, log zerolog.Logger, hooks *webhook.Service) {
+	r.Route("/api/webhooks", func(r chi.Router) {
+		r.Use(requireRole(auth.RoleAdmin))
+
+		r.Get("/", func(w http.ResponseWriter, req *http.Request) {
+			list, err := hooks.ListWebhooks(req.Context())
+			if err != nil {
+				writeServiceError(w, req, log, err, "LIST_FAILED", "failed to list webhooks")
+				return
+			}
+
+			w.Header().Set("Content-Type", "application/json")
+			_ = json.NewEncoder(w).Encode(map[string]interface{}{"data": list})
+		})
+
+		r.Post("/", func(w http.ResponseWriter, req *http.Request) {
+			var body webhook.CreateWebhookRequest
+			if err := json.NewDecoder(req.Body).Decode(&body); err != nil {
+				writeError(w, http.StatusBadRequest, "INVALID_BODY", "invalid request body")
+				return
+			}
+
+			hook, err := hooks.CreateWebhook(req.Context(), body)
+			if err != nil {
+				writeServiceError(w, req, log, err, "CREATE_FAILED", "failed to create webhook")
+				return
+			}
+
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusCreated)
+			_ = json.NewEncoder(w).Encode(map[string]interface{}{"data": hook})
+		})
+
+		r.Get("/events", func(w http.ResponseWriter, _ *http.Request) {
+			w.Header().Set("Content-Type", "application/json")
+			_ = json.NewEncoder(w).Encode(map[string]interface{}{"data": events.Types})
+		})
+
+		r.Get("/{id}", func(w http.ResponseWriter, req *http.Request) {
+			id, err := idParam(req)
+			if err != nil {
+				writeError(w, http.StatusBadRequest, "INVALID_ID", "invalid webhook ID")
+				return
+			}
+
+			hook, err := hooks.GetWebhook(req.Context(), id)
+			if err != nil {
+				writeServiceError(w, req, log, err, "GET_FAILED", "failed to get webhook")
+				return
+			}
+
+			w.Header().Set("Content-Type", "application/json")
+			_ = json.NewEncoder(w).Encode(map[string]interface{}{"data": hook})
+		})
+
+		r.Patch("/{id}", func(w http.ResponseWriter, req *http.Request) {
+			id, err := idParam(req)
+			if err != nil {
+				writeError(w, http.StatusBadRequest, "INVALID_ID", "invalid webhook ID")
+				return
+			}
+
+			var body webhook.UpdateWebhookRequest
+			if err := json.NewDecoder(req.Body).Decode(&body); err != nil {
+				writeError(w, http.StatusBadRequest, "INVALID_BODY", "invalid request body")
+				return
+			}
+
+			hook, err := hooks.UpdateWebhook(req.Context(), id, body)
+			if err != nil {
+				writeServiceError(w, req, log, err, "UPDATE_FAILED", "failed to update webhook")
+				return
+			}
+
+			w.Header().Set("Content-Type", "application/json")
+			_ = json.NewEncoder(w).Encode(map[string]interface{}{"data": hook})
+		})
+
+		r.Delete("/{id}", func(w http.ResponseWriter, req *http.Request) {
+			id, err := idParam(req)
+			if err != nil {
+				writeError(w, http.StatusBadRequest, "INVALID_ID", "invalid webhook ID")
+				return
+			}
+
+			if err := hooks.DeleteWebhook(req.Context(), id); err != nil {
+				writeServiceError(w, req, log, err, "DELETE_FAILED", "failed to delete webhook")
+				return
+			}
+
+			w.WriteHeader(http.StatusNoContent)
+		})
+
+		r.Post("/{id}/ping", func(w http.ResponseWriter, req *http.Request) {
+			id, err := idParam(req)
+			if err != nil {
+				writeError(w, http.StatusBadRequest, "INVALID_ID", "invalid webhook ID")
+				return
+			}
+
+			delivery, err := hooks.Ping(req.Context(), id)
+			if err != nil {
+				writeServiceError(w, req, log, err, "PING_FAILED", "failed to queue test delivery")
+				return
+			}
+
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusAccepted)
+			_ = json.NewEncoder(w).Encode(map[string]interface{}{"data": delivery})
+		})
+
+		r.Get("/{id}/deliveries", func(w http.ResponseWriter, req *http.Request) {
+			id, err := idParam(req)
+			if err != nil {
+				writeError(w, http.StatusBadRequest, "INVALID_ID", "invalid webhook ID")
+				return
+			}
+
+			limit := defaultDeliveryLimit
+			if raw := req.URL.Query().Get("limit"); raw != "" {
+				limit, err = strconv.Atoi(raw)
+				if err != nil || limit < 1 {
+					writeError(w, http.StatusBadRequest, "INVALID_LIMIT", "limit must be a positive number")
+					return
+				}
+				limit = min(limit, maxDeliveryLimit)
+			}
+
+			deliveries, err := hooks.ListDeliveries(req.Context(), id, limit)
+			if err != nil {
+				writeServiceError(w, req, log, err, "LIST_FAILED", "failed to list deliveries")
+				return
+			}
+
+			w.Header().Set("Content-Type", "application/json")
+			_ = json.NewEncoder(w).Encode(map[string]interface{}{"data": deliveries})
+		})
+
+		r.Get("/{id}/deliveries/{deliveryID}", func(w http.ResponseWriter, req *http.Request) {
+			id, deliveryID, err := deliveryParams(req)
+			if err != nil {
+				writeError(w, http.StatusBadRequest, "INVALID_ID", "invalid webhook or delivery ID")
+				return
+			}
+
+			delivery, err := hooks.GetDelivery(req.Context(), id, deliveryID)
+			if err != nil {
+				writeServiceError(w, req, log, err, "GET_FAILED", "failed to get delivery")
+				return
+			}
+
+			w.Header().Set("Content-Type", "application/json")
+			_ = json.NewEncoder(w).Encode(map[string]interface{}{"data": delivery})
+		})
+
+		r.Post("/{id}/deliveries/{deliveryID}/redeliver", func(w http.ResponseWriter, req *http.Request) {
+			id, deliveryID, err := deliveryParams(req)
+			if err != nil {
+				writeError(w, http.StatusBadRequest, "INVALID_ID", "invalid webhook or delivery ID")
+				return
+			}
+
+			delivery, err := hooks.Redeliver(req.Context(), id, deliveryID)
+			if err != nil {
+				writeServiceError(w, req, log, err, "REDELIVER_FAILED", "failed to redeliver")
+				return
+			}
+
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusAccepted)
+			_ = json.NewEncoder(w).Encode(map[string]interface{}{"data": delivery})
+		})
+	})
+}
+
+// deliveryParams returns the webhook and delivery IDs of a delivery route.
+func deliveryParams(req *http.Request) (int64, int64, error) {
+	id, err := idParam(req)
+	if err != nil {
+		return 0, 0, err
+	}
+	deliveryID, err := strconv.ParseInt(chi.URLParam(req, "deliveryID"), 10, 64)
+	if err != nil {
+		return 0, 0, err
+	}
+	return id, deliveryID, nil
+}
@@ -0,0 +1,32 @@
+package auth
+
+import "errors"
+
+var (
+	// ErrInvalidCredentials is returned when a username and password do not match.
+	ErrInvalidCredentials = errors.New("invalid username or password")
+
+	// ErrUnauthenticated is returned for a missing, expired or revoked session or API key.
+	ErrUnauthenticated = errors.New("not authenticated")
+
+	// ErrUserExists is returned when creating a user whose username is taken.
+	ErrUserExists = errors.New("user already exists")
+
+	// ErrUserNotFound is returned when a user is not found.
+	ErrUserNotFound = errors.New("user not found")
+
+	// ErrLastAdmin is returned when removing the only remaining admin.
+	ErrLastAdmin = errors.New("cannot remove the last admin")
+
+	// ErrInvalidUsername is returned for an empty or malformed username.
+	ErrInvalidUsername = errors.New("invalid username")
+
+	// ErrInvalidPassword is returned for a password that is too short or too long.
+	ErrInvalidPassword = errors.New("invalid password")
+
+	// ErrInvalidRole is returned for a role other than admin, member or readonly.
+	ErrInvalidRole = errors.New("invalid role")
+
+	// ErrAPIKeyNotFound is returned when an API key is not found.
+	ErrAPIKeyNotFound = errors.New("api key not found")
+)
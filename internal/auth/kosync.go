@@ -0,0 +1,104 @@
+package auth
+
+import (
+	"context"
+	"crypto/md5"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+
+	"golang.org/x/crypto/bcrypt"
+
+	"github.com/mangashelf/mangashelf/internal/database"
+)
+
+// KOReader's sync plugin never sends the password itself, only its MD5 as
+// the sync key. Keys are stored as bcrypt hashes like passwords, but apart
+// from them: a sync password can only be used to sync reading progress.
+
+// SetSyncPassword sets the password a user signs in to KOReader sync with.
+func (s *Service) SetSyncPassword(ctx context.Context, userID int64, password string) error {
+	if len(password) < minPasswordLength || len(password) > maxPasswordLength {
+		return ErrInvalidPassword
+	}
+	if _, err := s.getUser(ctx, userID); err != nil {
+		return err
+	}
+	return s.updateSyncKey(ctx, userID, SyncKey(password))
+}
+
+// ClearSyncPassword turns KOReader sync off for a user.
+func (s *Service) ClearSyncPassword(ctx context.Context, userID int64) error {
+	if _, err := s.getUser(ctx, userID); err != nil {
+		return err
+	}
+	err := s.db.UpdateUserKOSyncKey(ctx, database.UpdateUserKOSyncKeyParams{ID: userID})
+	if err != nil {
+		return fmt.Errorf("clear sync key: %w", err)
+	}
+	return nil
+}
+
+// RegisterSyncUser creates a read-only account from KOReader's registration
+// form. The account has a random password, so it can only sync until an
+// admin sets one.
+func (s *Service) RegisterSyncUser(ctx context.Context, username, key string) (*User, error) {
+	if key == "" {
+		return nil, ErrInvalidPassword
+	}
+	password, err := randomToken(24)
+	if err != nil {
+		return nil, err
+	}
+	user, err := s.CreateUser(ctx, CreateUserRequest{Username: username, Password: password, Role: RoleReadOnly})
+	if err != nil {
+		return nil, err
+	}
+	if err := s.updateSyncKey(ctx, user.ID, key); err != nil {
+		return nil, err
+	}
+	user.KOSyncEnabled = true
+	return user, nil
+}
+
+// CheckSyncKey returns the user matching username and the sync key sent by
+// KOReader.
+func (s *Service) CheckSyncKey(ctx context.Context, username, key string) (*User, error) {
+	dbUser, err := s.db.GetUserByUsername(ctx, username)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("get user: %w", err)
+	}
+	if err != nil || !dbUser.KosyncKeyHash.Valid {
+		// Compare anyway so unknown usernames take as long as wrong keys.
+		_ = bcrypt.CompareHashAndPassword(dummyHash(), []byte(key))
+		return nil, ErrInvalidCredentials
+	}
+
+	if bcrypt.CompareHashAndPassword([]byte(dbUser.KosyncKeyHash.String), []byte(strings.ToLower(key))) != nil {
+		return nil, ErrInvalidCredentials
+	}
+	return toUser(dbUser), nil
+}
+
+// SyncKey returns the key KOReader derives from a sync password.
+func SyncKey(password string) string {
+	sum := md5.Sum([]byte(password))
+	return hex.EncodeToString(sum[:])
+}
+
+func (s *Service) updateSyncKey(ctx context.Context, userID int64, key string) error {
+	hash, err := bcrypt.GenerateFromPassword([]byte(strings.ToLower(key)), bcrypt.DefaultCost)
+	if err != nil {
+		return fmt.Errorf("hash sync key: %w", err)
+	}
+	err = s.db.UpdateUserKOSyncKey(ctx, database.UpdateUserKOSyncKeyParams{
+		KosyncKeyHash: sql.NullString{String: string(hash), Valid: true},
+		ID:            userID,
+	})
+	if err != nil {
+		return fmt.Errorf("update sync key: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,30 @@
+package auth
+
+// Role controls what a user may do.
+type Role string
+
+const (
+	// RoleAdmin manages users in addition to everything members can do.
+	RoleAdmin Role = "admin"
+	// RoleMember adds, changes, downloads and removes manga.
+	RoleMember Role = "member"
+	// RoleReadOnly browses and reads the library and tracks its own progress.
+	RoleReadOnly Role = "readonly"
+)
+
+// roleRanks orders roles from least to most access.
+var roleRanks = map[Role]int{
+	RoleReadOnly: 1,
+	RoleMember:   2,
+	RoleAdmin:    3,
+}
+
+// Valid reports whether r is a known role.
+func (r Role) Valid() bool {
+	return roleRanks[r] > 0
+}
+
+// Allows reports whether r grants at least the access of required.
+func (r Role) Allows(required Role) bool {
+	return r.Valid() && roleRanks[r] >= roleRanks[required]
+}
@@ -0,0 +1,408 @@
+package auth
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+
+	"github.com/rs/zerolog"
+	"golang.org/x/crypto/bcrypt"
+
+	"github.com/mangashelf/mangashelf/internal/database"
+	"github.com/mangashelf/mangashelf/internal/library"
+)
+
+// Service manages user accounts, login sessions and API keys.
+type Service struct {
+	db         *database.Queries
+	sessionTTL time.Duration
+	log        zerolog.Logger
+}
+
+// Options configures authentication.
+type Options struct {
+	// SessionTTL is how long a login session lasts without being used.
+	SessionTTL time.Duration
+}
+
+// DefaultSessionTTL is used when Options.SessionTTL is not set.
+const DefaultSessionTTL = 30 * 24 * time.Hour
+
+// NewService creates a new authentication service.
+func NewService(db *database.Queries, opts Options, log zerolog.Logger) *Service {
+	if opts.SessionTTL <= 0 {
+		opts.SessionTTL = DefaultSessionTTL
+	}
+
+	return &Service{
+		db:         db,
+		sessionTTL: opts.SessionTTL,
+		log:        log.With().Str("component", "auth").Logger(),
+	}
+}
+
+const (
+	minPasswordLength = 8
+	// maxPasswordLength is the most bcrypt hashes; longer passwords are rejected
+	// rather than silently truncated.
+	maxPasswordLength = 72
+	maxUsernameLength = 64
+)
+
+// User is a user account as returned by the API.
+type User struct {
+	ID           int64                `json:"id"`
+	Username     string               `json:"username"`
+	Role         Role                 `json:"role"`
+	Restrictions library.Restrictions `json:"restrictions"`
+	CreatedAt    string               `json:"createdAt"`
+	LastLoginAt  string               `json:"lastLoginAt,omitempty"`
+	// KOSyncEnabled reports whether the user has a KOReader sync password.
+	KOSyncEnabled bool `json:"kosyncEnabled"`
+}
+
+// CreateUserRequest contains parameters for creating a user. Role defaults
+// to RoleMember.
+type CreateUserRequest struct {
+	Username     string               `json:"username"`
+	Password     string               `json:"password"`
+	Role         Role                 `json:"role"`
+	Restrictions library.Restrictions `json:"restrictions"`
+}
+
+// UpdateUserRequest changes a user's access. Nil fields are left untouched.
+type UpdateUserRequest struct {
+	Role         *Role                 `json:"role"`
+	Restrictions *library.Restrictions `json:"restrictions"`
+}
+
+// Bootstrap creates the first admin account when no users exist yet. An
+// empty username defaults to "admin" and an empty password is generated.
+// It returns the created user and the generated password, or a nil user
+// when accounts already exist.
+func (s *Service) Bootstrap(ctx context.Context, username, password string) (*User, string, error) {
+	count, err := s.db.CountUsers(ctx)
+	if err != nil {
+		return nil, "", fmt.Errorf("count users: %w", err)
+	}
+	if count > 0 {
+		return nil, "", nil
+	}
+
+	if username == "" {
+		username = "admin"
+	}
+	generated := ""
+	if password == "" {
+		if password, err = randomToken(12); err != nil {
+			return nil, "", err
+		}
+		generated = password
+	}
+
+	user, err := s.CreateUser(ctx, CreateUserRequest{Username: username, Password: password, Role: RoleAdmin})
+	if err != nil {
+		return nil, "", err
+	}
+	return user, generated, nil
+}
+
+// CreateUser adds a user account.
+func (s *Service) CreateUser(ctx context.Context, req CreateUserRequest) (*User, error) {
+	username := strings.TrimSpace(req.Username)
+	if !validUsername(username) {
+		return nil, ErrInvalidUsername
+	}
+
+	if req.Role == "" {
+		req.Role = RoleMember
+	}
+	if !req.Role.Valid() {
+		return nil, fmt.Errorf("%w: %s", ErrInvalidRole, req.Role)
+	}
+	restrictions, err := req.Restrictions.Normalize()
+	if err != nil {
+		return nil, err
+	}
+
+	hash, err := hashPassword(req.Password)
+	if err != nil {
+		return nil, err
+	}
+
+	dbUser, err := s.db.InsertUser(ctx, database.InsertUserParams{
+		Username:         username,
+		PasswordHash:     hash,
+		Role:             string(req.Role),
+		AllowedTags:      database.ToNullStringList(restrictions.AllowedTags),
+		ExcludedTags:     database.ToNullStringList(restrictions.ExcludedTags),
+		MaxContentRating: toNullString(restrictions.MaxContentRating),
+	})
+	if err != nil {
+		if strings.Contains(err.Error(), "UNIQUE constraint failed") {
+			return nil, ErrUserExists
+		}
+		return nil, fmt.Errorf("insert user: %w", err)
+	}
+
+	s.log.Info().Str("username", username).Str("role", string(req.Role)).Msg("user created")
+	return toUser(dbUser), nil
+}
+
+// UpdateUser changes a user's role and visibility restrictions. The last
+// admin cannot be demoted.
+func (s *Service) UpdateUser(ctx context.Context, id int64, req UpdateUserRequest) (*User, error) {
+	dbUser, err := s.getUser(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	user := toUser(dbUser)
+
+	if req.Role != nil {
+		if !req.Role.Valid() {
+			return nil, fmt.Errorf("%w: %s", ErrInvalidRole, *req.Role)
+		}
+		if user.Role == RoleAdmin && *req.Role != RoleAdmin {
+			if err := s.checkNotLastAdmin(ctx); err != nil {
+				return nil, err
+			}
+		}
+		user.Role = *req.Role
+	}
+	if req.Restrictions != nil {
+		if user.Restrictions, err = req.Restrictions.Normalize(); err != nil {
+			return nil, err
+		}
+	}
+
+	dbUser, err = s.db.UpdateUserAccess(ctx, database.UpdateUserAccessParams{
+		Role:             string(user.Role),
+		AllowedTags:      database.ToNullStringList(user.Restrictions.AllowedTags),
+		ExcludedTags:     database.ToNullStringList(user.Restrictions.ExcludedTags),
+		MaxContentRating: toNullString(user.Restrictions.MaxContentRating),
+		ID:               id,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("update user: %w", err)
+	}
+	return toUser(dbUser), nil
+}
+
+// DefaultUser returns the first admin. Requests are made as this user when
+// authentication is disabled.
+func (s *Service) DefaultUser(ctx context.Context) (*User, error) {
+	dbUser, err := s.db.GetFirstAdmin(ctx)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrUserNotFound
+		}
+		return nil, fmt.Errorf("get admin: %w", err)
+	}
+	return toUser(dbUser), nil
+}
+
+// GetUser returns a user by ID.
+func (s *Service) GetUser(ctx context.Context, id int64) (*User, error) {
+	dbUser, err := s.getUser(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	return toUser(dbUser), nil
+}
+
+// ListUsers returns all user accounts.
+func (s *Service) ListUsers(ctx context.Context) ([]*User, error) {
+	dbUsers, err := s.db.ListUsers(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("list users: %w", err)
+	}
+
+	users := make([]*User, 0, len(dbUsers))
+	for _, u := range dbUsers {
+		users = append(users, toUser(u))
+	}
+	return users, nil
+}
+
+// DeleteUser removes a user account along with its sessions and API keys.
+// The last admin cannot be removed.
+func (s *Service) DeleteUser(ctx context.Context, id int64) error {
+	dbUser, err := s.getUser(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	if Role(dbUser.Role) == RoleAdmin {
+		if err := s.checkNotLastAdmin(ctx); err != nil {
+			return err
+		}
+	}
+
+	deleted, err := s.db.DeleteUser(ctx, id)
+	if err != nil {
+		return fmt.Errorf("delete user: %w", err)
+	}
+	if deleted == 0 {
+		return ErrUserNotFound
+	}
+
+	s.log.Info().Str("username", dbUser.Username).Msg("user deleted")
+	return nil
+}
+
+// ChangePassword sets a new password after checking the current one. Other
+// sessions of the user are signed out; the session identified by keepToken
+// stays valid.
+func (s *Service) ChangePassword(ctx context.Context, userID int64, current, next, keepToken string) error {
+	dbUser, err := s.getUser(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if bcrypt.CompareHashAndPassword([]byte(dbUser.PasswordHash), []byte(current)) != nil {
+		return ErrInvalidCredentials
+	}
+
+	if err := s.updatePassword(ctx, userID, next); err != nil {
+		return err
+	}
+
+	err = s.db.DeleteOtherUserSessions(ctx, database.DeleteOtherUserSessionsParams{
+		UserID:    userID,
+		TokenHash: hashToken(keepToken),
+	})
+	if err != nil {
+		return fmt.Errorf("delete sessions: %w", err)
+	}
+	return nil
+}
+
+// SetPassword replaces a user's password without checking the old one and
+// signs out all of the user's sessions. API keys stay valid.
+func (s *Service) SetPassword(ctx context.Context, userID int64, password string) error {
+	if _, err := s.getUser(ctx, userID); err != nil {
+		return err
+	}
+	if err := s.updatePassword(ctx, userID, password); err != nil {
+		return err
+	}
+	if err := s.db.DeleteUserSessions(ctx, userID); err != nil {
+		return fmt.Errorf("delete sessions: %w", err)
+	}
+	return nil
+}
+
+// SetPasswordByUsername is SetPassword for a user identified by name.
+func (s *Service) SetPasswordByUsername(ctx context.Context, username, password string) error {
+	dbUser, err := s.db.GetUserByUsername(ctx, username)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrUserNotFound
+		}
+		return fmt.Errorf("get user: %w", err)
+	}
+	return s.SetPassword(ctx, dbUser.ID, password)
+}
+
+// CheckPassword returns the user matching username and password.
+func (s *Service) CheckPassword(ctx context.Context, username, password string) (*User, error) {
+	dbUser, err := s.db.GetUserByUsername(ctx, username)
+	if err != nil {
+		if !errors.Is(err, sql.ErrNoRows) {
+			return nil, fmt.Errorf("get user: %w", err)
+		}
+		// Compare anyway so unknown usernames take as long as wrong passwords.
+		_ = bcrypt.CompareHashAndPassword(dummyHash(), []byte(password))
+		return nil, ErrInvalidCredentials
+	}
+
+	if bcrypt.CompareHashAndPassword([]byte(dbUser.PasswordHash), []byte(password)) != nil {
+		return nil, ErrInvalidCredentials
+	}
+	return toUser(dbUser), nil
+}
+
+// checkNotLastAdmin fails when only one admin is left.
+func (s *Service) checkNotLastAdmin(ctx context.Context) error {
+	admins, err := s.db.CountAdmins(ctx)
+	if err != nil {
+		return fmt.Errorf("count admins: %w", err)
+	}
+	if admins <= 1 {
+		return ErrLastAdmin
+	}
+	return nil
+}
+
+func (s *Service) getUser(ctx context.Context, id int64) (*database.User, error) {
+	dbUser, err := s.db.GetUser(ctx, id)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrUserNotFound
+		}
+		return nil, fmt.Errorf("get user: %w", err)
+	}
+	return dbUser, nil
+}
+
+func (s *Service) updatePassword(ctx context.Context, userID int64, password string) error {
+	hash, err := hashPassword(password)
+	if err != nil {
+		return err
+	}
+	err = s.db.UpdateUserPassword(ctx, database.UpdateUserPasswordParams{PasswordHash: hash, ID: userID})
+	if err != nil {
+		return fmt.Errorf("update password: %w", err)
+	}
+	return nil
+}
+
+// dummyHash is compared against when a login names an unknown user.
+var dummyHash = sync.OnceValue(func() []byte {
+	hash, _ := bcrypt.GenerateFromPassword([]byte("mangashelf"), bcrypt.DefaultCost)
+	return hash
+})
+
+func hashPassword(password string) (string, error) {
+	if len(password) < minPasswordLength || len(password) > maxPasswordLength {
+		return "", ErrInvalidPassword
+	}
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return "", fmt.Errorf("hash password: %w", err)
+	}
+	return string(hash), nil
+}
+
+// validUsername accepts letters, digits, '.', '_', '-' and '@'.
+func validUsername(username string) bool {
+	if username == "" || len([]rune(username)) > maxUsernameLength {
+		return false
+	}
+	for _, r := range username {
+		if !unicode.IsLetter(r) && !unicode.IsDigit(r) && !strings.ContainsRune("._-@", r) {
+			return false
+		}
+	}
+	return true
+}
+
+func toUser(u *database.User) *User {
+	return &User{
+		ID:       u.ID,
+		Username: u.Username,
+		Role:     Role(u.Role),
+		Restrictions: library.Restrictions{
+			AllowedTags:      database.FromNullStringList(u.AllowedTags),
+			ExcludedTags:     database.FromNullStringList(u.ExcludedTags),
+			MaxContentRating: u.MaxContentRating.String,
+		},
+		CreatedAt:     u.CreatedAt.String,
+		LastLoginAt:   u.LastLoginAt.String,
+		KOSyncEnabled: u.KosyncKeyHash.Valid,
+	}
+}
@@ -0,0 +1,290 @@
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/mangashelf/mangashelf/internal/database"
+)
+
+// Session is a login session. Token is the secret sent back as a cookie;
+// only its hash is stored.
+type Session struct {
+	Token     string
+	User      *User
+	ExpiresAt time.Time
+}
+
+// Client describes where a login comes from.
+type Client struct {
+	UserAgent string
+	IPAddress string
+}
+
+// APIKey is a long-lived credential for scripts and reader apps.
+type APIKey struct {
+	ID         int64  `json:"id"`
+	Name       string `json:"name"`
+	Prefix     string `json:"prefix"`
+	CreatedAt  string `json:"createdAt"`
+	LastUsedAt string `json:"lastUsedAt,omitempty"`
+}
+
+// NewAPIKey is a freshly created API key. Key is only available here; it
+// cannot be retrieved again.
+type NewAPIKey struct {
+	APIKey
+	Key string `json:"key"`
+}
+
+const (
+	// apiKeyPrefix marks MangaShelf API keys so they can be told apart from
+	// passwords in Basic credentials.
+	apiKeyPrefix = "msk_"
+	// apiKeyShownLength is how much of a key is kept to identify it in listings.
+	apiKeyShownLength = len(apiKeyPrefix) + 6
+	// touchInterval limits how often last-seen times are written.
+	touchInterval = time.Minute
+)
+
+// Login checks the credentials and starts a session.
+func (s *Service) Login(ctx context.Context, username, password string, client Client) (*Session, error) {
+	user, err := s.CheckPassword(ctx, username, password)
+	if err != nil {
+		return nil, err
+	}
+
+	token, err := randomToken(32)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now().UTC()
+	expires := now.Add(s.sessionTTL)
+	err = s.db.InsertSession(ctx, database.InsertSessionParams{
+		UserID:     user.ID,
+		TokenHash:  hashToken(token),
+		UserAgent:  toNullString(client.UserAgent),
+		IpAddress:  toNullString(client.IPAddress),
+		LastSeenAt: toNullString(now.Format(database.TimeFormat)),
+		ExpiresAt:  expires.Format(database.TimeFormat),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("insert session: %w", err)
+	}
+
+	if err := s.db.UpdateUserLastLogin(ctx, user.ID); err != nil {
+		s.log.Warn().Err(err).Str("username", user.Username).Msg("failed to record login")
+	}
+
+	s.log.Info().Str("username", user.Username).Str("ip", client.IPAddress).Msg("user logged in")
+	return &Session{Token: token, User: user, ExpiresAt: expires}, nil
+}
+
+// Logout ends the session identified by token.
+func (s *Service) Logout(ctx context.Context, token string) error {
+	if err := s.db.DeleteSessionByTokenHash(ctx, hashToken(token)); err != nil {
+		return fmt.Errorf("delete session: %w", err)
+	}
+	return nil
+}
+
+// AuthenticateSession returns the session for token. Sessions expire after
+// the session TTL without use; each use extends them.
+func (s *Service) AuthenticateSession(ctx context.Context, token string) (*Session, error) {
+	if token == "" {
+		return nil, ErrUnauthenticated
+	}
+
+	session, err := s.db.GetSessionByTokenHash(ctx, hashToken(token))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrUnauthenticated
+		}
+		return nil, fmt.Errorf("get session: %w", err)
+	}
+
+	now := time.Now().UTC()
+	expires, err := time.Parse(database.TimeFormat, session.ExpiresAt)
+	if err != nil || !now.Before(expires) {
+		return nil, ErrUnauthenticated
+	}
+
+	user, err := s.GetUser(ctx, session.UserID)
+	if err != nil {
+		if errors.Is(err, ErrUserNotFound) {
+			return nil, ErrUnauthenticated
+		}
+		return nil, err
+	}
+
+	if due(session.LastSeenAt, now) {
+		expires = now.Add(s.sessionTTL)
+		err := s.db.TouchSession(ctx, database.TouchSessionParams{
+			LastSeenAt: toNullString(now.Format(database.TimeFormat)),
+			ExpiresAt:  expires.Format(database.TimeFormat),
+			ID:         session.ID,
+		})
+		if err != nil {
+			s.log.Warn().Err(err).Msg("failed to extend session")
+		}
+	}
+
+	return &Session{Token: token, User: user, ExpiresAt: expires}, nil
+}
+
+// PruneSessions deletes expired sessions.
+func (s *Service) PruneSessions(ctx context.Context) error {
+	now := time.Now().UTC().Format(database.TimeFormat)
+	if err := s.db.DeleteSessionsExpiredBefore(ctx, now); err != nil {
+		return fmt.Errorf("delete expired sessions: %w", err)
+	}
+	return nil
+}
+
+// CreateAPIKey creates a named API key for a user.
+func (s *Service) CreateAPIKey(ctx context.Context, userID int64, name string) (*NewAPIKey, error) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		name = "API key"
+	}
+
+	secret, err := randomToken(32)
+	if err != nil {
+		return nil, err
+	}
+	key := apiKeyPrefix + secret
+
+	dbKey, err := s.db.InsertAPIKey(ctx, database.InsertAPIKeyParams{
+		UserID:  userID,
+		Name:    name,
+		Prefix:  key[:apiKeyShownLength],
+		KeyHash: hashToken(key),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("insert api key: %w", err)
+	}
+
+	return &NewAPIKey{APIKey: *toAPIKey(dbKey), Key: key}, nil
+}
+
+// ListAPIKeys returns a user's API keys.
+func (s *Service) ListAPIKeys(ctx context.Context, userID int64) ([]*APIKey, error) {
+	dbKeys, err := s.db.ListUserAPIKeys(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("list api keys: %w", err)
+	}
+
+	keys := make([]*APIKey, 0, len(dbKeys))
+	for _, k := range dbKeys {
+		keys = append(keys, toAPIKey(k))
+	}
+	return keys, nil
+}
+
+// DeleteAPIKey revokes one of a user's API keys.
+func (s *Service) DeleteAPIKey(ctx context.Context, userID, id int64) error {
+	deleted, err := s.db.DeleteUserAPIKey(ctx, database.DeleteUserAPIKeyParams{ID: id, UserID: userID})
+	if err != nil {
+		return fmt.Errorf("delete api key: %w", err)
+	}
+	if deleted == 0 {
+		return ErrAPIKeyNotFound
+	}
+	return nil
+}
+
+// AuthenticateAPIKey returns the owner of key.
+func (s *Service) AuthenticateAPIKey(ctx context.Context, key string) (*User, error) {
+	if !IsAPIKey(key) {
+		return nil, ErrUnauthenticated
+	}
+
+	dbKey, err := s.db.GetAPIKeyByHash(ctx, hashToken(key))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrUnauthenticated
+		}
+		return nil, fmt.Errorf("get api key: %w", err)
+	}
+
+	user, err := s.GetUser(ctx, dbKey.UserID)
+	if err != nil {
+		if errors.Is(err, ErrUserNotFound) {
+			return nil, ErrUnauthenticated
+		}
+		return nil, err
+	}
+
+	if due(dbKey.LastUsedAt, time.Now().UTC()) {
+		if err := s.db.TouchAPIKey(ctx, dbKey.ID); err != nil {
+			s.log.Warn().Err(err).Msg("failed to record api key use")
+		}
+	}
+	return user, nil
+}
+
+// IsAPIKey reports whether s has the form of an API key.
+func IsAPIKey(s string) bool {
+	return strings.HasPrefix(s, apiKeyPrefix) && len(s) > apiKeyShownLength
+}
+
+type userKey struct{}
+
+// WithUser returns a context carrying the authenticated user.
+func WithUser(ctx context.Context, user *User) context.Context {
+	return context.WithValue(ctx, userKey{}, user)
+}
+
+// UserFromContext returns the authenticated user stored by WithUser.
+func UserFromContext(ctx context.Context) (*User, bool) {
+	user, ok := ctx.Value(userKey{}).(*User)
+	return user, ok && user != nil
+}
+
+// due reports whether a last-seen time is older than touchInterval.
+func due(lastSeen sql.NullString, now time.Time) bool {
+	if !lastSeen.Valid {
+		return true
+	}
+	t, err := time.Parse(database.TimeFormat, lastSeen.String)
+	return err != nil || now.Sub(t) >= touchInterval
+}
+
+// randomToken returns n random bytes encoded as URL-safe base64.
+func randomToken(n int) (string, error) {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("generate token: %w", err)
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// hashToken returns the hex SHA-256 of a session token or API key. Tokens
+// carry enough entropy that a fast hash is sufficient.
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+func toAPIKey(k *database.ApiKey) *APIKey {
+	return &APIKey{
+		ID:         k.ID,
+		Name:       k.Name,
+		Prefix:     k.Prefix,
+		CreatedAt:  k.CreatedAt.String,
+		LastUsedAt: k.LastUsedAt.String,
+	}
+}
+
+func toNullString(s string) sql.NullString {
+	return sql.NullString{String: s, Valid: s != ""}
+}
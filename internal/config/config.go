@@ -19,10 +19,12 @@ type Config struct {
 	Updates       UpdateConfig       `mapstructure:"updates"`
 	Metadata      MetadataConfig     `mapstructure:"metadata"`
 	Notifications NotificationConfig `mapstructure:"notifications"`
+	Webhooks      WebhookConfig      `mapstructure:"webhooks"`
 	Reader        ReaderConfig       `mapstructure:"reader"`
 	Sources       SourceConfig       `mapstructure:"sources"`
 	Logging       LoggingConfig      `mapstructure:"logging"`
 	Database      DatabaseConfig     `mapstructure:"database"`
+	Security      SecurityConfig     `mapstructure:"security"`
 }
 
 type ServerConfig struct {
@@ -30,11 +32,30 @@ type ServerConfig struct {
 	Port    int        `mapstructure:"port"`
 	BaseURL string     `mapstructure:"baseUrl"`
 	CORS    CORSConfig `mapstructure:"cors"`
+	// TrustedProxies lists the networks, as CIDRs or addresses, allowed to
+	// report the client address and scheme in forwarded headers.
+	TrustedProxies []string `mapstructure:"trustedProxies"`
+	// Socket, when set, listens on a Unix domain socket instead of Host and Port.
+	Socket string    `mapstructure:"socket"`
+	HTTP2  bool      `mapstructure:"http2"`
+	TLS    TLSConfig `mapstructure:"tls"`
+}
+
+// TLSConfig configures HTTPS. Certificate files are reloaded when they change.
+type TLSConfig struct {
+	Enabled    bool   `mapstructure:"enabled"`
+	CertFile   string `mapstructure:"certFile"`
+	KeyFile    string `mapstructure:"keyFile"`
+	MinVersion string `mapstructure:"minVersion"`
+	// RedirectPort, when set, serves plain HTTP redirects to HTTPS on that port.
+	RedirectPort int `mapstructure:"redirectPort"`
 }
 
 type CORSConfig struct {
-	Enabled bool     `mapstructure:"enabled"`
-	Origins []string `mapstructure:"origins"`
+	Enabled          bool          `mapstructure:"enabled"`
+	Origins          []string      `mapstructure:"origins"`
+	AllowCredentials bool          `mapstructure:"allowCredentials"`
+	MaxAge           time.Duration `mapstructure:"maxAge"`
 }
 
 type LibraryConfig struct {
@@ -47,9 +68,12 @@ type DownloaderConfig struct {
 	Workers       int           `mapstructure:"workers"`
 	RetryAttempts int           `mapstructure:"retryAttempts"`
 	RetryDelay    time.Duration `mapstructure:"retryDelay"`
-	Timeout       time.Duration `mapstructure:"timeout"`
-	RateLimit     string        `mapstructure:"rateLimit"`
-	UserAgent     string        `mapstructure:"userAgent"`
+	// MaxAttempts is how often a chapter download is tried before it is
+	// marked failed. Page retries within one attempt use RetryAttempts.
+	MaxAttempts int           `mapstructure:"maxAttempts"`
+	Timeout     time.Duration `mapstructure:"timeout"`
+	RateLimit   string        `mapstructure:"rateLimit"`
+	UserAgent   string        `mapstructure:"userAgent"`
 }
 
 type FormatConfig struct {
@@ -83,10 +107,43 @@ type NotificationConfig struct {
 	Enabled bool          `mapstructure:"enabled"`
 	Apprise AppriseConfig `mapstructure:"apprise"`
 	Events  EventConfig   `mapstructure:"events"`
+	// BatchWindow is how long events of one kind for the same manga are
+	// collected into a single notification.
+	BatchWindow time.Duration  `mapstructure:"batchWindow"`
+	Digest      DigestConfig   `mapstructure:"digest"`
+	Templates   TemplateConfig `mapstructure:"templates"`
+}
+
+// DigestConfig holds new chapters and failed downloads back from instant
+// notifications and sends them as one summary on Schedule, a cron
+// expression such as "0 9 * * *" or "@weekly".
+type DigestConfig struct {
+	Enabled  bool   `mapstructure:"enabled"`
+	Schedule string `mapstructure:"schedule"`
 }
 
+// AppriseConfig sets where notifications are sent. Each URL is notified
+// through the Apprise API server at API when set, and by running Command
+// otherwise.
 type AppriseConfig struct {
-	URLs []string `mapstructure:"urls"`
+	URLs    []string `mapstructure:"urls"`
+	API     string   `mapstructure:"api"`
+	Command string   `mapstructure:"command"`
+}
+
+// TemplateConfig overrides the notification text per event. Empty fields
+// keep the built-in text.
+type TemplateConfig struct {
+	NewChapters      MessageTemplate `mapstructure:"newChapters"`
+	DownloadComplete MessageTemplate `mapstructure:"downloadComplete"`
+	DownloadFailed   MessageTemplate `mapstructure:"downloadFailed"`
+	Digest           MessageTemplate `mapstructure:"digest"`
+}
+
+// MessageTemplate is a Go text/template for a notification's title and body.
+type MessageTemplate struct {
+	Title string `mapstructure:"title"`
+	Body  string `mapstructure:"body"`
 }
 
 type EventConfig struct {
@@ -95,6 +152,19 @@ type EventConfig struct {
 	DownloadFailed   bool `mapstructure:"downloadFailed"`
 }
 
+// WebhookConfig configures the delivery of webhooks, which are managed
+// through the API.
+type WebhookConfig struct {
+	// MaxAttempts is how often a delivery is tried before it is marked failed.
+	MaxAttempts int `mapstructure:"maxAttempts"`
+	// RetryDelay is the wait before the first retry; it doubles for each
+	// retry after that.
+	RetryDelay time.Duration `mapstructure:"retryDelay"`
+	Timeout    time.Duration `mapstructure:"timeout"`
+	// Retention is how long finished deliveries stay in the delivery log.
+	Retention time.Duration `mapstructure:"retention"`
+}
+
 type ReaderConfig struct {
 	DefaultMode      string `mapstructure:"defaultMode"`
 	DefaultDirection string `mapstructure:"defaultDirection"`
@@ -106,12 +176,79 @@ type SourceConfig struct {
 	CustomPath string         `mapstructure:"customPath"`
 	Default    string         `mapstructure:"default"`
 	Mangadex   MangadexConfig `mapstructure:"mangadex"`
+	Local      LocalConfig    `mapstructure:"local"`
+	Plugins    PluginConfig   `mapstructure:"plugins"`
+	Health     HealthConfig   `mapstructure:"health"`
+	Cache      CacheConfig    `mapstructure:"cache"`
+	// Proxy applies to every provider without an entry in Proxies.
+	Proxy   ProxyConfig            `mapstructure:"proxy"`
+	Proxies map[string]ProxyConfig `mapstructure:"proxies"`
+}
+
+// ProxyConfig routes provider traffic through an HTTP or SOCKS5 proxy.
+type ProxyConfig struct {
+	URL      string   `mapstructure:"url"`
+	Username string   `mapstructure:"username"`
+	Password string   `mapstructure:"password"`
+	NoProxy  []string `mapstructure:"noProxy"`
+}
+
+// CacheConfig configures the cache for provider responses.
+type CacheConfig struct {
+	Enabled bool `mapstructure:"enabled"`
+	// Persist keeps cached responses in the database across restarts.
+	Persist     bool           `mapstructure:"persist"`
+	MaxEntries  int            `mapstructure:"maxEntries"`
+	MaxBodySize int64          `mapstructure:"maxBodySize"`
+	TTL         CacheTTLConfig `mapstructure:"ttl"`
+}
+
+// CacheTTLConfig sets how long responses stay fresh per provider call.
+type CacheTTLConfig struct {
+	Search   time.Duration `mapstructure:"search"`
+	Manga    time.Duration `mapstructure:"manga"`
+	Chapters time.Duration `mapstructure:"chapters"`
+	Pages    time.Duration `mapstructure:"pages"`
+}
+
+// HealthConfig configures provider health checks and the circuit breaker.
+type HealthConfig struct {
+	FailureThreshold int           `mapstructure:"failureThreshold"`
+	OpenDuration     time.Duration `mapstructure:"openDuration"`
+	ProbeInterval    time.Duration `mapstructure:"probeInterval"`
+	ProbeTimeout     time.Duration `mapstructure:"probeTimeout"`
+}
+
+// PluginConfig configures executable scraper plugins found in the custom path.
+type PluginConfig struct {
+	Enabled     bool          `mapstructure:"enabled"`
+	Timeout     time.Duration `mapstructure:"timeout"`
+	MaxRestarts int           `mapstructure:"maxRestarts"`
+}
+
+// LocalConfig configures the provider serving series from a local folder tree.
+type LocalConfig struct {
+	Enabled bool   `mapstructure:"enabled"`
+	Path    string `mapstructure:"path"`
 }
 
 type MangadexConfig struct {
-	Language        string `mapstructure:"language"`
-	NSFW            bool   `mapstructure:"nsfw"`
-	ShowUnavailable bool   `mapstructure:"showUnavailable"`
+	Language        string   `mapstructure:"language"`
+	Languages       []string `mapstructure:"languages"`
+	NSFW            bool     `mapstructure:"nsfw"`
+	ShowUnavailable bool     `mapstructure:"showUnavailable"`
+}
+
+// PreferredLanguages returns the configured chapter languages in fallback order.
+// The legacy single "language" setting is used when no list is configured.
+func (c MangadexConfig) PreferredLanguages() []string {
+	if len(c.Languages) > 0 {
+		return c.Languages
+	}
+	if c.Language != "" {
+		return []string{c.Language}
+	}
+	return []string{"en"}
 }
 
 type LoggingConfig struct {
@@ -132,6 +269,31 @@ type DatabaseConfig struct {
 	WALMode bool   `mapstructure:"walMode"`
 }
 
+// SecurityConfig configures access to the server.
+type SecurityConfig struct {
+	Auth AuthConfig `mapstructure:"auth"`
+}
+
+// AuthConfig configures user authentication.
+type AuthConfig struct {
+	Enabled    bool          `mapstructure:"enabled"`
+	SessionTTL time.Duration `mapstructure:"sessionTtl"`
+	// SecureCookies marks session cookies Secure; enable behind an HTTPS proxy.
+	SecureCookies bool `mapstructure:"secureCookies"`
+	// KOSyncRegistration lets KOReader create read-only accounts through the
+	// sync server.
+	KOSyncRegistration bool `mapstructure:"kosyncRegistration"`
+	// Admin is the account created on first run, when no users exist.
+	Admin AdminConfig `mapstructure:"admin"`
+}
+
+// AdminConfig sets the credentials of the first-run admin account. An empty
+// password is generated and printed to the log.
+type AdminConfig struct {
+	Username string `mapstructure:"username"`
+	Password string `mapstructure:"password"`
+}
+
 // Load reads configuration from file, environment variables, and defaults.
 func Load(configPath string) (*Config, error) {
 	v := viper.New()
@@ -179,6 +341,16 @@ func setDefaults(v *viper.Viper) {
 	v.SetDefault("server.baseUrl", "")
 	v.SetDefault("server.cors.enabled", false)
 	v.SetDefault("server.cors.origins", []string{})
+	v.SetDefault("server.cors.allowCredentials", false)
+	v.SetDefault("server.cors.maxAge", "10m")
+	v.SetDefault("server.trustedProxies", []string{"127.0.0.0/8", "::1/128"})
+	v.SetDefault("server.socket", "")
+	v.SetDefault("server.http2", true)
+	v.SetDefault("server.tls.enabled", false)
+	v.SetDefault("server.tls.certFile", "")
+	v.SetDefault("server.tls.keyFile", "")
+	v.SetDefault("server.tls.minVersion", "1.2")
+	v.SetDefault("server.tls.redirectPort", 0)
 
 	v.SetDefault("library.path", "./data/manga")
 	v.SetDefault("library.scanOnStartup", true)
@@ -187,6 +359,7 @@ func setDefaults(v *viper.Viper) {
 	v.SetDefault("downloader.workers", 3)
 	v.SetDefault("downloader.retryAttempts", 3)
 	v.SetDefault("downloader.retryDelay", "5s")
+	v.SetDefault("downloader.maxAttempts", 3)
 	v.SetDefault("downloader.timeout", "30s")
 	v.SetDefault("downloader.rateLimit", "2/s")
 	v.SetDefault("downloader.userAgent", "MangaShelf/1.0")
@@ -210,9 +383,27 @@ func setDefaults(v *viper.Viper) {
 
 	v.SetDefault("notifications.enabled", false)
 	v.SetDefault("notifications.apprise.urls", []string{})
+	v.SetDefault("notifications.apprise.api", "")
+	v.SetDefault("notifications.apprise.command", "apprise")
 	v.SetDefault("notifications.events.newChapters", true)
 	v.SetDefault("notifications.events.downloadComplete", false)
 	v.SetDefault("notifications.events.downloadFailed", true)
+	v.SetDefault("notifications.batchWindow", "2m")
+	v.SetDefault("notifications.digest.enabled", false)
+	v.SetDefault("notifications.digest.schedule", "0 9 * * *")
+	v.SetDefault("notifications.templates.newChapters.title", "")
+	v.SetDefault("notifications.templates.newChapters.body", "")
+	v.SetDefault("notifications.templates.downloadComplete.title", "")
+	v.SetDefault("notifications.templates.downloadComplete.body", "")
+	v.SetDefault("notifications.templates.downloadFailed.title", "")
+	v.SetDefault("notifications.templates.downloadFailed.body", "")
+	v.SetDefault("notifications.templates.digest.title", "")
+	v.SetDefault("notifications.templates.digest.body", "")
+
+	v.SetDefault("webhooks.maxAttempts", 5)
+	v.SetDefault("webhooks.retryDelay", "1m")
+	v.SetDefault("webhooks.timeout", "10s")
+	v.SetDefault("webhooks.retention", "720h")
 
 	v.SetDefault("reader.defaultMode", "single")
 	v.SetDefault("reader.defaultDirection", "rtl")
@@ -222,8 +413,28 @@ func setDefaults(v *viper.Viper) {
 	v.SetDefault("sources.customPath", "./data/scrapers")
 	v.SetDefault("sources.default", "mangadex")
 	v.SetDefault("sources.mangadex.language", "en")
+	v.SetDefault("sources.mangadex.languages", []string{})
 	v.SetDefault("sources.mangadex.nsfw", false)
 	v.SetDefault("sources.mangadex.showUnavailable", false)
+	v.SetDefault("sources.local.enabled", false)
+	v.SetDefault("sources.local.path", "./data/local")
+	v.SetDefault("sources.plugins.enabled", true)
+	v.SetDefault("sources.plugins.timeout", "30s")
+	v.SetDefault("sources.plugins.maxRestarts", 5)
+	v.SetDefault("sources.health.failureThreshold", 5)
+	v.SetDefault("sources.health.openDuration", "1m")
+	v.SetDefault("sources.health.probeInterval", "5m")
+	v.SetDefault("sources.health.probeTimeout", "10s")
+	v.SetDefault("sources.cache.enabled", true)
+	v.SetDefault("sources.cache.persist", true)
+	v.SetDefault("sources.cache.maxEntries", 1000)
+	v.SetDefault("sources.cache.maxBodySize", 2<<20)
+	v.SetDefault("sources.cache.ttl.search", "10m")
+	v.SetDefault("sources.cache.ttl.manga", "6h")
+	v.SetDefault("sources.cache.ttl.chapters", "15m")
+	v.SetDefault("sources.cache.ttl.pages", "5m")
+	v.SetDefault("sources.proxy.url", "")
+	v.SetDefault("sources.proxy.noProxy", []string{})
 
 	v.SetDefault("logging.level", "info")
 	v.SetDefault("logging.format", "text")
@@ -234,4 +445,11 @@ func setDefaults(v *viper.Viper) {
 
 	v.SetDefault("database.path", "./data/mangashelf.db")
 	v.SetDefault("database.walMode", true)
+
+	v.SetDefault("security.auth.enabled", true)
+	v.SetDefault("security.auth.sessionTtl", "720h")
+	v.SetDefault("security.auth.secureCookies", false)
+	v.SetDefault("security.auth.kosyncRegistration", false)
+	v.SetDefault("security.auth.admin.username", "admin")
+	v.SetDefault("security.auth.admin.password", "")
 }
@@ -10,8 +10,24 @@ import (
 	"database/sql"
 )
 
+const countOtherChaptersWithFile = `-- name: CountOtherChaptersWithFile :one
+SELECT COUNT(*) AS count FROM chapter WHERE file_path = ? AND id != ?
+`
+
+type CountOtherChaptersWithFileParams struct {
+	FilePath sql.NullString `json:"file_path"`
+	ID       int64          `json:"id"`
+}
+
+func (q *Queries) CountOtherChaptersWithFile(ctx context.Context, arg CountOtherChaptersWithFileParams) (int64, error) {
+	row := q.db.QueryRowContext(ctx, countOtherChaptersWithFile, arg.FilePath, arg.ID)
+	var count int64
+	err := row.Scan(&count)
+	return count, err
+}
+
 const getChapter = `-- name: GetChapter :one
-SELECT id, manga_id, title, number, volume, source_id, url, status, file_path, file_size, page_count, is_read, current_page, read_at, published_at, downloaded_at, created_at FROM chapter WHERE id = ? LIMIT 1
+SELECT id, manga_id, title, number, volume, language, scanlation_groups, source_id, url, unavailable, status, file_path, file_size, page_count, published_at, downloaded_at, created_at FROM chapter WHERE id = ? LIMIT 1
 `
 
 func (q *Queries) GetChapter(ctx context.Context, id int64) (*Chapter, error) {
@@ -23,15 +39,15 @@ func (q *Queries) GetChapter(ctx context.Context, id int64) (*Chapter, error) {
 		&i.Title,
 		&i.Number,
 		&i.Volume,
+		&i.Language,
+		&i.ScanlationGroups,
 		&i.SourceID,
 		&i.Url,
+		&i.Unavailable,
 		&i.Status,
 		&i.FilePath,
 		&i.FileSize,
 		&i.PageCount,
-		&i.IsRead,
-		&i.CurrentPage,
-		&i.ReadAt,
 		&i.PublishedAt,
 		&i.DownloadedAt,
 		&i.CreatedAt,
@@ -41,24 +57,30 @@ func (q *Queries) GetChapter(ctx context.Context, id int64) (*Chapter, error) {
 
 const insertChapter = `-- name: InsertChapter :one
 INSERT INTO chapter (
-    manga_id, title, number, volume, source_id, url, published_at
-) VALUES (?, ?, ?, ?, ?, ?, ?)
+    manga_id, title, number, volume, language, scanlation_groups, source_id, url, unavailable, published_at
+) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
 ON CONFLICT (manga_id, source_id) DO UPDATE SET
     title = excluded.title,
     number = excluded.number,
     volume = excluded.volume,
-    url = excluded.url
-RETURNING id, manga_id, title, number, volume, source_id, url, status, file_path, file_size, page_count, is_read, current_page, read_at, published_at, downloaded_at, created_at
+    language = excluded.language,
+    scanlation_groups = excluded.scanlation_groups,
+    url = excluded.url,
+    unavailable = excluded.unavailable
+RETURNING id, manga_id, title, number, volume, language, scanlation_groups, source_id, url, unavailable, status, file_path, file_size, page_count, published_at, downloaded_at, created_at
 `
 
 type InsertChapterParams struct {
-	MangaID     int64          `json:"manga_id"`
-	Title       string         `json:"title"`
-	Number      float64        `json:"number"`
-	Volume      sql.NullString `json:"volume"`
-	SourceID    string         `json:"source_id"`
-	Url         string         `json:"url"`
-	PublishedAt sql.NullString `json:"published_at"`
+	MangaID          int64          `json:"manga_id"`
+	Title            string         `json:"title"`
+	Number           float64        `json:"number"`
+	Volume           sql.NullString `json:"volume"`
+	Language         sql.NullString `json:"language"`
+	ScanlationGroups sql.NullString `json:"scanlation_groups"`
+	SourceID         string         `json:"source_id"`
+	Url              string         `json:"url"`
+	Unavailable      sql.NullInt64  `json:"unavailable"`
+	PublishedAt      sql.NullString `json:"published_at"`
 }
 
 func (q *Queries) InsertChapter(ctx context.Context, arg InsertChapterParams) (*Chapter, error) {
@@ -67,8 +89,11 @@ func (q *Queries) InsertChapter(ctx context.Context, arg InsertChapterParams) (*
 		arg.Title,
 		arg.Number,
 		arg.Volume,
+		arg.Language,
+		arg.ScanlationGroups,
 		arg.SourceID,
 		arg.Url,
+		arg.Unavailable,
 		arg.PublishedAt,
 	)
 	var i Chapter
@@ -78,15 +103,15 @@ func (q *Queries) InsertChapter(ctx context.Context, arg InsertChapterParams) (*
 		&i.Title,
 		&i.Number,
 		&i.Volume,
+		&i.Language,
+		&i.ScanlationGroups,
 		&i.SourceID,
 		&i.Url,
+		&i.Unavailable,
 		&i.Status,
 		&i.FilePath,
 		&i.FileSize,
 		&i.PageCount,
-		&i.IsRead,
-		&i.CurrentPage,
-		&i.ReadAt,
 		&i.PublishedAt,
 		&i.DownloadedAt,
 		&i.CreatedAt,
@@ -95,7 +120,7 @@ func (q *Queries) InsertChapter(ctx context.Context, arg InsertChapterParams) (*
 }
 
 const listChaptersByManga = `-- name: ListChaptersByManga :many
-SELECT id, manga_id, title, number, volume, source_id, url, status, file_path, file_size, page_count, is_read, current_page, read_at, published_at, downloaded_at, created_at FROM chapter WHERE manga_id = ? ORDER BY number DESC
+SELECT id, manga_id, title, number, volume, language, scanlation_groups, source_id, url, unavailable, status, file_path, file_size, page_count, published_at, downloaded_at, created_at FROM chapter WHERE manga_id = ? ORDER BY number DESC
 `
 
 func (q *Queries) ListChaptersByManga(ctx context.Context, mangaID int64) ([]*Chapter, error) {
@@ -113,18 +138,100 @@ func (q *Queries) ListChaptersByManga(ctx context.Context, mangaID int64) ([]*Ch
 			&i.Title,
 			&i.Number,
 			&i.Volume,
+			&i.Language,
+			&i.ScanlationGroups,
 			&i.SourceID,
 			&i.Url,
+			&i.Unavailable,
+			&i.Status,
+			&i.FilePath,
+			&i.FileSize,
+			&i.PageCount,
+			&i.PublishedAt,
+			&i.DownloadedAt,
+			&i.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, &i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listChaptersWithProgress = `-- name: ListChaptersWithProgress :many
+SELECT c.id, c.manga_id, c.title, c.number, c.volume, c.language, c.scanlation_groups, c.source_id, c.url, c.unavailable, c.status, c.file_path, c.file_size, c.page_count, c.published_at, c.downloaded_at, c.created_at, p.is_read, p.current_page, p.read_at, p.updated_at AS progress_updated_at
+FROM chapter c
+LEFT JOIN chapter_progress p ON p.chapter_id = c.id AND p.user_id = ?
+WHERE c.manga_id = ?
+ORDER BY c.number DESC
+`
+
+type ListChaptersWithProgressRow struct {
+	ID                int64          `json:"id"`
+	MangaID           int64          `json:"manga_id"`
+	Title             string         `json:"title"`
+	Number            float64        `json:"number"`
+	Volume            sql.NullString `json:"volume"`
+	Language          sql.NullString `json:"language"`
+	ScanlationGroups  sql.NullString `json:"scanlation_groups"`
+	SourceID          string         `json:"source_id"`
+	Url               string         `json:"url"`
+	Unavailable       sql.NullInt64  `json:"unavailable"`
+	Status            sql.NullString `json:"status"`
+	FilePath          sql.NullString `json:"file_path"`
+	FileSize          sql.NullInt64  `json:"file_size"`
+	PageCount         sql.NullInt64  `json:"page_count"`
+	PublishedAt       sql.NullString `json:"published_at"`
+	DownloadedAt      sql.NullString `json:"downloaded_at"`
+	CreatedAt         sql.NullString `json:"created_at"`
+	IsRead            sql.NullInt64  `json:"is_read"`
+	CurrentPage       sql.NullInt64  `json:"current_page"`
+	ReadAt            sql.NullString `json:"read_at"`
+	ProgressUpdatedAt sql.NullString `json:"progress_updated_at"`
+}
+
+type ListChaptersWithProgressParams struct {
+	UserID  int64 `json:"user_id"`
+	MangaID int64 `json:"manga_id"`
+}
+
+func (q *Queries) ListChaptersWithProgress(ctx context.Context, arg ListChaptersWithProgressParams) ([]*ListChaptersWithProgressRow, error) {
+	rows, err := q.db.QueryContext(ctx, listChaptersWithProgress, arg.UserID, arg.MangaID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []*ListChaptersWithProgressRow{}
+	for rows.Next() {
+		var i ListChaptersWithProgressRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.MangaID,
+			&i.Title,
+			&i.Number,
+			&i.Volume,
+			&i.Language,
+			&i.ScanlationGroups,
+			&i.SourceID,
+			&i.Url,
+			&i.Unavailable,
 			&i.Status,
 			&i.FilePath,
 			&i.FileSize,
 			&i.PageCount,
-			&i.IsRead,
-			&i.CurrentPage,
-			&i.ReadAt,
 			&i.PublishedAt,
 			&i.DownloadedAt,
 			&i.CreatedAt,
+			&i.IsRead,
+			&i.CurrentPage,
+			&i.ReadAt,
+			&i.ProgressUpdatedAt,
 		); err != nil {
 			return nil, err
 		}
@@ -139,12 +246,26 @@ func (q *Queries) ListChaptersByManga(ctx context.Context, mangaID int64) ([]*Ch
 	return items, nil
 }
 
-const markChapterRead = `-- name: MarkChapterRead :exec
-UPDATE chapter SET is_read = 1, read_at = datetime('now') WHERE id = ?
+const resetInterruptedChapters = `-- name: ResetInterruptedChapters :exec
+UPDATE chapter SET status = 'queued' WHERE status = 'downloading'
+`
+
+func (q *Queries) ResetInterruptedChapters(ctx context.Context) error {
+	_, err := q.db.ExecContext(ctx, resetInterruptedChapters)
+	return err
+}
+
+const setChapterStatus = `-- name: SetChapterStatus :exec
+UPDATE chapter SET status = ? WHERE id = ?
 `
 
-func (q *Queries) MarkChapterRead(ctx context.Context, id int64) error {
-	_, err := q.db.ExecContext(ctx, markChapterRead, id)
+type SetChapterStatusParams struct {
+	Status sql.NullString `json:"status"`
+	ID     int64          `json:"id"`
+}
+
+func (q *Queries) SetChapterStatus(ctx context.Context, arg SetChapterStatusParams) error {
+	_, err := q.db.ExecContext(ctx, setChapterStatus, arg.Status, arg.ID)
 	return err
 }
 
@@ -156,7 +277,7 @@ UPDATE chapter SET
     page_count = ?,
     downloaded_at = CASE WHEN ? = 'completed' THEN datetime('now') ELSE downloaded_at END
 WHERE id = ?
-RETURNING id, manga_id, title, number, volume, source_id, url, status, file_path, file_size, page_count, is_read, current_page, read_at, published_at, downloaded_at, created_at
+RETURNING id, manga_id, title, number, volume, language, scanlation_groups, source_id, url, unavailable, status, file_path, file_size, page_count, published_at, downloaded_at, created_at
 `
 
 type UpdateChapterStatusParams struct {
@@ -184,32 +305,18 @@ func (q *Queries) UpdateChapterStatus(ctx context.Context, arg UpdateChapterStat
 		&i.Title,
 		&i.Number,
 		&i.Volume,
+		&i.Language,
+		&i.ScanlationGroups,
 		&i.SourceID,
 		&i.Url,
+		&i.Unavailable,
 		&i.Status,
 		&i.FilePath,
 		&i.FileSize,
 		&i.PageCount,
-		&i.IsRead,
-		&i.CurrentPage,
-		&i.ReadAt,
 		&i.PublishedAt,
 		&i.DownloadedAt,
 		&i.CreatedAt,
 	)
 	return &i, err
 }
-
-const updateReadingProgress = `-- name: UpdateReadingProgress :exec
-UPDATE chapter SET current_page = ? WHERE id = ?
-`
-
-type UpdateReadingProgressParams struct {
-	CurrentPage sql.NullInt64 `json:"current_page"`
-	ID          int64         `json:"id"`
-}
-
-func (q *Queries) UpdateReadingProgress(ctx context.Context, arg UpdateReadingProgressParams) error {
-	_, err := q.db.ExecContext(ctx, updateReadingProgress, arg.CurrentPage, arg.ID)
-	return err
-}
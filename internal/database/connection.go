@@ -7,6 +7,10 @@ import (
 	_ "github.com/mattn/go-sqlite3"
 )
 
+// TimeFormat matches the format produced by SQLite's datetime(), so times
+// written from Go compare correctly as text with stored ones.
+const TimeFormat = "2006-01-02 15:04:05"
+
 // Open creates a new SQLite database connection with optimized settings.
 func Open(path string) (*sql.DB, error) {
 	dsn := path + "?_journal=WAL&_timeout=5000&_fk=1"
@@ -10,6 +10,40 @@ import (
 	"database/sql"
 )
 
+const claimNextDownload = `-- name: ClaimNextDownload :one
+UPDATE download_queue SET
+    status = 'downloading',
+    attempts = attempts + 1,
+    started_at = datetime('now')
+WHERE id = (
+    SELECT id FROM download_queue
+    WHERE status = 'queued'
+      AND (next_attempt_at IS NULL OR next_attempt_at <= datetime('now'))
+    ORDER BY priority DESC, created_at ASC
+    LIMIT 1
+)
+RETURNING id, chapter_id, priority, attempts, max_attempts, last_error, status, created_at, started_at, completed_at, next_attempt_at
+`
+
+func (q *Queries) ClaimNextDownload(ctx context.Context) (*DownloadQueue, error) {
+	row := q.db.QueryRowContext(ctx, claimNextDownload)
+	var i DownloadQueue
+	err := row.Scan(
+		&i.ID,
+		&i.ChapterID,
+		&i.Priority,
+		&i.Attempts,
+		&i.MaxAttempts,
+		&i.LastError,
+		&i.Status,
+		&i.CreatedAt,
+		&i.StartedAt,
+		&i.CompletedAt,
+		&i.NextAttemptAt,
+	)
+	return &i, err
+}
+
 const deleteDownload = `-- name: DeleteDownload :exec
 DELETE FROM download_queue WHERE id = ?
 `
@@ -29,8 +63,9 @@ ON CONFLICT (chapter_id) DO UPDATE SET
     attempts = 0,
     last_error = NULL,
     started_at = NULL,
-    completed_at = NULL
-RETURNING id, chapter_id, priority, attempts, max_attempts, last_error, status, created_at, started_at, completed_at
+    completed_at = NULL,
+    next_attempt_at = NULL
+RETURNING id, chapter_id, priority, attempts, max_attempts, last_error, status, created_at, started_at, completed_at, next_attempt_at
 `
 
 type EnqueueDownloadParams struct {
@@ -52,12 +87,13 @@ func (q *Queries) EnqueueDownload(ctx context.Context, arg EnqueueDownloadParams
 		&i.CreatedAt,
 		&i.StartedAt,
 		&i.CompletedAt,
+		&i.NextAttemptAt,
 	)
 	return &i, err
 }
 
 const listQueue = `-- name: ListQueue :many
-SELECT id, chapter_id, priority, attempts, max_attempts, last_error, status, created_at, started_at, completed_at FROM download_queue
+SELECT id, chapter_id, priority, attempts, max_attempts, last_error, status, created_at, started_at, completed_at, next_attempt_at FROM download_queue
 ORDER BY priority DESC, created_at ASC
 `
 
@@ -81,6 +117,7 @@ func (q *Queries) ListQueue(ctx context.Context) ([]*DownloadQueue, error) {
 			&i.CreatedAt,
 			&i.StartedAt,
 			&i.CompletedAt,
+			&i.NextAttemptAt,
 		); err != nil {
 			return nil, err
 		}
@@ -95,6 +132,42 @@ func (q *Queries) ListQueue(ctx context.Context) ([]*DownloadQueue, error) {
 	return items, nil
 }
 
+const requeueDownload = `-- name: RequeueDownload :exec
+UPDATE download_queue SET
+    status = 'queued',
+    attempts = ?,
+    last_error = ?,
+    started_at = NULL,
+    next_attempt_at = ?
+WHERE id = ?
+`
+
+type RequeueDownloadParams struct {
+	Attempts      sql.NullInt64  `json:"attempts"`
+	LastError     sql.NullString `json:"last_error"`
+	NextAttemptAt sql.NullString `json:"next_attempt_at"`
+	ID            int64          `json:"id"`
+}
+
+func (q *Queries) RequeueDownload(ctx context.Context, arg RequeueDownloadParams) error {
+	_, err := q.db.ExecContext(ctx, requeueDownload,
+		arg.Attempts,
+		arg.LastError,
+		arg.NextAttemptAt,
+		arg.ID,
+	)
+	return err
+}
+
+const resetInterruptedDownloads = `-- name: ResetInterruptedDownloads :exec
+UPDATE download_queue SET status = 'queued' WHERE status = 'downloading'
+`
+
+func (q *Queries) ResetInterruptedDownloads(ctx context.Context) error {
+	_, err := q.db.ExecContext(ctx, resetInterruptedDownloads)
+	return err
+}
+
 const updateDownloadStatus = `-- name: UpdateDownloadStatus :one
 UPDATE download_queue SET
     status = ?,
@@ -103,7 +176,7 @@ UPDATE download_queue SET
     started_at = ?,
     completed_at = ?
 WHERE id = ?
-RETURNING id, chapter_id, priority, attempts, max_attempts, last_error, status, created_at, started_at, completed_at
+RETURNING id, chapter_id, priority, attempts, max_attempts, last_error, status, created_at, started_at, completed_at, next_attempt_at
 `
 
 type UpdateDownloadStatusParams struct {
@@ -136,6 +209,7 @@ func (q *Queries) UpdateDownloadStatus(ctx context.Context, arg UpdateDownloadSt
 		&i.CreatedAt,
 		&i.StartedAt,
 		&i.CompletedAt,
+		&i.NextAttemptAt,
 	)
 	return &i, err
 }
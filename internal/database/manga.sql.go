@@ -10,17 +10,20 @@ import (
 	"database/sql"
 )
 
-const deleteManga = `-- name: DeleteManga :exec
+const deleteManga = `-- name: DeleteManga :execrows
 DELETE FROM manga WHERE id = ?
 `
 
-func (q *Queries) DeleteManga(ctx context.Context, id int64) error {
-	_, err := q.db.ExecContext(ctx, deleteManga, id)
-	return err
+func (q *Queries) DeleteManga(ctx context.Context, id int64) (int64, error) {
+	result, err := q.db.ExecContext(ctx, deleteManga, id)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
 }
 
 const getManga = `-- name: GetManga :one
-SELECT id, title, slug, source, source_id, url, cover_url, cover_path, description, status, author, artist, genres, tags, anilist_id, mal_id, update_interval, auto_download, created_at, updated_at, last_checked_at FROM manga WHERE id = ? LIMIT 1
+SELECT id, title, slug, source, source_id, url, cover_url, cover_path, cover_locked, description, status, author, artist, genres, tags, content_rating, anilist_id, mal_id, update_interval, auto_download, languages, preferred_groups, blocked_groups, created_at, updated_at, last_checked_at FROM manga WHERE id = ? LIMIT 1
 `
 
 func (q *Queries) GetManga(ctx context.Context, id int64) (*Manga, error) {
@@ -35,16 +38,21 @@ func (q *Queries) GetManga(ctx context.Context, id int64) (*Manga, error) {
 		&i.Url,
 		&i.CoverUrl,
 		&i.CoverPath,
+		&i.CoverLocked,
 		&i.Description,
 		&i.Status,
 		&i.Author,
 		&i.Artist,
 		&i.Genres,
 		&i.Tags,
+		&i.ContentRating,
 		&i.AnilistID,
 		&i.MalID,
 		&i.UpdateInterval,
 		&i.AutoDownload,
+		&i.Languages,
+		&i.PreferredGroups,
+		&i.BlockedGroups,
 		&i.CreatedAt,
 		&i.UpdatedAt,
 		&i.LastCheckedAt,
@@ -53,7 +61,7 @@ func (q *Queries) GetManga(ctx context.Context, id int64) (*Manga, error) {
 }
 
 const getMangaBySlug = `-- name: GetMangaBySlug :one
-SELECT id, title, slug, source, source_id, url, cover_url, cover_path, description, status, author, artist, genres, tags, anilist_id, mal_id, update_interval, auto_download, created_at, updated_at, last_checked_at FROM manga WHERE slug = ? LIMIT 1
+SELECT id, title, slug, source, source_id, url, cover_url, cover_path, cover_locked, description, status, author, artist, genres, tags, content_rating, anilist_id, mal_id, update_interval, auto_download, languages, preferred_groups, blocked_groups, created_at, updated_at, last_checked_at FROM manga WHERE slug = ? LIMIT 1
 `
 
 func (q *Queries) GetMangaBySlug(ctx context.Context, slug string) (*Manga, error) {
@@ -68,16 +76,21 @@ func (q *Queries) GetMangaBySlug(ctx context.Context, slug string) (*Manga, erro
 		&i.Url,
 		&i.CoverUrl,
 		&i.CoverPath,
+		&i.CoverLocked,
 		&i.Description,
 		&i.Status,
 		&i.Author,
 		&i.Artist,
 		&i.Genres,
 		&i.Tags,
+		&i.ContentRating,
 		&i.AnilistID,
 		&i.MalID,
 		&i.UpdateInterval,
 		&i.AutoDownload,
+		&i.Languages,
+		&i.PreferredGroups,
+		&i.BlockedGroups,
 		&i.CreatedAt,
 		&i.UpdatedAt,
 		&i.LastCheckedAt,
@@ -86,7 +99,7 @@ func (q *Queries) GetMangaBySlug(ctx context.Context, slug string) (*Manga, erro
 }
 
 const getMangaForUpdate = `-- name: GetMangaForUpdate :many
-SELECT id, title, slug, source, source_id, url, cover_url, cover_path, description, status, author, artist, genres, tags, anilist_id, mal_id, update_interval, auto_download, created_at, updated_at, last_checked_at FROM manga
+SELECT id, title, slug, source, source_id, url, cover_url, cover_path, cover_locked, description, status, author, artist, genres, tags, content_rating, anilist_id, mal_id, update_interval, auto_download, languages, preferred_groups, blocked_groups, created_at, updated_at, last_checked_at FROM manga
 WHERE auto_download = 1
   AND (last_checked_at IS NULL OR last_checked_at < datetime('now', '-1 hour'))
 ORDER BY last_checked_at ASC
@@ -111,16 +124,21 @@ func (q *Queries) GetMangaForUpdate(ctx context.Context, limit int64) ([]*Manga,
 			&i.Url,
 			&i.CoverUrl,
 			&i.CoverPath,
+			&i.CoverLocked,
 			&i.Description,
 			&i.Status,
 			&i.Author,
 			&i.Artist,
 			&i.Genres,
 			&i.Tags,
+			&i.ContentRating,
 			&i.AnilistID,
 			&i.MalID,
 			&i.UpdateInterval,
 			&i.AutoDownload,
+			&i.Languages,
+			&i.PreferredGroups,
+			&i.BlockedGroups,
 			&i.CreatedAt,
 			&i.UpdatedAt,
 			&i.LastCheckedAt,
@@ -141,24 +159,26 @@ func (q *Queries) GetMangaForUpdate(ctx context.Context, limit int64) ([]*Manga,
 const insertManga = `-- name: InsertManga :one
 INSERT INTO manga (
     title, slug, source, source_id, url, cover_url, description,
-    status, author, artist, genres, tags
-) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
-RETURNING id, title, slug, source, source_id, url, cover_url, cover_path, description, status, author, artist, genres, tags, anilist_id, mal_id, update_interval, auto_download, created_at, updated_at, last_checked_at
+    status, author, artist, genres, tags, content_rating, languages
+) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+RETURNING id, title, slug, source, source_id, url, cover_url, cover_path, cover_locked, description, status, author, artist, genres, tags, content_rating, anilist_id, mal_id, update_interval, auto_download, languages, preferred_groups, blocked_groups, created_at, updated_at, last_checked_at
 `
 
 type InsertMangaParams struct {
-	Title       string         `json:"title"`
-	Slug        string         `json:"slug"`
-	Source      string         `json:"source"`
-	SourceID    string         `json:"source_id"`
-	Url         string         `json:"url"`
-	CoverUrl    sql.NullString `json:"cover_url"`
-	Description sql.NullString `json:"description"`
-	Status      sql.NullString `json:"status"`
-	Author      sql.NullString `json:"author"`
-	Artist      sql.NullString `json:"artist"`
-	Genres      sql.NullString `json:"genres"`
-	Tags        sql.NullString `json:"tags"`
+	Title         string         `json:"title"`
+	Slug          string         `json:"slug"`
+	Source        string         `json:"source"`
+	SourceID      string         `json:"source_id"`
+	Url           string         `json:"url"`
+	CoverUrl      sql.NullString `json:"cover_url"`
+	Description   sql.NullString `json:"description"`
+	Status        sql.NullString `json:"status"`
+	Author        sql.NullString `json:"author"`
+	Artist        sql.NullString `json:"artist"`
+	Genres        sql.NullString `json:"genres"`
+	Tags          sql.NullString `json:"tags"`
+	ContentRating sql.NullString `json:"content_rating"`
+	Languages     sql.NullString `json:"languages"`
 }
 
 func (q *Queries) InsertManga(ctx context.Context, arg InsertMangaParams) (*Manga, error) {
@@ -175,6 +195,8 @@ func (q *Queries) InsertManga(ctx context.Context, arg InsertMangaParams) (*Mang
 		arg.Artist,
 		arg.Genres,
 		arg.Tags,
+		arg.ContentRating,
+		arg.Languages,
 	)
 	var i Manga
 	err := row.Scan(
@@ -186,16 +208,21 @@ func (q *Queries) InsertManga(ctx context.Context, arg InsertMangaParams) (*Mang
 		&i.Url,
 		&i.CoverUrl,
 		&i.CoverPath,
+		&i.CoverLocked,
 		&i.Description,
 		&i.Status,
 		&i.Author,
 		&i.Artist,
 		&i.Genres,
 		&i.Tags,
+		&i.ContentRating,
 		&i.AnilistID,
 		&i.MalID,
 		&i.UpdateInterval,
 		&i.AutoDownload,
+		&i.Languages,
+		&i.PreferredGroups,
+		&i.BlockedGroups,
 		&i.CreatedAt,
 		&i.UpdatedAt,
 		&i.LastCheckedAt,
@@ -204,7 +231,7 @@ func (q *Queries) InsertManga(ctx context.Context, arg InsertMangaParams) (*Mang
 }
 
 const listManga = `-- name: ListManga :many
-SELECT id, title, slug, source, source_id, url, cover_url, cover_path, description, status, author, artist, genres, tags, anilist_id, mal_id, update_interval, auto_download, created_at, updated_at, last_checked_at FROM manga ORDER BY title ASC
+SELECT id, title, slug, source, source_id, url, cover_url, cover_path, cover_locked, description, status, author, artist, genres, tags, content_rating, anilist_id, mal_id, update_interval, auto_download, languages, preferred_groups, blocked_groups, created_at, updated_at, last_checked_at FROM manga ORDER BY title ASC
 `
 
 func (q *Queries) ListManga(ctx context.Context) ([]*Manga, error) {
@@ -225,16 +252,21 @@ func (q *Queries) ListManga(ctx context.Context) ([]*Manga, error) {
 			&i.Url,
 			&i.CoverUrl,
 			&i.CoverPath,
+			&i.CoverLocked,
 			&i.Description,
 			&i.Status,
 			&i.Author,
 			&i.Artist,
 			&i.Genres,
 			&i.Tags,
+			&i.ContentRating,
 			&i.AnilistID,
 			&i.MalID,
 			&i.UpdateInterval,
 			&i.AutoDownload,
+			&i.Languages,
+			&i.PreferredGroups,
+			&i.BlockedGroups,
 			&i.CreatedAt,
 			&i.UpdatedAt,
 			&i.LastCheckedAt,
@@ -254,41 +286,47 @@ func (q *Queries) ListManga(ctx context.Context) ([]*Manga, error) {
 
 const listMangaWithUnread = `-- name: ListMangaWithUnread :many
 SELECT
-    m.id, m.title, m.slug, m.source, m.source_id, m.url, m.cover_url, m.cover_path, m.description, m.status, m.author, m.artist, m.genres, m.tags, m.anilist_id, m.mal_id, m.update_interval, m.auto_download, m.created_at, m.updated_at, m.last_checked_at,
-    SUM(CASE WHEN c.is_read = 0 AND c.status = 'completed' THEN 1 ELSE 0 END) AS unread_count
+    m.id, m.title, m.slug, m.source, m.source_id, m.url, m.cover_url, m.cover_path, m.cover_locked, m.description, m.status, m.author, m.artist, m.genres, m.tags, m.content_rating, m.anilist_id, m.mal_id, m.update_interval, m.auto_download, m.languages, m.preferred_groups, m.blocked_groups, m.created_at, m.updated_at, m.last_checked_at,
+    SUM(CASE WHEN COALESCE(p.is_read, 0) = 0 AND c.status = 'completed' THEN 1 ELSE 0 END) AS unread_count
 FROM manga m
 LEFT JOIN chapter c ON c.manga_id = m.id
+LEFT JOIN chapter_progress p ON p.chapter_id = c.id AND p.user_id = ?
 GROUP BY m.id
 ORDER BY m.title ASC
 `
 
 type ListMangaWithUnreadRow struct {
-	ID             int64           `json:"id"`
-	Title          string          `json:"title"`
-	Slug           string          `json:"slug"`
-	Source         string          `json:"source"`
-	SourceID       string          `json:"source_id"`
-	Url            string          `json:"url"`
-	CoverUrl       sql.NullString  `json:"cover_url"`
-	CoverPath      sql.NullString  `json:"cover_path"`
-	Description    sql.NullString  `json:"description"`
-	Status         sql.NullString  `json:"status"`
-	Author         sql.NullString  `json:"author"`
-	Artist         sql.NullString  `json:"artist"`
-	Genres         sql.NullString  `json:"genres"`
-	Tags           sql.NullString  `json:"tags"`
-	AnilistID      sql.NullInt64   `json:"anilist_id"`
-	MalID          sql.NullInt64   `json:"mal_id"`
-	UpdateInterval sql.NullString  `json:"update_interval"`
-	AutoDownload   sql.NullInt64   `json:"auto_download"`
-	CreatedAt      sql.NullString  `json:"created_at"`
-	UpdatedAt      sql.NullString  `json:"updated_at"`
-	LastCheckedAt  sql.NullString  `json:"last_checked_at"`
-	UnreadCount    sql.NullFloat64 `json:"unread_count"`
+	ID              int64           `json:"id"`
+	Title           string          `json:"title"`
+	Slug            string          `json:"slug"`
+	Source          string          `json:"source"`
+	SourceID        string          `json:"source_id"`
+	Url             string          `json:"url"`
+	CoverUrl        sql.NullString  `json:"cover_url"`
+	CoverPath       sql.NullString  `json:"cover_path"`
+	CoverLocked     sql.NullInt64   `json:"cover_locked"`
+	Description     sql.NullString  `json:"description"`
+	Status          sql.NullString  `json:"status"`
+	Author          sql.NullString  `json:"author"`
+	Artist          sql.NullString  `json:"artist"`
+	Genres          sql.NullString  `json:"genres"`
+	Tags            sql.NullString  `json:"tags"`
+	ContentRating   sql.NullString  `json:"content_rating"`
+	AnilistID       sql.NullInt64   `json:"anilist_id"`
+	MalID           sql.NullInt64   `json:"mal_id"`
+	UpdateInterval  sql.NullString  `json:"update_interval"`
+	AutoDownload    sql.NullInt64   `json:"auto_download"`
+	Languages       sql.NullString  `json:"languages"`
+	PreferredGroups sql.NullString  `json:"preferred_groups"`
+	BlockedGroups   sql.NullString  `json:"blocked_groups"`
+	CreatedAt       sql.NullString  `json:"created_at"`
+	UpdatedAt       sql.NullString  `json:"updated_at"`
+	LastCheckedAt   sql.NullString  `json:"last_checked_at"`
+	UnreadCount     sql.NullFloat64 `json:"unread_count"`
 }
 
-func (q *Queries) ListMangaWithUnread(ctx context.Context) ([]*ListMangaWithUnreadRow, error) {
-	rows, err := q.db.QueryContext(ctx, listMangaWithUnread)
+func (q *Queries) ListMangaWithUnread(ctx context.Context, userID int64) ([]*ListMangaWithUnreadRow, error) {
+	rows, err := q.db.QueryContext(ctx, listMangaWithUnread, userID)
 	if err != nil {
 		return nil, err
 	}
@@ -305,16 +343,21 @@ func (q *Queries) ListMangaWithUnread(ctx context.Context) ([]*ListMangaWithUnre
 			&i.Url,
 			&i.CoverUrl,
 			&i.CoverPath,
+			&i.CoverLocked,
 			&i.Description,
 			&i.Status,
 			&i.Author,
 			&i.Artist,
 			&i.Genres,
 			&i.Tags,
+			&i.ContentRating,
 			&i.AnilistID,
 			&i.MalID,
 			&i.UpdateInterval,
 			&i.AutoDownload,
+			&i.Languages,
+			&i.PreferredGroups,
+			&i.BlockedGroups,
 			&i.CreatedAt,
 			&i.UpdatedAt,
 			&i.LastCheckedAt,
@@ -333,6 +376,43 @@ func (q *Queries) ListMangaWithUnread(ctx context.Context) ([]*ListMangaWithUnre
 	return items, nil
 }
 
+const listOlderMangaTitles = `-- name: ListOlderMangaTitles :many
+SELECT id, title, slug FROM manga WHERE id < ? ORDER BY id ASC
+`
+
+type ListOlderMangaTitlesRow struct {
+	ID    int64  `json:"id"`
+	Title string `json:"title"`
+	Slug  string `json:"slug"`
+}
+
+func (q *Queries) ListOlderMangaTitles(ctx context.Context, id int64) ([]*ListOlderMangaTitlesRow, error) {
+	rows, err := q.db.QueryContext(ctx, listOlderMangaTitles, id)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []*ListOlderMangaTitlesRow{}
+	for rows.Next() {
+		var i ListOlderMangaTitlesRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.Title,
+			&i.Slug,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, &i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const updateManga = `-- name: UpdateManga :one
 UPDATE manga SET
     title = ?,
@@ -344,24 +424,26 @@ UPDATE manga SET
     artist = ?,
     genres = ?,
     tags = ?,
+    content_rating = ?,
     anilist_id = ?,
     last_checked_at = datetime('now')
 WHERE id = ?
-RETURNING id, title, slug, source, source_id, url, cover_url, cover_path, description, status, author, artist, genres, tags, anilist_id, mal_id, update_interval, auto_download, created_at, updated_at, last_checked_at
+RETURNING id, title, slug, source, source_id, url, cover_url, cover_path, cover_locked, description, status, author, artist, genres, tags, content_rating, anilist_id, mal_id, update_interval, auto_download, languages, preferred_groups, blocked_groups, created_at, updated_at, last_checked_at
 `
 
 type UpdateMangaParams struct {
-	Title       string         `json:"title"`
-	CoverUrl    sql.NullString `json:"cover_url"`
-	CoverPath   sql.NullString `json:"cover_path"`
-	Description sql.NullString `json:"description"`
-	Status      sql.NullString `json:"status"`
-	Author      sql.NullString `json:"author"`
-	Artist      sql.NullString `json:"artist"`
-	Genres      sql.NullString `json:"genres"`
-	Tags        sql.NullString `json:"tags"`
-	AnilistID   sql.NullInt64  `json:"anilist_id"`
-	ID          int64          `json:"id"`
+	Title         string         `json:"title"`
+	CoverUrl      sql.NullString `json:"cover_url"`
+	CoverPath     sql.NullString `json:"cover_path"`
+	Description   sql.NullString `json:"description"`
+	Status        sql.NullString `json:"status"`
+	Author        sql.NullString `json:"author"`
+	Artist        sql.NullString `json:"artist"`
+	Genres        sql.NullString `json:"genres"`
+	Tags          sql.NullString `json:"tags"`
+	ContentRating sql.NullString `json:"content_rating"`
+	AnilistID     sql.NullInt64  `json:"anilist_id"`
+	ID            int64          `json:"id"`
 }
 
 func (q *Queries) UpdateManga(ctx context.Context, arg UpdateMangaParams) (*Manga, error) {
@@ -375,6 +457,7 @@ func (q *Queries) UpdateManga(ctx context.Context, arg UpdateMangaParams) (*Mang
 		arg.Artist,
 		arg.Genres,
 		arg.Tags,
+		arg.ContentRating,
 		arg.AnilistID,
 		arg.ID,
 	)
@@ -388,16 +471,207 @@ func (q *Queries) UpdateManga(ctx context.Context, arg UpdateMangaParams) (*Mang
 		&i.Url,
 		&i.CoverUrl,
 		&i.CoverPath,
+		&i.CoverLocked,
+		&i.Description,
+		&i.Status,
+		&i.Author,
+		&i.Artist,
+		&i.Genres,
+		&i.Tags,
+		&i.ContentRating,
+		&i.AnilistID,
+		&i.MalID,
+		&i.UpdateInterval,
+		&i.AutoDownload,
+		&i.Languages,
+		&i.PreferredGroups,
+		&i.BlockedGroups,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.LastCheckedAt,
+	)
+	return &i, err
+}
+
+const updateMangaCover = `-- name: UpdateMangaCover :one
+UPDATE manga SET cover_url = ?, cover_locked = ? WHERE id = ?
+RETURNING id, title, slug, source, source_id, url, cover_url, cover_path, cover_locked, description, status, author, artist, genres, tags, content_rating, anilist_id, mal_id, update_interval, auto_download, languages, preferred_groups, blocked_groups, created_at, updated_at, last_checked_at
+`
+
+type UpdateMangaCoverParams struct {
+	CoverUrl    sql.NullString `json:"cover_url"`
+	CoverLocked sql.NullInt64  `json:"cover_locked"`
+	ID          int64          `json:"id"`
+}
+
+func (q *Queries) UpdateMangaCover(ctx context.Context, arg UpdateMangaCoverParams) (*Manga, error) {
+	row := q.db.QueryRowContext(ctx, updateMangaCover,
+		arg.CoverUrl,
+		arg.CoverLocked,
+		arg.ID,
+	)
+	var i Manga
+	err := row.Scan(
+		&i.ID,
+		&i.Title,
+		&i.Slug,
+		&i.Source,
+		&i.SourceID,
+		&i.Url,
+		&i.CoverUrl,
+		&i.CoverPath,
+		&i.CoverLocked,
+		&i.Description,
+		&i.Status,
+		&i.Author,
+		&i.Artist,
+		&i.Genres,
+		&i.Tags,
+		&i.ContentRating,
+		&i.AnilistID,
+		&i.MalID,
+		&i.UpdateInterval,
+		&i.AutoDownload,
+		&i.Languages,
+		&i.PreferredGroups,
+		&i.BlockedGroups,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.LastCheckedAt,
+	)
+	return &i, err
+}
+
+const updateMangaCoverPath = `-- name: UpdateMangaCoverPath :one
+UPDATE manga SET cover_path = ? WHERE id = ?
+RETURNING id, title, slug, source, source_id, url, cover_url, cover_path, cover_locked, description, status, author, artist, genres, tags, content_rating, anilist_id, mal_id, update_interval, auto_download, languages, preferred_groups, blocked_groups, created_at, updated_at, last_checked_at
+`
+
+type UpdateMangaCoverPathParams struct {
+	CoverPath sql.NullString `json:"cover_path"`
+	ID        int64          `json:"id"`
+}
+
+func (q *Queries) UpdateMangaCoverPath(ctx context.Context, arg UpdateMangaCoverPathParams) (*Manga, error) {
+	row := q.db.QueryRowContext(ctx, updateMangaCoverPath, arg.CoverPath, arg.ID)
+	var i Manga
+	err := row.Scan(
+		&i.ID,
+		&i.Title,
+		&i.Slug,
+		&i.Source,
+		&i.SourceID,
+		&i.Url,
+		&i.CoverUrl,
+		&i.CoverPath,
+		&i.CoverLocked,
+		&i.Description,
+		&i.Status,
+		&i.Author,
+		&i.Artist,
+		&i.Genres,
+		&i.Tags,
+		&i.ContentRating,
+		&i.AnilistID,
+		&i.MalID,
+		&i.UpdateInterval,
+		&i.AutoDownload,
+		&i.Languages,
+		&i.PreferredGroups,
+		&i.BlockedGroups,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.LastCheckedAt,
+	)
+	return &i, err
+}
+
+const updateMangaGroupRules = `-- name: UpdateMangaGroupRules :one
+UPDATE manga SET preferred_groups = ?, blocked_groups = ? WHERE id = ?
+RETURNING id, title, slug, source, source_id, url, cover_url, cover_path, cover_locked, description, status, author, artist, genres, tags, content_rating, anilist_id, mal_id, update_interval, auto_download, languages, preferred_groups, blocked_groups, created_at, updated_at, last_checked_at
+`
+
+type UpdateMangaGroupRulesParams struct {
+	PreferredGroups sql.NullString `json:"preferred_groups"`
+	BlockedGroups   sql.NullString `json:"blocked_groups"`
+	ID              int64          `json:"id"`
+}
+
+func (q *Queries) UpdateMangaGroupRules(ctx context.Context, arg UpdateMangaGroupRulesParams) (*Manga, error) {
+	row := q.db.QueryRowContext(ctx, updateMangaGroupRules,
+		arg.PreferredGroups,
+		arg.BlockedGroups,
+		arg.ID,
+	)
+	var i Manga
+	err := row.Scan(
+		&i.ID,
+		&i.Title,
+		&i.Slug,
+		&i.Source,
+		&i.SourceID,
+		&i.Url,
+		&i.CoverUrl,
+		&i.CoverPath,
+		&i.CoverLocked,
+		&i.Description,
+		&i.Status,
+		&i.Author,
+		&i.Artist,
+		&i.Genres,
+		&i.Tags,
+		&i.ContentRating,
+		&i.AnilistID,
+		&i.MalID,
+		&i.UpdateInterval,
+		&i.AutoDownload,
+		&i.Languages,
+		&i.PreferredGroups,
+		&i.BlockedGroups,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.LastCheckedAt,
+	)
+	return &i, err
+}
+
+const updateMangaLanguages = `-- name: UpdateMangaLanguages :one
+UPDATE manga SET languages = ? WHERE id = ?
+RETURNING id, title, slug, source, source_id, url, cover_url, cover_path, cover_locked, description, status, author, artist, genres, tags, content_rating, anilist_id, mal_id, update_interval, auto_download, languages, preferred_groups, blocked_groups, created_at, updated_at, last_checked_at
+`
+
+type UpdateMangaLanguagesParams struct {
+	Languages sql.NullString `json:"languages"`
+	ID        int64          `json:"id"`
+}
+
+func (q *Queries) UpdateMangaLanguages(ctx context.Context, arg UpdateMangaLanguagesParams) (*Manga, error) {
+	row := q.db.QueryRowContext(ctx, updateMangaLanguages, arg.Languages, arg.ID)
+	var i Manga
+	err := row.Scan(
+		&i.ID,
+		&i.Title,
+		&i.Slug,
+		&i.Source,
+		&i.SourceID,
+		&i.Url,
+		&i.CoverUrl,
+		&i.CoverPath,
+		&i.CoverLocked,
 		&i.Description,
 		&i.Status,
 		&i.Author,
 		&i.Artist,
 		&i.Genres,
 		&i.Tags,
+		&i.ContentRating,
 		&i.AnilistID,
 		&i.MalID,
 		&i.UpdateInterval,
 		&i.AutoDownload,
+		&i.Languages,
+		&i.PreferredGroups,
+		&i.BlockedGroups,
 		&i.CreatedAt,
 		&i.UpdatedAt,
 		&i.LastCheckedAt,
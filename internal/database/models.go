@@ -8,61 +8,124 @@ import (
 	"database/sql"
 )
 
+type ApiKey struct {
+	ID         int64          `json:"id"`
+	UserID     int64          `json:"user_id"`
+	Name       string         `json:"name"`
+	Prefix     string         `json:"prefix"`
+	KeyHash    string         `json:"key_hash"`
+	CreatedAt  sql.NullString `json:"created_at"`
+	LastUsedAt sql.NullString `json:"last_used_at"`
+}
+
 type Chapter struct {
-	ID           int64          `json:"id"`
-	MangaID      int64          `json:"manga_id"`
-	Title        string         `json:"title"`
-	Number       float64        `json:"number"`
-	Volume       sql.NullString `json:"volume"`
-	SourceID     string         `json:"source_id"`
-	Url          string         `json:"url"`
-	Status       sql.NullString `json:"status"`
-	FilePath     sql.NullString `json:"file_path"`
-	FileSize     sql.NullInt64  `json:"file_size"`
-	PageCount    sql.NullInt64  `json:"page_count"`
-	IsRead       sql.NullInt64  `json:"is_read"`
-	CurrentPage  sql.NullInt64  `json:"current_page"`
-	ReadAt       sql.NullString `json:"read_at"`
-	PublishedAt  sql.NullString `json:"published_at"`
-	DownloadedAt sql.NullString `json:"downloaded_at"`
-	CreatedAt    sql.NullString `json:"created_at"`
+	ID               int64          `json:"id"`
+	MangaID          int64          `json:"manga_id"`
+	Title            string         `json:"title"`
+	Number           float64        `json:"number"`
+	Volume           sql.NullString `json:"volume"`
+	Language         sql.NullString `json:"language"`
+	ScanlationGroups sql.NullString `json:"scanlation_groups"`
+	SourceID         string         `json:"source_id"`
+	Url              string         `json:"url"`
+	Unavailable      sql.NullInt64  `json:"unavailable"`
+	Status           sql.NullString `json:"status"`
+	FilePath         sql.NullString `json:"file_path"`
+	FileSize         sql.NullInt64  `json:"file_size"`
+	PageCount        sql.NullInt64  `json:"page_count"`
+	PublishedAt      sql.NullString `json:"published_at"`
+	DownloadedAt     sql.NullString `json:"downloaded_at"`
+	CreatedAt        sql.NullString `json:"created_at"`
 }
 
-type DownloadQueue struct {
-	ID          int64          `json:"id"`
+type ChapterProgress struct {
+	UserID      int64          `json:"user_id"`
 	ChapterID   int64          `json:"chapter_id"`
-	Priority    sql.NullInt64  `json:"priority"`
-	Attempts    sql.NullInt64  `json:"attempts"`
-	MaxAttempts sql.NullInt64  `json:"max_attempts"`
-	LastError   sql.NullString `json:"last_error"`
-	Status      sql.NullString `json:"status"`
-	CreatedAt   sql.NullString `json:"created_at"`
-	StartedAt   sql.NullString `json:"started_at"`
-	CompletedAt sql.NullString `json:"completed_at"`
+	IsRead      sql.NullInt64  `json:"is_read"`
+	CurrentPage sql.NullInt64  `json:"current_page"`
+	ReadAt      sql.NullString `json:"read_at"`
+	UpdatedAt   sql.NullString `json:"updated_at"`
+}
+
+type DownloadQueue struct {
+	ID            int64          `json:"id"`
+	ChapterID     int64          `json:"chapter_id"`
+	Priority      sql.NullInt64  `json:"priority"`
+	Attempts      sql.NullInt64  `json:"attempts"`
+	MaxAttempts   sql.NullInt64  `json:"max_attempts"`
+	LastError     sql.NullString `json:"last_error"`
+	Status        sql.NullString `json:"status"`
+	CreatedAt     sql.NullString `json:"created_at"`
+	StartedAt     sql.NullString `json:"started_at"`
+	CompletedAt   sql.NullString `json:"completed_at"`
+	NextAttemptAt sql.NullString `json:"next_attempt_at"`
+}
+
+type KosyncDocument struct {
+	Document  string         `json:"document"`
+	ChapterID int64          `json:"chapter_id"`
+	Format    string         `json:"format"`
+	CreatedAt sql.NullString `json:"created_at"`
+}
+
+type KosyncProgress struct {
+	UserID     int64   `json:"user_id"`
+	Document   string  `json:"document"`
+	Progress   string  `json:"progress"`
+	Percentage float64 `json:"percentage"`
+	Device     string  `json:"device"`
+	DeviceID   string  `json:"device_id"`
+	Timestamp  int64   `json:"timestamp"`
 }
 
 type Manga struct {
-	ID             int64          `json:"id"`
-	Title          string         `json:"title"`
-	Slug           string         `json:"slug"`
-	Source         string         `json:"source"`
-	SourceID       string         `json:"source_id"`
-	Url            string         `json:"url"`
-	CoverUrl       sql.NullString `json:"cover_url"`
-	CoverPath      sql.NullString `json:"cover_path"`
-	Description    sql.NullString `json:"description"`
-	Status         sql.NullString `json:"status"`
-	Author         sql.NullString `json:"author"`
-	Artist         sql.NullString `json:"artist"`
-	Genres         sql.NullString `json:"genres"`
-	Tags           sql.NullString `json:"tags"`
-	AnilistID      sql.NullInt64  `json:"anilist_id"`
-	MalID          sql.NullInt64  `json:"mal_id"`
-	UpdateInterval sql.NullString `json:"update_interval"`
-	AutoDownload   sql.NullInt64  `json:"auto_download"`
-	CreatedAt      sql.NullString `json:"created_at"`
-	UpdatedAt      sql.NullString `json:"updated_at"`
-	LastCheckedAt  sql.NullString `json:"last_checked_at"`
+	ID              int64          `json:"id"`
+	Title           string         `json:"title"`
+	Slug            string         `json:"slug"`
+	Source          string         `json:"source"`
+	SourceID        string         `json:"source_id"`
+	Url             string         `json:"url"`
+	CoverUrl        sql.NullString `json:"cover_url"`
+	CoverPath       sql.NullString `json:"cover_path"`
+	CoverLocked     sql.NullInt64  `json:"cover_locked"`
+	Description     sql.NullString `json:"description"`
+	Status          sql.NullString `json:"status"`
+	Author          sql.NullString `json:"author"`
+	Artist          sql.NullString `json:"artist"`
+	Genres          sql.NullString `json:"genres"`
+	Tags            sql.NullString `json:"tags"`
+	ContentRating   sql.NullString `json:"content_rating"`
+	AnilistID       sql.NullInt64  `json:"anilist_id"`
+	MalID           sql.NullInt64  `json:"mal_id"`
+	UpdateInterval  sql.NullString `json:"update_interval"`
+	AutoDownload    sql.NullInt64  `json:"auto_download"`
+	Languages       sql.NullString `json:"languages"`
+	PreferredGroups sql.NullString `json:"preferred_groups"`
+	BlockedGroups   sql.NullString `json:"blocked_groups"`
+	CreatedAt       sql.NullString `json:"created_at"`
+	UpdatedAt       sql.NullString `json:"updated_at"`
+	LastCheckedAt   sql.NullString `json:"last_checked_at"`
+}
+
+type NotificationDigest struct {
+	ID        int64          `json:"id"`
+	MangaID   int64          `json:"manga_id"`
+	Event     string         `json:"event"`
+	Payload   string         `json:"payload"`
+	SentTo    sql.NullString `json:"sent_to"`
+	CreatedAt sql.NullString `json:"created_at"`
+}
+
+type ResponseCache struct {
+	Key          string         `json:"key"`
+	Provider     string         `json:"provider"`
+	Status       int64          `json:"status"`
+	Headers      sql.NullString `json:"headers"`
+	Body         []byte         `json:"body"`
+	Etag         sql.NullString `json:"etag"`
+	LastModified sql.NullString `json:"last_modified"`
+	StoredAt     string         `json:"stored_at"`
+	ExpiresAt    string         `json:"expires_at"`
 }
 
 type Scraper struct {
@@ -76,8 +139,60 @@ type Scraper struct {
 	UpdatedAt sql.NullString `json:"updated_at"`
 }
 
+type Session struct {
+	ID         int64          `json:"id"`
+	UserID     int64          `json:"user_id"`
+	TokenHash  string         `json:"token_hash"`
+	UserAgent  sql.NullString `json:"user_agent"`
+	IpAddress  sql.NullString `json:"ip_address"`
+	CreatedAt  sql.NullString `json:"created_at"`
+	LastSeenAt sql.NullString `json:"last_seen_at"`
+	ExpiresAt  string         `json:"expires_at"`
+}
+
 type Setting struct {
 	Key       string         `json:"key"`
 	Value     string         `json:"value"`
 	UpdatedAt sql.NullString `json:"updated_at"`
 }
+
+type User struct {
+	ID               int64          `json:"id"`
+	Username         string         `json:"username"`
+	PasswordHash     string         `json:"password_hash"`
+	Role             string         `json:"role"`
+	AllowedTags      sql.NullString `json:"allowed_tags"`
+	ExcludedTags     sql.NullString `json:"excluded_tags"`
+	MaxContentRating sql.NullString `json:"max_content_rating"`
+	KosyncKeyHash    sql.NullString `json:"kosync_key_hash"`
+	CreatedAt        sql.NullString `json:"created_at"`
+	UpdatedAt        sql.NullString `json:"updated_at"`
+	LastLoginAt      sql.NullString `json:"last_login_at"`
+}
+
+type Webhook struct {
+	ID        int64          `json:"id"`
+	Name      string         `json:"name"`
+	Url       string         `json:"url"`
+	Events    sql.NullString `json:"events"`
+	Secret    sql.NullString `json:"secret"`
+	Headers   sql.NullString `json:"headers"`
+	Enabled   int64          `json:"enabled"`
+	CreatedAt sql.NullString `json:"created_at"`
+	UpdatedAt sql.NullString `json:"updated_at"`
+}
+
+type WebhookDelivery struct {
+	ID             int64          `json:"id"`
+	WebhookID      int64          `json:"webhook_id"`
+	Event          string         `json:"event"`
+	Payload        string         `json:"payload"`
+	Status         string         `json:"status"`
+	Attempts       int64          `json:"attempts"`
+	ResponseStatus sql.NullInt64  `json:"response_status"`
+	ResponseBody   sql.NullString `json:"response_body"`
+	LastError      sql.NullString `json:"last_error"`
+	CreatedAt      sql.NullString `json:"created_at"`
+	NextAttemptAt  sql.NullString `json:"next_attempt_at"`
+	CompletedAt    sql.NullString `json:"completed_at"`
+}
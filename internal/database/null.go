@@ -0,0 +1,27 @@
+package database
+
+import (
+	"database/sql"
+	"encoding/json"
+)
+
+// ToNullStringList encodes a string list as a JSON array, or NULL when empty.
+func ToNullStringList(values []string) sql.NullString {
+	if len(values) == 0 {
+		return sql.NullString{}
+	}
+	data, _ := json.Marshal(values)
+	return sql.NullString{String: string(data), Valid: true}
+}
+
+// FromNullStringList decodes a JSON array column, ignoring malformed values.
+func FromNullStringList(ns sql.NullString) []string {
+	if !ns.Valid || ns.String == "" {
+		return nil
+	}
+	var values []string
+	if err := json.Unmarshal([]byte(ns.String), &values); err != nil {
+		return nil
+	}
+	return values
+}
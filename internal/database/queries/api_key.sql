@@ -0,0 +1,16 @@
+-- name: InsertAPIKey :one
+INSERT INTO api_key (user_id, name, prefix, key_hash)
+VALUES (?, ?, ?, ?)
+RETURNING *;
+
+-- name: GetAPIKeyByHash :one
+SELECT * FROM api_key WHERE key_hash = ? LIMIT 1;
+
+-- name: ListUserAPIKeys :many
+SELECT * FROM api_key WHERE user_id = ? ORDER BY created_at ASC, id ASC;
+
+-- name: TouchAPIKey :exec
+UPDATE api_key SET last_used_at = datetime('now') WHERE id = ?;
+
+-- name: DeleteUserAPIKey :execrows
+DELETE FROM api_key WHERE id = ? AND user_id = ?;
@@ -4,15 +4,25 @@ SELECT * FROM chapter WHERE id = ? LIMIT 1;
 -- name: ListChaptersByManga :many
 SELECT * FROM chapter WHERE manga_id = ? ORDER BY number DESC;
 
+-- name: ListChaptersWithProgress :many
+SELECT c.*, p.is_read, p.current_page, p.read_at, p.updated_at AS progress_updated_at
+FROM chapter c
+LEFT JOIN chapter_progress p ON p.chapter_id = c.id AND p.user_id = ?
+WHERE c.manga_id = ?
+ORDER BY c.number DESC;
+
 -- name: InsertChapter :one
 INSERT INTO chapter (
-    manga_id, title, number, volume, source_id, url, published_at
-) VALUES (?, ?, ?, ?, ?, ?, ?)
+    manga_id, title, number, volume, language, scanlation_groups, source_id, url, unavailable, published_at
+) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
 ON CONFLICT (manga_id, source_id) DO UPDATE SET
     title = excluded.title,
     number = excluded.number,
     volume = excluded.volume,
-    url = excluded.url
+    language = excluded.language,
+    scanlation_groups = excluded.scanlation_groups,
+    url = excluded.url,
+    unavailable = excluded.unavailable
 RETURNING *;
 
 -- name: UpdateChapterStatus :one
@@ -25,8 +35,11 @@ UPDATE chapter SET
 WHERE id = ?
 RETURNING *;
 
--- name: MarkChapterRead :exec
-UPDATE chapter SET is_read = 1, read_at = datetime('now') WHERE id = ?;
+-- name: ResetInterruptedChapters :exec
+UPDATE chapter SET status = 'queued' WHERE status = 'downloading';
+
+-- name: SetChapterStatus :exec
+UPDATE chapter SET status = ? WHERE id = ?;
 
--- name: UpdateReadingProgress :exec
-UPDATE chapter SET current_page = ? WHERE id = ?;
+-- name: CountOtherChaptersWithFile :one
+SELECT COUNT(*) AS count FROM chapter WHERE file_path = ? AND id != ?;
@@ -0,0 +1,29 @@
+-- name: GetChapterProgress :one
+SELECT * FROM chapter_progress
+WHERE user_id = ? AND chapter_id = ?;
+
+-- name: UpsertChapterProgress :one
+INSERT INTO chapter_progress (user_id, chapter_id, is_read, current_page, read_at)
+VALUES (?, ?, ?, ?, ?)
+ON CONFLICT (user_id, chapter_id) DO UPDATE SET
+    is_read = excluded.is_read,
+    current_page = excluded.current_page,
+    read_at = excluded.read_at,
+    updated_at = datetime('now')
+RETURNING *;
+
+-- name: DeleteChapterProgress :exec
+DELETE FROM chapter_progress
+WHERE user_id = ? AND chapter_id = ?;
+
+-- name: ListMangaReadingStats :many
+SELECT
+    c.manga_id,
+    COUNT(*) AS chapter_count,
+    SUM(CASE WHEN COALESCE(p.is_read, 0) = 0 THEN 1 ELSE 0 END) AS unread_count,
+    SUM(CASE WHEN COALESCE(p.is_read, 0) = 0 AND COALESCE(p.current_page, 0) > 0 THEN 1 ELSE 0 END) AS in_progress_count,
+    MAX(c.downloaded_at) AS last_downloaded_at
+FROM chapter c
+LEFT JOIN chapter_progress p ON p.chapter_id = c.id AND p.user_id = ?
+WHERE c.status = 'completed'
+GROUP BY c.manga_id;
@@ -12,9 +12,36 @@ ON CONFLICT (chapter_id) DO UPDATE SET
     attempts = 0,
     last_error = NULL,
     started_at = NULL,
-    completed_at = NULL
+    completed_at = NULL,
+    next_attempt_at = NULL
 RETURNING *;
 
+-- name: ClaimNextDownload :one
+UPDATE download_queue SET
+    status = 'downloading',
+    attempts = attempts + 1,
+    started_at = datetime('now')
+WHERE id = (
+    SELECT id FROM download_queue
+    WHERE status = 'queued'
+      AND (next_attempt_at IS NULL OR next_attempt_at <= datetime('now'))
+    ORDER BY priority DESC, created_at ASC
+    LIMIT 1
+)
+RETURNING *;
+
+-- name: ResetInterruptedDownloads :exec
+UPDATE download_queue SET status = 'queued' WHERE status = 'downloading';
+
+-- name: RequeueDownload :exec
+UPDATE download_queue SET
+    status = 'queued',
+    attempts = ?,
+    last_error = ?,
+    started_at = NULL,
+    next_attempt_at = ?
+WHERE id = ?;
+
 -- name: UpdateDownloadStatus :one
 UPDATE download_queue SET
     status = ?,
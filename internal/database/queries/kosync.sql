@@ -0,0 +1,32 @@
+-- name: UpsertKOSyncDocument :exec
+INSERT INTO kosync_document (document, chapter_id, format)
+VALUES (?, ?, ?)
+ON CONFLICT (document) DO UPDATE SET
+    chapter_id = excluded.chapter_id,
+    format = excluded.format;
+
+-- name: GetKOSyncDocument :one
+SELECT * FROM kosync_document WHERE document = ? LIMIT 1;
+
+-- name: ListChaptersWithoutKOSyncDocument :many
+SELECT c.* FROM chapter c
+WHERE c.status = 'completed'
+  AND NOT EXISTS (
+    SELECT 1 FROM kosync_document d WHERE d.chapter_id = c.id AND d.format = 'cbz'
+  )
+ORDER BY c.id ASC;
+
+-- name: GetKOSyncProgress :one
+SELECT * FROM kosync_progress
+WHERE user_id = ? AND document = ?;
+
+-- name: UpsertKOSyncProgress :one
+INSERT INTO kosync_progress (user_id, document, progress, percentage, device, device_id, timestamp)
+VALUES (?, ?, ?, ?, ?, ?, ?)
+ON CONFLICT (user_id, document) DO UPDATE SET
+    progress = excluded.progress,
+    percentage = excluded.percentage,
+    device = excluded.device,
+    device_id = excluded.device_id,
+    timestamp = excluded.timestamp
+RETURNING *;
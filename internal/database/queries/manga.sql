@@ -7,20 +7,24 @@ SELECT * FROM manga WHERE slug = ? LIMIT 1;
 -- name: ListManga :many
 SELECT * FROM manga ORDER BY title ASC;
 
+-- name: ListOlderMangaTitles :many
+SELECT id, title, slug FROM manga WHERE id < ? ORDER BY id ASC;
+
 -- name: ListMangaWithUnread :many
 SELECT
     m.*,
-    SUM(CASE WHEN c.is_read = 0 AND c.status = 'completed' THEN 1 ELSE 0 END) AS unread_count
+    SUM(CASE WHEN COALESCE(p.is_read, 0) = 0 AND c.status = 'completed' THEN 1 ELSE 0 END) AS unread_count
 FROM manga m
 LEFT JOIN chapter c ON c.manga_id = m.id
+LEFT JOIN chapter_progress p ON p.chapter_id = c.id AND p.user_id = ?
 GROUP BY m.id
 ORDER BY m.title ASC;
 
 -- name: InsertManga :one
 INSERT INTO manga (
     title, slug, source, source_id, url, cover_url, description,
-    status, author, artist, genres, tags
-) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+    status, author, artist, genres, tags, content_rating, languages
+) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
 RETURNING *;
 
 -- name: UpdateManga :one
@@ -34,12 +38,21 @@ UPDATE manga SET
     artist = ?,
     genres = ?,
     tags = ?,
+    content_rating = ?,
     anilist_id = ?,
     last_checked_at = datetime('now')
 WHERE id = ?
 RETURNING *;
 
--- name: DeleteManga :exec
+-- name: UpdateMangaLanguages :one
+UPDATE manga SET languages = ? WHERE id = ?
+RETURNING *;
+
+-- name: UpdateMangaGroupRules :one
+UPDATE manga SET preferred_groups = ?, blocked_groups = ? WHERE id = ?
+RETURNING *;
+
+-- name: DeleteManga :execrows
 DELETE FROM manga WHERE id = ?;
 
 -- name: GetMangaForUpdate :many
@@ -48,3 +61,11 @@ WHERE auto_download = 1
   AND (last_checked_at IS NULL OR last_checked_at < datetime('now', '-1 hour'))
 ORDER BY last_checked_at ASC
 LIMIT ?;
+
+-- name: UpdateMangaCoverPath :one
+UPDATE manga SET cover_path = ? WHERE id = ?
+RETURNING *;
+
+-- name: UpdateMangaCover :one
+UPDATE manga SET cover_url = ?, cover_locked = ? WHERE id = ?
+RETURNING *;
@@ -0,0 +1,13 @@
+-- name: InsertDigestEvent :exec
+INSERT INTO notification_digest (manga_id, event, payload)
+VALUES (?, ?, ?);
+
+-- name: ListDigestEvents :many
+SELECT * FROM notification_digest
+ORDER BY id ASC;
+
+-- name: SetDigestEventSentTo :exec
+UPDATE notification_digest SET sent_to = ? WHERE id = ?;
+
+-- name: DeleteDigestEvent :exec
+DELETE FROM notification_digest WHERE id = ?;
@@ -0,0 +1,24 @@
+-- name: GetCachedResponse :one
+SELECT * FROM response_cache
+WHERE key = ?;
+
+-- name: UpsertCachedResponse :exec
+INSERT INTO response_cache (
+    key, provider, status, headers, body, etag, last_modified, stored_at, expires_at
+) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)
+ON CONFLICT (key) DO UPDATE SET
+    status = excluded.status,
+    headers = excluded.headers,
+    body = excluded.body,
+    etag = excluded.etag,
+    last_modified = excluded.last_modified,
+    stored_at = excluded.stored_at,
+    expires_at = excluded.expires_at;
+
+-- name: DeleteCachedResponsesExpiredBefore :exec
+DELETE FROM response_cache
+WHERE expires_at < ?;
+
+-- name: DeleteCachedResponsesByProvider :exec
+DELETE FROM response_cache
+WHERE provider = ?;
@@ -0,0 +1,3 @@
+-- name: ListScrapers :many
+SELECT * FROM scraper
+ORDER BY name;
@@ -0,0 +1,21 @@
+-- name: InsertSession :exec
+INSERT INTO session (user_id, token_hash, user_agent, ip_address, last_seen_at, expires_at)
+VALUES (?, ?, ?, ?, ?, ?);
+
+-- name: GetSessionByTokenHash :one
+SELECT * FROM session WHERE token_hash = ? LIMIT 1;
+
+-- name: TouchSession :exec
+UPDATE session SET last_seen_at = ?, expires_at = ? WHERE id = ?;
+
+-- name: DeleteSessionByTokenHash :exec
+DELETE FROM session WHERE token_hash = ?;
+
+-- name: DeleteUserSessions :exec
+DELETE FROM session WHERE user_id = ?;
+
+-- name: DeleteSessionsExpiredBefore :exec
+DELETE FROM session WHERE expires_at < ?;
+
+-- name: DeleteOtherUserSessions :exec
+DELETE FROM session WHERE user_id = ? AND token_hash != ?;
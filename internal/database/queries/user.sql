@@ -0,0 +1,43 @@
+-- name: CountUsers :one
+SELECT COUNT(*) AS count FROM user;
+
+-- name: GetUser :one
+SELECT * FROM user WHERE id = ? LIMIT 1;
+
+-- name: GetUserByUsername :one
+SELECT * FROM user WHERE username = ? LIMIT 1;
+
+-- name: ListUsers :many
+SELECT * FROM user ORDER BY username ASC;
+
+-- name: InsertUser :one
+INSERT INTO user (username, password_hash, role, allowed_tags, excluded_tags, max_content_rating)
+VALUES (?, ?, ?, ?, ?, ?)
+RETURNING *;
+
+-- name: UpdateUserAccess :one
+UPDATE user SET
+    role = ?,
+    allowed_tags = ?,
+    excluded_tags = ?,
+    max_content_rating = ?
+WHERE id = ?
+RETURNING *;
+
+-- name: UpdateUserPassword :exec
+UPDATE user SET password_hash = ? WHERE id = ?;
+
+-- name: UpdateUserKOSyncKey :exec
+UPDATE user SET kosync_key_hash = ? WHERE id = ?;
+
+-- name: UpdateUserLastLogin :exec
+UPDATE user SET last_login_at = datetime('now') WHERE id = ?;
+
+-- name: DeleteUser :execrows
+DELETE FROM user WHERE id = ?;
+
+-- name: CountAdmins :one
+SELECT COUNT(*) AS count FROM user WHERE role = 'admin';
+
+-- name: GetFirstAdmin :one
+SELECT * FROM user WHERE role = 'admin' ORDER BY id ASC LIMIT 1;
@@ -0,0 +1,78 @@
+-- name: InsertWebhook :one
+INSERT INTO webhook (name, url, events, secret, headers, enabled)
+VALUES (?, ?, ?, ?, ?, ?)
+RETURNING *;
+
+-- name: GetWebhook :one
+SELECT * FROM webhook WHERE id = ? LIMIT 1;
+
+-- name: ListWebhooks :many
+SELECT * FROM webhook ORDER BY id ASC;
+
+-- name: ListEnabledWebhooks :many
+SELECT * FROM webhook WHERE enabled = 1 ORDER BY id ASC;
+
+-- name: UpdateWebhook :one
+UPDATE webhook SET
+    name = ?,
+    url = ?,
+    events = ?,
+    secret = ?,
+    headers = ?,
+    enabled = ?
+WHERE id = ?
+RETURNING *;
+
+-- name: DeleteWebhook :execrows
+DELETE FROM webhook WHERE id = ?;
+
+-- name: InsertWebhookDelivery :one
+INSERT INTO webhook_delivery (webhook_id, event, payload, next_attempt_at)
+VALUES (?, ?, ?, ?)
+RETURNING *;
+
+-- name: GetWebhookDelivery :one
+SELECT * FROM webhook_delivery WHERE id = ? AND webhook_id = ? LIMIT 1;
+
+-- name: ListWebhookDeliveries :many
+SELECT * FROM webhook_delivery
+WHERE webhook_id = ?
+ORDER BY id DESC
+LIMIT ?;
+
+-- name: ListDueWebhookIDs :many
+SELECT webhook_id FROM webhook_delivery
+WHERE status = 'pending' AND next_attempt_at <= ?
+GROUP BY webhook_id;
+
+-- name: ListDueWebhookDeliveries :many
+SELECT * FROM webhook_delivery
+WHERE webhook_id = ? AND status = 'pending' AND next_attempt_at <= ?
+ORDER BY next_attempt_at ASC, id ASC
+LIMIT ?;
+
+-- name: UpdateWebhookDelivery :exec
+UPDATE webhook_delivery SET
+    status = ?,
+    attempts = ?,
+    response_status = ?,
+    response_body = ?,
+    last_error = ?,
+    next_attempt_at = ?,
+    completed_at = ?
+WHERE id = ?;
+
+-- name: RedeliverWebhookDelivery :one
+UPDATE webhook_delivery SET
+    status = 'pending',
+    attempts = 0,
+    response_status = NULL,
+    response_body = NULL,
+    last_error = NULL,
+    next_attempt_at = ?,
+    completed_at = NULL
+WHERE id = ? AND webhook_id = ?
+RETURNING *;
+
+-- name: DeleteWebhookDeliveriesBefore :execrows
+DELETE FROM webhook_delivery WHERE status != 'pending' AND created_at < ?;
@@ -22,6 +22,7 @@ CREATE TABLE manga (
     -- Cover images
     cover_url       TEXT,
     cover_path      TEXT,
+    cover_locked    INTEGER DEFAULT 0,  -- Keep the chosen cover on metadata refresh
 
     -- Metadata
     description     TEXT,
@@ -30,6 +31,7 @@ CREATE TABLE manga (
     artist          TEXT,
     genres          TEXT,
     tags            TEXT,
+    content_rating  TEXT,  -- safe, suggestive, erotica or pornographic
 
     -- External IDs
     anilist_id      INTEGER,
@@ -38,6 +40,9 @@ CREATE TABLE manga (
     -- Settings
     update_interval TEXT DEFAULT '0 */6 * * *',
     auto_download   INTEGER DEFAULT 1,
+    languages       TEXT,
+    preferred_groups TEXT,
+    blocked_groups  TEXT,
 
     -- Timestamps
     created_at      TEXT DEFAULT (datetime('now')),
@@ -63,10 +68,14 @@ CREATE TABLE chapter (
     title           TEXT NOT NULL,
     number          REAL NOT NULL,
     volume          TEXT,
+    language        TEXT,
+    scanlation_groups TEXT,
 
     -- Source information
     source_id       TEXT NOT NULL,
     url             TEXT NOT NULL,
+    -- 1 when the source lists the chapter but offers no pages for it
+    unavailable     INTEGER DEFAULT 0,
 
     -- Download status
     status          TEXT DEFAULT 'pending' CHECK(status IN ('pending', 'queued', 'downloading', 'completed', 'failed')),
@@ -74,11 +83,6 @@ CREATE TABLE chapter (
     file_size       INTEGER,
     page_count      INTEGER,
 
-    -- Reading progress
-    is_read         INTEGER DEFAULT 0,
-    current_page    INTEGER DEFAULT 0,
-    read_at         TEXT,
-
     -- Timestamps
     published_at    TEXT,
     downloaded_at   TEXT,
@@ -111,6 +115,7 @@ CREATE TABLE download_queue (
     created_at      TEXT DEFAULT (datetime('now')),
     started_at      TEXT,
     completed_at    TEXT,
+    next_attempt_at TEXT,  -- Earliest retry of a failed attempt; NULL when due
 
     UNIQUE(chapter_id)
 );
@@ -143,6 +148,184 @@ CREATE TABLE scraper (
     updated_at      TEXT DEFAULT (datetime('now'))
 );
 
+-------------------------------------------------------------------------------
+-- RESPONSE CACHE TABLE
+-------------------------------------------------------------------------------
+CREATE TABLE response_cache (
+    key             TEXT PRIMARY KEY,
+    provider        TEXT NOT NULL,
+
+    status          INTEGER NOT NULL,
+    headers         TEXT,  -- JSON object
+    body            BLOB NOT NULL,
+
+    -- Validators for conditional requests
+    etag            TEXT,
+    last_modified   TEXT,
+
+    stored_at       TEXT NOT NULL,
+    expires_at      TEXT NOT NULL
+);
+
+CREATE INDEX idx_response_cache_expires_at ON response_cache(expires_at);
+
+-------------------------------------------------------------------------------
+-- USER TABLE
+-------------------------------------------------------------------------------
+CREATE TABLE user (
+    id              INTEGER PRIMARY KEY AUTOINCREMENT,
+
+    username        TEXT NOT NULL UNIQUE COLLATE NOCASE,
+    password_hash   TEXT NOT NULL,  -- bcrypt
+    role            TEXT NOT NULL DEFAULT 'member' CHECK(role IN ('admin', 'member', 'readonly')),
+
+    -- Visibility restrictions
+    allowed_tags    TEXT,  -- JSON array; when set, only manga with one of these tags are shown
+    excluded_tags   TEXT,  -- JSON array of tags whose manga are hidden
+    max_content_rating TEXT,  -- Highest content rating shown; NULL for no limit
+
+    -- KOReader sync
+    kosync_key_hash TEXT,  -- bcrypt of the MD5 of the sync password; NULL when unset
+
+    created_at      TEXT DEFAULT (datetime('now')),
+    updated_at      TEXT DEFAULT (datetime('now')),
+    last_login_at   TEXT
+);
+
+-------------------------------------------------------------------------------
+-- CHAPTER PROGRESS TABLE
+-------------------------------------------------------------------------------
+CREATE TABLE chapter_progress (
+    user_id         INTEGER NOT NULL REFERENCES user(id) ON DELETE CASCADE,
+    chapter_id      INTEGER NOT NULL REFERENCES chapter(id) ON DELETE CASCADE,
+
+    is_read         INTEGER DEFAULT 0,
+    current_page    INTEGER DEFAULT 0,
+    read_at         TEXT,
+    updated_at      TEXT DEFAULT (datetime('now')),
+
+    PRIMARY KEY (user_id, chapter_id)
+);
+
+CREATE INDEX idx_chapter_progress_chapter_id ON chapter_progress(chapter_id);
+
+-------------------------------------------------------------------------------
+-- SESSION TABLE
+-------------------------------------------------------------------------------
+CREATE TABLE session (
+    id              INTEGER PRIMARY KEY AUTOINCREMENT,
+    user_id         INTEGER NOT NULL REFERENCES user(id) ON DELETE CASCADE,
+
+    token_hash      TEXT NOT NULL UNIQUE,  -- SHA-256 of the cookie value
+    user_agent      TEXT,
+    ip_address      TEXT,
+
+    created_at      TEXT DEFAULT (datetime('now')),
+    last_seen_at    TEXT,
+    expires_at      TEXT NOT NULL
+);
+
+CREATE INDEX idx_session_user_id ON session(user_id);
+CREATE INDEX idx_session_expires_at ON session(expires_at);
+
+-------------------------------------------------------------------------------
+-- API KEY TABLE
+-------------------------------------------------------------------------------
+CREATE TABLE api_key (
+    id              INTEGER PRIMARY KEY AUTOINCREMENT,
+    user_id         INTEGER NOT NULL REFERENCES user(id) ON DELETE CASCADE,
+
+    name            TEXT NOT NULL,
+    prefix          TEXT NOT NULL,  -- Leading characters shown in listings
+    key_hash        TEXT NOT NULL UNIQUE,  -- SHA-256 of the key
+
+    created_at      TEXT DEFAULT (datetime('now')),
+    last_used_at    TEXT
+);
+
+CREATE INDEX idx_api_key_user_id ON api_key(user_id);
+
+-------------------------------------------------------------------------------
+-- KOREADER SYNC TABLES
+-------------------------------------------------------------------------------
+-- Chapter files handed out, by the hash KOReader identifies documents with
+CREATE TABLE kosync_document (
+    document        TEXT PRIMARY KEY,  -- Partial MD5 of the file, or MD5 of its name
+    chapter_id      INTEGER NOT NULL REFERENCES chapter(id) ON DELETE CASCADE,
+    format          TEXT NOT NULL CHECK(format IN ('cbz', 'epub')),
+
+    created_at      TEXT DEFAULT (datetime('now'))
+);
+
+CREATE INDEX idx_kosync_document_chapter_id ON kosync_document(chapter_id);
+
+-- Positions reported by KOReader, kept as sent so devices get them back
+CREATE TABLE kosync_progress (
+    user_id         INTEGER NOT NULL REFERENCES user(id) ON DELETE CASCADE,
+    document        TEXT NOT NULL,
+
+    progress        TEXT NOT NULL,  -- Page number or XPointer
+    percentage      REAL NOT NULL,
+    device          TEXT NOT NULL,
+    device_id       TEXT NOT NULL,
+    timestamp       INTEGER NOT NULL,  -- Unix time of the update
+
+    PRIMARY KEY (user_id, document)
+);
+
+-------------------------------------------------------------------------------
+-- WEBHOOK TABLES
+-------------------------------------------------------------------------------
+CREATE TABLE webhook (
+    id              INTEGER PRIMARY KEY AUTOINCREMENT,
+
+    name            TEXT NOT NULL,
+    url             TEXT NOT NULL,
+    events          TEXT,  -- JSON array of event types; NULL for every event
+    secret          TEXT,  -- HMAC-SHA256 signing key; NULL for unsigned deliveries
+    headers         TEXT,  -- JSON object of extra request headers
+    enabled         INTEGER NOT NULL DEFAULT 1,
+
+    created_at      TEXT DEFAULT (datetime('now')),
+    updated_at      TEXT DEFAULT (datetime('now'))
+);
+
+CREATE TABLE webhook_delivery (
+    id              INTEGER PRIMARY KEY AUTOINCREMENT,
+    webhook_id      INTEGER NOT NULL REFERENCES webhook(id) ON DELETE CASCADE,
+
+    event           TEXT NOT NULL,
+    payload         TEXT NOT NULL,  -- JSON body, sent unchanged on every attempt
+
+    status          TEXT NOT NULL DEFAULT 'pending' CHECK(status IN ('pending', 'delivered', 'failed')),
+    attempts        INTEGER NOT NULL DEFAULT 0,
+    response_status INTEGER,  -- HTTP status of the last attempt
+    response_body   TEXT,  -- Start of the last response body
+    last_error      TEXT,
+
+    created_at      TEXT DEFAULT (datetime('now')),
+    next_attempt_at TEXT,
+    completed_at    TEXT
+);
+
+CREATE INDEX idx_webhook_delivery_webhook_id ON webhook_delivery(webhook_id, id DESC);
+CREATE INDEX idx_webhook_delivery_due ON webhook_delivery(status, next_attempt_at);
+
+-------------------------------------------------------------------------------
+-- NOTIFICATION TABLES
+-------------------------------------------------------------------------------
+-- Events held back for the next digest notification
+CREATE TABLE notification_digest (
+    id              INTEGER PRIMARY KEY AUTOINCREMENT,
+    manga_id        INTEGER NOT NULL REFERENCES manga(id) ON DELETE CASCADE,
+
+    event           TEXT NOT NULL,
+    payload         TEXT NOT NULL,  -- JSON event, as sent to webhooks
+    sent_to         TEXT,           -- JSON array of SHA-256 hashes of the URLs already sent it
+
+    created_at      TEXT DEFAULT (datetime('now'))
+);
+
 -------------------------------------------------------------------------------
 -- TRIGGERS FOR UPDATED_AT
 -------------------------------------------------------------------------------
@@ -157,3 +340,15 @@ AFTER UPDATE ON scraper
 BEGIN
     UPDATE scraper SET updated_at = datetime('now') WHERE id = NEW.id;
 END;
+
+CREATE TRIGGER update_user_timestamp
+AFTER UPDATE ON user
+BEGIN
+    UPDATE user SET updated_at = datetime('now') WHERE id = NEW.id;
+END;
+
+CREATE TRIGGER update_webhook_timestamp
+AFTER UPDATE ON webhook
+BEGIN
+    UPDATE webhook SET updated_at = datetime('now') WHERE id = NEW.id;
+END;
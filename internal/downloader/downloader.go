@@ -0,0 +1,488 @@
+package downloader
+
+import (
+	"archive/zip"
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog"
+
+	"github.com/mangashelf/mangashelf/internal/config"
+	"github.com/mangashelf/mangashelf/internal/database"
+	"github.com/mangashelf/mangashelf/internal/events"
+	"github.com/mangashelf/mangashelf/internal/library"
+	"github.com/mangashelf/mangashelf/internal/scraper"
+)
+
+const (
+	// pollInterval is how often idle workers check the queue for new work.
+	pollInterval = 5 * time.Second
+
+	// maxRetryDelay caps the doubling wait between attempts.
+	maxRetryDelay = time.Hour
+)
+
+// Downloader works through the download queue and stores every chapter as a
+// CBZ archive in the library folder.
+type Downloader struct {
+	db          *database.Queries
+	scrapers    *scraper.Manager
+	cfg         config.DownloaderConfig
+	libraryPath string
+	events      *events.Bus
+	log         zerolog.Logger
+
+	// writing holds the archives being written, so two workers never pick
+	// the same file.
+	mu      sync.Mutex
+	writing map[string]bool
+}
+
+// New creates a downloader writing into libraryPath. Finished and failed
+// downloads are published to bus.
+func New(db *database.Queries, scrapers *scraper.Manager, cfg config.DownloaderConfig, libraryPath string, bus *events.Bus, log zerolog.Logger) *Downloader {
+	return &Downloader{
+		db:          db,
+		scrapers:    scrapers,
+		cfg:         cfg,
+		libraryPath: libraryPath,
+		events:      bus,
+		log:         log.With().Str("component", "downloader").Logger(),
+		writing:     make(map[string]bool),
+	}
+}
+
+// Run starts the download workers and blocks until ctx is cancelled.
+// Downloads interrupted by a previous shutdown are queued again first.
+func (d *Downloader) Run(ctx context.Context) {
+	if err := d.db.ResetInterruptedDownloads(ctx); err != nil {
+		d.log.Error().Err(err).Msg("failed to reset interrupted downloads")
+	}
+	if err := d.db.ResetInterruptedChapters(ctx); err != nil {
+		d.log.Error().Err(err).Msg("failed to reset interrupted chapters")
+	}
+
+	workers := d.cfg.Workers
+	if workers < 1 {
+		workers = 1
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			d.worker(ctx)
+		}()
+	}
+
+	d.log.Info().Int("workers", workers).Msg("downloader started")
+	wg.Wait()
+}
+
+// worker drains the queue, then waits for the next poll.
+func (d *Downloader) worker(ctx context.Context) {
+	ticker := time.NewTicker(pollInterval)
+	defer ticker.Stop()
+
+	for {
+		for ctx.Err() == nil && d.processNext(ctx) {
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// processNext claims and downloads one queued chapter. It reports whether a
+// job was found.
+func (d *Downloader) processNext(ctx context.Context) bool {
+	job, err := d.db.ClaimNextDownload(ctx)
+	if err != nil {
+		if !errors.Is(err, sql.ErrNoRows) && ctx.Err() == nil {
+			d.log.Error().Err(err).Msg("failed to claim download")
+		}
+		return false
+	}
+
+	if err := d.db.SetChapterStatus(ctx, database.SetChapterStatusParams{
+		Status: sql.NullString{String: "downloading", Valid: true},
+		ID:     job.ChapterID,
+	}); err != nil {
+		d.log.Error().Err(err).Int64("chapter", job.ChapterID).Msg("failed to mark chapter downloading")
+	}
+
+	chapter, err := d.download(ctx, job)
+	if err != nil {
+		// A download cut short by shutdown is not the chapter's fault; it
+		// is left for the next run.
+		if ctx.Err() != nil {
+			d.requeue(job, job.Attempts.Int64-1, job.LastError, sql.NullString{})
+			return true
+		}
+		d.fail(job, err)
+		return true
+	}
+
+	d.log.Info().
+		Int64("chapter", chapter.ID).
+		Str("path", chapter.FilePath.String).
+		Int64("pages", chapter.PageCount.Int64).
+		Msg("chapter downloaded")
+	d.publish(events.DownloadCompleted, chapter.ID, "")
+
+	return true
+}
+
+// download fetches every page of the job's chapter into a CBZ archive and
+// records the result on the chapter.
+func (d *Downloader) download(ctx context.Context, job *database.DownloadQueue) (*database.Chapter, error) {
+	chapter, err := d.db.GetChapter(ctx, job.ChapterID)
+	if err != nil {
+		return nil, fmt.Errorf("get chapter: %w", err)
+	}
+
+	manga, err := d.db.GetManga(ctx, chapter.MangaID)
+	if err != nil {
+		return nil, fmt.Errorf("get manga: %w", err)
+	}
+
+	provider, err := d.scrapers.Get(manga.Source)
+	if err != nil {
+		return nil, err
+	}
+
+	pages, err := d.scrapers.GetPages(ctx, manga.Source, chapter.SourceID)
+	if err != nil {
+		return nil, fmt.Errorf("fetch pages: %w", err)
+	}
+	if len(pages) == 0 {
+		return nil, errors.New("chapter has no pages")
+	}
+
+	folder, err := library.MangaFolder(ctx, d.db, manga)
+	if err != nil {
+		return nil, err
+	}
+	dir := filepath.Join(d.libraryPath, folder)
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("create manga directory: %w", err)
+	}
+
+	src := pageSource{
+		client: scraper.NewHTTPClient(manga.Source, d.cfg.Timeout),
+	}
+	src.opener, _ = provider.(scraper.Opener)
+
+	target, err := d.claimTarget(ctx, dir, chapter)
+	if err != nil {
+		return nil, err
+	}
+	defer d.releaseTarget(target)
+
+	if err := d.writeArchive(ctx, src, pages, target); err != nil {
+		return nil, err
+	}
+
+	info, err := os.Stat(target)
+	if err != nil {
+		return nil, fmt.Errorf("stat archive: %w", err)
+	}
+
+	chapter, err = d.db.UpdateChapterStatus(context.WithoutCancel(ctx), database.UpdateChapterStatusParams{
+		Status:    sql.NullString{String: "completed", Valid: true},
+		FilePath:  sql.NullString{String: target, Valid: true},
+		FileSize:  sql.NullInt64{Int64: info.Size(), Valid: true},
+		PageCount: sql.NullInt64{Int64: int64(len(pages)), Valid: true},
+		Column5:   "completed",
+		ID:        chapter.ID,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("update chapter: %w", err)
+	}
+
+	_, err = d.db.UpdateDownloadStatus(context.WithoutCancel(ctx), database.UpdateDownloadStatusParams{
+		Status:      sql.NullString{String: "completed", Valid: true},
+		Attempts:    job.Attempts,
+		StartedAt:   job.StartedAt,
+		CompletedAt: sql.NullString{String: time.Now().UTC().Format(database.TimeFormat), Valid: true},
+		ID:          job.ID,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("update download: %w", err)
+	}
+
+	return chapter, nil
+}
+
+// writeArchive stores the pages in a CBZ archive. The archive is written to a
+// temporary file first so a failed download never leaves a partial file behind.
+func (d *Downloader) writeArchive(ctx context.Context, src pageSource, pages []scraper.Page, target string) error {
+	tmp, err := os.CreateTemp(filepath.Dir(target), ".download-*.cbz")
+	if err != nil {
+		return fmt.Errorf("create archive: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+	defer tmp.Close()
+
+	zw := zip.NewWriter(tmp)
+	for _, page := range pages {
+		data, err := d.fetchPage(ctx, src, page)
+		if err != nil {
+			return err
+		}
+
+		w, err := zw.CreateHeader(&zip.FileHeader{
+			Name:     fmt.Sprintf("%03d%s", page.Index, pageExt(page)),
+			Method:   zip.Store,
+			Modified: time.Now(),
+		})
+		if err != nil {
+			return fmt.Errorf("add page %d: %w", page.Index, err)
+		}
+		if _, err := w.Write(data); err != nil {
+			return fmt.Errorf("write page %d: %w", page.Index, err)
+		}
+	}
+
+	if err := zw.Close(); err != nil {
+		return fmt.Errorf("finish archive: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("close archive: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), target); err != nil {
+		return fmt.Errorf("move archive: %w", err)
+	}
+	return nil
+}
+
+// fetchPage reads a page, retrying failures as configured.
+func (d *Downloader) fetchPage(ctx context.Context, src pageSource, page scraper.Page) ([]byte, error) {
+	var lastErr error
+	for attempt := 0; attempt <= d.cfg.RetryAttempts; attempt++ {
+		if attempt > 0 {
+			select {
+			case <-ctx.Done():
+				return nil, ctx.Err()
+			case <-time.After(d.cfg.RetryDelay):
+			}
+		}
+
+		data, err := d.readPage(ctx, src, page.URL)
+		if err == nil {
+			return data, nil
+		}
+		lastErr = err
+		d.log.Debug().Err(err).Int("page", page.Index).Int("attempt", attempt+1).Msg("page download failed")
+	}
+	return nil, fmt.Errorf("page %d: %w", page.Index, lastErr)
+}
+
+// pageSource is where the pages of a chapter are read from.
+type pageSource struct {
+	client *http.Client
+	opener scraper.Opener
+}
+
+// readPage reads a page through the provider's opener when it has one, and
+// over HTTP with the provider's client otherwise.
+func (d *Downloader) readPage(ctx context.Context, src pageSource, rawURL string) ([]byte, error) {
+	if src.opener != nil {
+		rc, err := src.opener.Open(ctx, rawURL)
+		if err != nil {
+			return nil, err
+		}
+		defer rc.Close()
+		return io.ReadAll(rc)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, rawURL, nil)
+	if err != nil {
+		return nil, fmt.Errorf("create request: %w", err)
+	}
+	req.Header.Set("User-Agent", d.cfg.UserAgent)
+
+	resp, err := src.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("execute request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusTooManyRequests {
+		return nil, scraper.ErrRateLimited
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status: %d", resp.StatusCode)
+	}
+
+	return io.ReadAll(resp.Body)
+}
+
+// fail records a failed attempt. The download is retried after a doubling
+// delay until the configured attempts are used up; only then are the queue
+// entry and the chapter marked failed and the failure published.
+func (d *Downloader) fail(job *database.DownloadQueue, cause error) {
+	ctx := context.Background()
+
+	attempts := job.Attempts.Int64
+	if attempts < int64(d.cfg.MaxAttempts) && !errors.Is(cause, scraper.ErrChapterNotFound) {
+		delay := min(d.cfg.RetryDelay<<min(attempts-1, 20), maxRetryDelay)
+		d.log.Warn().Err(cause).Int64("chapter", job.ChapterID).Int64("attempt", attempts).Dur("retryIn", delay).Msg("chapter download failed, retrying")
+		d.requeue(job, attempts, sql.NullString{String: cause.Error(), Valid: true}, sql.NullString{
+			String: time.Now().Add(delay).UTC().Format(database.TimeFormat),
+			Valid:  true,
+		})
+		return
+	}
+
+	d.log.Error().Err(cause).Int64("chapter", job.ChapterID).Int64("attempts", attempts).Msg("chapter download failed")
+
+	_, err := d.db.UpdateDownloadStatus(ctx, database.UpdateDownloadStatusParams{
+		Status:    sql.NullString{String: "failed", Valid: true},
+		Attempts:  job.Attempts,
+		LastError: sql.NullString{String: cause.Error(), Valid: true},
+		StartedAt: job.StartedAt,
+		ID:        job.ID,
+	})
+	if err != nil {
+		d.log.Error().Err(err).Int64("id", job.ID).Msg("failed to record download failure")
+	}
+
+	if err := d.db.SetChapterStatus(ctx, database.SetChapterStatusParams{
+		Status: sql.NullString{String: "failed", Valid: true},
+		ID:     job.ChapterID,
+	}); err != nil {
+		d.log.Error().Err(err).Int64("chapter", job.ChapterID).Msg("failed to mark chapter failed")
+	}
+
+	d.publish(events.DownloadFailed, job.ChapterID, cause.Error())
+}
+
+// requeue puts a job back in the queue, due at next or right away when next
+// is NULL, and marks its chapter queued again.
+func (d *Downloader) requeue(job *database.DownloadQueue, attempts int64, lastError, next sql.NullString) {
+	ctx := context.Background()
+
+	if err := d.db.RequeueDownload(ctx, database.RequeueDownloadParams{
+		Attempts:      sql.NullInt64{Int64: max(attempts, 0), Valid: true},
+		LastError:     lastError,
+		NextAttemptAt: next,
+		ID:            job.ID,
+	}); err != nil {
+		d.log.Error().Err(err).Int64("id", job.ID).Msg("failed to requeue download")
+	}
+
+	if err := d.db.SetChapterStatus(ctx, database.SetChapterStatusParams{
+		Status: sql.NullString{String: "queued", Valid: true},
+		ID:     job.ChapterID,
+	}); err != nil {
+		d.log.Error().Err(err).Int64("chapter", job.ChapterID).Msg("failed to mark chapter queued")
+	}
+}
+
+// publish raises a download event for a chapter.
+func (d *Downloader) publish(t events.Type, chapterID int64, cause string) {
+	ctx := context.Background()
+	chapter, err := d.db.GetChapter(ctx, chapterID)
+	if err != nil {
+		d.log.Warn().Err(err).Int64("chapter", chapterID).Msg("failed to load chapter for event")
+		return
+	}
+	manga, err := d.db.GetManga(ctx, chapter.MangaID)
+	if err != nil {
+		d.log.Warn().Err(err).Int64("chapter", chapterID).Msg("failed to load manga for event")
+		return
+	}
+
+	e := events.New(t, manga, chapter)
+	e.Error = cause
+	d.events.Publish(e)
+}
+
+// claimTarget picks the archive path for a chapter. Chapters sharing a
+// number, such as releases in other languages or unnumbered extras, would
+// overwrite each other's archive, so the chapter ID is added when the plain
+// name belongs to another chapter. A chapter downloaded again keeps its
+// archive. Release the path once it is recorded.
+func (d *Downloader) claimTarget(ctx context.Context, dir string, chapter *database.Chapter) (string, error) {
+	if prev := chapter.FilePath.String; prev != "" && filepath.Dir(prev) == dir {
+		d.mu.Lock()
+		defer d.mu.Unlock()
+		d.writing[prev] = true
+		return prev, nil
+	}
+
+	name := chapterFilename(chapter.Number)
+	target := filepath.Join(dir, name+".cbz")
+
+	taken, err := d.db.CountOtherChaptersWithFile(ctx, database.CountOtherChaptersWithFileParams{
+		FilePath: sql.NullString{String: target, Valid: true},
+		ID:       chapter.ID,
+	})
+	if err != nil {
+		return "", fmt.Errorf("check archive path: %w", err)
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if taken > 0 || d.writing[target] {
+		target = filepath.Join(dir, fmt.Sprintf("%s [%d].cbz", name, chapter.ID))
+	}
+	d.writing[target] = true
+	return target, nil
+}
+
+// releaseTarget frees an archive path claimed by claimTarget.
+func (d *Downloader) releaseTarget(target string) {
+	d.mu.Lock()
+	delete(d.writing, target)
+	d.mu.Unlock()
+}
+
+// chapterFilename formats a chapter number as "Chapter 0012" or "Chapter 0012.5".
+func chapterFilename(number float64) string {
+	s := strconv.FormatFloat(number, 'f', -1, 64)
+	whole, frac, _ := strings.Cut(s, ".")
+	for len(whole) < 4 {
+		whole = "0" + whole
+	}
+	if frac != "" {
+		return "Chapter " + whole + "." + frac
+	}
+	return "Chapter " + whole
+}
+
+// pageExt returns the image extension of a page, defaulting to ".jpg".
+func pageExt(page scraper.Page) string {
+	name := page.Filename
+	if name == "" {
+		name = page.URL
+	}
+	if i := strings.IndexAny(name, "?#"); i >= 0 {
+		name = name[:i]
+	}
+
+	ext := strings.ToLower(path.Ext(name))
+	switch ext {
+	case ".jpg", ".jpeg", ".png", ".gif", ".webp", ".avif", ".bmp":
+		return ext
+	}
+	return ".jpg"
+}
@@ -0,0 +1,264 @@
+// Package epub converts chapter archives into fixed-layout EPUB 3 books for
+// readers that do not open CBZ files.
+package epub
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"hash/crc32"
+	"image"
+	_ "image/gif"  // register decoder
+	_ "image/jpeg" // register decoder
+	_ "image/png"  // register decoder
+	"io"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+
+	_ "golang.org/x/image/webp" // register decoder
+)
+
+// defaultPageSize is the viewport used for pages whose size cannot be read.
+var defaultPageSize = image.Point{X: 800, Y: 1200}
+
+// mediaTypes maps image extensions to their EPUB media types.
+var mediaTypes = map[string]string{
+	".jpg":  "image/jpeg",
+	".jpeg": "image/jpeg",
+	".png":  "image/png",
+	".gif":  "image/gif",
+	".webp": "image/webp",
+	".avif": "image/avif",
+}
+
+// Book describes the EPUB to write.
+type Book struct {
+	// ID uniquely identifies the book, such as "urn:mangashelf:chapter:12".
+	ID       string
+	Title    string
+	Authors  []string
+	Language string
+	// Series and SeriesIndex place the book in a series collection.
+	Series      string
+	SeriesIndex float64
+	Modified    time.Time
+	// Pages are the page images in reading order. The first one is the cover.
+	Pages []*zip.File
+}
+
+// page is a page image as stored in the book.
+type page struct {
+	image     string
+	document  string
+	mediaType string
+	size      image.Point
+}
+
+// Write writes the book to w. Images are copied unchanged, one fixed-layout
+// page each. The same book is always written byte for byte the same, so
+// readers that identify files by their content recognize it again.
+func Write(w io.Writer, book Book) error {
+	if len(book.Pages) == 0 {
+		return fmt.Errorf("book has no pages")
+	}
+	if book.Language == "" {
+		book.Language = "en"
+	}
+	if book.Modified.IsZero() {
+		book.Modified = time.Now()
+	}
+
+	zw := zip.NewWriter(w)
+
+	if err := writeMimetype(zw); err != nil {
+		return err
+	}
+	if err := writeFile(zw, "META-INF/container.xml", book.Modified, []byte(containerXML)); err != nil {
+		return err
+	}
+
+	pages := make([]page, 0, len(book.Pages))
+	for i, f := range book.Pages {
+		ext := strings.ToLower(path.Ext(f.Name))
+		p := page{
+			image:     fmt.Sprintf("images/page-%03d%s", i+1, ext),
+			document:  fmt.Sprintf("pages/page-%03d.xhtml", i+1),
+			mediaType: mediaTypes[ext],
+			size:      imageSize(f),
+		}
+		if p.mediaType == "" {
+			p.mediaType = "application/octet-stream"
+		}
+
+		if err := copyImage(zw, "OEBPS/"+p.image, f); err != nil {
+			return err
+		}
+		if err := writeFile(zw, "OEBPS/"+p.document, book.Modified, pageXHTML(book.Title, i+1, p)); err != nil {
+			return err
+		}
+		pages = append(pages, p)
+	}
+
+	if err := writeFile(zw, "OEBPS/nav.xhtml", book.Modified, navXHTML(book.Title, pages)); err != nil {
+		return err
+	}
+	if err := writeFile(zw, "OEBPS/content.opf", book.Modified, packageOPF(book, pages)); err != nil {
+		return err
+	}
+
+	if err := zw.Close(); err != nil {
+		return fmt.Errorf("finish epub: %w", err)
+	}
+	return nil
+}
+
+const containerXML = `<?xml version="1.0" encoding="UTF-8"?>
+<container version="1.0" xmlns="urn:oasis:names:tc:opendocument:xmlns:container">
+  <rootfiles>
+    <rootfile full-path="OEBPS/content.opf" media-type="application/oebps-package+xml"/>
+  </rootfiles>
+</container>
+`
+
+func pageXHTML(title string, number int, p page) []byte {
+	var b bytes.Buffer
+	b.WriteString(`<?xml version="1.0" encoding="UTF-8"?>` + "\n")
+	b.WriteString(`<!DOCTYPE html>` + "\n")
+	b.WriteString(`<html xmlns="http://www.w3.org/1999/xhtml" xmlns:epub="http://www.idpf.org/2007/ops">` + "\n")
+	fmt.Fprintf(&b, "<head>\n  <title>%s</title>\n", escape(title))
+	fmt.Fprintf(&b, "  <meta name=\"viewport\" content=\"width=%d, height=%d\"/>\n", p.size.X, p.size.Y)
+	b.WriteString("  <style>html, body { margin: 0; padding: 0; } img { display: block; width: 100%; height: 100%; object-fit: contain; }</style>\n")
+	b.WriteString("</head>\n<body>\n")
+	fmt.Fprintf(&b, "  <img src=\"../%s\" alt=\"Page %d\"/>\n", p.image, number)
+	b.WriteString("</body>\n</html>\n")
+	return b.Bytes()
+}
+
+func navXHTML(title string, pages []page) []byte {
+	var b bytes.Buffer
+	b.WriteString(`<?xml version="1.0" encoding="UTF-8"?>` + "\n")
+	b.WriteString(`<!DOCTYPE html>` + "\n")
+	b.WriteString(`<html xmlns="http://www.w3.org/1999/xhtml" xmlns:epub="http://www.idpf.org/2007/ops">` + "\n")
+	fmt.Fprintf(&b, "<head><title>%s</title></head>\n<body>\n", escape(title))
+	b.WriteString("  <nav epub:type=\"toc\">\n    <ol>\n")
+	fmt.Fprintf(&b, "      <li><a href=\"%s\">%s</a></li>\n", pages[0].document, escape(title))
+	b.WriteString("    </ol>\n  </nav>\n")
+	b.WriteString("  <nav epub:type=\"page-list\" hidden=\"\">\n    <ol>\n")
+	for i, p := range pages {
+		fmt.Fprintf(&b, "      <li><a href=\"%s\">%d</a></li>\n", p.document, i+1)
+	}
+	b.WriteString("    </ol>\n  </nav>\n</body>\n</html>\n")
+	return b.Bytes()
+}
+
+func packageOPF(book Book, pages []page) []byte {
+	var b bytes.Buffer
+	b.WriteString(`<?xml version="1.0" encoding="UTF-8"?>` + "\n")
+	b.WriteString(`<package xmlns="http://www.idpf.org/2007/opf" version="3.0" unique-identifier="book-id" prefix="rendition: http://www.idpf.org/vocab/rendition/#">` + "\n")
+	b.WriteString("  <metadata xmlns:dc=\"http://purl.org/dc/elements/1.1/\">\n")
+	fmt.Fprintf(&b, "    <dc:identifier id=\"book-id\">%s</dc:identifier>\n", escape(book.ID))
+	fmt.Fprintf(&b, "    <dc:title>%s</dc:title>\n", escape(book.Title))
+	fmt.Fprintf(&b, "    <dc:language>%s</dc:language>\n", escape(book.Language))
+	for _, author := range book.Authors {
+		fmt.Fprintf(&b, "    <dc:creator>%s</dc:creator>\n", escape(author))
+	}
+	if book.Series != "" {
+		fmt.Fprintf(&b, "    <meta property=\"belongs-to-collection\" id=\"series\">%s</meta>\n", escape(book.Series))
+		b.WriteString("    <meta refines=\"#series\" property=\"collection-type\">series</meta>\n")
+		fmt.Fprintf(&b, "    <meta refines=\"#series\" property=\"group-position\">%s</meta>\n", strconv.FormatFloat(book.SeriesIndex, 'f', -1, 64))
+	}
+	fmt.Fprintf(&b, "    <meta property=\"dcterms:modified\">%s</meta>\n", book.Modified.UTC().Format(time.RFC3339))
+	b.WriteString("    <meta property=\"rendition:layout\">pre-paginated</meta>\n")
+	b.WriteString("    <meta property=\"rendition:spread\">none</meta>\n")
+	b.WriteString("    <meta name=\"cover\" content=\"img-001\"/>\n")
+	b.WriteString("  </metadata>\n  <manifest>\n")
+	b.WriteString("    <item id=\"nav\" href=\"nav.xhtml\" media-type=\"application/xhtml+xml\" properties=\"nav\"/>\n")
+	for i, p := range pages {
+		properties := ""
+		if i == 0 {
+			properties = ` properties="cover-image"`
+		}
+		fmt.Fprintf(&b, "    <item id=\"img-%03d\" href=\"%s\" media-type=\"%s\"%s/>\n", i+1, p.image, p.mediaType, properties)
+		fmt.Fprintf(&b, "    <item id=\"page-%03d\" href=\"%s\" media-type=\"application/xhtml+xml\"/>\n", i+1, p.document)
+	}
+	b.WriteString("  </manifest>\n  <spine>\n")
+	for i := range pages {
+		fmt.Fprintf(&b, "    <itemref idref=\"page-%03d\"/>\n", i+1)
+	}
+	b.WriteString("  </spine>\n</package>\n")
+	return b.Bytes()
+}
+
+// imageSize reads a page's dimensions from its header.
+func imageSize(f *zip.File) image.Point {
+	rc, err := f.Open()
+	if err != nil {
+		return defaultPageSize
+	}
+	defer rc.Close()
+
+	cfg, _, err := image.DecodeConfig(rc)
+	if err != nil || cfg.Width == 0 || cfg.Height == 0 {
+		return defaultPageSize
+	}
+	return image.Point{X: cfg.Width, Y: cfg.Height}
+}
+
+// copyImage stores a page image. Images are already compressed, so they are
+// not deflated again.
+func copyImage(zw *zip.Writer, name string, f *zip.File) error {
+	rc, err := f.Open()
+	if err != nil {
+		return fmt.Errorf("open page %s: %w", f.Name, err)
+	}
+	defer rc.Close()
+
+	w, err := zw.CreateHeader(&zip.FileHeader{Name: name, Method: zip.Store, Modified: f.Modified})
+	if err != nil {
+		return fmt.Errorf("add %s: %w", name, err)
+	}
+	if _, err := io.Copy(w, rc); err != nil {
+		return fmt.Errorf("write %s: %w", name, err)
+	}
+	return nil
+}
+
+// writeMimetype writes the mimetype entry, which readers expect first,
+// uncompressed and without extra fields or a data descriptor.
+func writeMimetype(zw *zip.Writer) error {
+	data := []byte("application/epub+zip")
+	w, err := zw.CreateRaw(&zip.FileHeader{
+		Name:               "mimetype",
+		Method:             zip.Store,
+		CRC32:              crc32.ChecksumIEEE(data),
+		CompressedSize64:   uint64(len(data)),
+		UncompressedSize64: uint64(len(data)),
+	})
+	if err != nil {
+		return fmt.Errorf("add mimetype: %w", err)
+	}
+	if _, err := w.Write(data); err != nil {
+		return fmt.Errorf("write mimetype: %w", err)
+	}
+	return nil
+}
+
+func writeFile(zw *zip.Writer, name string, modified time.Time, data []byte) error {
+	w, err := zw.CreateHeader(&zip.FileHeader{Name: name, Method: zip.Deflate, Modified: modified})
+	if err != nil {
+		return fmt.Errorf("add %s: %w", name, err)
+	}
+	if _, err := w.Write(data); err != nil {
+		return fmt.Errorf("write %s: %w", name, err)
+	}
+	return nil
+}
+
+func escape(s string) string {
+	var b strings.Builder
+	_ = xml.EscapeText(&b, []byte(s))
+	return b.String()
+}
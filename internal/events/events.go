@@ -0,0 +1,130 @@
+// Package events carries library and download events from the services that
+// raise them to the integrations that report them, such as webhooks.
+package events
+
+import (
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/mangashelf/mangashelf/internal/database"
+)
+
+// Type identifies an event.
+type Type string
+
+// Event types.
+const (
+	// NewChapters is raised when a chapter sync finds chapters that were
+	// not stored before. The first sync of a newly added manga raises none.
+	NewChapters Type = "chapter.new"
+	// DownloadCompleted is raised when a chapter has been downloaded.
+	DownloadCompleted Type = "download.completed"
+	// DownloadFailed is raised when a chapter download fails.
+	DownloadFailed Type = "download.failed"
+	// MangaAdded is raised when a manga is added to the library.
+	MangaAdded Type = "manga.added"
+	// MangaRemoved is raised when a manga is deleted from the library.
+	MangaRemoved Type = "manga.removed"
+)
+
+// Types lists every event type.
+var Types = []Type{NewChapters, DownloadCompleted, DownloadFailed, MangaAdded, MangaRemoved}
+
+// Valid reports whether t is a known event type.
+func (t Type) Valid() bool {
+	return slices.Contains(Types, t)
+}
+
+// Event is something that happened in the library.
+type Event struct {
+	Type Type      `json:"event"`
+	Time time.Time `json:"timestamp"`
+	// Manga is the manga the event concerns.
+	Manga Manga `json:"manga"`
+	// Chapters are the new chapters, or the downloaded or failed one.
+	Chapters []Chapter `json:"chapters,omitempty"`
+	// Error describes why a download failed.
+	Error string `json:"error,omitempty"`
+}
+
+// Manga identifies a manga in an event.
+type Manga struct {
+	ID     int64  `json:"id"`
+	Title  string `json:"title"`
+	Slug   string `json:"slug"`
+	Source string `json:"source"`
+	URL    string `json:"url"`
+}
+
+// Chapter identifies a chapter in an event.
+type Chapter struct {
+	ID       int64   `json:"id"`
+	Number   float64 `json:"number"`
+	Volume   string  `json:"volume,omitempty"`
+	Title    string  `json:"title,omitempty"`
+	Language string  `json:"language,omitempty"`
+	URL      string  `json:"url"`
+}
+
+// New returns an event about a manga and some of its chapters.
+func New(t Type, m *database.Manga, chapters ...*database.Chapter) Event {
+	e := Event{
+		Type: t,
+		Time: time.Now().UTC(),
+		Manga: Manga{
+			ID:     m.ID,
+			Title:  m.Title,
+			Slug:   m.Slug,
+			Source: m.Source,
+			URL:    m.Url,
+		},
+	}
+	for _, ch := range chapters {
+		e.Chapters = append(e.Chapters, Chapter{
+			ID:       ch.ID,
+			Number:   ch.Number,
+			Volume:   ch.Volume.String,
+			Title:    ch.Title,
+			Language: ch.Language.String,
+			URL:      ch.Url,
+		})
+	}
+	return e
+}
+
+// Handler receives published events. Handlers run on the publisher's
+// goroutine, so they must hand slow work off rather than block.
+type Handler func(Event)
+
+// Bus delivers events to every subscribed handler. A nil Bus discards
+// events, so services can publish without checking whether anyone listens.
+type Bus struct {
+	mu       sync.RWMutex
+	handlers []Handler
+}
+
+// NewBus returns a bus without subscribers.
+func NewBus() *Bus {
+	return &Bus{}
+}
+
+// Subscribe registers a handler for every event published afterwards.
+func (b *Bus) Subscribe(h Handler) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.handlers = append(b.handlers, h)
+}
+
+// Publish hands an event to every handler, in subscription order.
+func (b *Bus) Publish(e Event) {
+	if b == nil {
+		return
+	}
+	b.mu.RLock()
+	handlers := b.handlers
+	b.mu.RUnlock()
+	for _, h := range handlers {
+		h(e)
+	}
+}
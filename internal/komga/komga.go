@@ -0,0 +1,281 @@
+// Package komga defines the JSON documents of the subset of Komga's REST API
+// that reader apps use to browse a library and read from it.
+package komga
+
+import "time"
+
+// Series statuses.
+const (
+	StatusOngoing   = "ONGOING"
+	StatusEnded     = "ENDED"
+	StatusHiatus    = "HIATUS"
+	StatusAbandoned = "ABANDONED"
+)
+
+// Read statuses used to filter series and books.
+const (
+	ReadStatusUnread     = "UNREAD"
+	ReadStatusInProgress = "IN_PROGRESS"
+	ReadStatusRead       = "READ"
+)
+
+// Library is a Komga library.
+type Library struct {
+	ID          string `json:"id"`
+	Name        string `json:"name"`
+	Root        string `json:"root"`
+	Unavailable bool   `json:"unavailable"`
+}
+
+// Series is a Komga series.
+type Series struct {
+	ID                   string                  `json:"id"`
+	LibraryID            string                  `json:"libraryId"`
+	Name                 string                  `json:"name"`
+	URL                  string                  `json:"url"`
+	Created              time.Time               `json:"created"`
+	LastModified         time.Time               `json:"lastModified"`
+	FileLastModified     time.Time               `json:"fileLastModified"`
+	BooksCount           int                     `json:"booksCount"`
+	BooksReadCount       int                     `json:"booksReadCount"`
+	BooksUnreadCount     int                     `json:"booksUnreadCount"`
+	BooksInProgressCount int                     `json:"booksInProgressCount"`
+	Metadata             SeriesMetadata          `json:"metadata"`
+	BooksMetadata        BookMetadataAggregation `json:"booksMetadata"`
+	Deleted              bool                    `json:"deleted"`
+	Oneshot              bool                    `json:"oneshot"`
+}
+
+// SeriesMetadata is the metadata of a series. Komga lets users lock fields
+// against automatic updates; nothing is locked here.
+type SeriesMetadata struct {
+	Status               string     `json:"status"`
+	StatusLock           bool       `json:"statusLock"`
+	Title                string     `json:"title"`
+	TitleLock            bool       `json:"titleLock"`
+	TitleSort            string     `json:"titleSort"`
+	TitleSortLock        bool       `json:"titleSortLock"`
+	Summary              string     `json:"summary"`
+	SummaryLock          bool       `json:"summaryLock"`
+	ReadingDirection     string     `json:"readingDirection"`
+	ReadingDirectionLock bool       `json:"readingDirectionLock"`
+	Publisher            string     `json:"publisher"`
+	PublisherLock        bool       `json:"publisherLock"`
+	AgeRating            *int       `json:"ageRating"`
+	AgeRatingLock        bool       `json:"ageRatingLock"`
+	Language             string     `json:"language"`
+	LanguageLock         bool       `json:"languageLock"`
+	Genres               []string   `json:"genres"`
+	GenresLock           bool       `json:"genresLock"`
+	Tags                 []string   `json:"tags"`
+	TagsLock             bool       `json:"tagsLock"`
+	TotalBookCount       *int       `json:"totalBookCount"`
+	TotalBookCountLock   bool       `json:"totalBookCountLock"`
+	SharingLabels        []string   `json:"sharingLabels"`
+	SharingLabelsLock    bool       `json:"sharingLabelsLock"`
+	Links                []WebLink  `json:"links"`
+	LinksLock            bool       `json:"linksLock"`
+	AlternateTitles      []AltTitle `json:"alternateTitles"`
+	AlternateTitlesLock  bool       `json:"alternateTitlesLock"`
+	Created              time.Time  `json:"created"`
+	LastModified         time.Time  `json:"lastModified"`
+}
+
+// BookMetadataAggregation summarizes the metadata of a series' books.
+type BookMetadataAggregation struct {
+	Authors       []Author  `json:"authors"`
+	Tags          []string  `json:"tags"`
+	ReleaseDate   *string   `json:"releaseDate"`
+	Summary       string    `json:"summary"`
+	SummaryNumber string    `json:"summaryNumber"`
+	Created       time.Time `json:"created"`
+	LastModified  time.Time `json:"lastModified"`
+}
+
+// Book is a Komga book: a single chapter archive. Number is its position in
+// the series, starting at 1.
+type Book struct {
+	ID               string        `json:"id"`
+	SeriesID         string        `json:"seriesId"`
+	SeriesTitle      string        `json:"seriesTitle"`
+	LibraryID        string        `json:"libraryId"`
+	Name             string        `json:"name"`
+	URL              string        `json:"url"`
+	Number           int           `json:"number"`
+	Created          time.Time     `json:"created"`
+	LastModified     time.Time     `json:"lastModified"`
+	FileLastModified time.Time     `json:"fileLastModified"`
+	SizeBytes        int64         `json:"sizeBytes"`
+	Size             string        `json:"size"`
+	Media            Media         `json:"media"`
+	Metadata         BookMetadata  `json:"metadata"`
+	ReadProgress     *ReadProgress `json:"readProgress"`
+	Deleted          bool          `json:"deleted"`
+	FileHash         string        `json:"fileHash"`
+	Oneshot          bool          `json:"oneshot"`
+}
+
+// Media describes a book's file.
+type Media struct {
+	Status               string `json:"status"`
+	MediaType            string `json:"mediaType"`
+	MediaProfile         string `json:"mediaProfile"`
+	PagesCount           int    `json:"pagesCount"`
+	Comment              string `json:"comment"`
+	EpubDivinaCompatible bool   `json:"epubDivinaCompatible"`
+	EpubIsKepub          bool   `json:"epubIsKepub"`
+}
+
+// BookMetadata is the metadata of a book.
+type BookMetadata struct {
+	Title           string    `json:"title"`
+	TitleLock       bool      `json:"titleLock"`
+	Summary         string    `json:"summary"`
+	SummaryLock     bool      `json:"summaryLock"`
+	Number          string    `json:"number"`
+	NumberLock      bool      `json:"numberLock"`
+	NumberSort      float64   `json:"numberSort"`
+	NumberSortLock  bool      `json:"numberSortLock"`
+	ReleaseDate     *string   `json:"releaseDate"`
+	ReleaseDateLock bool      `json:"releaseDateLock"`
+	Authors         []Author  `json:"authors"`
+	AuthorsLock     bool      `json:"authorsLock"`
+	Tags            []string  `json:"tags"`
+	TagsLock        bool      `json:"tagsLock"`
+	Isbn            string    `json:"isbn"`
+	IsbnLock        bool      `json:"isbnLock"`
+	Links           []WebLink `json:"links"`
+	LinksLock       bool      `json:"linksLock"`
+	Created         time.Time `json:"created"`
+	LastModified    time.Time `json:"lastModified"`
+}
+
+// ReadProgress is a user's progress in a book.
+type ReadProgress struct {
+	// Page is the last page read, starting at 1.
+	Page         int       `json:"page"`
+	Completed    bool      `json:"completed"`
+	ReadDate     time.Time `json:"readDate"`
+	Created      time.Time `json:"created"`
+	LastModified time.Time `json:"lastModified"`
+	DeviceID     string    `json:"deviceId"`
+	DeviceName   string    `json:"deviceName"`
+}
+
+// ReadProgressUpdate changes a user's progress in a book. Nil fields are
+// left untouched.
+type ReadProgressUpdate struct {
+	Page      *int  `json:"page"`
+	Completed *bool `json:"completed"`
+}
+
+// Page describes a page of a book.
+type Page struct {
+	// Number is the page number, starting at 1.
+	Number    int    `json:"number"`
+	FileName  string `json:"fileName"`
+	MediaType string `json:"mediaType"`
+	Width     *int   `json:"width"`
+	Height    *int   `json:"height"`
+	SizeBytes int64  `json:"sizeBytes"`
+	Size      string `json:"size"`
+}
+
+// Author is a credited author of a book, with a role such as "writer".
+type Author struct {
+	Name string `json:"name"`
+	Role string `json:"role"`
+}
+
+// WebLink links to a page about a series or book.
+type WebLink struct {
+	Label string `json:"label"`
+	URL   string `json:"url"`
+}
+
+// AltTitle is an alternate title of a series.
+type AltTitle struct {
+	Label string `json:"label"`
+	Title string `json:"title"`
+}
+
+// User is the signed-in user.
+type User struct {
+	ID                 string   `json:"id"`
+	Email              string   `json:"email"`
+	Roles              []string `json:"roles"`
+	SharedAllLibraries bool     `json:"sharedAllLibraries"`
+	SharedLibrariesIDs []string `json:"sharedLibrariesIds"`
+	LabelsAllow        []string `json:"labelsAllow"`
+	LabelsExclude      []string `json:"labelsExclude"`
+}
+
+// ResultPage is a page of results, in the shape of a Spring Data page.
+type ResultPage[T any] struct {
+	Content          []T      `json:"content"`
+	Pageable         Pageable `json:"pageable"`
+	TotalElements    int      `json:"totalElements"`
+	TotalPages       int      `json:"totalPages"`
+	Last             bool     `json:"last"`
+	First            bool     `json:"first"`
+	Number           int      `json:"number"`
+	Size             int      `json:"size"`
+	NumberOfElements int      `json:"numberOfElements"`
+	Sort             Sort     `json:"sort"`
+	Empty            bool     `json:"empty"`
+}
+
+// Pageable describes the requested page.
+type Pageable struct {
+	PageNumber int  `json:"pageNumber"`
+	PageSize   int  `json:"pageSize"`
+	Offset     int  `json:"offset"`
+	Paged      bool `json:"paged"`
+	Unpaged    bool `json:"unpaged"`
+	Sort       Sort `json:"sort"`
+}
+
+// Sort reports whether results are sorted.
+type Sort struct {
+	Sorted   bool `json:"sorted"`
+	Unsorted bool `json:"unsorted"`
+	Empty    bool `json:"empty"`
+}
+
+// NewPage returns page number (starting at 0) of size items. A size of 0
+// returns every item on one page.
+func NewPage[T any](items []T, number, size int, sorted bool) ResultPage[T] {
+	total := len(items)
+	unpaged := size <= 0
+	if unpaged {
+		number, size = 0, max(total, 1)
+	}
+	pages := (total + size - 1) / size
+	start := min(number*size, total)
+	content := items[start:min(start+size, total)]
+	if content == nil {
+		content = []T{}
+	}
+
+	sort := Sort{Sorted: sorted, Unsorted: !sorted, Empty: !sorted}
+	return ResultPage[T]{
+		Content: content,
+		Pageable: Pageable{
+			PageNumber: number,
+			PageSize:   size,
+			Offset:     start,
+			Paged:      !unpaged,
+			Unpaged:    unpaged,
+			Sort:       sort,
+		},
+		TotalElements:    total,
+		TotalPages:       pages,
+		Last:             number >= pages-1,
+		First:            number == 0,
+		Number:           number,
+		Size:             size,
+		NumberOfElements: len(content),
+		Sort:             sort,
+		Empty:            len(content) == 0,
+	}
+}
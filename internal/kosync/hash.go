@@ -0,0 +1,95 @@
+package kosync
+
+import (
+	"crypto/md5"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+)
+
+// sampleSize is the length of each sample of a partial MD5.
+const sampleSize = 1024
+
+// sampleOffsets are where KOReader samples a file for its partial MD5: the
+// start, then 1 KiB shifted left by 0, 2, 4, ... 20 bits.
+var sampleOffsets = func() []int64 {
+	offsets := []int64{0}
+	for i := range 11 {
+		offsets = append(offsets, int64(sampleSize)<<(2*i))
+	}
+	return offsets
+}()
+
+// PartialMD5 returns KOReader's default document hash: the MD5 of up to
+// twelve 1 KiB samples spread through the file, stopping at the first that
+// lies past its end.
+func PartialMD5(r io.ReaderAt) (string, error) {
+	h := md5.New()
+	buf := make([]byte, sampleSize)
+	for _, off := range sampleOffsets {
+		n, err := r.ReadAt(buf, off)
+		if err != nil && err != io.EOF {
+			return "", fmt.Errorf("read sample: %w", err)
+		}
+		if n == 0 {
+			break
+		}
+		h.Write(buf[:n])
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// FilePartialMD5 returns the PartialMD5 of a file.
+func FilePartialMD5(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", fmt.Errorf("open file: %w", err)
+	}
+	defer f.Close()
+	return PartialMD5(f)
+}
+
+// NameMD5 returns the document hash KOReader uses when set to identify
+// documents by file name: the MD5 of the name.
+func NameMD5(name string) string {
+	sum := md5.Sum([]byte(name))
+	return hex.EncodeToString(sum[:])
+}
+
+// Hasher computes the PartialMD5 of data written to it, for files that are
+// generated while they are sent.
+type Hasher struct {
+	pos     int64
+	samples [][]byte
+}
+
+// NewHasher returns an empty Hasher.
+func NewHasher() *Hasher {
+	return &Hasher{samples: make([][]byte, len(sampleOffsets))}
+}
+
+// Write keeps the parts of p that fall into a sample. It never fails.
+func (h *Hasher) Write(p []byte) (int, error) {
+	start, end := h.pos, h.pos+int64(len(p))
+	for i, off := range sampleOffsets {
+		lo, hi := max(start, off), min(end, off+sampleSize)
+		if lo < hi {
+			h.samples[i] = append(h.samples[i], p[lo-start:hi-start]...)
+		}
+	}
+	h.pos = end
+	return len(p), nil
+}
+
+// Sum returns the PartialMD5 of the data written so far.
+func (h *Hasher) Sum() string {
+	sum := md5.New()
+	for _, sample := range h.samples {
+		if len(sample) == 0 {
+			break
+		}
+		sum.Write(sample)
+	}
+	return hex.EncodeToString(sum.Sum(nil))
+}
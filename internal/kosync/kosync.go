@@ -0,0 +1,282 @@
+// Package kosync implements the progress store behind KOReader's sync
+// server protocol. Documents are identified by KOReader's hash of the file;
+// hashes of chapter files handed out by MangaShelf map back to the chapter,
+// so progress on a device updates the chapter's reading progress.
+package kosync
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"math"
+	"regexp"
+	"strconv"
+	"time"
+
+	"github.com/rs/zerolog"
+
+	"github.com/mangashelf/mangashelf/internal/database"
+	"github.com/mangashelf/mangashelf/internal/library"
+)
+
+// Formats of chapter files.
+const (
+	FormatCBZ  = "cbz"
+	FormatEPUB = "epub"
+)
+
+// Device names the progress reported from MangaShelf's own reading progress.
+const (
+	Device   = "MangaShelf"
+	DeviceID = "mangashelf"
+)
+
+// Progress is a reading position as exchanged with KOReader.
+type Progress struct {
+	Document string `json:"document"`
+	// Progress is the page number in paged documents such as CBZ, and an
+	// XPointer in reflowable ones such as EPUB.
+	Progress   string  `json:"progress"`
+	Percentage float64 `json:"percentage"`
+	Device     string  `json:"device"`
+	DeviceID   string  `json:"device_id"`
+	// Timestamp is the Unix time the position was saved.
+	Timestamp int64 `json:"timestamp"`
+}
+
+// Service stores KOReader progress and maps it onto chapters.
+type Service struct {
+	db  *database.Queries
+	lib *library.Service
+	log zerolog.Logger
+}
+
+// NewService creates a new sync service.
+func NewService(db *database.Queries, lib *library.Service, log zerolog.Logger) *Service {
+	return &Service{
+		db:  db,
+		lib: lib,
+		log: log.With().Str("component", "kosync").Logger(),
+	}
+}
+
+// RecordDocument remembers a chapter file handed to a reader, by its partial
+// MD5 and by the MD5 of its file name.
+func (s *Service) RecordDocument(ctx context.Context, chapterID int64, format, fileName, hash string) error {
+	for _, document := range []string{hash, NameMD5(fileName)} {
+		err := s.db.UpsertKOSyncDocument(ctx, database.UpsertKOSyncDocumentParams{
+			Document:  document,
+			ChapterID: chapterID,
+			Format:    format,
+		})
+		if err != nil {
+			return fmt.Errorf("record document: %w", err)
+		}
+	}
+	return nil
+}
+
+// GetProgress returns a user's position in a document, or nil when there is
+// none. For chapter files, reading progress made elsewhere, such as in the
+// web reader, is returned when it is newer than the last position saved by
+// a device and on a different page.
+func (s *Service) GetProgress(ctx context.Context, userID int64, document string) (*Progress, error) {
+	var stored *Progress
+	row, err := s.db.GetKOSyncProgress(ctx, database.GetKOSyncProgressParams{UserID: userID, Document: document})
+	switch {
+	case err == nil:
+		stored = toProgress(row)
+	case !errors.Is(err, sql.ErrNoRows):
+		return nil, fmt.Errorf("get progress: %w", err)
+	}
+
+	doc, err := s.document(ctx, document)
+	if err != nil || doc == nil {
+		return stored, err
+	}
+	current, err := s.chapterProgress(ctx, userID, doc)
+	if err != nil {
+		if errors.Is(err, library.ErrChapterNotFound) {
+			return stored, nil
+		}
+		return nil, err
+	}
+	if current == nil {
+		return stored, nil
+	}
+	if stored != nil && (current.Timestamp <= stored.Timestamp || pageOf(doc.Format, current.Progress) == pageOf(doc.Format, stored.Progress)) {
+		return stored, nil
+	}
+	return current, nil
+}
+
+// UpdateProgress saves a user's position in a document. For chapter files it
+// also updates the chapter's reading progress, marking it read on the last
+// page. Going back to an earlier page leaves a read chapter read.
+func (s *Service) UpdateProgress(ctx context.Context, userID int64, p Progress) (*Progress, error) {
+	row, err := s.db.UpsertKOSyncProgress(ctx, database.UpsertKOSyncProgressParams{
+		UserID:     userID,
+		Document:   p.Document,
+		Progress:   p.Progress,
+		Percentage: p.Percentage,
+		Device:     p.Device,
+		DeviceID:   p.DeviceID,
+		Timestamp:  time.Now().Unix(),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("save progress: %w", err)
+	}
+	saved := toProgress(row)
+
+	doc, err := s.document(ctx, p.Document)
+	if err != nil {
+		return nil, err
+	}
+	if doc == nil {
+		return saved, nil
+	}
+
+	chapter, err := s.lib.GetChapter(ctx, doc.ChapterID)
+	if err != nil {
+		if errors.Is(err, library.ErrChapterNotFound) {
+			return saved, nil
+		}
+		return nil, err
+	}
+	pages := library.CountPages(chapter.PageCount.Int64, chapter.FilePath.String)
+	if pages == 0 {
+		return saved, nil
+	}
+
+	page := pageOf(doc.Format, p.Progress)
+	if page == 0 {
+		page = int(math.Ceil(p.Percentage * float64(pages)))
+	}
+	current := int64(min(max(page, 1), pages))
+	update := library.ProgressUpdate{CurrentPage: &current}
+	if current == int64(pages) {
+		read := true
+		update.Read = &read
+	}
+	if _, err := s.lib.UpdateProgress(ctx, userID, chapter.ID, update); err != nil {
+		return nil, err
+	}
+	return saved, nil
+}
+
+// document returns the chapter file a hash belongs to, or nil for documents
+// that did not come from MangaShelf. Unknown hashes are looked for among the
+// CBZ files not hashed yet, so files copied from the library folder are
+// found too.
+func (s *Service) document(ctx context.Context, document string) (*database.KosyncDocument, error) {
+	doc, err := s.db.GetKOSyncDocument(ctx, document)
+	if err == nil {
+		return doc, nil
+	}
+	if !errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("get document: %w", err)
+	}
+
+	chapters, err := s.db.ListChaptersWithoutKOSyncDocument(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("list chapters: %w", err)
+	}
+	var found *database.KosyncDocument
+	for _, ch := range chapters {
+		hash, err := FilePartialMD5(ch.FilePath.String)
+		if err != nil {
+			s.log.Debug().Err(err).Int64("chapter", ch.ID).Msg("chapter file not hashed")
+			continue
+		}
+		err = s.db.UpsertKOSyncDocument(ctx, database.UpsertKOSyncDocumentParams{
+			Document:  hash,
+			ChapterID: ch.ID,
+			Format:    FormatCBZ,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("record document: %w", err)
+		}
+		if hash == document {
+			found = &database.KosyncDocument{Document: hash, ChapterID: ch.ID, Format: FormatCBZ}
+		}
+	}
+	return found, nil
+}
+
+// chapterProgress converts a user's reading progress in a chapter into a
+// position in the document, or nil when the chapter has not been started.
+func (s *Service) chapterProgress(ctx context.Context, userID int64, doc *database.KosyncDocument) (*Progress, error) {
+	progress, err := s.lib.GetProgress(ctx, userID, doc.ChapterID)
+	if err != nil {
+		return nil, err
+	}
+	read := progress.IsRead.Int64 == 1
+	if !read && progress.CurrentPage.Int64 == 0 {
+		return nil, nil
+	}
+	chapter, err := s.lib.GetChapter(ctx, doc.ChapterID)
+	if err != nil {
+		return nil, err
+	}
+	pages := library.CountPages(chapter.PageCount.Int64, chapter.FilePath.String)
+	if pages == 0 {
+		return nil, nil
+	}
+
+	page := min(max(int(progress.CurrentPage.Int64), 1), pages)
+	if read {
+		page = pages
+	}
+	updated, err := time.Parse(database.TimeFormat, progress.UpdatedAt.String)
+	if err != nil {
+		updated = time.Now()
+	}
+	return &Progress{
+		Document:   doc.Document,
+		Progress:   formatPage(doc.Format, page),
+		Percentage: float64(page) / float64(pages),
+		Device:     Device,
+		DeviceID:   DeviceID,
+		Timestamp:  updated.Unix(),
+	}, nil
+}
+
+// docFragment matches the spine item of an EPUB XPointer, which is the page
+// in the books MangaShelf builds.
+var docFragment = regexp.MustCompile(`^/body/DocFragment\[(\d+)\]`)
+
+// pageOf returns the page number in a position, or 0 when it has none.
+func pageOf(format, progress string) int {
+	if format == FormatEPUB {
+		m := docFragment.FindStringSubmatch(progress)
+		if m == nil {
+			return 0
+		}
+		progress = m[1]
+	}
+	page, err := strconv.Atoi(progress)
+	if err != nil || page < 0 {
+		return 0
+	}
+	return page
+}
+
+// formatPage returns the position of the start of a page.
+func formatPage(format string, page int) string {
+	if format == FormatEPUB {
+		return fmt.Sprintf("/body/DocFragment[%d]", page)
+	}
+	return strconv.Itoa(page)
+}
+
+func toProgress(p *database.KosyncProgress) *Progress {
+	return &Progress{
+		Document:   p.Document,
+		Progress:   p.Progress,
+		Percentage: p.Percentage,
+		Device:     p.Device,
+		DeviceID:   p.DeviceID,
+		Timestamp:  p.Timestamp,
+	}
+}
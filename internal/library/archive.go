@@ -0,0 +1,219 @@
+package library
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"fmt"
+	"image"
+	"image/jpeg"
+	"io"
+	"mime"
+	"path"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/mangashelf/mangashelf/internal/database"
+)
+
+// Archive is an open chapter archive.
+type Archive struct {
+	*zip.ReadCloser
+	// Pages lists the archive's images in reading order.
+	Pages []*zip.File
+}
+
+// pageExts are the image types read from chapter archives.
+var pageExts = []string{".jpg", ".jpeg", ".png", ".gif", ".webp", ".avif"}
+
+// OpenArchive opens a CBZ archive and lists its images in reading order.
+// Other files, such as ComicInfo.xml, are skipped.
+func OpenArchive(filePath string) (*Archive, error) {
+	zr, err := zip.OpenReader(filePath)
+	if err != nil {
+		return nil, fmt.Errorf("open archive: %w", err)
+	}
+
+	var pages []*zip.File
+	for _, f := range zr.File {
+		if f.FileInfo().IsDir() || strings.HasPrefix(path.Base(f.Name), ".") {
+			continue
+		}
+		if slices.Contains(pageExts, strings.ToLower(path.Ext(f.Name))) {
+			pages = append(pages, f)
+		}
+	}
+	slices.SortFunc(pages, func(a, b *zip.File) int {
+		return strings.Compare(strings.ToLower(a.Name), strings.ToLower(b.Name))
+	})
+
+	return &Archive{ReadCloser: zr, Pages: pages}, nil
+}
+
+// CountPages returns the number of pages of a downloaded chapter. Chapters
+// imported from elsewhere may not record their page count, so their archive
+// is counted instead. It returns 0 when the archive cannot be read.
+func CountPages(stored int64, filePath string) int {
+	if stored > 0 {
+		return int(stored)
+	}
+	archive, err := OpenArchive(filePath)
+	if err != nil {
+		return 0
+	}
+	defer archive.Close()
+	return len(archive.Pages)
+}
+
+// ChapterFile returns a downloaded chapter and the path of its archive.
+func (s *Service) ChapterFile(ctx context.Context, id int64) (*database.Chapter, string, error) {
+	chapter, err := s.GetChapter(ctx, id)
+	if err != nil {
+		return nil, "", err
+	}
+	if chapter.Status.String != "completed" || !fileExists(chapter.FilePath.String) {
+		return nil, "", ErrChapterNotDownloaded
+	}
+	return chapter, chapter.FilePath.String, nil
+}
+
+// PageInfo describes a page of a chapter archive without reading its image.
+type PageInfo struct {
+	// Number is the page number, starting at 1.
+	Number      int
+	FileName    string
+	ContentType string
+	// Width and Height are zero when the image header cannot be read.
+	Width  int
+	Height int
+	Size   int64
+}
+
+// ChapterPages lists the pages of a downloaded chapter.
+func (s *Service) ChapterPages(ctx context.Context, chapterID int64) ([]PageInfo, error) {
+	_, filePath, err := s.ChapterFile(ctx, chapterID)
+	if err != nil {
+		return nil, err
+	}
+
+	archive, err := OpenArchive(filePath)
+	if err != nil {
+		return nil, err
+	}
+	defer archive.Close()
+
+	pages := make([]PageInfo, 0, len(archive.Pages))
+	for i, f := range archive.Pages {
+		info := PageInfo{
+			Number:      i + 1,
+			FileName:    path.Base(f.Name),
+			ContentType: pageContentType(f.Name),
+			Size:        int64(f.UncompressedSize64),
+		}
+		if rc, err := f.Open(); err == nil {
+			if cfg, _, err := image.DecodeConfig(rc); err == nil {
+				info.Width, info.Height = cfg.Width, cfg.Height
+			}
+			rc.Close()
+		}
+		pages = append(pages, info)
+	}
+	return pages, nil
+}
+
+// Page is a page image read from a chapter archive.
+type Page struct {
+	Data        []byte
+	ContentType string
+	ModTime     time.Time
+	// Number is the page number, starting at 1.
+	Number int
+	// Count is the number of pages in the chapter.
+	Count int
+}
+
+// maxPageBytes is the largest page image read from an archive.
+const maxPageBytes = 50 << 20
+
+// ChapterPage reads page number (starting at 1) of a downloaded chapter.
+// A positive maxWidth scales wider pages down to it, as JPEG.
+func (s *Service) ChapterPage(ctx context.Context, chapterID int64, number, maxWidth int) (*Page, error) {
+	_, filePath, err := s.ChapterFile(ctx, chapterID)
+	if err != nil {
+		return nil, err
+	}
+
+	archive, err := OpenArchive(filePath)
+	if err != nil {
+		return nil, err
+	}
+	defer archive.Close()
+
+	if number < 1 || number > len(archive.Pages) {
+		return nil, ErrPageNotFound
+	}
+	f := archive.Pages[number-1]
+
+	rc, err := f.Open()
+	if err != nil {
+		return nil, fmt.Errorf("open page: %w", err)
+	}
+	defer rc.Close()
+	data, err := io.ReadAll(io.LimitReader(rc, maxPageBytes))
+	if err != nil {
+		return nil, fmt.Errorf("read page: %w", err)
+	}
+
+	page := &Page{
+		Data:        data,
+		ContentType: pageContentType(f.Name),
+		ModTime:     f.Modified,
+		Number:      number,
+		Count:       len(archive.Pages),
+	}
+
+	if maxWidth > 0 {
+		if err := scalePage(page, maxWidth); err != nil {
+			// Pages that cannot be decoded are served as stored.
+			s.log.Debug().Err(err).Int64("chapter", chapterID).Int("page", number).Msg("page not scaled")
+		}
+	}
+	return page, nil
+}
+
+// pageContentType returns the media type of a page image by its name.
+func pageContentType(name string) string {
+	if t := mime.TypeByExtension(strings.ToLower(path.Ext(name))); t != "" {
+		return t
+	}
+	return "application/octet-stream"
+}
+
+// scalePage re-encodes a page as JPEG at maxWidth when it is wider. Pages
+// over maxImagePixels are left as stored rather than decoded.
+func scalePage(page *Page, maxWidth int) error {
+	cfg, _, err := image.DecodeConfig(bytes.NewReader(page.Data))
+	if err != nil {
+		return fmt.Errorf("decode page: %w", err)
+	}
+	if cfg.Width <= maxWidth {
+		return nil
+	}
+	if tooManyPixels(cfg) {
+		return fmt.Errorf("%w: %dx%d", ErrImageTooLarge, cfg.Width, cfg.Height)
+	}
+
+	src, _, err := image.Decode(bytes.NewReader(page.Data))
+	if err != nil {
+		return fmt.Errorf("decode page: %w", err)
+	}
+
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, resize(src, maxWidth), &jpeg.Options{Quality: 85}); err != nil {
+		return fmt.Errorf("encode page: %w", err)
+	}
+	page.Data = buf.Bytes()
+	page.ContentType = "image/jpeg"
+	return nil
+}
@@ -0,0 +1,430 @@
+package library
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"fmt"
+	"image"
+	"image/color"
+	_ "image/gif" // register decoder
+	"image/jpeg"
+	_ "image/png" // register decoder
+	"io"
+	"mime"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"golang.org/x/image/draw"
+	_ "golang.org/x/image/webp" // register decoder
+
+	"github.com/mangashelf/mangashelf/internal/database"
+	"github.com/mangashelf/mangashelf/internal/scraper"
+)
+
+const (
+	// coverTimeout bounds a single cover download.
+	coverTimeout = 30 * time.Second
+
+	// maxCoverBytes is the largest cover image accepted.
+	maxCoverBytes = 20 << 20
+
+	// maxImagePixels is the largest width×height decoded. Decoding allocates
+	// memory per pixel, so small but highly compressed images are refused.
+	maxImagePixels = 50_000_000
+
+	// thumbnailDir is the folder, inside a manga's folder, holding cover thumbnails.
+	thumbnailDir = ".thumbnails"
+)
+
+// coverWidths maps the thumbnail sizes served for covers to their width in pixels.
+var coverWidths = map[string]int{
+	"small":  160,
+	"medium": 320,
+	"large":  640,
+}
+
+// DownloadCover fetches the manga's cover from its source into the manga's
+// folder, generates thumbnails and records the file in cover_path.
+func (s *Service) DownloadCover(ctx context.Context, manga *database.Manga) (*database.Manga, error) {
+	if !manga.CoverUrl.Valid || manga.CoverUrl.String == "" {
+		return nil, ErrCoverNotFound
+	}
+
+	data, contentType, err := s.fetchCover(ctx, manga.Source, manga.CoverUrl.String)
+	if err != nil {
+		return nil, fmt.Errorf("fetch cover: %w", err)
+	}
+
+	ext := coverExt(contentType, manga.CoverUrl.String)
+	updated, err := s.storeCover(ctx, manga, data, ext)
+	if err != nil {
+		return nil, err
+	}
+
+	s.log.Debug().Int64("id", manga.ID).Str("path", updated.CoverPath.String).Msg("cover downloaded")
+	return updated, nil
+}
+
+// ListCovers returns the alternate covers the manga's source offers.
+func (s *Service) ListCovers(ctx context.Context, id int64) ([]scraper.Cover, error) {
+	manga, err := s.GetManga(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	covers, err := s.scrapers.GetCovers(ctx, manga.Source, manga.SourceID)
+	if err != nil {
+		return nil, fmt.Errorf("fetch covers: %w", err)
+	}
+	return covers, nil
+}
+
+// SelectCover replaces the cover with one of the alternate covers offered by
+// the source and locks it against metadata refreshes.
+func (s *Service) SelectCover(ctx context.Context, id int64, coverURL string) (*database.Manga, error) {
+	covers, err := s.ListCovers(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	offered := false
+	for _, c := range covers {
+		if c.URL == coverURL {
+			offered = true
+			break
+		}
+	}
+	if !offered {
+		return nil, ErrUnknownCover
+	}
+
+	manga, err := s.db.UpdateMangaCover(ctx, database.UpdateMangaCoverParams{
+		CoverUrl:    toNullString(coverURL),
+		CoverLocked: sql.NullInt64{Int64: 1, Valid: true},
+		ID:          id,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("update cover: %w", err)
+	}
+	return s.DownloadCover(ctx, manga)
+}
+
+// UploadCover stores an uploaded image as the cover and locks it against
+// metadata refreshes. JPEG, PNG, GIF and WebP images are accepted.
+func (s *Service) UploadCover(ctx context.Context, id int64, data []byte) (*database.Manga, error) {
+	manga, err := s.GetManga(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	cfg, format, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, ErrInvalidImage
+	}
+	if tooManyPixels(cfg) {
+		return nil, ErrImageTooLarge
+	}
+	ext := "." + format
+	if format == "jpeg" {
+		ext = ".jpg"
+	}
+
+	manga, err = s.storeCover(ctx, manga, data, ext)
+	if err != nil {
+		return nil, err
+	}
+
+	manga, err = s.db.UpdateMangaCover(ctx, database.UpdateMangaCoverParams{
+		CoverUrl:    manga.CoverUrl,
+		CoverLocked: sql.NullInt64{Int64: 1, Valid: true},
+		ID:          id,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("lock cover: %w", err)
+	}
+
+	s.log.Info().Int64("id", id).Msg("cover uploaded")
+	return manga, nil
+}
+
+// ResetCover unlocks the cover and restores the source's default one.
+func (s *Service) ResetCover(ctx context.Context, id int64) (*database.Manga, error) {
+	manga, err := s.GetManga(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	remote, err := s.scrapers.GetManga(ctx, manga.Source, manga.SourceID)
+	if err != nil {
+		return nil, fmt.Errorf("fetch manga: %w", err)
+	}
+
+	manga, err = s.db.UpdateMangaCover(ctx, database.UpdateMangaCoverParams{
+		CoverUrl:    toNullString(remote.CoverURL),
+		CoverLocked: sql.NullInt64{Int64: 0, Valid: true},
+		ID:          id,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("update cover: %w", err)
+	}
+
+	if !s.downloadCovers || remote.CoverURL == "" {
+		return manga, nil
+	}
+	return s.DownloadCover(ctx, manga)
+}
+
+// CoverFile returns the path of a manga's cover image in the given size:
+// "small", "medium", "large", or empty for the full-size image. Covers not
+// yet stored locally are downloaded first when cover downloads are enabled,
+// and missing thumbnails are regenerated.
+func (s *Service) CoverFile(ctx context.Context, id int64, size string) (string, error) {
+	if _, ok := coverWidths[size]; size != "" && !ok {
+		return "", ErrInvalidCoverSize
+	}
+
+	manga, err := s.GetManga(ctx, id)
+	if err != nil {
+		return "", err
+	}
+
+	if !fileExists(manga.CoverPath.String) {
+		if !s.downloadCovers {
+			return "", ErrCoverNotFound
+		}
+		manga, err = s.DownloadCover(ctx, manga)
+		if err != nil {
+			return "", err
+		}
+	}
+
+	original := manga.CoverPath.String
+	if size == "" {
+		return original, nil
+	}
+
+	thumb := thumbnailPath(original, size)
+	if fileExists(thumb) {
+		return thumb, nil
+	}
+	if err := writeThumbnails(original); err != nil {
+		// Images that cannot be decoded are served at full size.
+		s.log.Debug().Err(err).Int64("id", id).Msg("cover thumbnails unavailable")
+		return original, nil
+	}
+	return thumb, nil
+}
+
+// storeCover writes a cover image into the manga's folder, replacing the
+// previous one and its thumbnails, and records it in cover_path.
+func (s *Service) storeCover(ctx context.Context, manga *database.Manga, data []byte, ext string) (*database.Manga, error) {
+	folder, err := MangaFolder(ctx, s.db, manga)
+	if err != nil {
+		return nil, err
+	}
+	dir := filepath.Join(s.libraryPath, folder)
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("create manga directory: %w", err)
+	}
+
+	target := filepath.Join(dir, "cover"+ext)
+	if err := writeFileAtomic(target, data); err != nil {
+		return nil, fmt.Errorf("write cover: %w", err)
+	}
+	if old := manga.CoverPath.String; old != "" && old != target {
+		os.Remove(old)
+	}
+
+	os.RemoveAll(filepath.Join(dir, thumbnailDir))
+	if err := writeThumbnails(target); err != nil {
+		s.log.Debug().Err(err).Int64("id", manga.ID).Msg("skipped cover thumbnails")
+	}
+
+	updated, err := s.db.UpdateMangaCoverPath(ctx, database.UpdateMangaCoverPathParams{
+		CoverPath: sql.NullString{String: target, Valid: true},
+		ID:        manga.ID,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("update cover path: %w", err)
+	}
+	return updated, nil
+}
+
+// fetchCover reads a cover image through the provider's opener when it has
+// one, and over HTTP with the provider's client otherwise.
+func (s *Service) fetchCover(ctx context.Context, source, rawURL string) ([]byte, string, error) {
+	if provider, err := s.scrapers.Get(source); err == nil {
+		if opener, ok := provider.(scraper.Opener); ok {
+			rc, err := opener.Open(ctx, rawURL)
+			if err != nil {
+				return nil, "", err
+			}
+			defer rc.Close()
+			data, err := readLimited(rc)
+			return data, "", err
+		}
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, rawURL, nil)
+	if err != nil {
+		return nil, "", fmt.Errorf("create request: %w", err)
+	}
+	req.Header.Set("User-Agent", "MangaShelf/1.0")
+
+	resp, err := scraper.NewHTTPClient(source, coverTimeout).Do(req)
+	if err != nil {
+		return nil, "", fmt.Errorf("execute request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, "", fmt.Errorf("unexpected status: %d", resp.StatusCode)
+	}
+
+	data, err := readLimited(resp.Body)
+	return data, resp.Header.Get("Content-Type"), err
+}
+
+// readLimited reads an image body, rejecting ones over maxCoverBytes.
+func readLimited(r io.Reader) ([]byte, error) {
+	data, err := io.ReadAll(io.LimitReader(r, maxCoverBytes+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(data) > maxCoverBytes {
+		return nil, fmt.Errorf("cover larger than %d bytes", maxCoverBytes)
+	}
+	return data, nil
+}
+
+// tooManyPixels reports whether an image is over maxImagePixels.
+func tooManyPixels(cfg image.Config) bool {
+	return int64(cfg.Width)*int64(cfg.Height) > maxImagePixels
+}
+
+// writeThumbnails generates every thumbnail size for a cover image. Covers
+// narrower than a thumbnail are re-encoded at their own size; covers over
+// maxImagePixels get no thumbnails.
+func writeThumbnails(original string) error {
+	f, err := os.Open(original)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	cfg, _, err := image.DecodeConfig(f)
+	if err != nil {
+		return fmt.Errorf("decode cover: %w", err)
+	}
+	if tooManyPixels(cfg) {
+		return fmt.Errorf("%w: %dx%d", ErrImageTooLarge, cfg.Width, cfg.Height)
+	}
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+
+	src, _, err := image.Decode(f)
+	if err != nil {
+		return fmt.Errorf("decode cover: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Join(filepath.Dir(original), thumbnailDir), 0o755); err != nil {
+		return fmt.Errorf("create thumbnail directory: %w", err)
+	}
+
+	bounds := src.Bounds()
+	if bounds.Empty() {
+		return fmt.Errorf("cover has no pixels")
+	}
+	for size, width := range coverWidths {
+		if bounds.Dx() < width {
+			width = bounds.Dx()
+		}
+
+		var buf bytes.Buffer
+		if err := jpeg.Encode(&buf, resize(src, width), &jpeg.Options{Quality: 85}); err != nil {
+			return fmt.Errorf("encode %s thumbnail: %w", size, err)
+		}
+		if err := writeFileAtomic(thumbnailPath(original, size), buf.Bytes()); err != nil {
+			return fmt.Errorf("write %s thumbnail: %w", size, err)
+		}
+	}
+	return nil
+}
+
+// resize scales an image to width, keeping its aspect ratio. Transparent
+// areas are flattened onto white, as JPEG has no alpha.
+func resize(src image.Image, width int) *image.RGBA {
+	bounds := src.Bounds()
+	height := max(bounds.Dy()*width/bounds.Dx(), 1)
+
+	dst := image.NewRGBA(image.Rect(0, 0, width, height))
+	draw.Draw(dst, dst.Bounds(), image.NewUniform(color.White), image.Point{}, draw.Src)
+	draw.CatmullRom.Scale(dst, dst.Bounds(), src, bounds, draw.Over, nil)
+	return dst
+}
+
+// thumbnailPath returns where the thumbnail of a cover is stored.
+func thumbnailPath(original, size string) string {
+	return filepath.Join(filepath.Dir(original), thumbnailDir, "cover-"+size+".jpg")
+}
+
+// coverExt picks the file extension for a cover from its content type,
+// then its URL, defaulting to ".jpg".
+func coverExt(contentType, rawURL string) string {
+	if mediaType, _, err := mime.ParseMediaType(contentType); err == nil {
+		switch mediaType {
+		case "image/jpeg":
+			return ".jpg"
+		case "image/png":
+			return ".png"
+		case "image/webp":
+			return ".webp"
+		case "image/gif":
+			return ".gif"
+		}
+	}
+
+	if i := strings.IndexAny(rawURL, "?#"); i >= 0 {
+		rawURL = rawURL[:i]
+	}
+	switch ext := strings.ToLower(path.Ext(rawURL)); ext {
+	case ".jpg", ".png", ".webp", ".gif":
+		return ext
+	case ".jpeg":
+		return ".jpg"
+	}
+	return ".jpg"
+}
+
+// writeFileAtomic writes data to a temporary file next to target and renames
+// it into place, so readers never see a partial file.
+func writeFileAtomic(target string, data []byte) error {
+	tmp, err := os.CreateTemp(filepath.Dir(target), ".cover-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), target)
+}
+
+// fileExists reports whether path names an existing regular file.
+func fileExists(path string) bool {
+	if path == "" {
+		return false
+	}
+	info, err := os.Stat(path)
+	return err == nil && info.Mode().IsRegular()
+}
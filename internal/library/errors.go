@@ -6,6 +6,36 @@ var (
 	// ErrMangaExists is returned when trying to add a manga that already exists.
 	ErrMangaExists = errors.New("manga already exists in library")
 
+	// ErrSlugConflict is returned when no free slug is found for a new manga.
+	ErrSlugConflict = errors.New("slug already in use")
+
 	// ErrMangaNotFound is returned when a manga is not found.
 	ErrMangaNotFound = errors.New("manga not found")
+
+	// ErrChapterNotFound is returned when a chapter is not found.
+	ErrChapterNotFound = errors.New("chapter not found")
+
+	// ErrChapterNotDownloaded is returned when a chapter's file is not in the library.
+	ErrChapterNotDownloaded = errors.New("chapter not downloaded")
+
+	// ErrPageNotFound is returned for a page number outside a chapter.
+	ErrPageNotFound = errors.New("page not found")
+
+	// ErrCoverNotFound is returned when a manga has no cover available.
+	ErrCoverNotFound = errors.New("cover not found")
+
+	// ErrInvalidCoverSize is returned for an unknown cover thumbnail size.
+	ErrInvalidCoverSize = errors.New("invalid cover size")
+
+	// ErrInvalidImage is returned when an uploaded cover is not a supported image.
+	ErrInvalidImage = errors.New("invalid image")
+
+	// ErrImageTooLarge is returned for images with more than maxImagePixels.
+	ErrImageTooLarge = errors.New("image too large")
+
+	// ErrUnknownCover is returned when selecting a cover the source does not offer.
+	ErrUnknownCover = errors.New("cover not offered by source")
+
+	// ErrInvalidContentRating is returned for a content rating that is not in ContentRatings.
+	ErrInvalidContentRating = errors.New("invalid content rating")
 )
@@ -0,0 +1,163 @@
+package library
+
+import (
+	"database/sql"
+	"encoding/json"
+	"sort"
+	"strings"
+
+	"github.com/mangashelf/mangashelf/internal/database"
+	"github.com/mangashelf/mangashelf/internal/scraper"
+)
+
+// groupRules decides which scanlation group release of a chapter to download.
+// Entries match a group by ID or by case-insensitive name.
+type groupRules struct {
+	preferred []string
+	blocked   []string
+}
+
+// groupRulesFor loads the group rules stored on a manga.
+func groupRulesFor(manga *database.Manga) groupRules {
+	return groupRules{
+		preferred: database.FromNullStringList(manga.PreferredGroups),
+		blocked:   database.FromNullStringList(manga.BlockedGroups),
+	}
+}
+
+// releaseKey identifies the releases of one chapter. Unnumbered chapters,
+// such as oneshots and extras, all have number 0, so they are told apart by
+// volume and title as well.
+type releaseKey struct {
+	number float64
+	volume string
+	title  string
+}
+
+// releaseKeyOf returns the release key of a chapter.
+func releaseKeyOf(ch *database.Chapter) releaseKey {
+	if ch.Number != 0 {
+		return releaseKey{number: ch.Number}
+	}
+	return releaseKey{volume: ch.Volume.String, title: strings.ToLower(strings.TrimSpace(ch.Title))}
+}
+
+// selectReleases picks one release per chapter. Releases by a blocked
+// group and releases the source cannot serve are never picked. A release that is already queued or downloaded wins
+// so the same chapter is not fetched twice; otherwise the release by the most
+// preferred group wins, then the earliest published one.
+func (r groupRules) selectReleases(chapters []*database.Chapter) []*database.Chapter {
+	byKey := make(map[releaseKey][]*database.Chapter)
+	var keys []releaseKey
+
+	for _, ch := range chapters {
+		if ch.Unavailable.Int64 == 1 || r.isBlocked(fromNullGroups(ch.ScanlationGroups)) {
+			continue
+		}
+		key := releaseKeyOf(ch)
+		if _, ok := byKey[key]; !ok {
+			keys = append(keys, key)
+		}
+		byKey[key] = append(byKey[key], ch)
+	}
+
+	selected := make([]*database.Chapter, 0, len(keys))
+	for _, key := range keys {
+		releases := byKey[key]
+		sort.SliceStable(releases, func(i, j int) bool {
+			a, b := releases[i], releases[j]
+			if isActive(a) != isActive(b) {
+				return isActive(a)
+			}
+			ra, rb := r.rank(fromNullGroups(a.ScanlationGroups)), r.rank(fromNullGroups(b.ScanlationGroups))
+			if ra != rb {
+				return ra < rb
+			}
+			if a.PublishedAt.String != b.PublishedAt.String {
+				return a.PublishedAt.String < b.PublishedAt.String
+			}
+			return a.ID < b.ID
+		})
+		selected = append(selected, releases[0])
+	}
+
+	return selected
+}
+
+// rank returns the position of the best preferred group in a release, or
+// len(preferred) when none of its groups is preferred.
+func (r groupRules) rank(groups []scraper.ScanlationGroup) int {
+	best := len(r.preferred)
+	for i, entry := range r.preferred {
+		if i < best && matchesAny(entry, groups) {
+			best = i
+		}
+	}
+	return best
+}
+
+// isBlocked reports whether any group of a release is blocked.
+func (r groupRules) isBlocked(groups []scraper.ScanlationGroup) bool {
+	for _, entry := range r.blocked {
+		if matchesAny(entry, groups) {
+			return true
+		}
+	}
+	return false
+}
+
+// matchesAny reports whether a rule entry matches one of the groups.
+func matchesAny(entry string, groups []scraper.ScanlationGroup) bool {
+	for _, g := range groups {
+		if entry == g.ID || strings.EqualFold(entry, g.Name) {
+			return true
+		}
+	}
+	return false
+}
+
+// isActive reports whether a chapter is already queued, downloading or downloaded.
+func isActive(ch *database.Chapter) bool {
+	switch ch.Status.String {
+	case "queued", "downloading", "completed":
+		return true
+	}
+	return false
+}
+
+// normalizeGroupRule trims rule entries and drops empty or repeated ones.
+func normalizeGroupRule(entries []string) []string {
+	seen := make(map[string]bool, len(entries))
+	result := make([]string, 0, len(entries))
+	for _, entry := range entries {
+		entry = strings.TrimSpace(entry)
+		key := strings.ToLower(entry)
+		if entry == "" || seen[key] {
+			continue
+		}
+		seen[key] = true
+		result = append(result, entry)
+	}
+	return result
+}
+
+// toNullGroups encodes scanlation groups as a JSON array, or NULL when empty.
+func toNullGroups(groups []scraper.ScanlationGroup) sql.NullString {
+	if len(groups) == 0 {
+		return sql.NullString{Valid: false}
+	}
+	data, _ := json.Marshal(groups)
+	return sql.NullString{String: string(data), Valid: true}
+}
+
+// fromNullGroups decodes a scanlation group column, ignoring malformed values.
+func fromNullGroups(ns sql.NullString) []scraper.ScanlationGroup {
+	if !ns.Valid || ns.String == "" {
+		return nil
+	}
+	var groups []scraper.ScanlationGroup
+	if err := json.Unmarshal([]byte(ns.String), &groups); err != nil {
+		return nil
+	}
+	return groups
+}
@@ -0,0 +1,148 @@
+package library
+
+import (
+	"database/sql"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/mangashelf/mangashelf/internal/database"
+	"github.com/mangashelf/mangashelf/internal/scraper"
+)
+
+// release builds a stored chapter released by the named groups. Group IDs
+// are "id-" followed by the lowercased name.
+func release(id int64, number float64, groups ...string) *database.Chapter {
+	var sg []scraper.ScanlationGroup
+	for _, name := range groups {
+		sg = append(sg, scraper.ScanlationGroup{ID: "id-" + strings.ToLower(name), Name: name})
+	}
+	return &database.Chapter{
+		ID:               id,
+		Number:           number,
+		Title:            "Chapter",
+		ScanlationGroups: toNullGroups(sg),
+	}
+}
+
+func TestSelectReleases(t *testing.T) {
+	published := func(ch *database.Chapter, at string) *database.Chapter {
+		ch.PublishedAt = sql.NullString{String: at, Valid: true}
+		return ch
+	}
+	status := func(ch *database.Chapter, s string) *database.Chapter {
+		ch.Status = sql.NullString{String: s, Valid: true}
+		return ch
+	}
+	titled := func(ch *database.Chapter, volume, title string) *database.Chapter {
+		ch.Volume = sql.NullString{String: volume, Valid: volume != ""}
+		ch.Title = title
+		return ch
+	}
+	unavailable := func(ch *database.Chapter) *database.Chapter {
+		ch.Unavailable = sql.NullInt64{Int64: 1, Valid: true}
+		return ch
+	}
+
+	tests := []struct {
+		name     string
+		rules    groupRules
+		chapters []*database.Chapter
+		wantIDs  []int64
+	}{
+		{
+			name:     "one release per chapter",
+			chapters: []*database.Chapter{release(1, 1, "A"), release(2, 2, "A")},
+			wantIDs:  []int64{1, 2},
+		},
+		{
+			name:     "tie goes to the earliest published",
+			chapters: []*database.Chapter{published(release(1, 1, "A"), "2024-02-01 00:00:00"), published(release(2, 1, "B"), "2024-01-01 00:00:00")},
+			wantIDs:  []int64{2},
+		},
+		{
+			name:     "full tie goes to the lowest ID",
+			chapters: []*database.Chapter{release(2, 1, "B"), release(1, 1, "A")},
+			wantIDs:  []int64{1},
+		},
+		{
+			name:     "preferred group wins over publication date",
+			rules:    groupRules{preferred: []string{"B"}},
+			chapters: []*database.Chapter{published(release(1, 1, "A"), "2024-01-01 00:00:00"), published(release(2, 1, "B"), "2024-02-01 00:00:00")},
+			wantIDs:  []int64{2},
+		},
+		{
+			name:     "earlier preference wins",
+			rules:    groupRules{preferred: []string{"id-c", "b"}},
+			chapters: []*database.Chapter{release(1, 1, "B"), release(2, 1, "C")},
+			wantIDs:  []int64{2},
+		},
+		{
+			name:     "blocked release is skipped",
+			rules:    groupRules{blocked: []string{"a"}},
+			chapters: []*database.Chapter{release(1, 1, "A"), release(2, 1, "B")},
+			wantIDs:  []int64{2},
+		},
+		{
+			name:     "chapter with only blocked releases is left out",
+			rules:    groupRules{blocked: []string{"id-a", "B"}},
+			chapters: []*database.Chapter{release(1, 1, "A"), release(2, 1, "B"), release(3, 2, "C")},
+			wantIDs:  []int64{3},
+		},
+		{
+			name:     "joint release with a blocked group is skipped",
+			rules:    groupRules{blocked: []string{"B"}},
+			chapters: []*database.Chapter{release(1, 1, "A", "B"), release(2, 1, "C")},
+			wantIDs:  []int64{2},
+		},
+		{
+			name:     "active release wins over a preferred one",
+			rules:    groupRules{preferred: []string{"B"}},
+			chapters: []*database.Chapter{status(release(1, 1, "A"), "completed"), release(2, 1, "B")},
+			wantIDs:  []int64{1},
+		},
+		{
+			name:     "unavailable release is skipped",
+			chapters: []*database.Chapter{unavailable(release(1, 1, "A")), release(2, 1, "B"), unavailable(release(3, 2, "A"))},
+			wantIDs:  []int64{2},
+		},
+		{
+			name: "unnumbered chapters are kept apart",
+			chapters: []*database.Chapter{
+				titled(release(1, 0, "A"), "", "Oneshot"),
+				titled(release(2, 0, "A"), "", "Extra"),
+				titled(release(3, 0, "A"), "2", "Extra"),
+			},
+			wantIDs: []int64{1, 2, 3},
+		},
+		{
+			name: "unnumbered releases of the same chapter are grouped",
+			chapters: []*database.Chapter{
+				titled(release(1, 0, "A"), "1", "Oneshot"),
+				titled(release(2, 0, "B"), "1", " oneshot "),
+			},
+			wantIDs: []int64{1},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []int64
+			for _, ch := range tt.rules.selectReleases(tt.chapters) {
+				got = append(got, ch.ID)
+			}
+			slices.Sort(got)
+			if !slices.Equal(got, tt.wantIDs) {
+				t.Errorf("selected %v, want %v", got, tt.wantIDs)
+			}
+		})
+	}
+}
+
+func TestNormalizeGroupRule(t *testing.T) {
+	got := normalizeGroupRule([]string{" Official ", "official", "", "Other"})
+	want := []string{"Official", "Other"}
+	if !slices.Equal(got, want) {
+		t.Errorf("normalizeGroupRule = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,47 @@
+package library
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/mangashelf/mangashelf/internal/database"
+)
+
+// FolderName returns the folder a manga's files are stored in, relative to
+// the library path. The title is made safe to use as a folder name on every
+// platform, falling back to the slug when nothing usable is left.
+func FolderName(title, slug string) string {
+	name := strings.Map(func(r rune) rune {
+		if r < 32 || strings.ContainsRune(`<>:"/\|?*`, r) {
+			return '_'
+		}
+		return r
+	}, title)
+
+	name = strings.Trim(strings.TrimSpace(name), ".")
+	if name == "" {
+		return slug
+	}
+	return name
+}
+
+// MangaFolder returns the folder a manga's files are stored in, relative to
+// the library path. The title folder belongs to the oldest manga with that
+// title; the same title added from another source uses its unique slug, so
+// the two never share covers or chapter files. Folder names are compared
+// case-insensitively, as on Windows and macOS.
+func MangaFolder(ctx context.Context, db *database.Queries, manga *database.Manga) (string, error) {
+	name := FolderName(manga.Title, manga.Slug)
+
+	older, err := db.ListOlderMangaTitles(ctx, manga.ID)
+	if err != nil {
+		return "", fmt.Errorf("list manga titles: %w", err)
+	}
+	for _, m := range older {
+		if strings.EqualFold(FolderName(m.Title, m.Slug), name) {
+			return manga.Slug, nil
+		}
+	}
+	return name, nil
+}
@@ -0,0 +1,164 @@
+package library
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/mangashelf/mangashelf/internal/database"
+)
+
+// ProgressUpdate changes a user's reading progress in a chapter. Nil fields
+// are left untouched.
+type ProgressUpdate struct {
+	// CurrentPage is the last page read, starting at 1.
+	CurrentPage *int64 `json:"currentPage"`
+	// Read marks the chapter read or unread.
+	Read *bool `json:"read"`
+}
+
+// MarkReadRequest marks several chapters of a manga read or unread.
+type MarkReadRequest struct {
+	Read bool `json:"read"`
+	// UpTo limits the change to chapters numbered up to and including it.
+	// All chapters are changed when it is nil.
+	UpTo *float64 `json:"upTo"`
+}
+
+// GetChapter retrieves a chapter by ID. Chapters of manga hidden by the
+// context's restrictions are reported as not found.
+func (s *Service) GetChapter(ctx context.Context, id int64) (*database.Chapter, error) {
+	chapter, err := s.db.GetChapter(ctx, id)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrChapterNotFound
+		}
+		return nil, fmt.Errorf("get chapter: %w", err)
+	}
+
+	if _, err := s.GetManga(ctx, chapter.MangaID); err != nil {
+		if errors.Is(err, ErrMangaNotFound) {
+			return nil, ErrChapterNotFound
+		}
+		return nil, err
+	}
+	return chapter, nil
+}
+
+// GetProgress returns a user's reading progress in a chapter. Chapters the
+// user has not opened yet have zero progress.
+func (s *Service) GetProgress(ctx context.Context, userID, chapterID int64) (*database.ChapterProgress, error) {
+	if _, err := s.GetChapter(ctx, chapterID); err != nil {
+		return nil, err
+	}
+	return s.progress(ctx, userID, chapterID)
+}
+
+// UpdateProgress changes a user's reading progress in a chapter.
+func (s *Service) UpdateProgress(ctx context.Context, userID, chapterID int64, req ProgressUpdate) (*database.ChapterProgress, error) {
+	if _, err := s.GetChapter(ctx, chapterID); err != nil {
+		return nil, err
+	}
+
+	progress, err := s.progress(ctx, userID, chapterID)
+	if err != nil {
+		return nil, err
+	}
+
+	if req.CurrentPage != nil {
+		progress.CurrentPage = sql.NullInt64{Int64: max(*req.CurrentPage, 0), Valid: true}
+	}
+	if req.Read != nil {
+		setRead(progress, *req.Read)
+	}
+
+	return s.saveProgress(ctx, progress)
+}
+
+// ResetProgress forgets a user's progress in a chapter, leaving it unread.
+func (s *Service) ResetProgress(ctx context.Context, userID, chapterID int64) error {
+	if _, err := s.GetChapter(ctx, chapterID); err != nil {
+		return err
+	}
+
+	err := s.db.DeleteChapterProgress(ctx, database.DeleteChapterProgressParams{
+		UserID:    userID,
+		ChapterID: chapterID,
+	})
+	if err != nil {
+		return fmt.Errorf("delete progress: %w", err)
+	}
+	return nil
+}
+
+// MarkRead marks a manga's chapters read or unread for a user and returns
+// the chapters with the updated progress.
+func (s *Service) MarkRead(ctx context.Context, userID, mangaID int64, req MarkReadRequest) ([]*database.ListChaptersWithProgressRow, error) {
+	chapters, err := s.ListChapters(ctx, userID, mangaID)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, ch := range chapters {
+		if req.UpTo != nil && ch.Number > *req.UpTo {
+			continue
+		}
+		if (ch.IsRead.Int64 == 1) == req.Read {
+			continue
+		}
+
+		progress, err := s.progress(ctx, userID, ch.ID)
+		if err != nil {
+			return nil, err
+		}
+		setRead(progress, req.Read)
+		if _, err := s.saveProgress(ctx, progress); err != nil {
+			return nil, err
+		}
+	}
+
+	return s.ListChapters(ctx, userID, mangaID)
+}
+
+// progress loads stored progress, or zero progress when there is none.
+func (s *Service) progress(ctx context.Context, userID, chapterID int64) (*database.ChapterProgress, error) {
+	progress, err := s.db.GetChapterProgress(ctx, database.GetChapterProgressParams{
+		UserID:    userID,
+		ChapterID: chapterID,
+	})
+	if errors.Is(err, sql.ErrNoRows) {
+		return &database.ChapterProgress{UserID: userID, ChapterID: chapterID}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("get progress: %w", err)
+	}
+	return progress, nil
+}
+
+func (s *Service) saveProgress(ctx context.Context, p *database.ChapterProgress) (*database.ChapterProgress, error) {
+	saved, err := s.db.UpsertChapterProgress(ctx, database.UpsertChapterProgressParams{
+		UserID:      p.UserID,
+		ChapterID:   p.ChapterID,
+		IsRead:      sql.NullInt64{Int64: p.IsRead.Int64, Valid: true},
+		CurrentPage: sql.NullInt64{Int64: p.CurrentPage.Int64, Valid: true},
+		ReadAt:      p.ReadAt,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("save progress: %w", err)
+	}
+	return saved, nil
+}
+
+// setRead updates the read flag, recording when a chapter was first read.
+func setRead(p *database.ChapterProgress, read bool) {
+	switch {
+	case read && p.IsRead.Int64 != 1:
+		p.IsRead = sql.NullInt64{Int64: 1, Valid: true}
+		p.ReadAt = toNullString(time.Now().UTC().Format(database.TimeFormat))
+	case !read:
+		p.IsRead = sql.NullInt64{Int64: 0, Valid: true}
+		p.ReadAt = sql.NullString{}
+	}
+}
@@ -0,0 +1,84 @@
+package library
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/mangashelf/mangashelf/internal/database"
+)
+
+// ContentRatings lists the content ratings from least to most explicit.
+var ContentRatings = []string{"safe", "suggestive", "erotica", "pornographic"}
+
+// Restrictions limit which manga a user can see. Manga outside them are
+// left out of listings and reported as not found.
+type Restrictions struct {
+	// AllowedTags, when set, only shows manga with at least one of these
+	// genres or tags.
+	AllowedTags []string `json:"allowedTags,omitempty"`
+	// ExcludedTags hides manga with any of these genres or tags.
+	ExcludedTags []string `json:"excludedTags,omitempty"`
+	// MaxContentRating hides manga rated above it. Manga without a rating
+	// are hidden too, since nothing is known about them.
+	MaxContentRating string `json:"maxContentRating,omitempty"`
+}
+
+// Normalize lowercases and de-duplicates tags and checks the content rating.
+func (r Restrictions) Normalize() (Restrictions, error) {
+	r.AllowedTags = normalizeList(r.AllowedTags)
+	r.ExcludedTags = normalizeList(r.ExcludedTags)
+	r.MaxContentRating = strings.ToLower(strings.TrimSpace(r.MaxContentRating))
+	if r.MaxContentRating != "" && !slices.Contains(ContentRatings, r.MaxContentRating) {
+		return r, fmt.Errorf("%w: %s", ErrInvalidContentRating, r.MaxContentRating)
+	}
+	return r, nil
+}
+
+// Allows reports whether a manga is visible under the restrictions.
+func (r Restrictions) Allows(m *database.Manga) bool {
+	if r.MaxContentRating != "" {
+		rating := slices.Index(ContentRatings, strings.ToLower(m.ContentRating.String))
+		if rating < 0 || rating > slices.Index(ContentRatings, r.MaxContentRating) {
+			return false
+		}
+	}
+
+	if len(r.AllowedTags) == 0 && len(r.ExcludedTags) == 0 {
+		return true
+	}
+
+	tags := make(map[string]bool)
+	for _, tag := range append(database.FromNullStringList(m.Genres), database.FromNullStringList(m.Tags)...) {
+		tags[strings.ToLower(tag)] = true
+	}
+	for _, tag := range r.ExcludedTags {
+		if tags[tag] {
+			return false
+		}
+	}
+	if len(r.AllowedTags) == 0 {
+		return true
+	}
+	for _, tag := range r.AllowedTags {
+		if tags[tag] {
+			return true
+		}
+	}
+	return false
+}
+
+type restrictionsKey struct{}
+
+// WithRestrictions returns a context whose library calls only see manga
+// allowed by r.
+func WithRestrictions(ctx context.Context, r Restrictions) context.Context {
+	return context.WithValue(ctx, restrictionsKey{}, r)
+}
+
+// restrictionsFrom returns the restrictions stored by WithRestrictions.
+func restrictionsFrom(ctx context.Context) Restrictions {
+	r, _ := ctx.Value(restrictionsKey{}).(Restrictions)
+	return r
+}
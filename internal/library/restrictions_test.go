@@ -0,0 +1,113 @@
+package library
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"slices"
+	"testing"
+
+	"github.com/mangashelf/mangashelf/internal/database"
+)
+
+func TestRestrictionsAllows(t *testing.T) {
+	manga := func(rating string, genres, tags []string) *database.Manga {
+		return &database.Manga{
+			ContentRating: sql.NullString{String: rating, Valid: rating != ""},
+			Genres:        database.ToNullStringList(genres),
+			Tags:          database.ToNullStringList(tags),
+		}
+	}
+
+	tests := []struct {
+		name         string
+		restrictions Restrictions
+		manga        *database.Manga
+		want         bool
+	}{
+		{"no restrictions", Restrictions{}, manga("", nil, nil), true},
+		{"rating below the maximum", Restrictions{MaxContentRating: "suggestive"}, manga("safe", nil, nil), true},
+		{"rating at the maximum", Restrictions{MaxContentRating: "suggestive"}, manga("suggestive", nil, nil), true},
+		{"rating above the maximum", Restrictions{MaxContentRating: "suggestive"}, manga("erotica", nil, nil), false},
+		{"rating compared case-insensitively", Restrictions{MaxContentRating: "safe"}, manga("Safe", nil, nil), true},
+		{"unrated with a maximum", Restrictions{MaxContentRating: "pornographic"}, manga("", nil, nil), false},
+		{"unknown rating with a maximum", Restrictions{MaxContentRating: "pornographic"}, manga("mature", nil, nil), false},
+		{"allowed genre", Restrictions{AllowedTags: []string{"action"}}, manga("", []string{"Action"}, nil), true},
+		{"allowed tag", Restrictions{AllowedTags: []string{"isekai"}}, manga("", []string{"Drama"}, []string{"Isekai"}), true},
+		{"no allowed tag", Restrictions{AllowedTags: []string{"action"}}, manga("", []string{"Drama"}, nil), false},
+		{"no tags with an allow list", Restrictions{AllowedTags: []string{"action"}}, manga("", nil, nil), false},
+		{"excluded genre", Restrictions{ExcludedTags: []string{"horror"}}, manga("", []string{"Horror"}, nil), false},
+		{"excluded tag", Restrictions{ExcludedTags: []string{"gore"}}, manga("", nil, []string{"Gore"}), false},
+		{"no excluded tag", Restrictions{ExcludedTags: []string{"horror"}}, manga("", []string{"Comedy"}, nil), true},
+		{"exclusion beats allowance", Restrictions{AllowedTags: []string{"action"}, ExcludedTags: []string{"gore"}}, manga("", []string{"Action"}, []string{"Gore"}), false},
+		{"rating checked before tags", Restrictions{MaxContentRating: "safe", AllowedTags: []string{"action"}}, manga("erotica", []string{"Action"}, nil), false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r, err := tt.restrictions.Normalize()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := r.Allows(tt.manga); got != tt.want {
+				t.Errorf("Allows = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRestrictionsNormalize(t *testing.T) {
+	r, err := Restrictions{
+		AllowedTags:      []string{" Action ", "action", ""},
+		ExcludedTags:     []string{"Gore"},
+		MaxContentRating: " Suggestive ",
+	}.Normalize()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(r.AllowedTags, []string{"action"}) || !slices.Equal(r.ExcludedTags, []string{"gore"}) {
+		t.Errorf("tags = %q, %q", r.AllowedTags, r.ExcludedTags)
+	}
+	if r.MaxContentRating != "suggestive" {
+		t.Errorf("MaxContentRating = %q, want suggestive", r.MaxContentRating)
+	}
+
+	if _, err := (Restrictions{MaxContentRating: "mature"}).Normalize(); !errors.Is(err, ErrInvalidContentRating) {
+		t.Errorf("err = %v, want ErrInvalidContentRating", err)
+	}
+}
+
+func TestRestrictionsHideManga(t *testing.T) {
+	s := newTestService(t, &fakeProvider{})
+	ctx := context.Background()
+
+	visible, err := s.db.InsertManga(ctx, database.InsertMangaParams{Title: "Visible", Slug: "visible", Source: "fake", SourceID: "a", ContentRating: sql.NullString{String: "safe", Valid: true}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	hidden, err := s.db.InsertManga(ctx, database.InsertMangaParams{Title: "Hidden", Slug: "hidden", Source: "fake", SourceID: "b", ContentRating: sql.NullString{String: "erotica", Valid: true}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	restricted := WithRestrictions(ctx, Restrictions{MaxContentRating: "safe"})
+
+	list, err := s.ListManga(restricted)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(list) != 1 || list[0].ID != visible.ID {
+		t.Errorf("ListManga returned %d manga, want only the visible one", len(list))
+	}
+	if _, err := s.GetManga(restricted, hidden.ID); !errors.Is(err, ErrMangaNotFound) {
+		t.Errorf("GetManga: err = %v, want ErrMangaNotFound", err)
+	}
+	if err := s.DeleteManga(restricted, hidden.ID); !errors.Is(err, ErrMangaNotFound) {
+		t.Errorf("DeleteManga: err = %v, want ErrMangaNotFound", err)
+	}
+	if _, err := s.GetManga(ctx, hidden.ID); err != nil {
+		t.Errorf("hidden manga was deleted: %v", err)
+	}
+	if err := s.DeleteManga(restricted, visible.ID); err != nil {
+		t.Errorf("DeleteManga of a visible manga: %v", err)
+	}
+}
@@ -4,69 +4,125 @@ import (
 	"context"
 	"database/sql"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"strings"
 
 	"github.com/rs/zerolog"
 
 	"github.com/mangashelf/mangashelf/internal/database"
+	"github.com/mangashelf/mangashelf/internal/events"
 	"github.com/mangashelf/mangashelf/internal/scraper"
 )
 
 // Service manages the manga library.
 type Service struct {
-	db       *database.Queries
-	scrapers *scraper.Manager
-	log      zerolog.Logger
+	db             *database.Queries
+	scrapers       *scraper.Manager
+	libraryPath    string
+	downloadCovers bool
+	events         *events.Bus
+	log            zerolog.Logger
+}
+
+// Options configures where the library keeps its files.
+type Options struct {
+	// LibraryPath is the root folder holding a folder per manga.
+	LibraryPath string
+	// DownloadCovers stores cover images in the manga folders when manga are added.
+	DownloadCovers bool
+	// Events receives manga added and removed, and new chapter events.
+	Events *events.Bus
 }
 
 // NewService creates a new library service.
-func NewService(db *database.Queries, scrapers *scraper.Manager, log zerolog.Logger) *Service {
+func NewService(db *database.Queries, scrapers *scraper.Manager, opts Options, log zerolog.Logger) *Service {
 	return &Service{
-		db:       db,
-		scrapers: scrapers,
-		log:      log.With().Str("component", "library").Logger(),
+		db:             db,
+		scrapers:       scrapers,
+		libraryPath:    opts.LibraryPath,
+		downloadCovers: opts.DownloadCovers,
+		events:         opts.Events,
+		log:            log.With().Str("component", "library").Logger(),
 	}
 }
 
 // AddMangaRequest contains parameters for adding manga to the library.
+// Either Source and SourceID, or a URL on a supported source, must be set.
 type AddMangaRequest struct {
-	Source   string `json:"source"`
-	SourceID string `json:"sourceId"`
+	Source    string   `json:"source"`
+	SourceID  string   `json:"sourceId"`
+	URL       string   `json:"url,omitempty"`
+	Languages []string `json:"languages,omitempty"`
+}
+
+// UpdateMangaRequest contains per-manga settings to change. Nil fields are left untouched.
+type UpdateMangaRequest struct {
+	// Languages overrides the source's preferred chapter languages, in fallback order.
+	// An empty list restores the source default.
+	Languages *[]string `json:"languages"`
+
+	// PreferredGroups lists scanlation groups, by ID or name, to download from first.
+	PreferredGroups *[]string `json:"preferredGroups"`
+
+	// BlockedGroups lists scanlation groups, by ID or name, never to download from.
+	BlockedGroups *[]string `json:"blockedGroups"`
 }
 
 // AddManga fetches manga from a source and adds it to the library.
 func (s *Service) AddManga(ctx context.Context, req AddMangaRequest) (*database.Manga, error) {
+	if req.Source == "" || req.SourceID == "" {
+		resolved, err := s.scrapers.ResolveURL(ctx, req.URL)
+		if err != nil {
+			return nil, fmt.Errorf("resolve url: %w", err)
+		}
+		req.Source, req.SourceID = resolved.Provider, resolved.MangaID
+	}
+
 	manga, err := s.scrapers.GetManga(ctx, req.Source, req.SourceID)
 	if err != nil {
 		return nil, fmt.Errorf("fetch manga: %w", err)
 	}
 
-	slug := generateSlug(manga.Title)
-
 	genresJSON, _ := json.Marshal(manga.Genres)
 	tagsJSON, _ := json.Marshal(manga.Tags)
 
-	dbManga, err := s.db.InsertManga(ctx, database.InsertMangaParams{
-		Title:       manga.Title,
-		Slug:        slug,
-		Source:      req.Source,
-		SourceID:    req.SourceID,
-		Url:         manga.URL,
-		CoverUrl:    toNullString(manga.CoverURL),
-		Description: toNullString(manga.Description),
-		Status:      toNullString(manga.Status),
-		Author:      toNullString(manga.Author),
-		Artist:      toNullString(manga.Artist),
-		Genres:      toNullString(string(genresJSON)),
-		Tags:        toNullString(string(tagsJSON)),
-	})
-	if err != nil {
-		if strings.Contains(err.Error(), "UNIQUE constraint failed") {
+	params := database.InsertMangaParams{
+		Title:         manga.Title,
+		Source:        req.Source,
+		SourceID:      req.SourceID,
+		Url:           manga.URL,
+		CoverUrl:      toNullString(manga.CoverURL),
+		Description:   toNullString(manga.Description),
+		Status:        toNullString(manga.Status),
+		Author:        toNullString(manga.Author),
+		Artist:        toNullString(manga.Artist),
+		Genres:        toNullString(string(genresJSON)),
+		Tags:          toNullString(string(tagsJSON)),
+		ContentRating: toNullString(manga.ContentRating),
+		Languages:     database.ToNullStringList(normalizeList(req.Languages)),
+	}
+
+	// Titles that slug the same, such as ones differing only in punctuation,
+	// fall through to candidates with a suffix derived from the source ID.
+	var dbManga *database.Manga
+	for _, slug := range slugCandidates(manga.Title, req.Source, req.SourceID) {
+		params.Slug = slug
+		dbManga, err = s.db.InsertManga(ctx, params)
+		if err == nil {
+			break
+		}
+		switch uniqueViolation(err) {
+		case "manga.slug":
+			continue
+		case "manga.source, manga.source_id":
 			return nil, ErrMangaExists
 		}
 		return nil, fmt.Errorf("insert manga: %w", err)
 	}
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrSlugConflict, params.Slug)
+	}
 
 	s.log.Info().
 		Str("title", manga.Title).
@@ -74,52 +130,313 @@ func (s *Service) AddManga(ctx context.Context, req AddMangaRequest) (*database.
 		Int64("id", dbManga.ID).
 		Msg("manga added to library")
 
+	if s.downloadCovers && dbManga.CoverUrl.Valid {
+		if updated, err := s.DownloadCover(ctx, dbManga); err != nil {
+			s.log.Warn().Err(err).Int64("id", dbManga.ID).Msg("failed to download cover")
+		} else {
+			dbManga = updated
+		}
+	}
+
+	s.events.Publish(events.New(events.MangaAdded, dbManga))
 	return dbManga, nil
 }
 
-// GetManga retrieves a manga by ID.
+// GetManga retrieves a manga by ID. Manga hidden by the context's
+// restrictions are reported as not found.
 func (s *Service) GetManga(ctx context.Context, id int64) (*database.Manga, error) {
 	manga, err := s.db.GetManga(ctx, id)
 	if err != nil {
-		if err == sql.ErrNoRows {
+		if errors.Is(err, sql.ErrNoRows) {
 			return nil, ErrMangaNotFound
 		}
 		return nil, fmt.Errorf("get manga: %w", err)
 	}
+	if !restrictionsFrom(ctx).Allows(manga) {
+		return nil, ErrMangaNotFound
+	}
 	return manga, nil
 }
 
-// ListManga returns all manga in the library.
+// ListManga returns the manga in the library allowed by the context's restrictions.
 func (s *Service) ListManga(ctx context.Context) ([]*database.Manga, error) {
 	manga, err := s.db.ListManga(ctx)
 	if err != nil {
 		return nil, fmt.Errorf("list manga: %w", err)
 	}
+
+	restrictions := restrictionsFrom(ctx)
+	visible := manga[:0]
+	for _, m := range manga {
+		if restrictions.Allows(m) {
+			visible = append(visible, m)
+		}
+	}
+	return visible, nil
+}
+
+// UpdateManga changes per-manga settings.
+func (s *Service) UpdateManga(ctx context.Context, id int64, req UpdateMangaRequest) (*database.Manga, error) {
+	manga, err := s.GetManga(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if req.Languages != nil {
+		manga, err = s.db.UpdateMangaLanguages(ctx, database.UpdateMangaLanguagesParams{
+			Languages: database.ToNullStringList(normalizeList(*req.Languages)),
+			ID:        id,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("update languages: %w", err)
+		}
+	}
+
+	if req.PreferredGroups != nil || req.BlockedGroups != nil {
+		preferred, blocked := manga.PreferredGroups, manga.BlockedGroups
+		if req.PreferredGroups != nil {
+			preferred = database.ToNullStringList(normalizeGroupRule(*req.PreferredGroups))
+		}
+		if req.BlockedGroups != nil {
+			blocked = database.ToNullStringList(normalizeGroupRule(*req.BlockedGroups))
+		}
+
+		manga, err = s.db.UpdateMangaGroupRules(ctx, database.UpdateMangaGroupRulesParams{
+			PreferredGroups: preferred,
+			BlockedGroups:   blocked,
+			ID:              id,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("update group rules: %w", err)
+		}
+	}
+
 	return manga, nil
 }
 
-// DeleteManga removes a manga from the library.
+// ListChapters returns the stored chapters of a manga with the user's
+// reading progress.
+func (s *Service) ListChapters(ctx context.Context, userID, mangaID int64) ([]*database.ListChaptersWithProgressRow, error) {
+	if _, err := s.GetManga(ctx, mangaID); err != nil {
+		return nil, err
+	}
+
+	chapters, err := s.db.ListChaptersWithProgress(ctx, database.ListChaptersWithProgressParams{
+		UserID:  userID,
+		MangaID: mangaID,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("list chapters: %w", err)
+	}
+	return chapters, nil
+}
+
+// RefreshMetadata updates the manga's details from its source. A locked
+// cover is kept; otherwise a changed cover is downloaded again.
+func (s *Service) RefreshMetadata(ctx context.Context, id int64) (*database.Manga, error) {
+	manga, err := s.GetManga(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	remote, err := s.scrapers.GetManga(ctx, manga.Source, manga.SourceID)
+	if err != nil {
+		return nil, fmt.Errorf("fetch manga: %w", err)
+	}
+
+	coverURL, coverPath := manga.CoverUrl, manga.CoverPath
+	coverChanged := false
+	if manga.CoverLocked.Int64 == 0 && remote.CoverURL != coverURL.String {
+		coverURL = toNullString(remote.CoverURL)
+		coverChanged = true
+	}
+
+	genresJSON, _ := json.Marshal(remote.Genres)
+	tagsJSON, _ := json.Marshal(remote.Tags)
+
+	updated, err := s.db.UpdateManga(ctx, database.UpdateMangaParams{
+		Title:         remote.Title,
+		CoverUrl:      coverURL,
+		CoverPath:     coverPath,
+		Description:   toNullString(remote.Description),
+		Status:        toNullString(remote.Status),
+		Author:        toNullString(remote.Author),
+		Artist:        toNullString(remote.Artist),
+		Genres:        toNullString(string(genresJSON)),
+		Tags:          toNullString(string(tagsJSON)),
+		ContentRating: toNullString(remote.ContentRating),
+		AnilistID:     manga.AnilistID,
+		ID:            id,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("update manga: %w", err)
+	}
+
+	if coverChanged && s.downloadCovers && coverURL.Valid {
+		if withCover, err := s.DownloadCover(ctx, updated); err != nil {
+			s.log.Warn().Err(err).Int64("id", id).Msg("failed to download cover")
+		} else {
+			updated = withCover
+		}
+	}
+
+	return updated, nil
+}
+
+// SyncChapters fetches the chapter list from the manga's source and stores it.
+// The manga's language override is used when set, otherwise the source default applies.
+func (s *Service) SyncChapters(ctx context.Context, mangaID int64) ([]*database.Chapter, error) {
+	manga, err := s.GetManga(ctx, mangaID)
+	if err != nil {
+		return nil, err
+	}
+
+	languages := database.FromNullStringList(manga.Languages)
+	chapters, err := s.scrapers.GetChaptersInLanguages(ctx, manga.Source, manga.SourceID, languages)
+	if err != nil {
+		return nil, fmt.Errorf("fetch chapters: %w", err)
+	}
+
+	existing, err := s.db.ListChaptersByManga(ctx, mangaID)
+	if err != nil {
+		return nil, fmt.Errorf("list chapters: %w", err)
+	}
+	known := make(map[string]bool, len(existing))
+	for _, ch := range existing {
+		known[ch.SourceID] = true
+	}
+
+	var added []*database.Chapter
+	for _, ch := range chapters {
+		var publishedAt string
+		if !ch.PublishedAt.IsZero() {
+			publishedAt = ch.PublishedAt.UTC().Format(database.TimeFormat)
+		}
+		var unavailable int64
+		if ch.Unavailable {
+			unavailable = 1
+		}
+
+		stored, err := s.db.InsertChapter(ctx, database.InsertChapterParams{
+			MangaID:          mangaID,
+			Title:            ch.Title,
+			Number:           ch.Number,
+			Volume:           toNullString(ch.Volume),
+			Language:         toNullString(ch.Language),
+			ScanlationGroups: toNullGroups(ch.Groups),
+			SourceID:         ch.ID,
+			Url:              ch.URL,
+			Unavailable:      sql.NullInt64{Int64: unavailable, Valid: true},
+			PublishedAt:      toNullString(publishedAt),
+		})
+		if err != nil {
+			return nil, fmt.Errorf("insert chapter: %w", err)
+		}
+		if !known[ch.ID] {
+			added = append(added, stored)
+		}
+	}
+
+	s.log.Info().
+		Int64("id", mangaID).
+		Strs("languages", languages).
+		Int("chapters", len(chapters)).
+		Int("new", len(added)).
+		Msg("chapters synced")
+
+	// The first sync of a manga finds its whole back catalogue, which is
+	// not news.
+	if len(existing) > 0 && len(added) > 0 {
+		s.events.Publish(events.New(events.NewChapters, manga, added...))
+	}
+
+	stored, err := s.db.ListChaptersByManga(ctx, mangaID)
+	if err != nil {
+		return nil, fmt.Errorf("list chapters: %w", err)
+	}
+	return stored, nil
+}
+
+// QueueDownloads enqueues one release per chapter, picked by the manga's
+// preferred and blocked scanlation group rules. Chapters that already have a
+// queued or downloaded release are skipped.
+func (s *Service) QueueDownloads(ctx context.Context, mangaID int64) ([]*database.DownloadQueue, error) {
+	manga, err := s.GetManga(ctx, mangaID)
+	if err != nil {
+		return nil, err
+	}
+
+	chapters, err := s.db.ListChaptersByManga(ctx, mangaID)
+	if err != nil {
+		return nil, fmt.Errorf("list chapters: %w", err)
+	}
+
+	queued := []*database.DownloadQueue{}
+	for _, ch := range groupRulesFor(manga).selectReleases(chapters) {
+		if isActive(ch) {
+			continue
+		}
+
+		entry, err := s.db.EnqueueDownload(ctx, database.EnqueueDownloadParams{
+			ChapterID: ch.ID,
+			Priority:  sql.NullInt64{Int64: 0, Valid: true},
+		})
+		if err != nil {
+			return nil, fmt.Errorf("enqueue chapter %d: %w", ch.ID, err)
+		}
+
+		if err := s.db.SetChapterStatus(ctx, database.SetChapterStatusParams{
+			Status: toNullString("queued"),
+			ID:     ch.ID,
+		}); err != nil {
+			return nil, fmt.Errorf("mark chapter %d queued: %w", ch.ID, err)
+		}
+
+		queued = append(queued, entry)
+	}
+
+	s.log.Info().
+		Int64("id", mangaID).
+		Int("queued", len(queued)).
+		Msg("chapters queued for download")
+
+	return queued, nil
+}
+
+// DeleteManga removes a manga from the library. Manga hidden by the
+// context's restrictions are reported as not found.
 func (s *Service) DeleteManga(ctx context.Context, id int64) error {
-	err := s.db.DeleteManga(ctx, id)
+	manga, err := s.GetManga(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	deleted, err := s.db.DeleteManga(ctx, id)
 	if err != nil {
 		return fmt.Errorf("delete manga: %w", err)
 	}
+	if deleted == 0 {
+		return ErrMangaNotFound
+	}
 	s.log.Info().Int64("id", id).Msg("manga deleted from library")
+	s.events.Publish(events.New(events.MangaRemoved, manga))
 	return nil
 }
 
-// generateSlug creates a URL-friendly slug from a title.
-func generateSlug(title string) string {
-	slug := strings.ToLower(title)
-	slug = strings.ReplaceAll(slug, " ", "-")
-
-	var result strings.Builder
-	for _, r := range slug {
-		if (r >= 'a' && r <= 'z') || (r >= '0' && r <= '9') || r == '-' {
-			result.WriteRune(r)
+// normalizeList trims, lowercases and de-duplicates values such as language
+// codes or tags, keeping their order and dropping empty ones.
+func normalizeList(values []string) []string {
+	seen := make(map[string]bool, len(values))
+	result := make([]string, 0, len(values))
+	for _, value := range values {
+		value = strings.ToLower(strings.TrimSpace(value))
+		if value == "" || seen[value] {
+			continue
 		}
+		seen[value] = true
+		result = append(result, value)
 	}
-	return result.String()
+	return result
 }
 
 // toNullString converts a string to sql.NullString.
@@ -0,0 +1,86 @@
+package library
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/mangashelf/mangashelf/internal/database"
+)
+
+// ShelfEntry is a manga with a user's reading state of its downloaded chapters.
+type ShelfEntry struct {
+	*database.Manga
+	// Downloaded reports whether any chapter has been downloaded.
+	Downloaded bool
+	// ChapterCount is the number of downloaded chapters.
+	ChapterCount int64
+	// UnreadCount is the number of downloaded chapters the user has not read.
+	UnreadCount int64
+	// InProgressCount is the number of unread chapters the user has started.
+	InProgressCount int64
+	// LastDownloadedAt is when the newest chapter was downloaded, in SQLite's
+	// datetime format, or empty when nothing was downloaded.
+	LastDownloadedAt string
+}
+
+// ListShelf returns the manga visible to the context's restrictions with a
+// user's unread chapter counts, ordered by title.
+func (s *Service) ListShelf(ctx context.Context, userID int64) ([]*ShelfEntry, error) {
+	manga, err := s.ListManga(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	stats, err := s.db.ListMangaReadingStats(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("list reading stats: %w", err)
+	}
+	byManga := make(map[int64]*database.ListMangaReadingStatsRow, len(stats))
+	for _, st := range stats {
+		byManga[st.MangaID] = st
+	}
+
+	entries := make([]*ShelfEntry, 0, len(manga))
+	for _, m := range manga {
+		entries = append(entries, newShelfEntry(m, byManga[m.ID]))
+	}
+	return entries, nil
+}
+
+// GetShelfEntry returns a manga with a user's reading state. Manga hidden by
+// the context's restrictions are reported as not found.
+func (s *Service) GetShelfEntry(ctx context.Context, userID, mangaID int64) (*ShelfEntry, error) {
+	manga, err := s.GetManga(ctx, mangaID)
+	if err != nil {
+		return nil, err
+	}
+
+	stats, err := s.db.ListMangaReadingStats(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("list reading stats: %w", err)
+	}
+	for _, st := range stats {
+		if st.MangaID == mangaID {
+			return newShelfEntry(manga, st), nil
+		}
+	}
+	return newShelfEntry(manga, nil), nil
+}
+
+func newShelfEntry(m *database.Manga, st *database.ListMangaReadingStatsRow) *ShelfEntry {
+	entry := &ShelfEntry{Manga: m}
+	if st != nil {
+		entry.Downloaded = true
+		entry.ChapterCount = st.ChapterCount
+		entry.UnreadCount = int64(st.UnreadCount.Float64)
+		entry.InProgressCount = int64(st.InProgressCount.Float64)
+		entry.LastDownloadedAt = st.LastDownloadedAt.String
+	}
+	return entry
+}
+
+// StringList decodes a list column of a stored manga, such as Genres or Tags.
+func StringList(ns sql.NullString) []string {
+	return database.FromNullStringList(ns)
+}
@@ -0,0 +1,105 @@
+package library
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+const (
+	// maxSlugLength bounds slugs, in runes, before any suffix is added.
+	maxSlugLength = 80
+
+	// slugAttempts is the number of numbered candidates tried after the
+	// source-derived one.
+	slugAttempts = 8
+)
+
+// slugReplacements transliterates letters that do not decompose into an
+// ASCII base letter plus combining marks.
+var slugReplacements = map[rune]string{
+	'ß': "ss", 'æ': "ae", 'œ': "oe", 'ø': "o", 'đ': "d", 'ð': "d",
+	'ł': "l", 'þ': "th", 'ı': "i", 'ħ': "h", '&': "and",
+}
+
+// generateSlug creates a URL-friendly slug from a title. Accents are
+// stripped from Latin letters, letters and digits of other scripts are kept,
+// and everything else collapses into single dashes.
+func generateSlug(title string) string {
+	var b strings.Builder
+	dash := false
+	var last rune
+
+	for _, r := range norm.NFKD.String(strings.ToLower(title)) {
+		if unicode.Is(unicode.Mn, r) {
+			// Accents on Latin letters are dropped; marks in other scripts,
+			// such as Japanese dakuten, are part of the letter.
+			if last > unicode.MaxASCII && !dash {
+				b.WriteRune(r)
+			}
+			continue
+		}
+		last = r
+		if repl, ok := slugReplacements[r]; ok {
+			if dash && b.Len() > 0 {
+				b.WriteByte('-')
+			}
+			dash = false
+			b.WriteString(repl)
+			continue
+		}
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			if dash && b.Len() > 0 {
+				b.WriteByte('-')
+			}
+			dash = false
+			b.WriteRune(unicode.ToLower(r))
+			continue
+		}
+		dash = true
+	}
+
+	slug := []rune(b.String())
+	if len(slug) > maxSlugLength {
+		slug = []rune(strings.TrimRight(string(slug[:maxSlugLength]), "-"))
+	}
+	if len(slug) == 0 {
+		return "manga"
+	}
+	return norm.NFC.String(string(slug))
+}
+
+// slugCandidates lists the slugs tried for a new manga, in order: the title
+// slug, the slug with a suffix derived from the source and source ID, then
+// that with a counter. The same manga always gets the same candidates.
+func slugCandidates(title, source, sourceID string) []string {
+	base := generateSlug(title)
+
+	sum := sha1.Sum([]byte(source + ":" + sourceID))
+	suffixed := base + "-" + hex.EncodeToString(sum[:3])
+
+	candidates := []string{base, suffixed}
+	for i := 2; i < 2+slugAttempts; i++ {
+		candidates = append(candidates, suffixed+"-"+strconv.Itoa(i))
+	}
+	return candidates
+}
+
+// uniqueViolation returns the columns named by a SQLite UNIQUE constraint
+// error, such as "manga.slug", or "" for other errors.
+func uniqueViolation(err error) string {
+	const prefix = "UNIQUE constraint failed: "
+	if err == nil {
+		return ""
+	}
+	msg := err.Error()
+	i := strings.Index(msg, prefix)
+	if i < 0 {
+		return ""
+	}
+	return msg[i+len(prefix):]
+}
@@ -0,0 +1,182 @@
+package library
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"strings"
+	"testing"
+	"unicode/utf8"
+
+	"github.com/rs/zerolog"
+
+	"github.com/mangashelf/mangashelf/internal/database"
+	"github.com/mangashelf/mangashelf/internal/scraper"
+)
+
+func TestGenerateSlug(t *testing.T) {
+	tests := []struct {
+		title string
+		want  string
+	}{
+		{"One Piece", "one-piece"},
+		{"  Spaced   Out  ", "spaced-out"},
+		{"Pokémon: Adventures!", "pokemon-adventures"},
+		{"Déjà-vu 2", "deja-vu-2"},
+		{"Straße & Œuvre", "strasse-and-oeuvre"},
+		{"Ａｂｃ １２３", "abc-123"},
+		{"進撃の巨人", "進撃の巨人"},
+		{"ワンパンマン", "ワンパンマン"},
+		{"ガ", "ガ"},
+		{"나 혼자만 레벨업", "나-혼자만-레벨업"},
+		{"Re:Zero", "re-zero"},
+		{"Re-Zero", "re-zero"},
+		{"!!!", "manga"},
+		{"", "manga"},
+	}
+	for _, tt := range tests {
+		if got := generateSlug(tt.title); got != tt.want {
+			t.Errorf("generateSlug(%q) = %q, want %q", tt.title, got, tt.want)
+		}
+	}
+}
+
+func TestGenerateSlugTruncates(t *testing.T) {
+	got := generateSlug(strings.Repeat("ab ", 40))
+	if n := utf8.RuneCountInString(got); n > maxSlugLength {
+		t.Errorf("slug has %d runes, want at most %d", n, maxSlugLength)
+	}
+	if strings.HasSuffix(got, "-") {
+		t.Errorf("slug %q ends with a dash", got)
+	}
+}
+
+func TestSlugCandidates(t *testing.T) {
+	got := slugCandidates("Re:Zero", "mangadex", "abc")
+	if len(got) != 2+slugAttempts {
+		t.Fatalf("got %d candidates, want %d", len(got), 2+slugAttempts)
+	}
+	if got[0] != "re-zero" {
+		t.Errorf("first candidate = %q, want the title slug", got[0])
+	}
+	if !strings.HasPrefix(got[1], "re-zero-") || got[2] != got[1]+"-2" {
+		t.Errorf("suffixed candidates = %q, %q", got[1], got[2])
+	}
+
+	if again := slugCandidates("Re:Zero", "mangadex", "abc"); again[1] != got[1] {
+		t.Errorf("suffix is not deterministic: %q then %q", got[1], again[1])
+	}
+	if other := slugCandidates("Re:Zero", "mangadex", "xyz"); other[1] == got[1] {
+		t.Errorf("different source IDs share the suffix %q", got[1])
+	}
+}
+
+func TestAddMangaSlugConflicts(t *testing.T) {
+	titles := map[string]string{
+		"a": "Re:Zero",
+		"b": "Re-Zero",
+		"c": "Re Zero",
+	}
+	s := newTestService(t, &fakeProvider{titles: titles})
+	ctx := context.Background()
+
+	first, err := s.AddManga(ctx, AddMangaRequest{Source: "fake", SourceID: "a"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if first.Slug != "re-zero" {
+		t.Errorf("first slug = %q, want re-zero", first.Slug)
+	}
+
+	// A different manga whose title slugs the same gets the suffixed slug.
+	second, err := s.AddManga(ctx, AddMangaRequest{Source: "fake", SourceID: "b"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := slugCandidates("Re-Zero", "fake", "b")[1]; second.Slug != want {
+		t.Errorf("second slug = %q, want %q", second.Slug, want)
+	}
+
+	// The same manga again is a duplicate, not a slug conflict.
+	if _, err := s.AddManga(ctx, AddMangaRequest{Source: "fake", SourceID: "a"}); !errors.Is(err, ErrMangaExists) {
+		t.Errorf("adding the same manga again: err = %v, want ErrMangaExists", err)
+	}
+
+	third, err := s.AddManga(ctx, AddMangaRequest{Source: "fake", SourceID: "c"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if third.Slug == first.Slug || third.Slug == second.Slug {
+		t.Errorf("third slug %q collides", third.Slug)
+	}
+}
+
+func TestAddMangaSlugCandidatesExhausted(t *testing.T) {
+	s := newTestService(t, &fakeProvider{titles: map[string]string{"a": "Taken", "b": "Taken"}})
+	ctx := context.Background()
+
+	// Occupy every candidate of manga b with other manga.
+	for i, slug := range slugCandidates("Taken", "fake", "b") {
+		_, err := s.db.InsertManga(ctx, database.InsertMangaParams{
+			Title:    "Taken",
+			Slug:     slug,
+			Source:   "other",
+			SourceID: string(rune('a' + i)),
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if _, err := s.AddManga(ctx, AddMangaRequest{Source: "fake", SourceID: "b"}); !errors.Is(err, ErrSlugConflict) {
+		t.Errorf("err = %v, want ErrSlugConflict", err)
+	}
+}
+
+// newTestService returns a library service backed by a fresh database, with
+// the provider registered.
+func newTestService(t *testing.T, provider scraper.Provider) *Service {
+	t.Helper()
+
+	db, err := database.Open(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+	if err := database.Migrate(db); err != nil {
+		t.Fatal(err)
+	}
+
+	scrapers := scraper.NewManager(zerolog.Nop())
+	scrapers.Register(provider)
+	return NewService(database.New(db), scrapers, Options{LibraryPath: t.TempDir()}, zerolog.Nop())
+}
+
+// fakeProvider serves manga titles by ID.
+type fakeProvider struct {
+	titles map[string]string
+}
+
+func (p *fakeProvider) Info() scraper.ProviderInfo {
+	return scraper.ProviderInfo{ID: "fake", Name: "Fake"}
+}
+
+func (p *fakeProvider) Search(context.Context, string) ([]scraper.MangaResult, error) {
+	return nil, nil
+}
+
+func (p *fakeProvider) GetManga(_ context.Context, id string) (*scraper.Manga, error) {
+	title, ok := p.titles[id]
+	if !ok {
+		return nil, scraper.ErrMangaNotFound
+	}
+	return &scraper.Manga{ID: id, Title: title, URL: "https://example.com/" + id}, nil
+}
+
+func (p *fakeProvider) GetChapters(context.Context, string) ([]scraper.Chapter, error) {
+	return nil, nil
+}
+
+func (p *fakeProvider) GetPages(context.Context, string) ([]scraper.Page, error) {
+	return nil, nil
+}
@@ -0,0 +1,93 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os/exec"
+	"strings"
+)
+
+// Apprise notification types, which set the icon and colour services show.
+const (
+	typeInfo    = "info"
+	typeSuccess = "success"
+	typeFailure = "failure"
+)
+
+// message is a rendered notification.
+type message struct {
+	Title string
+	Body  string
+	Type  string
+}
+
+// sender delivers a message to one Apprise URL.
+type sender interface {
+	send(ctx context.Context, url string, m message) error
+}
+
+// apiSender posts notifications to an Apprise API server's stateless
+// notify endpoint.
+type apiSender struct {
+	endpoint string
+	client   *http.Client
+}
+
+func (s apiSender) send(ctx context.Context, url string, m message) error {
+	payload, _ := json.Marshal(map[string]string{
+		"urls":   url,
+		"title":  m.Title,
+		"body":   m.Body,
+		"type":   m.Type,
+		"format": "text",
+	})
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.endpoint, bytes.NewReader(payload))
+	if err != nil {
+		return fmt.Errorf("create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("execute request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("unexpected status: %d: %s", resp.StatusCode, strings.TrimSpace(string(body)))
+	}
+	return nil
+}
+
+// cliSender runs the apprise command line tool.
+type cliSender struct {
+	command string
+}
+
+func (s cliSender) send(ctx context.Context, url string, m message) error {
+	cmd := exec.CommandContext(ctx, s.command, "--title", m.Title, "--body", m.Body, "--notification-type", m.Type, "--", url)
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("run %s: %w: %s", s.command, err, strings.TrimSpace(string(out)))
+	}
+	return nil
+}
+
+// newSender returns the API sender when an Apprise API server is set, and
+// the command line sender otherwise.
+func newSender(api, command string) sender {
+	if api == "" {
+		return cliSender{command: command}
+	}
+	return apiSender{
+		endpoint: strings.TrimRight(api, "/") + "/notify/",
+		client:   &http.Client{},
+	}
+}
@@ -0,0 +1,262 @@
+package notify
+
+import (
+	"cmp"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/mangashelf/mangashelf/internal/database"
+	"github.com/mangashelf/mangashelf/internal/events"
+)
+
+// DigestEvent names the digest notification in templates and tests. It is
+// not published on the event bus.
+const DigestEvent events.Type = "digest"
+
+// Digest is what the digest template is rendered with: the new chapters and
+// failed downloads held back since the last digest, grouped by manga.
+type Digest struct {
+	// Since is when the oldest event in the digest happened.
+	Since  time.Time
+	Series []Series
+}
+
+// Series is one manga's part of a digest.
+type Series struct {
+	Manga    events.Manga
+	Chapters []events.Chapter
+	// Failed are the chapters whose downloads failed, with why in Errors.
+	Failed []events.Chapter
+	Errors []string
+}
+
+// Count returns the number of new chapters.
+func (d Digest) Count() int {
+	n := 0
+	for _, s := range d.Series {
+		n += len(s.Chapters)
+	}
+	return n
+}
+
+// Failures returns the number of failed downloads.
+func (d Digest) Failures() int {
+	n := 0
+	for _, s := range d.Series {
+		n += len(s.Failed)
+	}
+	return n
+}
+
+// hold stores an event for the next digest.
+func (s *Service) hold(e events.Event) {
+	payload, err := json.Marshal(e)
+	if err != nil {
+		s.log.Error().Err(err).Str("event", string(e.Type)).Msg("failed to encode digest event")
+		return
+	}
+	if err := s.db.InsertDigestEvent(context.Background(), database.InsertDigestEventParams{
+		MangaID: e.Manga.ID,
+		Event:   string(e.Type),
+		Payload: string(payload),
+	}); err != nil {
+		s.log.Error().Err(err).Str("event", string(e.Type)).Int64("manga", e.Manga.ID).Msg("failed to hold event for digest")
+	}
+}
+
+// Run sends a digest on the configured schedule until ctx is cancelled. It
+// returns at once when digests are off.
+func (s *Service) Run(ctx context.Context) {
+	if s.schedule == nil {
+		return
+	}
+
+	for {
+		timer := time.NewTimer(time.Until(s.schedule.Next(time.Now())))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+
+		if err := s.SendDigest(ctx); err != nil {
+			s.log.Error().Err(err).Msg("failed to send digest")
+		}
+	}
+}
+
+// SendDigest sends the held events as one notification to every URL. Each
+// URL gets the events it has not been sent yet, so a URL that fails gets
+// them with the next digest while the others are not sent them twice. Events
+// are cleared once every URL has them. Nothing is sent when no events are
+// held.
+func (s *Service) SendDigest(ctx context.Context) error {
+	if len(s.urls) == 0 {
+		return ErrNotConfigured
+	}
+
+	rows, err := s.db.ListDigestEvents(ctx)
+	if err != nil {
+		return fmt.Errorf("list digest events: %w", err)
+	}
+	if len(rows) == 0 {
+		return nil
+	}
+
+	var held []heldEvent
+	for _, row := range rows {
+		var e events.Event
+		if err := json.Unmarshal([]byte(row.Payload), &e); err != nil {
+			s.log.Warn().Err(err).Int64("id", row.ID).Msg("dropping unreadable digest event")
+			if err := s.db.DeleteDigestEvent(ctx, row.ID); err != nil {
+				return fmt.Errorf("clear digest event: %w", err)
+			}
+			continue
+		}
+		held = append(held, heldEvent{id: row.ID, event: e, sentTo: database.FromNullStringList(row.SentTo)})
+	}
+
+	var errs []error
+	for _, url := range s.urls {
+		target := digestTarget(url)
+		var pending []*heldEvent
+		for i := range held {
+			if !slices.Contains(held[i].sentTo, target) {
+				pending = append(pending, &held[i])
+			}
+		}
+		if len(pending) == 0 {
+			continue
+		}
+
+		digest := buildDigest(pending)
+		title, body, err := s.templates[DigestEvent].render(digest)
+		if err != nil {
+			return fmt.Errorf("%w: %w", ErrSendFailed, err)
+		}
+		if err := s.sendTo(ctx, url, message{Title: title, Body: body, Type: typeInfo}); err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		s.log.Info().Int("series", len(digest.Series)).Int("chapters", digest.Count()).Int("failures", digest.Failures()).Msg("digest sent")
+
+		for _, h := range pending {
+			h.sentTo = append(h.sentTo, target)
+			if err := s.db.SetDigestEventSentTo(ctx, database.SetDigestEventSentToParams{
+				SentTo: database.ToNullStringList(h.sentTo),
+				ID:     h.id,
+			}); err != nil {
+				return fmt.Errorf("record digest delivery: %w", err)
+			}
+		}
+	}
+
+	for _, h := range held {
+		if !s.sentToAll(h) {
+			continue
+		}
+		if err := s.db.DeleteDigestEvent(ctx, h.id); err != nil {
+			return fmt.Errorf("clear digest event: %w", err)
+		}
+	}
+
+	if len(errs) > 0 {
+		return fmt.Errorf("%w: %w", ErrSendFailed, errors.Join(errs...))
+	}
+	return nil
+}
+
+// heldEvent is an event held for the digest and the URLs it was sent to.
+type heldEvent struct {
+	id     int64
+	event  events.Event
+	sentTo []string
+}
+
+// sentToAll reports whether a held event was sent to every configured URL.
+func (s *Service) sentToAll(h heldEvent) bool {
+	for _, url := range s.urls {
+		if !slices.Contains(h.sentTo, digestTarget(url)) {
+			return false
+		}
+	}
+	return true
+}
+
+// digestTarget identifies a URL in sent_to. Apprise URLs carry tokens, so
+// only their hash is stored.
+func digestTarget(url string) string {
+	sum := sha256.Sum256([]byte(url))
+	return hex.EncodeToString(sum[:])
+}
+
+// buildDigest groups held events by manga, sorted by title.
+func buildDigest(held []*heldEvent) Digest {
+	digest := Digest{}
+	series := make(map[int64]*Series)
+	for _, h := range held {
+		e := h.event
+		if digest.Since.IsZero() || e.Time.Before(digest.Since) {
+			digest.Since = e.Time
+		}
+
+		entry, ok := series[e.Manga.ID]
+		if !ok {
+			entry = &Series{}
+			series[e.Manga.ID] = entry
+		}
+		// Keep the latest details, such as a renamed title.
+		entry.Manga = e.Manga
+		switch e.Type {
+		case events.NewChapters:
+			entry.Chapters = append(entry.Chapters, e.Chapters...)
+		case events.DownloadFailed:
+			for _, ch := range e.Chapters {
+				entry.Failed = append(entry.Failed, ch)
+				entry.Errors = append(entry.Errors, e.Error)
+			}
+		}
+	}
+	for _, entry := range series {
+		digest.Series = append(digest.Series, *entry)
+	}
+	slices.SortFunc(digest.Series, func(a, b Series) int {
+		return cmp.Compare(strings.ToLower(a.Manga.Title), strings.ToLower(b.Manga.Title))
+	})
+	return digest
+}
+
+// sendDigest renders a digest and delivers it to every URL.
+func (s *Service) sendDigest(ctx context.Context, digest Digest) error {
+	title, body, err := s.templates[DigestEvent].render(digest)
+	if err != nil {
+		return fmt.Errorf("%w: %w", ErrSendFailed, err)
+	}
+	return s.deliver(ctx, message{Title: title, Body: body, Type: typeInfo})
+}
+
+// sampleDigest is sent by Test for the digest.
+func sampleDigest() Digest {
+	return Digest{
+		Since: time.Now().Add(-24 * time.Hour),
+		Series: []Series{
+			{
+				Manga:    events.Manga{Title: "MangaShelf Test", Slug: "mangashelf-test"},
+				Chapters: []events.Chapter{{Number: 1, Title: "Sample Chapter"}, {Number: 2, Title: "Another Sample Chapter"}},
+			},
+			{
+				Manga:  events.Manga{Title: "Another Test", Slug: "another-test"},
+				Failed: []events.Chapter{{Number: 5, Title: "Failed Sample Chapter"}},
+				Errors: []string{"sample error"},
+			},
+		},
+	}
+}
@@ -0,0 +1,233 @@
+// Package notify sends notifications about new chapters and download
+// outcomes through Apprise, to any of the services it supports.
+package notify
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/robfig/cron/v3"
+	"github.com/rs/zerolog"
+
+	"github.com/mangashelf/mangashelf/internal/config"
+	"github.com/mangashelf/mangashelf/internal/database"
+	"github.com/mangashelf/mangashelf/internal/events"
+)
+
+// sendTimeout bounds the delivery of one notification to one URL.
+const sendTimeout = 30 * time.Second
+
+var (
+	// ErrNotConfigured is returned when no Apprise URLs are configured.
+	ErrNotConfigured = errors.New("notifications not configured")
+
+	// ErrInvalidEvent is returned for an event type that is not notified.
+	ErrInvalidEvent = errors.New("event type has no notification")
+
+	// ErrSendFailed is returned when a notification could not be sent.
+	ErrSendFailed = errors.New("notification failed")
+)
+
+// batchKey groups events into one notification.
+type batchKey struct {
+	event   events.Type
+	mangaID int64
+}
+
+// batch collects events until its timer sends it.
+type batch struct {
+	summary Summary
+	timer   *time.Timer
+}
+
+// Service turns events into notifications.
+type Service struct {
+	db        *database.Queries
+	urls      []string
+	enabled   map[events.Type]bool
+	window    time.Duration
+	templates map[events.Type]messageTemplate
+	sender    sender
+	log       zerolog.Logger
+	// schedule is when digests are sent; nil when digests are off.
+	schedule cron.Schedule
+
+	mu      sync.Mutex
+	pending map[batchKey]*batch
+}
+
+// NewService creates a notification service. It fails when a configured
+// template or the digest schedule does not parse. Call Run to send digests.
+func NewService(db *database.Queries, cfg config.NotificationConfig, log zerolog.Logger) (*Service, error) {
+	templates, err := parseTemplates(cfg.Templates)
+	if err != nil {
+		return nil, fmt.Errorf("parse templates: %w", err)
+	}
+
+	var schedule cron.Schedule
+	if cfg.Digest.Enabled {
+		schedule, err = cron.ParseStandard(cfg.Digest.Schedule)
+		if err != nil {
+			return nil, fmt.Errorf("parse digest schedule: %w", err)
+		}
+	}
+
+	return &Service{
+		db:   db,
+		urls: cfg.Apprise.URLs,
+		enabled: map[events.Type]bool{
+			events.NewChapters:       cfg.Events.NewChapters,
+			events.DownloadCompleted: cfg.Events.DownloadComplete,
+			events.DownloadFailed:    cfg.Events.DownloadFailed,
+		},
+		window:    cfg.BatchWindow,
+		templates: templates,
+		sender:    newSender(cfg.Apprise.API, cfg.Apprise.Command),
+		log:       log.With().Str("component", "notify").Logger(),
+		schedule:  schedule,
+		pending:   make(map[batchKey]*batch),
+	}, nil
+}
+
+// Handle collects an event into its manga's batch for the event type. The
+// batch is sent once the batch window has passed since its first event.
+// With digests on, new chapters and failed downloads are held for the next
+// digest instead. It is meant to be subscribed to the event bus.
+func (s *Service) Handle(e events.Event) {
+	if !s.enabled[e.Type] || len(s.urls) == 0 {
+		return
+	}
+	if s.schedule != nil && e.Type != events.DownloadCompleted {
+		s.hold(e)
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	key := batchKey{event: e.Type, mangaID: e.Manga.ID}
+	b, ok := s.pending[key]
+	if !ok {
+		b = &batch{summary: Summary{Event: e.Type, Manga: e.Manga}}
+		b.timer = time.AfterFunc(s.window, func() { s.release(key) })
+		s.pending[key] = b
+	}
+	// Keep the latest details, such as a renamed title.
+	b.summary.Manga = e.Manga
+	for _, ch := range e.Chapters {
+		b.summary.Chapters = append(b.summary.Chapters, ch)
+		if e.Type == events.DownloadFailed {
+			b.summary.Errors = append(b.summary.Errors, e.Error)
+		}
+	}
+}
+
+// Flush sends every pending batch at once, such as on shutdown.
+func (s *Service) Flush(ctx context.Context) {
+	s.mu.Lock()
+	batches := make([]*batch, 0, len(s.pending))
+	for key, b := range s.pending {
+		if b.timer.Stop() {
+			batches = append(batches, b)
+		}
+		delete(s.pending, key)
+	}
+	s.mu.Unlock()
+
+	for _, b := range batches {
+		s.notify(ctx, b.summary)
+	}
+}
+
+// Test sends a sample notification for an event type, or a sample digest for
+// DigestEvent, to every URL whether or not notifications are enabled.
+func (s *Service) Test(ctx context.Context, t events.Type) error {
+	if len(s.urls) == 0 {
+		return ErrNotConfigured
+	}
+	if _, ok := s.templates[t]; !ok {
+		return fmt.Errorf("%w: %s", ErrInvalidEvent, t)
+	}
+	if t == DigestEvent {
+		return s.sendDigest(ctx, sampleDigest())
+	}
+
+	summary := Summary{
+		Event: t,
+		Manga: events.Manga{Title: "MangaShelf Test", Slug: "mangashelf-test"},
+		Chapters: []events.Chapter{
+			{Number: 1, Title: "Sample Chapter"},
+			{Number: 2, Title: "Another Sample Chapter"},
+		},
+	}
+	if t == events.DownloadFailed {
+		summary.Errors = []string{"sample error", "another sample error"}
+	}
+	return s.send(ctx, summary)
+}
+
+// release sends a batch whose window has passed.
+func (s *Service) release(key batchKey) {
+	s.mu.Lock()
+	b, ok := s.pending[key]
+	delete(s.pending, key)
+	s.mu.Unlock()
+
+	if ok {
+		s.notify(context.Background(), b.summary)
+	}
+}
+
+// notify sends a summary, logging failures.
+func (s *Service) notify(ctx context.Context, summary Summary) {
+	if err := s.send(ctx, summary); err != nil {
+		s.log.Error().Err(err).Str("event", string(summary.Event)).Int64("manga", summary.Manga.ID).Msg("failed to send notification")
+		return
+	}
+	s.log.Debug().Str("event", string(summary.Event)).Int64("manga", summary.Manga.ID).Int("chapters", summary.Count()).Msg("notification sent")
+}
+
+// send renders a summary and delivers it to every URL.
+func (s *Service) send(ctx context.Context, summary Summary) error {
+	title, body, err := s.templates[summary.Event].render(summary)
+	if err != nil {
+		return fmt.Errorf("%w: %w", ErrSendFailed, err)
+	}
+	return s.deliver(ctx, message{Title: title, Body: body, Type: messageType(summary.Event)})
+}
+
+// deliver sends a message to every URL, trying all of them even when one
+// fails.
+func (s *Service) deliver(ctx context.Context, m message) error {
+	var errs []error
+	for _, url := range s.urls {
+		if err := s.sendTo(ctx, url, m); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if len(errs) > 0 {
+		return fmt.Errorf("%w: %w", ErrSendFailed, errors.Join(errs...))
+	}
+	return nil
+}
+
+// sendTo delivers a message to one URL within sendTimeout.
+func (s *Service) sendTo(ctx context.Context, url string, m message) error {
+	ctx, cancel := context.WithTimeout(ctx, sendTimeout)
+	defer cancel()
+	return s.sender.send(ctx, url, m)
+}
+
+// messageType returns the Apprise notification type for an event.
+func messageType(t events.Type) string {
+	switch t {
+	case events.DownloadCompleted:
+		return typeSuccess
+	case events.DownloadFailed:
+		return typeFailure
+	}
+	return typeInfo
+}
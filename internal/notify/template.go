@@ -0,0 +1,124 @@
+package notify
+
+import (
+	"bytes"
+	"cmp"
+	"fmt"
+	"strconv"
+	"strings"
+	"text/template"
+
+	"github.com/mangashelf/mangashelf/internal/config"
+	"github.com/mangashelf/mangashelf/internal/events"
+)
+
+// Summary is what notification templates are rendered with: one or more
+// events of the same type for one manga.
+type Summary struct {
+	Event events.Type
+	Manga events.Manga
+	// Chapters are the new, downloaded or failed chapters, in the order the
+	// events arrived.
+	Chapters []events.Chapter
+	// Errors holds why each chapter's download failed, in the order of
+	// Chapters.
+	Errors []string
+}
+
+// Count returns the number of chapters.
+func (s Summary) Count() int {
+	return len(s.Chapters)
+}
+
+// defaultTemplates are used for the fields left empty in the configuration.
+var defaultTemplates = map[events.Type]config.MessageTemplate{
+	events.NewChapters: {
+		Title: `{{.Manga.Title}}: {{.Count}} new {{plural .Count "chapter" "chapters"}}`,
+		Body:  `{{plural .Count "Chapter" "Chapters"}} {{numbers .Chapters}} {{plural .Count "is" "are"}} out.`,
+	},
+	events.DownloadCompleted: {
+		Title: `{{.Manga.Title}}: {{.Count}} {{plural .Count "chapter" "chapters"}} downloaded`,
+		Body:  `Downloaded {{plural .Count "chapter" "chapters"}} {{numbers .Chapters}}.`,
+	},
+	events.DownloadFailed: {
+		Title: `{{.Manga.Title}}: {{.Count}} {{plural .Count "download" "downloads"}} failed`,
+		Body:  `{{range $i, $c := .Chapters}}{{if $i}}{{"\n"}}{{end}}Chapter {{number $c}}: {{index $.Errors $i}}{{end}}`,
+	},
+	DigestEvent: {
+		Title: `MangaShelf digest: {{with .Count}}{{.}} new {{plural . "chapter" "chapters"}}{{end}}{{if and .Count .Failures}}, {{end}}{{with .Failures}}{{.}} failed {{plural . "download" "downloads"}}{{end}}`,
+		Body:  `{{range $i, $s := .Series}}{{if $i}}{{"\n\n"}}{{end}}{{$s.Manga.Title}}{{with $s.Chapters}}{{"\n"}}{{len .}} new: {{numbers .}}{{end}}{{with $s.Failed}}{{"\n"}}{{len .}} failed: {{numbers .}}{{end}}{{with $s.Manga.URL}}{{"\n"}}{{.}}{{end}}{{end}}`,
+	},
+}
+
+// funcs are available to notification templates.
+var funcs = template.FuncMap{
+	"number":  chapterNumber,
+	"numbers": chapterNumbers,
+	"plural": func(n int, singular, plural string) string {
+		if n == 1 {
+			return singular
+		}
+		return plural
+	},
+}
+
+// messageTemplate renders the title and body of one event type.
+type messageTemplate struct {
+	title *template.Template
+	body  *template.Template
+}
+
+// parseTemplates parses the configured templates, falling back to the
+// built-in ones for empty fields.
+func parseTemplates(cfg config.TemplateConfig) (map[events.Type]messageTemplate, error) {
+	configured := map[events.Type]config.MessageTemplate{
+		events.NewChapters:       cfg.NewChapters,
+		events.DownloadCompleted: cfg.DownloadComplete,
+		events.DownloadFailed:    cfg.DownloadFailed,
+		DigestEvent:              cfg.Digest,
+	}
+
+	templates := make(map[events.Type]messageTemplate, len(configured))
+	for t, tmpl := range configured {
+		def := defaultTemplates[t]
+		title, err := template.New(string(t) + " title").Funcs(funcs).Parse(cmp.Or(tmpl.Title, def.Title))
+		if err != nil {
+			return nil, fmt.Errorf("%s title: %w", t, err)
+		}
+		body, err := template.New(string(t) + " body").Funcs(funcs).Parse(cmp.Or(tmpl.Body, def.Body))
+		if err != nil {
+			return nil, fmt.Errorf("%s body: %w", t, err)
+		}
+		templates[t] = messageTemplate{title: title, body: body}
+	}
+	return templates, nil
+}
+
+// render returns the title and body for a Summary or Digest.
+func (t messageTemplate) render(s any) (string, string, error) {
+	var title, body bytes.Buffer
+	if err := t.title.Execute(&title, s); err != nil {
+		return "", "", fmt.Errorf("render title: %w", err)
+	}
+	if err := t.body.Execute(&body, s); err != nil {
+		return "", "", fmt.Errorf("render body: %w", err)
+	}
+	return strings.TrimSpace(title.String()), strings.TrimSpace(body.String()), nil
+}
+
+// chapterNumber formats a chapter number without trailing zeros.
+func chapterNumber(ch events.Chapter) string {
+	return strconv.FormatFloat(ch.Number, 'f', -1, 64)
+}
+
+// chapterNumbers lists chapter numbers, such as "12, 13 and 13.5".
+func chapterNumbers(chapters []events.Chapter) string {
+	numbers := make([]string, len(chapters))
+	for i, ch := range chapters {
+		numbers[i] = chapterNumber(ch)
+	}
+	if len(numbers) < 2 {
+		return strings.Join(numbers, "")
+	}
+	return strings.Join(numbers[:len(numbers)-1], ", ") + " and " + numbers[len(numbers)-1]
+}
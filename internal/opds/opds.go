@@ -0,0 +1,180 @@
+// Package opds defines the Atom documents of an OPDS 1.2 catalog and its
+// OpenSearch description.
+package opds
+
+import (
+	"encoding/xml"
+	"io"
+	"time"
+)
+
+// Media types of catalog documents.
+const (
+	TypeNavigation  = "application/atom+xml;profile=opds-catalog;kind=navigation"
+	TypeAcquisition = "application/atom+xml;profile=opds-catalog;kind=acquisition"
+	TypeEntry       = "application/atom+xml;type=entry;profile=opds-catalog"
+	TypeOpenSearch  = "application/opensearchdescription+xml"
+)
+
+// Link relations used by catalogs.
+const (
+	RelSelf        = "self"
+	RelStart       = "start"
+	RelUp          = "up"
+	RelNext        = "next"
+	RelPrevious    = "previous"
+	RelFirst       = "first"
+	RelLast        = "last"
+	RelSearch      = "search"
+	RelSubsection  = "subsection"
+	RelNew         = "http://opds-spec.org/sort/new"
+	RelAcquisition = "http://opds-spec.org/acquisition"
+	RelImage       = "http://opds-spec.org/image"
+	RelThumbnail   = "http://opds-spec.org/image/thumbnail"
+	// RelStream links to the pages of a publication for the Page Streaming
+	// Extension (OPDS-PSE).
+	RelStream = "http://vaemendis.net/opds-pse/stream"
+)
+
+// Feed is an OPDS catalog feed.
+type Feed struct {
+	XMLName         xml.Name `xml:"feed"`
+	Xmlns           string   `xml:"xmlns,attr"`
+	XmlnsDC         string   `xml:"xmlns:dc,attr"`
+	XmlnsOPDS       string   `xml:"xmlns:opds,attr"`
+	XmlnsOpenSearch string   `xml:"xmlns:opensearch,attr"`
+	XmlnsThr        string   `xml:"xmlns:thr,attr"`
+	XmlnsPSE        string   `xml:"xmlns:pse,attr"`
+
+	ID      string  `xml:"id"`
+	Title   string  `xml:"title"`
+	Updated string  `xml:"updated"`
+	Author  *Author `xml:"author,omitempty"`
+	Icon    string  `xml:"icon,omitempty"`
+	Links   []Link  `xml:"link"`
+
+	TotalResults int `xml:"opensearch:totalResults,omitempty"`
+	ItemsPerPage int `xml:"opensearch:itemsPerPage,omitempty"`
+	StartIndex   int `xml:"opensearch:startIndex,omitempty"`
+
+	Entries []Entry `xml:"entry"`
+}
+
+// NewFeed returns a feed with the catalog namespaces set.
+func NewFeed(id, title string, updated time.Time) *Feed {
+	return &Feed{
+		Xmlns:           "http://www.w3.org/2005/Atom",
+		XmlnsDC:         "http://purl.org/dc/terms/",
+		XmlnsOPDS:       "http://opds-spec.org/2010/catalog",
+		XmlnsOpenSearch: "http://a9.com/-/spec/opensearch/1.1/",
+		XmlnsThr:        "http://purl.org/syndication/thread/1.0",
+		XmlnsPSE:        "http://vaemendis.net/opds-pse/ns",
+		ID:              id,
+		Title:           title,
+		Updated:         Time(updated),
+	}
+}
+
+// Entry is a navigation or publication entry.
+type Entry struct {
+	ID         string     `xml:"id"`
+	Title      string     `xml:"title"`
+	Updated    string     `xml:"updated"`
+	Authors    []Author   `xml:"author,omitempty"`
+	Language   string     `xml:"dc:language,omitempty"`
+	Issued     string     `xml:"dc:issued,omitempty"`
+	Categories []Category `xml:"category,omitempty"`
+	Summary    *Text      `xml:"summary,omitempty"`
+	Content    *Text      `xml:"content,omitempty"`
+	Links      []Link     `xml:"link"`
+}
+
+// Author is the author of a feed or entry.
+type Author struct {
+	Name string `xml:"name"`
+	URI  string `xml:"uri,omitempty"`
+}
+
+// Category is a genre or tag of an entry.
+type Category struct {
+	Term  string `xml:"term,attr"`
+	Label string `xml:"label,attr,omitempty"`
+}
+
+// Text is plain text content.
+type Text struct {
+	Type  string `xml:"type,attr"`
+	Value string `xml:",chardata"`
+}
+
+// PlainText returns text content of type "text".
+func PlainText(s string) *Text {
+	return &Text{Type: "text", Value: s}
+}
+
+// Link points to another feed, a file or an image.
+type Link struct {
+	Rel    string `xml:"rel,attr,omitempty"`
+	Href   string `xml:"href,attr"`
+	Type   string `xml:"type,attr,omitempty"`
+	Title  string `xml:"title,attr,omitempty"`
+	Length int64  `xml:"length,attr,omitempty"`
+	// Count is the number of entries behind a navigation link.
+	Count int `xml:"thr:count,attr,omitempty"`
+
+	// PageCount is the number of pages behind a stream link.
+	PageCount int `xml:"pse:count,attr,omitempty"`
+	// LastRead is the last page read from a stream link, starting at 0.
+	LastRead *int `xml:"pse:lastRead,attr,omitempty"`
+	// LastReadDate is when LastRead was recorded.
+	LastReadDate string `xml:"pse:lastReadDate,attr,omitempty"`
+}
+
+// Time formats a time for Atom documents.
+func Time(t time.Time) string {
+	return t.UTC().Format(time.RFC3339)
+}
+
+// OpenSearchDescription describes how to search a catalog.
+type OpenSearchDescription struct {
+	XMLName        xml.Name        `xml:"OpenSearchDescription"`
+	Xmlns          string          `xml:"xmlns,attr"`
+	ShortName      string          `xml:"ShortName"`
+	Description    string          `xml:"Description"`
+	InputEncoding  string          `xml:"InputEncoding"`
+	OutputEncoding string          `xml:"OutputEncoding"`
+	URLs           []OpenSearchURL `xml:"Url"`
+}
+
+// OpenSearchURL is a search URL template in which {searchTerms} is replaced
+// by the query.
+type OpenSearchURL struct {
+	Type     string `xml:"type,attr"`
+	Template string `xml:"template,attr"`
+}
+
+// NewOpenSearchDescription returns a description searching with template.
+func NewOpenSearchDescription(name, description, template string) *OpenSearchDescription {
+	return &OpenSearchDescription{
+		Xmlns:          "http://a9.com/-/spec/opensearch/1.1/",
+		ShortName:      name,
+		Description:    description,
+		InputEncoding:  "UTF-8",
+		OutputEncoding: "UTF-8",
+		URLs:           []OpenSearchURL{{Type: "application/atom+xml", Template: template}},
+	}
+}
+
+// Write encodes a feed or description as an indented XML document.
+func Write(w io.Writer, v any) error {
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	if err := enc.Encode(v); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
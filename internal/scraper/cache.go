@@ -0,0 +1,307 @@
+package scraper
+
+import (
+	"bytes"
+	"container/list"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog"
+)
+
+// CallType identifies the provider call a request is made for. Cached
+// responses expire after the TTL configured for their call type.
+type CallType string
+
+const (
+	CallSearch   CallType = "search"
+	CallManga    CallType = "manga"
+	CallChapters CallType = "chapters"
+	CallPages    CallType = "pages"
+)
+
+// cachedHeaders lists the response headers kept with cached responses.
+var cachedHeaders = []string{"Content-Type", "Etag", "Last-Modified"}
+
+type callTypeKey struct{}
+type bypassCacheKey struct{}
+
+// withCallType tags requests made with ctx as part of a provider call.
+func withCallType(ctx context.Context, call CallType) context.Context {
+	return context.WithValue(ctx, callTypeKey{}, call)
+}
+
+// WithoutCache returns a context whose provider calls skip cached responses
+// and fetch from the source. Stored validators are still sent, so unchanged
+// responses cost a 304 instead of a full download.
+func WithoutCache(ctx context.Context) context.Context {
+	return context.WithValue(ctx, bypassCacheKey{}, true)
+}
+
+// CachedResponse is a response body stored by the cache.
+type CachedResponse struct {
+	Provider     string
+	Status       int
+	Header       http.Header
+	Body         []byte
+	ETag         string
+	LastModified string
+	StoredAt     time.Time
+	ExpiresAt    time.Time
+}
+
+// CacheStore persists cached responses beyond the in-memory LRU.
+type CacheStore interface {
+	// Load returns the response stored under key, or nil when there is none.
+	Load(ctx context.Context, key string) (*CachedResponse, error)
+	Store(ctx context.Context, key string, r *CachedResponse) error
+}
+
+// CacheOptions configures the response cache.
+type CacheOptions struct {
+	// MaxEntries bounds the in-memory LRU.
+	MaxEntries int
+	// MaxBodySize is the largest response body cached, in bytes.
+	MaxBodySize int64
+	// TTL is how long responses stay fresh per call type. Call types
+	// without a positive TTL are not cached.
+	TTL map[CallType]time.Duration
+}
+
+// DefaultCacheOptions returns the cache settings used when none are configured.
+func DefaultCacheOptions() CacheOptions {
+	return CacheOptions{
+		MaxEntries:  1000,
+		MaxBodySize: 2 << 20,
+		TTL: map[CallType]time.Duration{
+			CallSearch:   10 * time.Minute,
+			CallManga:    6 * time.Hour,
+			CallChapters: 15 * time.Minute,
+			CallPages:    5 * time.Minute,
+		},
+	}
+}
+
+// ResponseCache caches successful GET responses made during provider calls.
+// Fresh responses are served without touching the network; stale ones are
+// revalidated with If-None-Match or If-Modified-Since when the source sent
+// an ETag or Last-Modified header.
+type ResponseCache struct {
+	opts  CacheOptions
+	store CacheStore
+	log   zerolog.Logger
+
+	mu      sync.Mutex
+	entries map[string]*list.Element
+	lru     *list.List
+}
+
+type cacheEntry struct {
+	key  string
+	resp *CachedResponse
+}
+
+// NewResponseCache creates a cache. store may be nil to keep responses in
+// memory only.
+func NewResponseCache(opts CacheOptions, store CacheStore, log zerolog.Logger) *ResponseCache {
+	defaults := DefaultCacheOptions()
+	if opts.MaxEntries <= 0 {
+		opts.MaxEntries = defaults.MaxEntries
+	}
+	if opts.MaxBodySize <= 0 {
+		opts.MaxBodySize = defaults.MaxBodySize
+	}
+	if opts.TTL == nil {
+		opts.TTL = defaults.TTL
+	}
+
+	return &ResponseCache{
+		opts:    opts,
+		store:   store,
+		log:     log.With().Str("component", "scraper-cache").Logger(),
+		entries: make(map[string]*list.Element),
+		lru:     list.New(),
+	}
+}
+
+var responseCache struct {
+	sync.RWMutex
+	cache *ResponseCache
+}
+
+// SetResponseCache installs the cache used for every provider's requests.
+// A nil cache disables caching.
+func SetResponseCache(c *ResponseCache) {
+	responseCache.Lock()
+	defer responseCache.Unlock()
+	responseCache.cache = c
+}
+
+func currentResponseCache() *ResponseCache {
+	responseCache.RLock()
+	defer responseCache.RUnlock()
+	return responseCache.cache
+}
+
+// roundTrip answers req from the cache or sends it through next, storing
+// the response.
+func (c *ResponseCache) roundTrip(providerID string, req *http.Request, next http.RoundTripper) (*http.Response, error) {
+	ctx := req.Context()
+	call, _ := ctx.Value(callTypeKey{}).(CallType)
+	ttl := c.opts.TTL[call]
+	if req.Method != http.MethodGet || ttl <= 0 {
+		return next.RoundTrip(req)
+	}
+
+	key := providerID + " " + req.URL.String()
+	bypass, _ := ctx.Value(bypassCacheKey{}).(bool)
+	cached := c.get(ctx, key)
+
+	now := time.Now()
+	if cached != nil && !bypass && now.Before(cached.ExpiresAt) {
+		return cached.response(req), nil
+	}
+
+	conditional := cached != nil && (cached.ETag != "" || cached.LastModified != "")
+	if conditional {
+		req = req.Clone(ctx)
+		if cached.ETag != "" {
+			req.Header.Set("If-None-Match", cached.ETag)
+		}
+		if cached.LastModified != "" {
+			req.Header.Set("If-Modified-Since", cached.LastModified)
+		}
+	}
+
+	resp, err := next.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode == http.StatusNotModified && conditional {
+		resp.Body.Close()
+		revalidated := *cached
+		revalidated.StoredAt = now
+		revalidated.ExpiresAt = now.Add(ttl)
+		c.put(ctx, key, &revalidated)
+		return revalidated.response(req), nil
+	}
+
+	if resp.StatusCode != http.StatusOK || strings.Contains(resp.Header.Get("Cache-Control"), "no-store") {
+		return resp, nil
+	}
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, c.opts.MaxBodySize+1))
+	if err != nil {
+		resp.Body.Close()
+		return nil, err
+	}
+	if int64(len(body)) > c.opts.MaxBodySize {
+		resp.Body = readCloser{io.MultiReader(bytes.NewReader(body), resp.Body), resp.Body}
+		return resp, nil
+	}
+	resp.Body.Close()
+	resp.Body = io.NopCloser(bytes.NewReader(body))
+
+	stored := &CachedResponse{
+		Provider:     providerID,
+		Status:       resp.StatusCode,
+		Header:       make(http.Header),
+		Body:         body,
+		ETag:         resp.Header.Get("ETag"),
+		LastModified: resp.Header.Get("Last-Modified"),
+		StoredAt:     now,
+		ExpiresAt:    now.Add(ttl),
+	}
+	for _, h := range cachedHeaders {
+		if v := resp.Header.Get(h); v != "" {
+			stored.Header.Set(h, v)
+		}
+	}
+	c.put(ctx, key, stored)
+
+	return resp, nil
+}
+
+// get looks up key in memory, then in the store.
+func (c *ResponseCache) get(ctx context.Context, key string) *CachedResponse {
+	c.mu.Lock()
+	if el, ok := c.entries[key]; ok {
+		c.lru.MoveToFront(el)
+		resp := el.Value.(*cacheEntry).resp
+		c.mu.Unlock()
+		return resp
+	}
+	c.mu.Unlock()
+
+	if c.store == nil {
+		return nil
+	}
+	resp, err := c.store.Load(ctx, key)
+	if err != nil {
+		c.log.Warn().Err(err).Str("key", key).Msg("failed to load cached response")
+		return nil
+	}
+	if resp != nil {
+		c.remember(key, resp)
+	}
+	return resp
+}
+
+// put stores a response in memory and in the store.
+func (c *ResponseCache) put(ctx context.Context, key string, resp *CachedResponse) {
+	c.remember(key, resp)
+
+	if c.store == nil {
+		return
+	}
+	if err := c.store.Store(ctx, key, resp); err != nil {
+		c.log.Warn().Err(err).Str("key", key).Msg("failed to persist cached response")
+	}
+}
+
+// remember adds a response to the LRU, evicting the least recently used
+// entries beyond MaxEntries.
+func (c *ResponseCache) remember(key string, resp *CachedResponse) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if el, ok := c.entries[key]; ok {
+		el.Value.(*cacheEntry).resp = resp
+		c.lru.MoveToFront(el)
+		return
+	}
+
+	c.entries[key] = c.lru.PushFront(&cacheEntry{key: key, resp: resp})
+	for c.lru.Len() > c.opts.MaxEntries {
+		oldest := c.lru.Back()
+		c.lru.Remove(oldest)
+		delete(c.entries, oldest.Value.(*cacheEntry).key)
+	}
+}
+
+// response builds an HTTP response from the cached copy.
+func (r *CachedResponse) response(req *http.Request) *http.Response {
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", r.Status, http.StatusText(r.Status)),
+		StatusCode:    r.Status,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        r.Header.Clone(),
+		Body:          io.NopCloser(bytes.NewReader(r.Body)),
+		ContentLength: int64(len(r.Body)),
+		Request:       req,
+	}
+}
+
+// readCloser pairs a reader with the closer of the body it wraps.
+type readCloser struct {
+	io.Reader
+	io.Closer
+}
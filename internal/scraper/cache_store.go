@@ -0,0 +1,84 @@
+package scraper
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/mangashelf/mangashelf/internal/database"
+)
+
+// SQLCacheStore persists cached responses in the response_cache table.
+type SQLCacheStore struct {
+	db *database.Queries
+}
+
+// NewSQLCacheStore creates a store backed by the database.
+func NewSQLCacheStore(db *database.Queries) *SQLCacheStore {
+	return &SQLCacheStore{db: db}
+}
+
+// Load returns the response stored under key, or nil when there is none.
+func (s *SQLCacheStore) Load(ctx context.Context, key string) (*CachedResponse, error) {
+	row, err := s.db.GetCachedResponse(ctx, key)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	header := make(http.Header)
+	if row.Headers.Valid && row.Headers.String != "" {
+		if err := json.Unmarshal([]byte(row.Headers.String), &header); err != nil {
+			return nil, err
+		}
+	}
+	storedAt, _ := time.Parse(database.TimeFormat, row.StoredAt)
+	expiresAt, _ := time.Parse(database.TimeFormat, row.ExpiresAt)
+
+	return &CachedResponse{
+		Provider:     row.Provider,
+		Status:       int(row.Status),
+		Header:       header,
+		Body:         row.Body,
+		ETag:         row.Etag.String,
+		LastModified: row.LastModified.String,
+		StoredAt:     storedAt,
+		ExpiresAt:    expiresAt,
+	}, nil
+}
+
+// Store saves a response under key, replacing any previous one.
+func (s *SQLCacheStore) Store(ctx context.Context, key string, r *CachedResponse) error {
+	header, err := json.Marshal(r.Header)
+	if err != nil {
+		return err
+	}
+
+	return s.db.UpsertCachedResponse(ctx, database.UpsertCachedResponseParams{
+		Key:          key,
+		Provider:     r.Provider,
+		Status:       int64(r.Status),
+		Headers:      sql.NullString{String: string(header), Valid: true},
+		Body:         r.Body,
+		Etag:         sql.NullString{String: r.ETag, Valid: r.ETag != ""},
+		LastModified: sql.NullString{String: r.LastModified, Valid: r.LastModified != ""},
+		StoredAt:     r.StoredAt.UTC().Format(database.TimeFormat),
+		ExpiresAt:    r.ExpiresAt.UTC().Format(database.TimeFormat),
+	})
+}
+
+// Prune deletes responses that expired before the given time. Expired
+// responses are otherwise kept so they can be revalidated.
+func (s *SQLCacheStore) Prune(ctx context.Context, before time.Time) error {
+	return s.db.DeleteCachedResponsesExpiredBefore(ctx, before.UTC().Format(database.TimeFormat))
+}
+
+// Clear deletes every response stored for a provider.
+func (s *SQLCacheStore) Clear(ctx context.Context, providerID string) error {
+	return s.db.DeleteCachedResponsesByProvider(ctx, providerID)
+}
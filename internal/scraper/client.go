@@ -0,0 +1,88 @@
+package scraper
+
+import (
+	"net/http"
+	"sync"
+	"time"
+)
+
+// transports holds the transport overrides installed with SetTransport.
+var transports = struct {
+	sync.RWMutex
+	byProvider map[string]http.RoundTripper
+	fallback   http.RoundTripper
+}{
+	byProvider: make(map[string]http.RoundTripper),
+}
+
+// NewHTTPClient returns the client a provider uses for its requests. All
+// provider traffic goes through clients built here so it can be recorded,
+// replayed or routed through a proxy per provider.
+func NewHTTPClient(providerID string, timeout time.Duration) *http.Client {
+	return &http.Client{
+		Timeout:   timeout,
+		Transport: Transport(providerID),
+	}
+}
+
+// Transport returns the round tripper for a provider's requests. It looks up
+// the installed transport on every request, so overrides also apply to
+// clients created earlier.
+func Transport(providerID string) http.RoundTripper {
+	return providerTransport{id: providerID}
+}
+
+// SetTransport installs the transport used for a provider's requests. An
+// empty provider ID sets the transport for every provider without its own;
+// a nil transport removes the override. The previous override is returned so
+// it can be restored later.
+func SetTransport(providerID string, rt http.RoundTripper) http.RoundTripper {
+	transports.Lock()
+	defer transports.Unlock()
+
+	if providerID == "" {
+		prev := transports.fallback
+		transports.fallback = rt
+		return prev
+	}
+
+	prev := transports.byProvider[providerID]
+	if rt == nil {
+		delete(transports.byProvider, providerID)
+	} else {
+		transports.byProvider[providerID] = rt
+	}
+	return prev
+}
+
+// InstalledTransport returns the transport currently serving a provider's
+// requests: its own override, the fallback, or http.DefaultTransport.
+func InstalledTransport(providerID string) http.RoundTripper {
+	return transportFor(providerID)
+}
+
+// transportFor returns the transport installed for a provider.
+func transportFor(providerID string) http.RoundTripper {
+	transports.RLock()
+	defer transports.RUnlock()
+
+	if rt, ok := transports.byProvider[providerID]; ok {
+		return rt
+	}
+	if transports.fallback != nil {
+		return transports.fallback
+	}
+	return http.DefaultTransport
+}
+
+type providerTransport struct {
+	id string
+}
+
+func (t providerTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	next := transportFor(t.id)
+	if cache := currentResponseCache(); cache != nil {
+		return cache.roundTrip(t.id, req, next)
+	}
+	return next.RoundTrip(req)
+}
@@ -0,0 +1,287 @@
+package declarative
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"regexp"
+	"strings"
+
+	"github.com/andybalholm/cascadia"
+	"gopkg.in/yaml.v3"
+
+	"github.com/mangashelf/mangashelf/internal/scraper"
+)
+
+// idPattern restricts source IDs to the characters allowed in provider IDs.
+var idPattern = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]*$`)
+
+// Definition describes an HTML site in terms of URL templates and CSS
+// selectors. It is decoded from a YAML or JSON file.
+//
+// URL templates may reference {baseUrl}, {query} (search only, URL-encoded),
+// {page} and {id}.
+type Definition struct {
+	ID        string            `yaml:"id"`
+	Name      string            `yaml:"name"`
+	BaseURL   string            `yaml:"baseUrl"`
+	Languages []string          `yaml:"languages"`
+	NSFW      bool              `yaml:"nsfw"`
+	Headers   map[string]string `yaml:"headers"`
+
+	Search   SearchDefinition   `yaml:"search"`
+	Manga    MangaDefinition    `yaml:"manga"`
+	Chapters ChaptersDefinition `yaml:"chapters"`
+	Pages    PagesDefinition    `yaml:"pages"`
+}
+
+// SearchDefinition describes the search results page.
+type SearchDefinition struct {
+	URL        string     `yaml:"url"`
+	Items      Selector   `yaml:"items"`
+	ID         Field      `yaml:"id"`
+	Title      Field      `yaml:"title"`
+	Cover      Field      `yaml:"cover"`
+	Link       Field      `yaml:"link"`
+	Pagination Pagination `yaml:"pagination"`
+}
+
+// MangaDefinition describes the manga details page.
+type MangaDefinition struct {
+	URL         string            `yaml:"url"`
+	Title       Field             `yaml:"title"`
+	Description Field             `yaml:"description"`
+	Cover       Field             `yaml:"cover"`
+	Status      Field             `yaml:"status"`
+	StatusMap   map[string]string `yaml:"statusMap"`
+	Author      Field             `yaml:"author"`
+	Artist      Field             `yaml:"artist"`
+	Genres      Field             `yaml:"genres"`
+	Tags        Field             `yaml:"tags"`
+}
+
+// ChaptersDefinition describes the chapter list. The URL defaults to the
+// manga page.
+type ChaptersDefinition struct {
+	URL         string     `yaml:"url"`
+	Items       Selector   `yaml:"items"`
+	ID          Field      `yaml:"id"`
+	Title       Field      `yaml:"title"`
+	Number      Field      `yaml:"number"`
+	Volume      Field      `yaml:"volume"`
+	Link        Field      `yaml:"link"`
+	Date        Field      `yaml:"date"`
+	DateFormats []string   `yaml:"dateFormats"`
+	Pagination  Pagination `yaml:"pagination"`
+}
+
+// PagesDefinition describes the chapter reader page.
+type PagesDefinition struct {
+	URL        string     `yaml:"url"`
+	Images     Field      `yaml:"images"`
+	Pagination Pagination `yaml:"pagination"`
+}
+
+// Pagination describes how to reach further pages of a listing. Pages are
+// followed through the Next link when set, and otherwise by incrementing
+// {page} until a page yields no items or MaxPages is reached.
+type Pagination struct {
+	Start    int      `yaml:"start"`
+	MaxPages int      `yaml:"maxPages"`
+	Next     Selector `yaml:"next"`
+}
+
+// Selector is a CSS selector compiled when the definition is validated.
+type Selector struct {
+	Raw string
+	sel cascadia.Selector
+}
+
+// UnmarshalYAML decodes a selector from a plain string.
+func (s *Selector) UnmarshalYAML(node *yaml.Node) error {
+	return node.Decode(&s.Raw)
+}
+
+// IsZero reports whether the selector is unset.
+func (s Selector) IsZero() bool {
+	return s.Raw == ""
+}
+
+// Field extracts a value from an element. The selector is matched inside the
+// current item, or the whole document outside of item lists; an empty
+// selector uses the item itself. Attr names the attribute to read, with
+// "text" (the default) and "html" reading the element content. When Regex is
+// set its first capture group, or the whole match, replaces the value.
+//
+// A field may be written as a plain selector string.
+type Field struct {
+	Selector Selector `yaml:"selector"`
+	Attr     string   `yaml:"attr"`
+	Regex    string   `yaml:"regex"`
+	Default  string   `yaml:"default"`
+
+	re *regexp.Regexp
+}
+
+// UnmarshalYAML accepts either a selector string or a field mapping.
+func (f *Field) UnmarshalYAML(node *yaml.Node) error {
+	if node.Kind == yaml.ScalarNode {
+		return node.Decode(&f.Selector.Raw)
+	}
+
+	type plain Field
+	return node.Decode((*plain)(f))
+}
+
+// IsZero reports whether the field is unset.
+func (f Field) IsZero() bool {
+	return f.Selector.Raw == "" && f.Attr == "" && f.Regex == "" && f.Default == ""
+}
+
+// validator collects every problem found in a definition so they can be
+// reported together.
+type validator struct {
+	errs []error
+}
+
+func (v *validator) addf(format string, args ...any) {
+	v.errs = append(v.errs, fmt.Errorf(format, args...))
+}
+
+func (v *validator) selector(name string, s *Selector, required bool) {
+	if s.Raw == "" {
+		if required {
+			v.addf("%s: selector is required", name)
+		}
+		return
+	}
+
+	sel, err := cascadia.Compile(s.Raw)
+	if err != nil {
+		v.addf("%s: invalid selector %q: %v", name, s.Raw, err)
+		return
+	}
+	s.sel = sel
+}
+
+func (v *validator) field(name string, f *Field, required bool) {
+	if f.IsZero() {
+		if required {
+			v.addf("%s: field is required", name)
+		}
+		return
+	}
+
+	v.selector(name+".selector", &f.Selector, false)
+
+	if f.Regex != "" {
+		re, err := regexp.Compile(f.Regex)
+		if err != nil {
+			v.addf("%s.regex: %v", name, err)
+		} else {
+			f.re = re
+		}
+	}
+}
+
+func (v *validator) template(name, tmpl string, required bool, placeholders ...string) {
+	if tmpl == "" {
+		if required {
+			v.addf("%s: url is required", name)
+		}
+		return
+	}
+
+	for _, p := range placeholders {
+		if !strings.Contains(tmpl, p) {
+			v.addf("%s: url must contain %s", name, p)
+		}
+	}
+
+	rest := tmpl
+	for {
+		start := strings.IndexByte(rest, '{')
+		if start < 0 {
+			break
+		}
+		end := strings.IndexByte(rest[start:], '}')
+		if end < 0 {
+			v.addf("%s: unterminated placeholder in %q", name, tmpl)
+			break
+		}
+		switch rest[start : start+end+1] {
+		case "{baseUrl}", "{query}", "{page}", "{id}":
+		default:
+			v.addf("%s: unknown placeholder %s", name, rest[start:start+end+1])
+		}
+		rest = rest[start+end+1:]
+	}
+}
+
+func (v *validator) pagination(name string, p *Pagination) {
+	if p.MaxPages < 0 {
+		v.addf("%s.maxPages: must not be negative", name)
+	}
+	v.selector(name+".next", &p.Next, false)
+}
+
+// Validate checks the definition and compiles its selectors and patterns.
+// All problems are returned joined in a single error.
+func (d *Definition) Validate() error {
+	v := &validator{}
+
+	if !idPattern.MatchString(d.ID) {
+		v.addf("id: must be lowercase letters, digits, '-' or '_'")
+	}
+	if d.Name == "" {
+		v.addf("name: is required")
+	}
+	if u, err := url.Parse(d.BaseURL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		v.addf("baseUrl: must be an absolute http(s) URL")
+	}
+
+	v.template("search", d.Search.URL, true, "{query}")
+	v.selector("search.items", &d.Search.Items, true)
+	v.field("search.id", &d.Search.ID, true)
+	v.field("search.title", &d.Search.Title, true)
+	v.field("search.cover", &d.Search.Cover, false)
+	v.field("search.link", &d.Search.Link, false)
+	v.pagination("search.pagination", &d.Search.Pagination)
+
+	v.template("manga", d.Manga.URL, true, "{id}")
+	v.field("manga.title", &d.Manga.Title, true)
+	v.field("manga.description", &d.Manga.Description, false)
+	v.field("manga.cover", &d.Manga.Cover, false)
+	v.field("manga.status", &d.Manga.Status, false)
+	v.field("manga.author", &d.Manga.Author, false)
+	v.field("manga.artist", &d.Manga.Artist, false)
+	v.field("manga.genres", &d.Manga.Genres, false)
+	v.field("manga.tags", &d.Manga.Tags, false)
+	for raw, status := range d.Manga.StatusMap {
+		if scraper.NormalizeStatus(status) != status {
+			v.addf("manga.statusMap.%s: unknown status %q", raw, status)
+		}
+	}
+
+	v.template("chapters", d.Chapters.URL, false, "{id}")
+	v.selector("chapters.items", &d.Chapters.Items, true)
+	v.field("chapters.id", &d.Chapters.ID, true)
+	v.field("chapters.title", &d.Chapters.Title, false)
+	v.field("chapters.number", &d.Chapters.Number, false)
+	v.field("chapters.volume", &d.Chapters.Volume, false)
+	v.field("chapters.link", &d.Chapters.Link, false)
+	v.field("chapters.date", &d.Chapters.Date, false)
+	if !d.Chapters.Date.IsZero() && len(d.Chapters.DateFormats) == 0 {
+		v.addf("chapters.dateFormats: required when chapters.date is set")
+	}
+	v.pagination("chapters.pagination", &d.Chapters.Pagination)
+
+	v.template("pages", d.Pages.URL, true, "{id}")
+	v.field("pages.images", &d.Pages.Images, true)
+	if !d.Pages.Images.IsZero() && d.Pages.Images.Selector.IsZero() {
+		v.addf("pages.images: selector is required")
+	}
+	v.pagination("pages.pagination", &d.Pages.Pagination)
+
+	return errors.Join(v.errs...)
+}
@@ -0,0 +1,198 @@
+package declarative
+
+import (
+	"bytes"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"golang.org/x/net/html"
+)
+
+var (
+	chapterNumberPattern = regexp.MustCompile(`(?i)(?:chapter|ch|episode|ep)\.?\s*(\d+(?:\.\d+)?)`)
+	numberPattern        = regexp.MustCompile(`\d+(?:\.\d+)?`)
+	relativeDatePattern  = regexp.MustCompile(`(?i)^(\d+|an?)\s+(second|minute|hour|day|week|month|year)s?\s+ago$`)
+)
+
+// value extracts a single value of the field from the element, or the
+// field's default when nothing matches.
+func (f *Field) value(n *html.Node) string {
+	values := f.values(n, 1)
+	if len(values) == 0 {
+		return f.Default
+	}
+	return values[0]
+}
+
+// values extracts up to limit non-empty values of the field from the
+// element. A limit of zero returns every value; a field
+// with nothing to read returns none.
+func (f *Field) values(n *html.Node, limit int) []string {
+	if n == nil || (f.Selector.IsZero() && f.Attr == "" && f.Regex == "") {
+		return nil
+	}
+
+	nodes := []*html.Node{n}
+	if f.Selector.sel != nil {
+		nodes = f.Selector.sel.MatchAll(n)
+	}
+
+	var values []string
+	for _, node := range nodes {
+		v := f.apply(read(node, f.Attr))
+		if v == "" {
+			continue
+		}
+		values = append(values, v)
+		if limit > 0 && len(values) == limit {
+			break
+		}
+	}
+	return values
+}
+
+// apply runs the field's regex over a value.
+func (f *Field) apply(v string) string {
+	if f.re == nil {
+		return v
+	}
+
+	m := f.re.FindStringSubmatch(v)
+	switch {
+	case m == nil:
+		return ""
+	case len(m) > 1:
+		return strings.TrimSpace(m[1])
+	default:
+		return strings.TrimSpace(m[0])
+	}
+}
+
+// read returns an attribute of the node, its text or its inner HTML.
+func read(n *html.Node, attr string) string {
+	switch attr {
+	case "", "text":
+		return textContent(n)
+	case "html":
+		var buf bytes.Buffer
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			_ = html.Render(&buf, c)
+		}
+		return strings.TrimSpace(buf.String())
+	default:
+		return strings.TrimSpace(attrValue(n, attr))
+	}
+}
+
+// attrValue returns the value of an attribute, or "" when it is missing.
+func attrValue(n *html.Node, name string) string {
+	for _, a := range n.Attr {
+		if a.Key == name {
+			return a.Val
+		}
+	}
+	return ""
+}
+
+// textContent returns the text of a node with whitespace collapsed.
+func textContent(n *html.Node) string {
+	var sb strings.Builder
+	var walk func(*html.Node)
+	walk = func(n *html.Node) {
+		if n.Type == html.TextNode {
+			sb.WriteString(n.Data)
+			sb.WriteByte(' ')
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			walk(c)
+		}
+	}
+	walk(n)
+	return strings.Join(strings.Fields(sb.String()), " ")
+}
+
+// resolveURL resolves a possibly relative link against the page it was found on.
+func resolveURL(base *url.URL, ref string) string {
+	if ref == "" {
+		return ""
+	}
+	u, err := url.Parse(ref)
+	if err != nil {
+		return ref
+	}
+	return base.ResolveReference(u).String()
+}
+
+// parseChapterNumber reads a chapter number from a value such as "12.5" or
+// "Chapter 12.5: Title". It returns 0 when no number is found.
+func parseChapterNumber(s string) float64 {
+	if m := chapterNumberPattern.FindStringSubmatch(s); m != nil {
+		s = m[1]
+	} else {
+		s = numberPattern.FindString(s)
+	}
+	n, _ := strconv.ParseFloat(s, 64)
+	return n
+}
+
+// parseDate parses a date using Go reference layouts. The special layout
+// "relative" accepts "today", "yesterday" and "3 days ago" style values.
+func parseDate(s string, layouts []string, now time.Time) time.Time {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return time.Time{}
+	}
+
+	for _, layout := range layouts {
+		if layout == "relative" {
+			if t, ok := parseRelativeDate(s, now); ok {
+				return t
+			}
+			continue
+		}
+		if t, err := time.Parse(layout, s); err == nil {
+			return t
+		}
+	}
+	return time.Time{}
+}
+
+// parseRelativeDate parses dates given relative to now.
+func parseRelativeDate(s string, now time.Time) (time.Time, bool) {
+	switch strings.ToLower(s) {
+	case "just now", "now", "today":
+		return now, true
+	case "yesterday":
+		return now.AddDate(0, 0, -1), true
+	}
+
+	m := relativeDatePattern.FindStringSubmatch(s)
+	if m == nil {
+		return time.Time{}, false
+	}
+
+	n := 1
+	if v, err := strconv.Atoi(m[1]); err == nil {
+		n = v
+	}
+
+	switch strings.ToLower(m[2]) {
+	case "second":
+		return now.Add(-time.Duration(n) * time.Second), true
+	case "minute":
+		return now.Add(-time.Duration(n) * time.Minute), true
+	case "hour":
+		return now.Add(-time.Duration(n) * time.Hour), true
+	case "day":
+		return now.AddDate(0, 0, -n), true
+	case "week":
+		return now.AddDate(0, 0, -7*n), true
+	case "month":
+		return now.AddDate(0, -n, 0), true
+	default:
+		return now.AddDate(-n, 0, 0), true
+	}
+}
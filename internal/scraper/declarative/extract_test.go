@@ -0,0 +1,89 @@
+package declarative
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseChapterNumber(t *testing.T) {
+	tests := []struct {
+		in   string
+		want float64
+	}{
+		{"12", 12},
+		{"12.5", 12.5},
+		{"Chapter 12.5: Title", 12.5},
+		{"chapter12", 12},
+		{"Vol. 3 Ch. 45", 45},
+		{"Vol.2 Chapter 10 - 20 pages", 10},
+		{"Ep 7", 7},
+		{"Episode 100", 100},
+		{"Season 2 - 14", 2},
+		{"Oneshot", 0},
+		{"", 0},
+	}
+	for _, tt := range tests {
+		if got := parseChapterNumber(tt.in); got != tt.want {
+			t.Errorf("parseChapterNumber(%q) = %g, want %g", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestParseRelativeDate(t *testing.T) {
+	now := time.Date(2024, 3, 15, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		in     string
+		want   time.Time
+		wantOK bool
+	}{
+		{"today", now, true},
+		{"Just now", now, true},
+		{"now", now, true},
+		{"Yesterday", now.AddDate(0, 0, -1), true},
+		{"10 seconds ago", now.Add(-10 * time.Second), true},
+		{"a minute ago", now.Add(-time.Minute), true},
+		{"an hour ago", now.Add(-time.Hour), true},
+		{"5 hours ago", now.Add(-5 * time.Hour), true},
+		{"3 days ago", now.AddDate(0, 0, -3), true},
+		{"1 day ago", now.AddDate(0, 0, -1), true},
+		{"2 Weeks ago", now.AddDate(0, 0, -14), true},
+		{"2 months ago", now.AddDate(0, -2, 0), true},
+		{"a year ago", now.AddDate(-1, 0, 0), true},
+		{"tomorrow", time.Time{}, false},
+		{"3 days", time.Time{}, false},
+		{"about 3 days ago", time.Time{}, false},
+		{"", time.Time{}, false},
+	}
+	for _, tt := range tests {
+		got, ok := parseRelativeDate(tt.in, now)
+		if ok != tt.wantOK || !got.Equal(tt.want) {
+			t.Errorf("parseRelativeDate(%q) = %v, %v, want %v, %v", tt.in, got, ok, tt.want, tt.wantOK)
+		}
+	}
+}
+
+func TestParseDate(t *testing.T) {
+	now := time.Date(2024, 3, 15, 12, 0, 0, 0, time.UTC)
+	layouts := []string{"Jan 2, 2006", "2006-01-02", "relative"}
+
+	tests := []struct {
+		in   string
+		want time.Time
+	}{
+		{"Mar 4, 2024", time.Date(2024, 3, 4, 0, 0, 0, 0, time.UTC)},
+		{" 2023-12-31 ", time.Date(2023, 12, 31, 0, 0, 0, 0, time.UTC)},
+		{"2 days ago", now.AddDate(0, 0, -2)},
+		{"31/12/2023", time.Time{}},
+		{"", time.Time{}},
+	}
+	for _, tt := range tests {
+		if got := parseDate(tt.in, layouts, now); !got.Equal(tt.want) {
+			t.Errorf("parseDate(%q) = %v, want %v", tt.in, got, tt.want)
+		}
+	}
+
+	if got := parseDate("2 days ago", []string{"2006-01-02"}, now); !got.IsZero() {
+		t.Errorf("relative date parsed without the relative layout: %v", got)
+	}
+}
@@ -0,0 +1,79 @@
+package declarative
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// definitionExts lists the file extensions read as source definitions.
+// JSON is decoded by the YAML parser, which accepts it as a subset.
+var definitionExts = map[string]bool{".yaml": true, ".yml": true, ".json": true}
+
+// LoadFile reads, validates and compiles a single source definition.
+func LoadFile(path string) (*Source, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+	dec.KnownFields(true)
+
+	var def Definition
+	if err := dec.Decode(&def); err != nil {
+		return nil, fmt.Errorf("decode: %w", err)
+	}
+
+	return New(&def)
+}
+
+// LoadDir loads every source definition in dir. Valid definitions are
+// returned even when others fail; the failures are joined into the returned
+// error, one per file. A missing directory yields no sources and no error.
+func LoadDir(dir string) ([]*Source, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	sort.Slice(entries, func(i, j int) bool { return entries[i].Name() < entries[j].Name() })
+
+	var sources []*Source
+	var errs []error
+	ids := make(map[string]string)
+
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || strings.HasPrefix(name, ".") || !definitionExts[strings.ToLower(filepath.Ext(name))] {
+			continue
+		}
+
+		path := filepath.Join(dir, name)
+		source, err := LoadFile(path)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", path, err))
+			continue
+		}
+
+		id := source.def.ID
+		if other, ok := ids[id]; ok {
+			errs = append(errs, fmt.Errorf("%s: id %q is already defined in %s", path, id, other))
+			continue
+		}
+		ids[id] = path
+
+		sources = append(sources, source)
+	}
+
+	return sources, errors.Join(errs...)
+}
@@ -0,0 +1,396 @@
+package declarative
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+
+	"golang.org/x/net/html"
+
+	"github.com/mangashelf/mangashelf/internal/scraper"
+)
+
+const (
+	userAgent = "MangaShelf/1.0"
+
+	// maxPagesLimit bounds pagination when a definition sets no limit.
+	maxPagesLimit = 50
+
+	// maxBodySize bounds the size of a fetched HTML document.
+	maxBodySize = 10 << 20
+)
+
+// Source implements the scraper.Provider interface from a Definition.
+type Source struct {
+	def    *Definition
+	base   *url.URL
+	client *http.Client
+}
+
+// New validates a definition and builds a provider from it.
+func New(def *Definition) (*Source, error) {
+	if err := def.Validate(); err != nil {
+		return nil, err
+	}
+
+	base, err := url.Parse(strings.TrimRight(def.BaseURL, "/"))
+	if err != nil {
+		return nil, fmt.Errorf("baseUrl: %w", err)
+	}
+
+	return &Source{
+		def:    def,
+		base:   base,
+		client: scraper.NewHTTPClient(def.ID, 30*time.Second),
+	}, nil
+}
+
+// Info returns provider metadata.
+func (s *Source) Info() scraper.ProviderInfo {
+	languages := s.def.Languages
+	if languages == nil {
+		languages = []string{}
+	}
+
+	return scraper.ProviderInfo{
+		ID:        s.def.ID,
+		Name:      s.def.Name,
+		BaseURL:   s.base.String(),
+		Languages: languages,
+		IsNSFW:    s.def.NSFW,
+	}
+}
+
+// Search finds manga matching the query.
+func (s *Source) Search(ctx context.Context, query string) ([]scraper.MangaResult, error) {
+	def := &s.def.Search
+	results := make([]scraper.MangaResult, 0)
+	seen := make(map[string]bool)
+
+	err := s.paginate(ctx, def.URL, url.QueryEscape(query), "", def.Pagination, scraper.ErrSourceUnavailable, func(doc *html.Node, pageURL *url.URL) int {
+		found := 0
+		for _, item := range def.Items.sel.MatchAll(doc) {
+			id := def.ID.value(item)
+			if id == "" || seen[id] {
+				continue
+			}
+			seen[id] = true
+			found++
+
+			link := resolveURL(pageURL, def.Link.value(item))
+			if link == "" {
+				link = s.expand(s.def.Manga.URL, "", id, 0)
+			}
+
+			results = append(results, scraper.MangaResult{
+				ID:       id,
+				Title:    def.Title.value(item),
+				CoverURL: resolveURL(pageURL, def.Cover.value(item)),
+				URL:      link,
+			})
+		}
+		return found
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return results, nil
+}
+
+// GetManga fetches full details for a manga.
+func (s *Source) GetManga(ctx context.Context, id string) (*scraper.Manga, error) {
+	def := &s.def.Manga
+	mangaURL := s.expand(def.URL, "", id, 0)
+
+	doc, pageURL, err := s.fetch(ctx, mangaURL, scraper.ErrMangaNotFound)
+	if err != nil {
+		return nil, err
+	}
+
+	status := def.Status.value(doc)
+	if mapped, ok := lookupStatus(def.StatusMap, status); ok {
+		status = mapped
+	} else {
+		status = scraper.NormalizeStatus(status)
+	}
+
+	manga := &scraper.Manga{
+		ID:          id,
+		Title:       def.Title.value(doc),
+		Description: def.Description.value(doc),
+		CoverURL:    resolveURL(pageURL, def.Cover.value(doc)),
+		Status:      status,
+		Author:      def.Author.value(doc),
+		Artist:      def.Artist.value(doc),
+		Genres:      def.Genres.values(doc, 0),
+		Tags:        def.Tags.values(doc, 0),
+		URL:         mangaURL,
+	}
+
+	if manga.Title == "" {
+		return nil, scraper.ErrMangaNotFound
+	}
+
+	return manga, nil
+}
+
+// GetChapters fetches all chapters for a manga.
+func (s *Source) GetChapters(ctx context.Context, mangaID string) ([]scraper.Chapter, error) {
+	def := &s.def.Chapters
+
+	tmpl := def.URL
+	if tmpl == "" {
+		tmpl = s.def.Manga.URL
+	}
+
+	now := time.Now().UTC()
+	var chapters []scraper.Chapter
+	seen := make(map[string]bool)
+
+	err := s.paginate(ctx, tmpl, "", mangaID, def.Pagination, scraper.ErrMangaNotFound, func(doc *html.Node, pageURL *url.URL) int {
+		found := 0
+		for _, item := range def.Items.sel.MatchAll(doc) {
+			id := def.ID.value(item)
+			if id == "" || seen[id] {
+				continue
+			}
+			seen[id] = true
+			found++
+
+			title := def.Title.value(item)
+
+			var number float64
+			if def.Number.IsZero() {
+				number = parseChapterNumber(title)
+			} else {
+				number = parseChapterNumber(def.Number.value(item))
+			}
+
+			if title == "" {
+				title = fmt.Sprintf("Chapter %g", number)
+			}
+
+			link := resolveURL(pageURL, def.Link.value(item))
+			if link == "" {
+				link = s.expand(s.def.Pages.URL, "", id, 0)
+			}
+
+			chapters = append(chapters, scraper.Chapter{
+				ID:          id,
+				Title:       title,
+				Number:      number,
+				Volume:      def.Volume.value(item),
+				Language:    s.language(),
+				URL:         link,
+				PublishedAt: parseDate(def.Date.value(item), def.DateFormats, now),
+			})
+		}
+		return found
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return chapters, nil
+}
+
+// GetPages fetches all page URLs for a chapter.
+func (s *Source) GetPages(ctx context.Context, chapterID string) ([]scraper.Page, error) {
+	def := &s.def.Pages
+	var pages []scraper.Page
+	seen := make(map[string]bool)
+
+	err := s.paginate(ctx, def.URL, "", chapterID, def.Pagination, scraper.ErrChapterNotFound, func(doc *html.Node, pageURL *url.URL) int {
+		found := 0
+		for _, node := range def.Images.Selector.sel.MatchAll(doc) {
+			src := imageSource(node, def.Images)
+			if src == "" {
+				continue
+			}
+			u := resolveURL(pageURL, src)
+			if seen[u] {
+				continue
+			}
+			seen[u] = true
+			found++
+
+			pages = append(pages, scraper.Page{
+				Index:    len(pages) + 1,
+				URL:      u,
+				Filename: fmt.Sprintf("%03d%s", len(pages)+1, imageExt(u)),
+			})
+		}
+		return found
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return pages, nil
+}
+
+// paginate fetches the first page of a listing and keeps following further
+// pages as long as the pagination rules allow and pages yield new items.
+// handle returns the number of items on a page not seen on earlier pages, so
+// a site that ignores the page number stops after the second fetch.
+func (s *Source) paginate(ctx context.Context, tmpl, query, id string, p Pagination, notFound error, handle func(doc *html.Node, pageURL *url.URL) int) error {
+	start := p.Start
+	if start == 0 && strings.Contains(tmpl, "{page}") {
+		start = 1
+	}
+
+	maxPages := p.MaxPages
+	if maxPages == 0 {
+		maxPages = maxPagesLimit
+	}
+
+	next := s.expand(tmpl, query, id, start)
+	visited := make(map[string]bool)
+
+	for page := 0; page < maxPages && next != "" && !visited[next]; page++ {
+		if page > 0 {
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-time.After(200 * time.Millisecond):
+			}
+		}
+		visited[next] = true
+
+		doc, pageURL, err := s.fetch(ctx, next, notFound)
+		if err != nil {
+			if page > 0 && errors.Is(err, notFound) {
+				return nil
+			}
+			return err
+		}
+
+		if handle(doc, pageURL) == 0 {
+			return nil
+		}
+
+		switch {
+		case p.Next.sel != nil:
+			link := p.Next.sel.MatchFirst(doc)
+			if link == nil {
+				return nil
+			}
+			next = resolveURL(pageURL, attrValue(link, "href"))
+		case strings.Contains(tmpl, "{page}"):
+			next = s.expand(tmpl, query, id, start+page+1)
+		default:
+			return nil
+		}
+	}
+
+	return nil
+}
+
+// fetch downloads and parses an HTML document.
+func (s *Source) fetch(ctx context.Context, rawURL string, notFound error) (*html.Node, *url.URL, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, rawURL, nil)
+	if err != nil {
+		return nil, nil, fmt.Errorf("create request: %w", err)
+	}
+	req.Header.Set("User-Agent", userAgent)
+	for k, v := range s.def.Headers {
+		req.Header.Set(k, v)
+	}
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return nil, nil, fmt.Errorf("execute request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, nil, notFound
+	}
+
+	if resp.StatusCode == http.StatusTooManyRequests {
+		return nil, nil, scraper.ErrRateLimited
+	}
+
+	if resp.StatusCode >= http.StatusInternalServerError {
+		return nil, nil, fmt.Errorf("%w: status %d", scraper.ErrSourceUnavailable, resp.StatusCode)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, nil, fmt.Errorf("unexpected status: %d", resp.StatusCode)
+	}
+
+	doc, err := html.Parse(io.LimitReader(resp.Body, maxBodySize))
+	if err != nil {
+		return nil, nil, fmt.Errorf("parse document: %w", err)
+	}
+
+	return doc, resp.Request.URL, nil
+}
+
+// expand fills the placeholders of a URL template.
+func (s *Source) expand(tmpl, query, id string, page int) string {
+	return strings.NewReplacer(
+		"{baseUrl}", s.base.String(),
+		"{query}", query,
+		"{id}", id,
+		"{page}", strconv.Itoa(page),
+	).Replace(tmpl)
+}
+
+// language returns the language of chapters on this source when it
+// publishes in a single one.
+func (s *Source) language() string {
+	if len(s.def.Languages) == 1 {
+		return s.def.Languages[0]
+	}
+	return ""
+}
+
+// imageSource reads the image URL of a page element. Without an explicit
+// attribute the common lazy-loading attributes are tried before src.
+func imageSource(n *html.Node, f Field) string {
+	if f.Attr != "" {
+		return f.apply(read(n, f.Attr))
+	}
+
+	for _, attr := range []string{"data-src", "data-lazy-src", "data-original", "src"} {
+		if v := strings.TrimSpace(attrValue(n, attr)); v != "" && !strings.HasPrefix(v, "data:") {
+			return f.apply(v)
+		}
+	}
+	return ""
+}
+
+// imageExt returns the image extension of a URL, defaulting to ".jpg".
+func imageExt(rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return ".jpg"
+	}
+
+	ext := strings.ToLower(path.Ext(u.Path))
+	switch ext {
+	case ".jpg", ".jpeg", ".png", ".gif", ".webp", ".avif":
+		return ext
+	}
+	return ".jpg"
+}
+
+// lookupStatus maps a raw status through the definition's status map,
+// ignoring case.
+func lookupStatus(statusMap map[string]string, raw string) (string, bool) {
+	for k, v := range statusMap {
+		if strings.EqualFold(strings.TrimSpace(k), strings.TrimSpace(raw)) {
+			return v, true
+		}
+	}
+	return "", false
+}
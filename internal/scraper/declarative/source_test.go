@@ -0,0 +1,440 @@
+package declarative
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/andybalholm/cascadia"
+	"golang.org/x/net/html"
+	"gopkg.in/yaml.v3"
+
+	"github.com/mangashelf/mangashelf/internal/scraper"
+)
+
+// testDefinition is a complete definition for the site served by
+// newTestSite. {server} is replaced with the site's URL.
+const testDefinition = `
+id: test-site
+name: Test Site
+baseUrl: "{server}"
+languages: [en]
+search:
+  url: "{baseUrl}/search?q={query}&page={page}"
+  items: .result
+  id:
+    selector: a
+    attr: href
+    regex: /manga/([^/]+)
+  title: a
+  cover:
+    selector: img
+    attr: src
+  link:
+    selector: a
+    attr: href
+manga:
+  url: "{baseUrl}/manga/{id}"
+  title: h1
+  description: .synopsis
+  status: .status
+  statusMap:
+    Publishing: ongoing
+  author: .author
+  genres: .genre
+chapters:
+  items: li.chapter
+  id:
+    selector: a
+    attr: href
+    regex: /read/([^/]+)
+  title: a
+  date: .date
+  dateFormats: ["2006-01-02", relative]
+pages:
+  url: "{baseUrl}/read/{id}"
+  images: img.page
+`
+
+func loadTestDefinition(t *testing.T, server string) *Definition {
+	t.Helper()
+
+	var def Definition
+	if err := yaml.Unmarshal([]byte(strings.ReplaceAll(testDefinition, "{server}", server)), &def); err != nil {
+		t.Fatal(err)
+	}
+	return &def
+}
+
+// newTestSite serves a small manga site and returns a source for it.
+func newTestSite(t *testing.T) *Source {
+	t.Helper()
+
+	results := map[string]string{
+		"1": `<div class="result"><a href="/manga/op">One Piece</a><img src="/covers/op.jpg"></div>
+		      <div class="result"><a href="/manga/opx">One Piece Party</a></div>`,
+		"2": `<div class="result"><a href="/manga/op">One Piece</a></div>
+		      <div class="result"><a href="/manga/opz">One Piece Z</a></div>`,
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /search", func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("q") != "one piece" {
+			http.Error(w, "bad query", http.StatusBadRequest)
+			return
+		}
+		fmt.Fprint(w, results[r.URL.Query().Get("page")])
+	})
+	mux.HandleFunc("GET /manga/op", func(w http.ResponseWriter, _ *http.Request) {
+		fmt.Fprint(w, `<h1> One Piece </h1>
+			<p class="synopsis">Pirates.</p>
+			<span class="status">publishing</span>
+			<span class="author">Oda</span>
+			<a class="genre">Action</a><a class="genre">Adventure</a>
+			<ul>
+			  <li class="chapter"><a href="/read/op-2">Chapter 2.5</a><span class="date">2 days ago</span></li>
+			  <li class="chapter"><a href="/read/op-1">Ch. 1 - Romance Dawn</a><span class="date">2024-01-02</span></li>
+			  <li class="chapter"><a href="/read/op-1">Ch. 1 - Romance Dawn</a></li>
+			</ul>`)
+	})
+	mux.HandleFunc("GET /read/op-1", func(w http.ResponseWriter, _ *http.Request) {
+		fmt.Fprint(w, `<img class="page" src="data:image/gif;base64,R0lGOD" data-src="/img/1.png">
+			<img class="page" src="https://cdn.example.com/img/2">
+			<img class="page" src="/img/1.png">
+			<img class="ad" src="/ad.jpg">`)
+	})
+	mux.HandleFunc("GET /read/broken", func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusBadGateway)
+	})
+	srv := httptest.NewServer(mux)
+	t.Cleanup(srv.Close)
+
+	s, err := New(loadTestDefinition(t, srv.URL))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s
+}
+
+func TestSource(t *testing.T) {
+	s := newTestSite(t)
+	ctx := context.Background()
+	base := s.base.String()
+
+	t.Run("search", func(t *testing.T) {
+		results, err := s.Search(ctx, "one piece")
+		if err != nil {
+			t.Fatal(err)
+		}
+		want := []scraper.MangaResult{
+			{ID: "op", Title: "One Piece", CoverURL: base + "/covers/op.jpg", URL: base + "/manga/op"},
+			{ID: "opx", Title: "One Piece Party", URL: base + "/manga/opx"},
+			{ID: "opz", Title: "One Piece Z", URL: base + "/manga/opz"},
+		}
+		if !slices.Equal(results, want) {
+			t.Errorf("results = %+v, want %+v", results, want)
+		}
+	})
+
+	t.Run("manga", func(t *testing.T) {
+		manga, err := s.GetManga(ctx, "op")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if manga.Title != "One Piece" || manga.Description != "Pirates." || manga.Author != "Oda" {
+			t.Errorf("manga = %+v", manga)
+		}
+		if manga.Status != "ongoing" {
+			t.Errorf("status = %q, want the mapped status ongoing", manga.Status)
+		}
+		if !slices.Equal(manga.Genres, []string{"Action", "Adventure"}) {
+			t.Errorf("genres = %q", manga.Genres)
+		}
+		if manga.URL != base+"/manga/op" {
+			t.Errorf("URL = %q", manga.URL)
+		}
+
+		if _, err := s.GetManga(ctx, "missing"); !errors.Is(err, scraper.ErrMangaNotFound) {
+			t.Errorf("missing manga: err = %v, want ErrMangaNotFound", err)
+		}
+	})
+
+	t.Run("chapters", func(t *testing.T) {
+		chapters, err := s.GetChapters(ctx, "op")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(chapters) != 2 {
+			t.Fatalf("got %d chapters, want 2", len(chapters))
+		}
+
+		ch := chapters[0]
+		if ch.ID != "op-2" || ch.Number != 2.5 || ch.Language != "en" || ch.URL != base+"/read/op-2" {
+			t.Errorf("first chapter = %+v", ch)
+		}
+		if age := time.Since(ch.PublishedAt); age < 47*time.Hour || age > 49*time.Hour {
+			t.Errorf("relative date parsed as %v", ch.PublishedAt)
+		}
+
+		ch = chapters[1]
+		if ch.ID != "op-1" || ch.Number != 1 || ch.Title != "Ch. 1 - Romance Dawn" {
+			t.Errorf("second chapter = %+v", ch)
+		}
+		if !ch.PublishedAt.Equal(time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC)) {
+			t.Errorf("date = %v", ch.PublishedAt)
+		}
+	})
+
+	t.Run("pages", func(t *testing.T) {
+		pages, err := s.GetPages(ctx, "op-1")
+		if err != nil {
+			t.Fatal(err)
+		}
+		want := []scraper.Page{
+			{Index: 1, URL: base + "/img/1.png", Filename: "001.png"},
+			{Index: 2, URL: "https://cdn.example.com/img/2", Filename: "002.jpg"},
+		}
+		if !slices.Equal(pages, want) {
+			t.Errorf("pages = %+v, want %+v", pages, want)
+		}
+
+		if _, err := s.GetPages(ctx, "missing"); !errors.Is(err, scraper.ErrChapterNotFound) {
+			t.Errorf("missing chapter: err = %v, want ErrChapterNotFound", err)
+		}
+		if _, err := s.GetPages(ctx, "broken"); !errors.Is(err, scraper.ErrSourceUnavailable) {
+			t.Errorf("server error: err = %v, want ErrSourceUnavailable", err)
+		}
+	})
+}
+
+func TestPaginate(t *testing.T) {
+	// listing renders a page of items with an optional next link.
+	listing := func(next string, items ...string) string {
+		var sb strings.Builder
+		for _, item := range items {
+			fmt.Fprintf(&sb, `<li>%s</li>`, item)
+		}
+		if next != "" {
+			fmt.Fprintf(&sb, `<a class="next" href="%s">Next</a>`, next)
+		}
+		return sb.String()
+	}
+
+	tests := []struct {
+		name      string
+		tmpl      string
+		p         Pagination
+		serve     func(page int) (int, string)
+		wantPages []int
+		wantErr   error
+	}{
+		{
+			name: "single page without pagination",
+			tmpl: "{baseUrl}/list",
+			serve: func(int) (int, string) {
+				return http.StatusOK, listing("", "a", "b")
+			},
+			wantPages: []int{0},
+		},
+		{
+			name: "stops at a page without items",
+			tmpl: "{baseUrl}/list?page={page}",
+			serve: func(page int) (int, string) {
+				if page > 3 {
+					return http.StatusOK, listing("")
+				}
+				return http.StatusOK, listing("", strconv.Itoa(page))
+			},
+			wantPages: []int{1, 2, 3, 4},
+		},
+		{
+			name: "site ignoring the page number stops after the second fetch",
+			tmpl: "{baseUrl}/list?page={page}",
+			serve: func(int) (int, string) {
+				return http.StatusOK, listing("", "a", "b")
+			},
+			wantPages: []int{1, 2},
+		},
+		{
+			name: "stops at maxPages",
+			tmpl: "{baseUrl}/list?page={page}",
+			p:    Pagination{MaxPages: 2},
+			serve: func(page int) (int, string) {
+				return http.StatusOK, listing("", strconv.Itoa(page))
+			},
+			wantPages: []int{1, 2},
+		},
+		{
+			name: "starts at the configured page",
+			tmpl: "{baseUrl}/list?page={page}",
+			p:    Pagination{Start: 5, MaxPages: 2},
+			serve: func(page int) (int, string) {
+				return http.StatusOK, listing("", strconv.Itoa(page))
+			},
+			wantPages: []int{5, 6},
+		},
+		{
+			name: "follows next links until there is none",
+			tmpl: "{baseUrl}/list",
+			p:    Pagination{Next: Selector{Raw: "a.next"}},
+			serve: func(page int) (int, string) {
+				if page == 2 {
+					return http.StatusOK, listing("", "c")
+				}
+				return http.StatusOK, listing(fmt.Sprintf("/list?page=%d", page+1), strconv.Itoa(page))
+			},
+			wantPages: []int{0, 1, 2},
+		},
+		{
+			name: "next link back to a visited page stops",
+			tmpl: "{baseUrl}/list?page={page}",
+			p:    Pagination{Next: Selector{Raw: "a.next"}},
+			serve: func(page int) (int, string) {
+				return http.StatusOK, listing("?page=1", strconv.Itoa(page))
+			},
+			wantPages: []int{1},
+		},
+		{
+			name: "not found after the first page ends the listing",
+			tmpl: "{baseUrl}/list?page={page}",
+			serve: func(page int) (int, string) {
+				if page > 2 {
+					return http.StatusNotFound, ""
+				}
+				return http.StatusOK, listing("", strconv.Itoa(page))
+			},
+			wantPages: []int{1, 2, 3},
+		},
+		{
+			name: "not found on the first page is an error",
+			tmpl: "{baseUrl}/list?page={page}",
+			serve: func(int) (int, string) {
+				return http.StatusNotFound, ""
+			},
+			wantPages: []int{1},
+			wantErr:   scraper.ErrMangaNotFound,
+		},
+		{
+			name: "server error after the first page is an error",
+			tmpl: "{baseUrl}/list?page={page}",
+			serve: func(page int) (int, string) {
+				if page > 1 {
+					return http.StatusServiceUnavailable, ""
+				}
+				return http.StatusOK, listing("", "a")
+			},
+			wantPages: []int{1, 2},
+			wantErr:   scraper.ErrSourceUnavailable,
+		},
+	}
+
+	items := cascadia.MustCompile("li")
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var fetched []int
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				page, _ := strconv.Atoi(r.URL.Query().Get("page"))
+				fetched = append(fetched, page)
+				status, body := tt.serve(page)
+				w.WriteHeader(status)
+				fmt.Fprint(w, body)
+			}))
+			defer srv.Close()
+
+			s, err := New(loadTestDefinition(t, srv.URL))
+			if err != nil {
+				t.Fatal(err)
+			}
+			p := tt.p
+			v := &validator{}
+			v.pagination("pagination", &p)
+			if len(v.errs) > 0 {
+				t.Fatal(v.errs)
+			}
+
+			seen := make(map[string]bool)
+			err = s.paginate(context.Background(), tt.tmpl, "", "", p, scraper.ErrMangaNotFound, func(doc *html.Node, _ *url.URL) int {
+				found := 0
+				for _, item := range items.MatchAll(doc) {
+					if text := textContent(item); !seen[text] {
+						seen[text] = true
+						found++
+					}
+				}
+				return found
+			})
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("err = %v, want %v", err, tt.wantErr)
+			}
+			if !slices.Equal(fetched, tt.wantPages) {
+				t.Errorf("fetched pages %v, want %v", fetched, tt.wantPages)
+			}
+		})
+	}
+}
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		modify  func(d *Definition)
+		wantErr string
+	}{
+		{"valid", func(*Definition) {}, ""},
+		{"chapters URL is optional", func(d *Definition) { d.Chapters.URL = "" }, ""},
+		{"uppercase ID", func(d *Definition) { d.ID = "Test" }, "id: must be"},
+		{"missing name", func(d *Definition) { d.Name = "" }, "name: is required"},
+		{"relative base URL", func(d *Definition) { d.BaseURL = "/manga" }, "baseUrl: must be an absolute http(s) URL"},
+		{"non-HTTP base URL", func(d *Definition) { d.BaseURL = "ftp://example.com" }, "baseUrl: must be an absolute http(s) URL"},
+		{"missing search URL", func(d *Definition) { d.Search.URL = "" }, "search: url is required"},
+		{"search URL without query", func(d *Definition) { d.Search.URL = "{baseUrl}/all" }, "search: url must contain {query}"},
+		{"unknown placeholder", func(d *Definition) { d.Manga.URL = "{baseUrl}/{slug}/{id}" }, "manga: unknown placeholder {slug}"},
+		{"unterminated placeholder", func(d *Definition) { d.Pages.URL = "{baseUrl}/read/{id" }, "pages: unterminated placeholder"},
+		{"missing item selector", func(d *Definition) { d.Chapters.Items = Selector{} }, "chapters.items: selector is required"},
+		{"invalid selector", func(d *Definition) { d.Search.Items = Selector{Raw: "div["} }, "search.items: invalid selector"},
+		{"invalid field selector", func(d *Definition) { d.Manga.Title.Selector.Raw = "h1>>" }, "manga.title.selector: invalid selector"},
+		{"invalid regex", func(d *Definition) { d.Chapters.ID.Regex = "(" }, "chapters.id.regex:"},
+		{"missing required field", func(d *Definition) { d.Manga.Title = Field{} }, "manga.title: field is required"},
+		{"unknown mapped status", func(d *Definition) { d.Manga.StatusMap["Done"] = "finished" }, `manga.statusMap.Done: unknown status "finished"`},
+		{"date without formats", func(d *Definition) { d.Chapters.DateFormats = nil }, "chapters.dateFormats: required when chapters.date is set"},
+		{"negative maxPages", func(d *Definition) { d.Search.Pagination.MaxPages = -1 }, "search.pagination.maxPages: must not be negative"},
+		{"invalid next selector", func(d *Definition) { d.Pages.Pagination.Next.Raw = "a[" }, "pages.pagination.next: invalid selector"},
+		{"images without selector", func(d *Definition) { d.Pages.Images = Field{Attr: "data-src"} }, "pages.images: selector is required"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			def := loadTestDefinition(t, "https://example.com")
+			def.Chapters.URL = "{baseUrl}/manga/{id}/chapters"
+			tt.modify(def)
+
+			err := def.Validate()
+			switch {
+			case tt.wantErr == "" && err != nil:
+				t.Errorf("unexpected error: %v", err)
+			case tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)):
+				t.Errorf("err = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestValidateReportsEveryProblem(t *testing.T) {
+	err := (&Definition{}).Validate()
+	if err == nil {
+		t.Fatal("empty definition accepted")
+	}
+	for _, want := range []string{"id:", "name:", "baseUrl:", "search: url is required", "manga.title:", "pages.images:"} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("error does not mention %q:\n%v", want, err)
+		}
+	}
+}
@@ -1,6 +1,9 @@
 package scraper
 
-import "errors"
+import (
+	"errors"
+	"fmt"
+)
 
 var (
 	// ErrProviderNotFound is returned when a provider ID doesn't exist.
@@ -17,4 +20,26 @@ var (
 
 	// ErrSourceUnavailable is returned when the source is down.
 	ErrSourceUnavailable = errors.New("source unavailable")
+
+	// ErrUnsupportedURL is returned when no provider recognises a URL.
+	ErrUnsupportedURL = errors.New("unsupported url")
+
+	// ErrNotSupported is returned when a provider lacks an optional capability.
+	ErrNotSupported = errors.New("not supported by provider")
 )
+
+// SourceError wraps an error returned by a provider call, so callers can
+// tell failures of the source apart from local ones. Sentinel errors stay
+// reachable through errors.Is.
+type SourceError struct {
+	Provider string
+	Err      error
+}
+
+func (e *SourceError) Error() string {
+	return fmt.Sprintf("%s: %v", e.Provider, e.Err)
+}
+
+func (e *SourceError) Unwrap() error {
+	return e.Err
+}
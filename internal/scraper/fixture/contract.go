@@ -0,0 +1,206 @@
+package fixture
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"net/url"
+	"time"
+
+	"github.com/mangashelf/mangashelf/internal/scraper"
+)
+
+// validStatuses lists the manga statuses the library accepts.
+var validStatuses = map[string]bool{
+	"ongoing": true, "completed": true, "hiatus": true, "cancelled": true, "unknown": true,
+}
+
+// Scenario selects the calls made when checking a provider. Search runs
+// with Query; the manga and chapter default to the first search result and
+// the first chapter.
+type Scenario struct {
+	Query     string
+	MangaID   string
+	ChapterID string
+}
+
+// Step is the outcome of one provider call.
+type Step struct {
+	Name     string        `json:"name"`
+	Count    int           `json:"count"`
+	Duration time.Duration `json:"duration"`
+	Error    string        `json:"error,omitempty"`
+}
+
+// Report lists the calls made against a provider and every contract
+// violation found in their results.
+type Report struct {
+	Provider   string   `json:"provider"`
+	Steps      []Step   `json:"steps"`
+	Violations []string `json:"violations"`
+}
+
+// OK reports whether every call succeeded without violations.
+func (r *Report) OK() bool {
+	if len(r.Violations) > 0 {
+		return false
+	}
+	for _, s := range r.Steps {
+		if s.Error != "" {
+			return false
+		}
+	}
+	return true
+}
+
+func (r *Report) violation(format string, args ...any) {
+	r.Violations = append(r.Violations, fmt.Sprintf(format, args...))
+}
+
+func (r *Report) step(name string, start time.Time, count int, err error) bool {
+	s := Step{Name: name, Count: count, Duration: time.Since(start)}
+	if err != nil {
+		s.Error = err.Error()
+	}
+	r.Steps = append(r.Steps, s)
+	return err == nil
+}
+
+// Check runs the scenario against a provider and asserts the
+// scraper.Provider contract: IDs and titles are set, cover and page URLs are
+// absolute, chapter numbers are valid and page indexes increase.
+func Check(ctx context.Context, p scraper.Provider, s Scenario) *Report {
+	info := p.Info()
+	r := &Report{Provider: info.ID, Steps: []Step{}, Violations: []string{}}
+
+	if info.ID == "" {
+		r.violation("info: id is empty")
+	}
+	if info.Name == "" {
+		r.violation("info: name is empty")
+	}
+
+	mangaID := s.MangaID
+	if s.Query != "" {
+		start := time.Now()
+		results, err := p.Search(ctx, s.Query)
+		if r.step("search", start, len(results), err) {
+			r.checkResults(results)
+			if mangaID == "" && len(results) > 0 {
+				mangaID = results[0].ID
+			}
+		}
+	}
+	if mangaID == "" {
+		if s.Query != "" && len(r.Violations) == 0 {
+			r.violation("search: no results for %q", s.Query)
+		}
+		return r
+	}
+
+	start := time.Now()
+	manga, err := p.GetManga(ctx, mangaID)
+	if r.step("manga", start, 1, err) {
+		r.checkManga(manga)
+	}
+
+	start = time.Now()
+	chapters, err := p.GetChapters(ctx, mangaID)
+	chapterID := s.ChapterID
+	if r.step("chapters", start, len(chapters), err) {
+		r.checkChapters(chapters)
+		if chapterID == "" && len(chapters) > 0 {
+			chapterID = chapters[0].ID
+		}
+	}
+	if chapterID == "" {
+		return r
+	}
+
+	start = time.Now()
+	pages, err := p.GetPages(ctx, chapterID)
+	if r.step("pages", start, len(pages), err) {
+		r.checkPages(pages)
+	}
+
+	return r
+}
+
+func (r *Report) checkResults(results []scraper.MangaResult) {
+	for i, m := range results {
+		if m.ID == "" {
+			r.violation("search[%d]: id is empty", i)
+		}
+		if m.Title == "" {
+			r.violation("search[%d]: title is empty", i)
+		}
+		if m.CoverURL != "" && !isAbsoluteURL(m.CoverURL) {
+			r.violation("search[%d]: cover url %q is not absolute", i, m.CoverURL)
+		}
+	}
+}
+
+func (r *Report) checkManga(m *scraper.Manga) {
+	if m == nil {
+		r.violation("manga: nil result without error")
+		return
+	}
+	if m.ID == "" {
+		r.violation("manga: id is empty")
+	}
+	if m.Title == "" {
+		r.violation("manga: title is empty")
+	}
+	if m.CoverURL != "" && !isAbsoluteURL(m.CoverURL) {
+		r.violation("manga: cover url %q is not absolute", m.CoverURL)
+	}
+	if !validStatuses[m.Status] {
+		r.violation("manga: unknown status %q", m.Status)
+	}
+}
+
+func (r *Report) checkChapters(chapters []scraper.Chapter) {
+	seen := make(map[string]bool, len(chapters))
+	numbered := false
+
+	for i, ch := range chapters {
+		if ch.ID == "" {
+			r.violation("chapters[%d]: id is empty", i)
+		} else if seen[ch.ID] {
+			r.violation("chapters[%d]: duplicate id %q", i, ch.ID)
+		}
+		seen[ch.ID] = true
+
+		if math.IsNaN(ch.Number) || math.IsInf(ch.Number, 0) || ch.Number < 0 {
+			r.violation("chapters[%d]: invalid number %v", i, ch.Number)
+		}
+		if ch.Number > 0 {
+			numbered = true
+		}
+	}
+
+	if len(chapters) > 1 && !numbered {
+		r.violation("chapters: no chapter number could be parsed")
+	}
+}
+
+func (r *Report) checkPages(pages []scraper.Page) {
+	if len(pages) == 0 {
+		r.violation("pages: chapter has no pages")
+	}
+
+	for i, pg := range pages {
+		if i > 0 && pg.Index <= pages[i-1].Index {
+			r.violation("pages[%d]: index %d does not follow %d", i, pg.Index, pages[i-1].Index)
+		}
+		if !isAbsoluteURL(pg.URL) {
+			r.violation("pages[%d]: url %q is not absolute", i, pg.URL)
+		}
+	}
+}
+
+// isAbsoluteURL reports whether s parses as a URL with a scheme.
+func isAbsoluteURL(s string) bool {
+	u, err := url.Parse(s)
+	return err == nil && u.Scheme != ""
+}
@@ -0,0 +1,205 @@
+package fixture
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+	"unicode/utf8"
+)
+
+// ErrNoRecording is returned by a replayer for requests missing from the fixture.
+var ErrNoRecording = errors.New("no recorded response")
+
+// recordedHeaders lists the response headers kept in fixtures. Everything
+// else, cookies in particular, is dropped.
+var recordedHeaders = []string{"Content-Type", "Etag", "Last-Modified", "Location", "Retry-After"}
+
+// Fixture is a set of recorded HTTP exchanges.
+type Fixture struct {
+	Provider   string     `json:"provider"`
+	RecordedAt time.Time  `json:"recordedAt"`
+	Exchanges  []Exchange `json:"exchanges"`
+}
+
+// Exchange is one recorded request and its response. Bodies that are not
+// valid UTF-8 are stored base64 encoded.
+type Exchange struct {
+	Method       string            `json:"method"`
+	URL          string            `json:"url"`
+	Status       int               `json:"status"`
+	Headers      map[string]string `json:"headers,omitempty"`
+	Body         string            `json:"body"`
+	BodyEncoding string            `json:"bodyEncoding,omitempty"`
+}
+
+// Load reads a fixture file.
+func Load(path string) (*Fixture, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var f Fixture
+	if err := json.Unmarshal(data, &f); err != nil {
+		return nil, fmt.Errorf("decode fixture: %w", err)
+	}
+	return &f, nil
+}
+
+// Save writes the fixture to path, creating parent directories.
+func (f *Fixture) Save(path string) error {
+	data, err := json.MarshalIndent(f, "", "  ")
+	if err != nil {
+		return fmt.Errorf("encode fixture: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return fmt.Errorf("create fixture directory: %w", err)
+	}
+	return os.WriteFile(path, append(data, '\n'), 0o644)
+}
+
+// body returns the decoded response body.
+func (e *Exchange) body() ([]byte, error) {
+	if e.BodyEncoding == "base64" {
+		return base64.StdEncoding.DecodeString(e.Body)
+	}
+	return []byte(e.Body), nil
+}
+
+// Recorder is an http.RoundTripper that forwards requests to Next and keeps
+// every exchange.
+type Recorder struct {
+	Next http.RoundTripper
+
+	mu      sync.Mutex
+	fixture Fixture
+}
+
+// NewRecorder creates a recorder for a provider's traffic.
+func NewRecorder(providerID string, next http.RoundTripper) *Recorder {
+	if next == nil {
+		next = http.DefaultTransport
+	}
+	return &Recorder{
+		Next:    next,
+		fixture: Fixture{Provider: providerID, Exchanges: []Exchange{}},
+	}
+}
+
+// RoundTrip performs the request and records the exchange.
+func (r *Recorder) RoundTrip(req *http.Request) (*http.Response, error) {
+	resp, err := r.Next.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	resp.Body = io.NopCloser(bytes.NewReader(data))
+
+	ex := Exchange{
+		Method:  req.Method,
+		URL:     req.URL.String(),
+		Status:  resp.StatusCode,
+		Headers: make(map[string]string),
+	}
+	for _, h := range recordedHeaders {
+		if v := resp.Header.Get(h); v != "" {
+			ex.Headers[h] = v
+		}
+	}
+	if utf8.Valid(data) {
+		ex.Body = string(data)
+	} else {
+		ex.Body = base64.StdEncoding.EncodeToString(data)
+		ex.BodyEncoding = "base64"
+	}
+
+	r.mu.Lock()
+	r.fixture.Exchanges = append(r.fixture.Exchanges, ex)
+	r.mu.Unlock()
+
+	return resp, nil
+}
+
+// Fixture returns the exchanges recorded so far.
+func (r *Recorder) Fixture() *Fixture {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	f := r.fixture
+	f.RecordedAt = time.Now().UTC()
+	f.Exchanges = append([]Exchange(nil), r.fixture.Exchanges...)
+	return &f
+}
+
+// Replayer is an http.RoundTripper that answers requests from a fixture
+// without touching the network. Requests are matched by method and URL;
+// repeated requests get the recorded responses in order, and the last one
+// once those run out.
+type Replayer struct {
+	mu      sync.Mutex
+	fixture *Fixture
+	used    map[int]bool
+}
+
+// NewReplayer creates a replayer for a fixture.
+func NewReplayer(f *Fixture) *Replayer {
+	return &Replayer{fixture: f, used: make(map[int]bool)}
+}
+
+// RoundTrip answers the request from the fixture.
+func (r *Replayer) RoundTrip(req *http.Request) (*http.Response, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	url := req.URL.String()
+	match := -1
+	for i, ex := range r.fixture.Exchanges {
+		if ex.Method != req.Method || ex.URL != url {
+			continue
+		}
+		match = i
+		if !r.used[i] {
+			break
+		}
+	}
+	if match < 0 {
+		return nil, fmt.Errorf("%w for %s %s", ErrNoRecording, req.Method, url)
+	}
+	r.used[match] = true
+
+	ex := r.fixture.Exchanges[match]
+	body, err := ex.body()
+	if err != nil {
+		return nil, fmt.Errorf("decode recorded body: %w", err)
+	}
+
+	header := make(http.Header)
+	for k, v := range ex.Headers {
+		header.Set(k, v)
+	}
+
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", ex.Status, http.StatusText(ex.Status)),
+		StatusCode:    ex.Status,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        header,
+		Body:          io.NopCloser(bytes.NewReader(body)),
+		ContentLength: int64(len(body)),
+		Request:       req,
+	}, nil
+}
@@ -0,0 +1,44 @@
+package fixture
+
+import (
+	"net/http"
+
+	"github.com/mangashelf/mangashelf/internal/scraper"
+)
+
+// Session routes a provider's HTTP traffic through a recorder or a replayer
+// until it is stopped.
+type Session struct {
+	providerID string
+	path       string
+	recorder   *Recorder
+	previous   http.RoundTripper
+}
+
+// Record starts recording the provider's live traffic into path. Requests
+// still go through the transport installed before, such as a proxy.
+func Record(providerID, path string) *Session {
+	rec := NewRecorder(providerID, scraper.InstalledTransport(providerID))
+	prev := scraper.SetTransport(providerID, rec)
+	return &Session{providerID: providerID, path: path, recorder: rec, previous: prev}
+}
+
+// Replay starts answering the provider's requests from the fixture at path.
+func Replay(providerID, path string) (*Session, error) {
+	f, err := Load(path)
+	if err != nil {
+		return nil, err
+	}
+	prev := scraper.SetTransport(providerID, NewReplayer(f))
+	return &Session{providerID: providerID, path: path, previous: prev}, nil
+}
+
+// Stop restores the provider's transport. A recording session writes its
+// fixture file.
+func (s *Session) Stop() error {
+	scraper.SetTransport(s.providerID, s.previous)
+	if s.recorder == nil {
+		return nil
+	}
+	return s.recorder.Fixture().Save(s.path)
+}
@@ -0,0 +1,258 @@
+package scraper
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/url"
+	"sync"
+	"time"
+)
+
+// Circuit breaker states.
+const (
+	CircuitClosed   = "closed"
+	CircuitOpen     = "open"
+	CircuitHalfOpen = "half-open"
+)
+
+// latencyWeight is the weight of the newest sample in the latency average.
+const latencyWeight = 0.2
+
+// HealthChecker is implemented by providers with a cheap way to tell whether
+// the source is reachable. Providers without one are probed with a HEAD
+// request to their base URL.
+type HealthChecker interface {
+	HealthCheck(ctx context.Context) error
+}
+
+// HealthOptions configures failure tracking and the circuit breaker.
+type HealthOptions struct {
+	// FailureThreshold is the number of consecutive failures that opens
+	// the circuit.
+	FailureThreshold int
+	// OpenDuration is how long an open circuit rejects calls before one
+	// trial call is let through.
+	OpenDuration time.Duration
+	// ProbeInterval is how often every provider is health checked.
+	ProbeInterval time.Duration
+	// ProbeTimeout bounds a single health check.
+	ProbeTimeout time.Duration
+}
+
+// DefaultHealthOptions returns the settings used when none are configured.
+func DefaultHealthOptions() HealthOptions {
+	return HealthOptions{
+		FailureThreshold: 5,
+		OpenDuration:     time.Minute,
+		ProbeInterval:    5 * time.Minute,
+		ProbeTimeout:     10 * time.Second,
+	}
+}
+
+// ProviderHealth is a snapshot of a provider's call statistics and circuit state.
+type ProviderHealth struct {
+	Circuit             string     `json:"circuit"`
+	Healthy             bool       `json:"healthy"`
+	Requests            int64      `json:"requests"`
+	Failures            int64      `json:"failures"`
+	SuccessRate         float64    `json:"successRate"`
+	AvgLatencyMs        float64    `json:"avgLatencyMs"`
+	ConsecutiveFailures int        `json:"consecutiveFailures"`
+	LastError           string     `json:"lastError,omitempty"`
+	LastErrorAt         *time.Time `json:"lastErrorAt,omitempty"`
+	OpenedAt            *time.Time `json:"openedAt,omitempty"`
+	LastCheckAt         *time.Time `json:"lastCheckAt,omitempty"`
+	LastCheckError      string     `json:"lastCheckError,omitempty"`
+}
+
+// SourceStatus combines a provider's metadata with its health.
+type SourceStatus struct {
+	ProviderInfo
+	Health ProviderHealth `json:"health"`
+}
+
+// health tracks calls to one provider. Only availability failures, meaning
+// ErrSourceUnavailable and timeouts, count against the circuit; errors such
+// as ErrMangaNotFound show the source is answering.
+type health struct {
+	mu   sync.Mutex
+	opts HealthOptions
+
+	circuit      string
+	requests     int64
+	failures     int64
+	latency      float64
+	consecutive  int
+	trialPending bool
+
+	lastError      string
+	lastErrorAt    time.Time
+	openedAt       time.Time
+	lastCheckAt    time.Time
+	lastCheckError string
+}
+
+func newHealth(opts HealthOptions) *health {
+	return &health{opts: opts, circuit: CircuitClosed}
+}
+
+// allow reports whether a call may go through. An open circuit moves to
+// half-open once OpenDuration has passed and lets a single trial call through.
+func (h *health) allow(now time.Time) bool {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	switch h.circuit {
+	case CircuitOpen:
+		if now.Sub(h.openedAt) < h.opts.OpenDuration {
+			return false
+		}
+		h.circuit = CircuitHalfOpen
+		h.trialPending = true
+		return true
+	case CircuitHalfOpen:
+		if h.trialPending {
+			return false
+		}
+		h.trialPending = true
+		return true
+	}
+	return true
+}
+
+// record stores the outcome of a call and reports whether it opened the
+// circuit. A cancelled call says nothing about the source; it only frees the
+// half-open trial for another call.
+func (h *health) record(now time.Time, elapsed time.Duration, err error) bool {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if errors.Is(err, context.Canceled) {
+		h.trialPending = false
+		return false
+	}
+
+	h.requests++
+	ms := float64(elapsed) / float64(time.Millisecond)
+	if h.requests == 1 {
+		h.latency = ms
+	} else {
+		h.latency += latencyWeight * (ms - h.latency)
+	}
+
+	if isAvailabilityError(err) {
+		h.failures++
+		wasOpen := h.circuit == CircuitOpen
+		h.fail(now, err)
+		return !wasOpen && h.circuit == CircuitOpen
+	}
+	h.succeed()
+	return false
+}
+
+// recordCheck stores the outcome of a health check and reports whether it
+// opened the circuit. A successful check closes the circuit. A check that
+// finds the source unavailable counts towards FailureThreshold like a failed
+// call; other errors, such as a missing probe manga, leave the circuit as is.
+func (h *health) recordCheck(now time.Time, err error) bool {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	switch {
+	case errors.Is(err, context.Canceled):
+		return false
+	case err == nil:
+		h.lastCheckAt = now
+		h.lastCheckError = ""
+		h.succeed()
+		return false
+	}
+
+	h.lastCheckAt = now
+	h.lastCheckError = err.Error()
+	if !isAvailabilityError(err) || h.circuit == CircuitOpen {
+		return false
+	}
+	h.fail(now, err)
+	return h.circuit == CircuitOpen
+}
+
+func (h *health) fail(now time.Time, err error) {
+	h.consecutive++
+	h.lastError = err.Error()
+	h.lastErrorAt = now
+
+	if h.circuit == CircuitHalfOpen || h.consecutive >= h.opts.FailureThreshold {
+		h.open(now)
+	}
+}
+
+func (h *health) succeed() {
+	h.consecutive = 0
+	h.circuit = CircuitClosed
+	h.trialPending = false
+}
+
+func (h *health) open(now time.Time) {
+	h.circuit = CircuitOpen
+	h.openedAt = now
+	h.trialPending = false
+}
+
+// snapshot returns the current statistics.
+func (h *health) snapshot() ProviderHealth {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	s := ProviderHealth{
+		Circuit:             h.circuit,
+		Healthy:             h.circuit == CircuitClosed,
+		Requests:            h.requests,
+		Failures:            h.failures,
+		SuccessRate:         1,
+		AvgLatencyMs:        h.latency,
+		ConsecutiveFailures: h.consecutive,
+		LastError:           h.lastError,
+		LastErrorAt:         timePtr(h.lastErrorAt),
+		LastCheckAt:         timePtr(h.lastCheckAt),
+		LastCheckError:      h.lastCheckError,
+	}
+	if h.requests > 0 {
+		s.SuccessRate = float64(h.requests-h.failures) / float64(h.requests)
+	}
+	if h.circuit != CircuitClosed {
+		s.OpenedAt = timePtr(h.openedAt)
+	}
+	return s
+}
+
+// isAvailabilityError reports whether err means the source could not be
+// reached. Besides timeouts this covers the transport errors returned by
+// http.Client, such as refused connections and DNS or TLS failures.
+func isAvailabilityError(err error) bool {
+	if err == nil {
+		return false
+	}
+	if errors.Is(err, ErrSourceUnavailable) || errors.Is(err, context.DeadlineExceeded) {
+		return true
+	}
+	var urlErr *url.Error
+	if errors.As(err, &urlErr) && urlErr.Op != "parse" {
+		return true
+	}
+	var opErr *net.OpError
+	if errors.As(err, &opErr) {
+		return true
+	}
+	var netErr net.Error
+	return errors.As(err, &netErr) && netErr.Timeout()
+}
+
+func timePtr(t time.Time) *time.Time {
+	if t.IsZero() {
+		return nil
+	}
+	t = t.UTC()
+	return &t
+}
@@ -0,0 +1,527 @@
+package local
+
+import (
+	"archive/zip"
+	"context"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/mangashelf/mangashelf/internal/scraper"
+)
+
+// urlScheme is used for page and cover URLs served from the local directory.
+const urlScheme = "local"
+
+var (
+	imageExts   = map[string]bool{".jpg": true, ".jpeg": true, ".png": true, ".gif": true, ".webp": true, ".avif": true, ".bmp": true}
+	archiveExts = map[string]bool{".cbz": true, ".zip": true}
+	coverNames  = map[string]bool{"cover": true, "folder": true, "poster": true}
+
+	chapterNumberPattern = regexp.MustCompile(`(?i)(?:chapter|ch|c|#)\.?\s*(\d+(?:\.\d+)?)`)
+	volumePattern        = regexp.MustCompile(`(?i)\bv(?:ol(?:ume)?)?\.?\s*(\d+)`)
+	numberPattern        = regexp.MustCompile(`\d+(?:\.\d+)?`)
+	digitsPattern        = regexp.MustCompile(`\d+|\D+`)
+)
+
+// Local implements the scraper.Provider interface on top of a directory tree.
+// Every folder directly below the root is a series. Its chapters are
+// sub-folders of images or CBZ archives, and series metadata is read from
+// series.json or ComicInfo.xml when present.
+type Local struct {
+	root string
+}
+
+// New creates a provider serving the series below root.
+func New(root string) *Local {
+	return &Local{root: root}
+}
+
+// Info returns provider metadata.
+func (l *Local) Info() scraper.ProviderInfo {
+	return scraper.ProviderInfo{
+		ID:        "local",
+		Name:      "Local Library",
+		BaseURL:   "",
+		Languages: []string{},
+		IsNSFW:    false,
+	}
+}
+
+// Search finds series whose folder name or title contains the query.
+func (l *Local) Search(ctx context.Context, query string) ([]scraper.MangaResult, error) {
+	entries, err := os.ReadDir(l.root)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", scraper.ErrSourceUnavailable, err)
+	}
+
+	query = strings.ToLower(strings.TrimSpace(query))
+	results := make([]scraper.MangaResult, 0)
+
+	for _, entry := range entries {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		if !entry.IsDir() || isHidden(entry.Name()) {
+			continue
+		}
+
+		manga := l.readSeries(entry.Name())
+		if !strings.Contains(strings.ToLower(manga.Title), query) &&
+			!strings.Contains(strings.ToLower(entry.Name()), query) {
+			continue
+		}
+
+		results = append(results, scraper.MangaResult{
+			ID:       manga.ID,
+			Title:    manga.Title,
+			CoverURL: manga.CoverURL,
+			URL:      manga.URL,
+		})
+	}
+
+	return results, nil
+}
+
+// GetManga reads the details of a series folder.
+func (l *Local) GetManga(ctx context.Context, id string) (*scraper.Manga, error) {
+	dir, err := l.resolve(id)
+	if err != nil || strings.Contains(id, "/") {
+		return nil, scraper.ErrMangaNotFound
+	}
+	if info, err := os.Stat(dir); err != nil || !info.IsDir() {
+		return nil, scraper.ErrMangaNotFound
+	}
+
+	manga := l.readSeries(id)
+
+	if manga.CoverURL == "" {
+		chapters, err := l.GetChapters(ctx, id)
+		if err == nil && len(chapters) > 0 {
+			if pages, err := l.GetPages(ctx, chapters[0].ID); err == nil && len(pages) > 0 {
+				manga.CoverURL = pages[0].URL
+			}
+		}
+	}
+
+	return manga, nil
+}
+
+// GetChapters lists the chapter folders and archives of a series.
+func (l *Local) GetChapters(ctx context.Context, mangaID string) ([]scraper.Chapter, error) {
+	dir, err := l.resolve(mangaID)
+	if err != nil || strings.Contains(mangaID, "/") {
+		return nil, scraper.ErrMangaNotFound
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, scraper.ErrMangaNotFound
+	}
+
+	chapters := make([]scraper.Chapter, 0, len(entries))
+	for _, entry := range entries {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		if isHidden(entry.Name()) {
+			continue
+		}
+
+		chapter, ok := l.readChapter(mangaID, entry)
+		if ok {
+			chapters = append(chapters, chapter)
+		}
+	}
+
+	sort.SliceStable(chapters, func(i, j int) bool {
+		if chapters[i].Number != chapters[j].Number {
+			return chapters[i].Number < chapters[j].Number
+		}
+		return naturalLess(chapters[i].ID, chapters[j].ID)
+	})
+
+	return chapters, nil
+}
+
+// GetPages lists the images of a chapter folder or archive in reading order.
+func (l *Local) GetPages(_ context.Context, chapterID string) ([]scraper.Page, error) {
+	p, err := l.resolve(chapterID)
+	if err != nil {
+		return nil, scraper.ErrChapterNotFound
+	}
+
+	info, err := os.Stat(p)
+	if err != nil {
+		return nil, scraper.ErrChapterNotFound
+	}
+
+	var names []string
+	if info.IsDir() {
+		names, err = listImageFiles(p)
+	} else {
+		names, err = listArchiveImages(p)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("list pages: %w", err)
+	}
+
+	pages := make([]scraper.Page, 0, len(names))
+	for i, name := range names {
+		pageURL := l.url(chapterID+"/"+name, "")
+		if !info.IsDir() {
+			pageURL = l.url(chapterID, name)
+		}
+
+		pages = append(pages, scraper.Page{
+			Index:    i + 1,
+			URL:      pageURL,
+			Filename: path.Base(name),
+		})
+	}
+
+	return pages, nil
+}
+
+// Open reads a page or cover previously returned by this provider.
+func (l *Local) Open(_ context.Context, rawURL string) (io.ReadCloser, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil || u.Scheme != urlScheme {
+		return nil, fmt.Errorf("not a local url: %s", rawURL)
+	}
+
+	p, err := l.resolve(strings.TrimPrefix(u.Path, "/"))
+	if err != nil {
+		return nil, err
+	}
+
+	if u.Fragment == "" {
+		return os.Open(p)
+	}
+
+	zr, err := zip.OpenReader(p)
+	if err != nil {
+		return nil, fmt.Errorf("open archive: %w", err)
+	}
+	for _, f := range zr.File {
+		if f.Name != u.Fragment {
+			continue
+		}
+		rc, err := f.Open()
+		if err != nil {
+			zr.Close()
+			return nil, fmt.Errorf("open archive entry: %w", err)
+		}
+		return &archiveEntry{ReadCloser: rc, archive: zr}, nil
+	}
+	zr.Close()
+
+	return nil, fmt.Errorf("archive entry not found: %s", u.Fragment)
+}
+
+// HealthCheck reports whether the library folder is readable.
+func (l *Local) HealthCheck(_ context.Context) error {
+	info, err := os.Stat(l.root)
+	if err != nil {
+		return fmt.Errorf("%w: %v", scraper.ErrSourceUnavailable, err)
+	}
+	if !info.IsDir() {
+		return fmt.Errorf("%w: %s is not a directory", scraper.ErrSourceUnavailable, l.root)
+	}
+	return nil
+}
+
+// readSeries builds series details from the folder name and any metadata files.
+func (l *Local) readSeries(id string) *scraper.Manga {
+	dir := filepath.Join(l.root, id)
+	manga := &scraper.Manga{
+		ID:     id,
+		Title:  id,
+		Status: "unknown",
+		URL:    l.url(id, ""),
+	}
+
+	if data, err := os.ReadFile(filepath.Join(dir, "series.json")); err == nil {
+		if meta, err := parseSeriesJSON(data); err == nil {
+			if meta.Name != "" {
+				manga.Title = meta.Name
+			}
+			manga.Description = meta.DescriptionText
+			if manga.Description == "" {
+				manga.Description = meta.DescriptionFormatted
+			}
+			manga.Status = scraper.NormalizeStatus(meta.Status)
+		}
+	}
+
+	if info := l.seriesComicInfo(dir); info != nil {
+		if info.Series != "" && manga.Title == id {
+			manga.Title = info.Series
+		}
+		if manga.Description == "" {
+			manga.Description = info.Summary
+		}
+		manga.Author = info.Writer
+		manga.Artist = info.Penciller
+		manga.Genres = splitList(info.Genre)
+		manga.Tags = splitList(info.Tags)
+	}
+
+	if entries, err := os.ReadDir(dir); err == nil {
+		for _, entry := range entries {
+			ext := strings.ToLower(filepath.Ext(entry.Name()))
+			base := strings.ToLower(strings.TrimSuffix(entry.Name(), filepath.Ext(entry.Name())))
+			if !entry.IsDir() && imageExts[ext] && coverNames[base] {
+				manga.CoverURL = l.url(id+"/"+entry.Name(), "")
+				break
+			}
+		}
+	}
+
+	return manga
+}
+
+// seriesComicInfo returns series level ComicInfo from the folder root, or
+// from the first archive that carries one.
+func (l *Local) seriesComicInfo(dir string) *comicInfo {
+	if data, err := os.ReadFile(filepath.Join(dir, "ComicInfo.xml")); err == nil {
+		if info, err := parseComicInfo(data); err == nil {
+			return info
+		}
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil
+	}
+	for _, entry := range entries {
+		if entry.IsDir() || !archiveExts[strings.ToLower(filepath.Ext(entry.Name()))] {
+			continue
+		}
+		if info := archiveComicInfo(filepath.Join(dir, entry.Name())); info != nil {
+			return info
+		}
+	}
+	return nil
+}
+
+// readChapter describes a chapter folder or archive. It reports false for
+// entries that are neither.
+func (l *Local) readChapter(mangaID string, entry os.DirEntry) (scraper.Chapter, bool) {
+	p := filepath.Join(l.root, mangaID, entry.Name())
+	id := mangaID + "/" + entry.Name()
+	name := strings.TrimSuffix(entry.Name(), filepath.Ext(entry.Name()))
+
+	var pageCount int
+	var info *comicInfo
+
+	if entry.IsDir() {
+		images, err := listImageFiles(p)
+		if err != nil || len(images) == 0 {
+			return scraper.Chapter{}, false
+		}
+		pageCount = len(images)
+		if data, err := os.ReadFile(filepath.Join(p, "ComicInfo.xml")); err == nil {
+			info, _ = parseComicInfo(data)
+		}
+	} else {
+		if !archiveExts[strings.ToLower(filepath.Ext(entry.Name()))] {
+			return scraper.Chapter{}, false
+		}
+		images, err := listArchiveImages(p)
+		if err != nil || len(images) == 0 {
+			return scraper.Chapter{}, false
+		}
+		pageCount = len(images)
+		info = archiveComicInfo(p)
+	}
+
+	chapter := scraper.Chapter{
+		ID:        id,
+		Title:     name,
+		Number:    parseChapterNumber(name),
+		Volume:    parseVolume(name),
+		URL:       l.url(id, ""),
+		PageCount: pageCount,
+	}
+
+	if fi, err := entry.Info(); err == nil {
+		chapter.PublishedAt = fi.ModTime().UTC()
+	}
+
+	if info != nil {
+		if info.Title != "" {
+			chapter.Title = info.Title
+		}
+		if n, err := strconv.ParseFloat(strings.TrimSpace(info.Number), 64); err == nil {
+			chapter.Number = n
+		}
+		if info.Volume != "" {
+			chapter.Volume = info.Volume
+		}
+		if t := info.publishedAt(); !t.IsZero() {
+			chapter.PublishedAt = t
+		}
+		chapter.Language = info.LanguageISO
+		if info.ScanInformation != "" {
+			chapter.Groups = []scraper.ScanlationGroup{{ID: info.ScanInformation, Name: info.ScanInformation}}
+		}
+	}
+
+	return chapter, true
+}
+
+// resolve maps a slash separated path relative to the root onto the file
+// system. Cleaning it as an absolute path keeps ".." from escaping the root.
+func (l *Local) resolve(rel string) (string, error) {
+	clean := path.Clean("/" + rel)
+	if clean == "/" {
+		return "", fmt.Errorf("invalid local path: %q", rel)
+	}
+	return filepath.Join(l.root, filepath.FromSlash(clean)), nil
+}
+
+// url builds a local URL for a path relative to the root, optionally
+// pointing at an entry inside an archive.
+func (l *Local) url(rel, entry string) string {
+	u := url.URL{Scheme: urlScheme, Path: "/" + rel, Fragment: entry}
+	return u.String()
+}
+
+// archiveEntry closes the archive together with the entry being read.
+type archiveEntry struct {
+	io.ReadCloser
+	archive *zip.ReadCloser
+}
+
+func (e *archiveEntry) Close() error {
+	err := e.ReadCloser.Close()
+	if cerr := e.archive.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
+
+// listImageFiles returns the image files of a folder in natural order.
+func listImageFiles(dir string) ([]string, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	var names []string
+	for _, entry := range entries {
+		if !entry.IsDir() && !isHidden(entry.Name()) && imageExts[strings.ToLower(filepath.Ext(entry.Name()))] {
+			names = append(names, entry.Name())
+		}
+	}
+	sort.Slice(names, func(i, j int) bool { return naturalLess(names[i], names[j]) })
+	return names, nil
+}
+
+// listArchiveImages returns the image entries of a CBZ archive in natural order.
+func listArchiveImages(archive string) ([]string, error) {
+	zr, err := zip.OpenReader(archive)
+	if err != nil {
+		return nil, err
+	}
+	defer zr.Close()
+
+	var names []string
+	for _, f := range zr.File {
+		if f.FileInfo().IsDir() || isHidden(path.Base(f.Name)) {
+			continue
+		}
+		if imageExts[strings.ToLower(path.Ext(f.Name))] {
+			names = append(names, f.Name)
+		}
+	}
+	sort.Slice(names, func(i, j int) bool { return naturalLess(names[i], names[j]) })
+	return names, nil
+}
+
+// archiveComicInfo reads ComicInfo.xml from a CBZ archive, if present.
+func archiveComicInfo(archive string) *comicInfo {
+	zr, err := zip.OpenReader(archive)
+	if err != nil {
+		return nil
+	}
+	defer zr.Close()
+
+	for _, f := range zr.File {
+		if !strings.EqualFold(path.Base(f.Name), "ComicInfo.xml") {
+			continue
+		}
+		rc, err := f.Open()
+		if err != nil {
+			return nil
+		}
+		data, err := io.ReadAll(rc)
+		rc.Close()
+		if err != nil {
+			return nil
+		}
+		info, err := parseComicInfo(data)
+		if err != nil {
+			return nil
+		}
+		return info
+	}
+	return nil
+}
+
+// parseChapterNumber extracts a chapter number from a file or folder name.
+func parseChapterNumber(name string) float64 {
+	if m := chapterNumberPattern.FindStringSubmatch(name); m != nil {
+		n, _ := strconv.ParseFloat(m[1], 64)
+		return n
+	}
+
+	rest := volumePattern.ReplaceAllString(name, "")
+	if m := numberPattern.FindString(rest); m != "" {
+		n, _ := strconv.ParseFloat(m, 64)
+		return n
+	}
+	return 0
+}
+
+// parseVolume extracts a volume number from a file or folder name.
+func parseVolume(name string) string {
+	if m := volumePattern.FindStringSubmatch(name); m != nil {
+		if v := strings.TrimLeft(m[1], "0"); v != "" {
+			return v
+		}
+		return "0"
+	}
+	return ""
+}
+
+// naturalLess orders strings so that embedded numbers compare numerically,
+// e.g. "page2" before "page10".
+func naturalLess(a, b string) bool {
+	ca, cb := digitsPattern.FindAllString(a, -1), digitsPattern.FindAllString(b, -1)
+	for i := 0; i < len(ca) && i < len(cb); i++ {
+		if ca[i] == cb[i] {
+			continue
+		}
+		na, errA := strconv.ParseFloat(ca[i], 64)
+		nb, errB := strconv.ParseFloat(cb[i], 64)
+		if errA == nil && errB == nil && na != nb {
+			return na < nb
+		}
+		return ca[i] < cb[i]
+	}
+	return len(ca) < len(cb)
+}
+
+// isHidden reports whether a file name is a dotfile.
+func isHidden(name string) bool {
+	return strings.HasPrefix(name, ".")
+}
@@ -0,0 +1,84 @@
+package local
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"strings"
+	"time"
+)
+
+// comicInfo is the subset of the ComicInfo.xml schema used by the provider.
+type comicInfo struct {
+	XMLName         xml.Name `xml:"ComicInfo"`
+	Title           string   `xml:"Title"`
+	Series          string   `xml:"Series"`
+	Number          string   `xml:"Number"`
+	Volume          string   `xml:"Volume"`
+	Summary         string   `xml:"Summary"`
+	Year            int      `xml:"Year"`
+	Month           int      `xml:"Month"`
+	Day             int      `xml:"Day"`
+	Writer          string   `xml:"Writer"`
+	Penciller       string   `xml:"Penciller"`
+	Genre           string   `xml:"Genre"`
+	Tags            string   `xml:"Tags"`
+	LanguageISO     string   `xml:"LanguageISO"`
+	ScanInformation string   `xml:"ScanInformation"`
+}
+
+// seriesJSON is the Mylar series.json format written by many library tools.
+type seriesJSON struct {
+	Metadata seriesMetadata `json:"metadata"`
+}
+
+type seriesMetadata struct {
+	Name                 string `json:"name"`
+	DescriptionText      string `json:"description_text"`
+	DescriptionFormatted string `json:"description_formatted"`
+	Status               string `json:"status"`
+	Publisher            string `json:"publisher"`
+}
+
+// parseComicInfo decodes a ComicInfo.xml document.
+func parseComicInfo(data []byte) (*comicInfo, error) {
+	var info comicInfo
+	if err := xml.Unmarshal(data, &info); err != nil {
+		return nil, err
+	}
+	return &info, nil
+}
+
+// parseSeriesJSON decodes a series.json document.
+func parseSeriesJSON(data []byte) (*seriesMetadata, error) {
+	var series seriesJSON
+	if err := json.Unmarshal(data, &series); err != nil {
+		return nil, err
+	}
+	return &series.Metadata, nil
+}
+
+// publishedAt returns the release date recorded in ComicInfo, if any.
+func (c *comicInfo) publishedAt() time.Time {
+	if c.Year == 0 {
+		return time.Time{}
+	}
+	month, day := c.Month, c.Day
+	if month == 0 {
+		month = 1
+	}
+	if day == 0 {
+		day = 1
+	}
+	return time.Date(c.Year, time.Month(month), day, 0, 0, 0, 0, time.UTC)
+}
+
+// splitList splits a comma separated ComicInfo list field.
+func splitList(s string) []string {
+	var values []string
+	for _, v := range strings.Split(s, ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			values = append(values, v)
+		}
+	}
+	return values
+}
@@ -3,23 +3,60 @@ package scraper
 import (
 	"context"
 	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
 	"sync"
+	"time"
 
 	"github.com/rs/zerolog"
 )
 
 // Manager handles registration and access to manga providers.
 type Manager struct {
-	providers map[string]Provider
-	mu        sync.RWMutex
-	log       zerolog.Logger
+	providers  map[string]Provider
+	health     map[string]*health
+	healthOpts HealthOptions
+	mu         sync.RWMutex
+	log        zerolog.Logger
 }
 
 // NewManager creates a new scraper manager.
 func NewManager(log zerolog.Logger) *Manager {
 	return &Manager{
-		providers: make(map[string]Provider),
-		log:       log.With().Str("component", "scraper").Logger(),
+		providers:  make(map[string]Provider),
+		health:     make(map[string]*health),
+		healthOpts: DefaultHealthOptions(),
+		log:        log.With().Str("component", "scraper").Logger(),
+	}
+}
+
+// SetHealthOptions replaces the circuit breaker settings. Zero values keep
+// the defaults.
+func (m *Manager) SetHealthOptions(opts HealthOptions) {
+	defaults := DefaultHealthOptions()
+	if opts.FailureThreshold <= 0 {
+		opts.FailureThreshold = defaults.FailureThreshold
+	}
+	if opts.OpenDuration <= 0 {
+		opts.OpenDuration = defaults.OpenDuration
+	}
+	if opts.ProbeInterval <= 0 {
+		opts.ProbeInterval = defaults.ProbeInterval
+	}
+	if opts.ProbeTimeout <= 0 {
+		opts.ProbeTimeout = defaults.ProbeTimeout
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.healthOpts = opts
+	for _, h := range m.health {
+		h.mu.Lock()
+		h.opts = opts
+		h.mu.Unlock()
 	}
 }
 
@@ -30,6 +67,7 @@ func (m *Manager) Register(provider Provider) {
 
 	info := provider.Info()
 	m.providers[info.ID] = provider
+	m.health[info.ID] = newHealth(m.healthOpts)
 	m.log.Info().Str("provider", info.ID).Str("name", info.Name).Msg("registered provider")
 }
 
@@ -40,7 +78,7 @@ func (m *Manager) Get(id string) (Provider, error) {
 
 	provider, ok := m.providers[id]
 	if !ok {
-		return nil, fmt.Errorf("provider not found: %s", id)
+		return nil, fmt.Errorf("%w: %s", ErrProviderNotFound, id)
 	}
 	return provider, nil
 }
@@ -57,38 +95,280 @@ func (m *Manager) List() []ProviderInfo {
 	return infos
 }
 
-// Search searches for manga using the specified provider.
-func (m *Manager) Search(ctx context.Context, providerID, query string) ([]MangaResult, error) {
+// Statuses returns info and health for all registered providers, ordered by ID.
+func (m *Manager) Statuses() []SourceStatus {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	statuses := make([]SourceStatus, 0, len(m.providers))
+	for id, p := range m.providers {
+		statuses = append(statuses, SourceStatus{
+			ProviderInfo: p.Info(),
+			Health:       m.health[id].snapshot(),
+		})
+	}
+	sort.Slice(statuses, func(i, j int) bool { return statuses[i].ID < statuses[j].ID })
+	return statuses
+}
+
+// call runs fn against a provider through its circuit breaker and records
+// the outcome. While the circuit is open calls fail at once with
+// ErrSourceUnavailable instead of waiting for the source to time out. Errors
+// from fn are wrapped in a SourceError.
+func (m *Manager) call(providerID string, fn func(Provider) error) error {
 	provider, err := m.Get(providerID)
 	if err != nil {
-		return nil, err
+		return err
 	}
-	return provider.Search(ctx, query)
+
+	m.mu.RLock()
+	h := m.health[providerID]
+	m.mu.RUnlock()
+
+	if !h.allow(time.Now()) {
+		return fmt.Errorf("%w: %s circuit is open", ErrSourceUnavailable, providerID)
+	}
+
+	start := time.Now()
+	err = fn(provider)
+	if opened := h.record(time.Now(), time.Since(start), err); opened {
+		m.log.Warn().Err(err).Str("provider", providerID).Msg("provider unavailable, circuit opened")
+	}
+	if err != nil {
+		return &SourceError{Provider: providerID, Err: err}
+	}
+	return nil
 }
 
-// GetManga fetches manga details from the specified provider.
-func (m *Manager) GetManga(ctx context.Context, providerID, mangaID string) (*Manga, error) {
-	provider, err := m.Get(providerID)
+// RunHealthChecks probes every provider at the configured interval until ctx
+// is cancelled. A successful probe closes an open circuit.
+func (m *Manager) RunHealthChecks(ctx context.Context) {
+	m.mu.RLock()
+	interval := m.healthOpts.ProbeInterval
+	m.mu.RUnlock()
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			m.CheckHealth(ctx)
+		}
+	}
+}
+
+// CheckHealth probes every provider once.
+func (m *Manager) CheckHealth(ctx context.Context) {
+	m.mu.RLock()
+	providers := make(map[string]Provider, len(m.providers))
+	for id, p := range m.providers {
+		providers[id] = p
+	}
+	timeout := m.healthOpts.ProbeTimeout
+	m.mu.RUnlock()
+
+	var wg sync.WaitGroup
+	for id, p := range providers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			probeCtx, cancel := context.WithTimeout(ctx, timeout)
+			defer cancel()
+
+			ok, err := m.probe(probeCtx, p)
+			if !ok || ctx.Err() != nil {
+				return
+			}
+
+			m.mu.RLock()
+			h := m.health[id]
+			m.mu.RUnlock()
+
+			wasOpen := h.snapshot().Circuit != CircuitClosed
+			opened := h.recordCheck(time.Now(), err)
+
+			switch {
+			case opened:
+				m.log.Warn().Err(err).Str("provider", id).Msg("provider unavailable, circuit opened")
+			case err != nil:
+				m.log.Warn().Err(err).Str("provider", id).Msg("health check failed")
+			case wasOpen:
+				m.log.Info().Str("provider", id).Msg("provider recovered, circuit closed")
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+// probe runs a provider's health check, falling back to a HEAD request to
+// its base URL. It reports false when the provider cannot be probed.
+func (m *Manager) probe(ctx context.Context, p Provider) (bool, error) {
+	if hc, ok := p.(HealthChecker); ok {
+		return true, hc.HealthCheck(ctx)
+	}
+
+	info := p.Info()
+	baseURL := info.BaseURL
+	if baseURL == "" {
+		return false, nil
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodHead, baseURL, nil)
 	if err != nil {
-		return nil, err
+		return false, nil
+	}
+	req.Header.Set("User-Agent", "MangaShelf/1.0")
+
+	resp, err := NewHTTPClient(info.ID, 0).Do(req)
+	if err != nil {
+		return true, fmt.Errorf("%w: %v", ErrSourceUnavailable, err)
 	}
-	return provider.GetManga(ctx, mangaID)
+	resp.Body.Close()
+
+	if resp.StatusCode >= http.StatusInternalServerError {
+		return true, fmt.Errorf("%w: status %d", ErrSourceUnavailable, resp.StatusCode)
+	}
+	return true, nil
 }
 
-// GetChapters fetches chapters from the specified provider.
-func (m *Manager) GetChapters(ctx context.Context, providerID, mangaID string) ([]Chapter, error) {
-	provider, err := m.Get(providerID)
+// ResolveURL finds the provider whose site a link belongs to and resolves the
+// manga it points to. ErrUnsupportedURL is returned when no provider matches.
+func (m *Manager) ResolveURL(ctx context.Context, rawURL string) (*ResolvedURL, error) {
+	u, err := url.Parse(strings.TrimSpace(rawURL))
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return nil, fmt.Errorf("%w: %s", ErrUnsupportedURL, rawURL)
+	}
+
+	m.mu.RLock()
+	var resolver URLResolver
+	var providerID string
+	for id, p := range m.providers {
+		r, ok := p.(URLResolver)
+		if !ok {
+			continue
+		}
+		base, err := url.Parse(p.Info().BaseURL)
+		if err != nil || !sameHost(base.Hostname(), u.Hostname()) {
+			continue
+		}
+		resolver, providerID = r, id
+		break
+	}
+	m.mu.RUnlock()
+
+	if resolver == nil {
+		return nil, fmt.Errorf("%w: %s", ErrUnsupportedURL, rawURL)
+	}
+
+	ctx = withCallType(ctx, CallManga)
+	var resolved *ResolvedURL
+	err = m.call(providerID, func(Provider) error {
+		var err error
+		resolved, err = resolver.ResolveURL(ctx, u)
+		return err
+	})
 	if err != nil {
 		return nil, err
 	}
-	return provider.GetChapters(ctx, mangaID)
+	resolved.Provider = providerID
+	return resolved, nil
+}
+
+// sameHost compares host names case-insensitively, ignoring a leading "www.".
+func sameHost(a, b string) bool {
+	a = strings.TrimPrefix(strings.ToLower(a), "www.")
+	b = strings.TrimPrefix(strings.ToLower(b), "www.")
+	return a != "" && a == b
+}
+
+// Search searches for manga using the specified provider.
+func (m *Manager) Search(ctx context.Context, providerID, query string) ([]MangaResult, error) {
+	ctx = withCallType(ctx, CallSearch)
+	var results []MangaResult
+	err := m.call(providerID, func(p Provider) error {
+		var err error
+		results, err = p.Search(ctx, query)
+		return err
+	})
+	return results, err
+}
+
+// GetManga fetches manga details from the specified provider.
+func (m *Manager) GetManga(ctx context.Context, providerID, mangaID string) (*Manga, error) {
+	ctx = withCallType(ctx, CallManga)
+	var manga *Manga
+	err := m.call(providerID, func(p Provider) error {
+		var err error
+		manga, err = p.GetManga(ctx, mangaID)
+		return err
+	})
+	return manga, err
+}
+
+// GetChapters fetches chapters from the specified provider.
+func (m *Manager) GetChapters(ctx context.Context, providerID, mangaID string) ([]Chapter, error) {
+	ctx = withCallType(ctx, CallChapters)
+	var chapters []Chapter
+	err := m.call(providerID, func(p Provider) error {
+		var err error
+		chapters, err = p.GetChapters(ctx, mangaID)
+		return err
+	})
+	return chapters, err
+}
+
+// GetChaptersInLanguages fetches chapters in the given languages, in order of
+// preference. Providers without language support, or an empty language list,
+// fall back to the provider's default chapter listing.
+func (m *Manager) GetChaptersInLanguages(ctx context.Context, providerID, mangaID string, languages []string) ([]Chapter, error) {
+	ctx = withCallType(ctx, CallChapters)
+	var chapters []Chapter
+	err := m.call(providerID, func(p Provider) error {
+		var err error
+		if lp, ok := p.(LanguageProvider); ok && len(languages) > 0 {
+			chapters, err = lp.GetChaptersInLanguages(ctx, mangaID, languages)
+		} else {
+			chapters, err = p.GetChapters(ctx, mangaID)
+		}
+		return err
+	})
+	return chapters, err
 }
 
 // GetPages fetches pages from the specified provider.
 func (m *Manager) GetPages(ctx context.Context, providerID, chapterID string) ([]Page, error) {
+	ctx = withCallType(ctx, CallPages)
+	var pages []Page
+	err := m.call(providerID, func(p Provider) error {
+		var err error
+		pages, err = p.GetPages(ctx, chapterID)
+		return err
+	})
+	return pages, err
+}
+
+// GetCovers lists the alternate covers of a manga. ErrNotSupported is
+// returned for providers without CoverProvider.
+func (m *Manager) GetCovers(ctx context.Context, providerID, mangaID string) ([]Cover, error) {
 	provider, err := m.Get(providerID)
 	if err != nil {
 		return nil, err
 	}
-	return provider.GetPages(ctx, chapterID)
+	cp, ok := provider.(CoverProvider)
+	if !ok {
+		return nil, fmt.Errorf("%w: %s has no alternate covers", ErrNotSupported, providerID)
+	}
+
+	ctx = withCallType(ctx, CallManga)
+	var covers []Cover
+	err = m.call(providerID, func(Provider) error {
+		var err error
+		covers, err = cp.GetCovers(ctx, mangaID)
+		return err
+	})
+	return covers, err
 }
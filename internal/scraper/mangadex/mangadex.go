@@ -6,6 +6,9 @@ import (
 	"fmt"
 	"net/http"
 	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/mangashelf/mangashelf/internal/scraper"
@@ -17,19 +20,58 @@ const (
 	userAgent = "MangaShelf/1.0"
 )
 
+// Options configures language and content filtering for the MangaDex provider.
+type Options struct {
+	// Languages lists the preferred chapter languages in fallback order.
+	Languages []string
+	// NSFW includes erotica and pornographic titles.
+	NSFW bool
+	// ShowUnavailable includes chapters hosted externally or without pages.
+	ShowUnavailable bool
+	// CoverSize selects the cover resolution for manga details: "medium"
+	// (256px), "large" (512px) or "extraLarge" (the original upload).
+	// Search results always use the 256px thumbnail.
+	CoverSize string
+}
+
+// uuidPattern matches MangaDex resource IDs.
+var uuidPattern = regexp.MustCompile(`^[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}$`)
+
 // MangaDex implements the scraper.Provider interface.
 type MangaDex struct {
-	client   *http.Client
-	language string
+	client          *http.Client
+	languages       []string
+	nsfw            bool
+	showUnavailable bool
+	coverSuffix     string
 }
 
 // New creates a new MangaDex provider.
-func New(language string) *MangaDex {
+func New(opts Options) *MangaDex {
+	languages := opts.Languages
+	if len(languages) == 0 {
+		languages = []string{"en"}
+	}
+
 	return &MangaDex{
-		client: &http.Client{
-			Timeout: 30 * time.Second,
-		},
-		language: language,
+		client:          scraper.NewHTTPClient("mangadex", 30*time.Second),
+		languages:       languages,
+		nsfw:            opts.NSFW,
+		showUnavailable: opts.ShowUnavailable,
+		coverSuffix:     coverSuffix(opts.CoverSize),
+	}
+}
+
+// coverSuffix maps a cover size to the suffix MangaDex appends to cover file
+// names for its thumbnails.
+func coverSuffix(size string) string {
+	switch size {
+	case "small", "medium":
+		return ".256.jpg"
+	case "extraLarge", "original":
+		return ""
+	default:
+		return ".512.jpg"
 	}
 }
 
@@ -40,13 +82,19 @@ func (m *MangaDex) Info() scraper.ProviderInfo {
 		Name:      "MangaDex",
 		BaseURL:   "https://mangadex.org",
 		Languages: []string{"en", "ja", "ko", "zh", "es", "fr", "de", "it", "pt-br", "ru"},
-		IsNSFW:    false,
+		IsNSFW:    m.nsfw,
 	}
 }
 
 // Search finds manga matching the query.
 func (m *MangaDex) Search(ctx context.Context, query string) ([]scraper.MangaResult, error) {
-	endpoint := fmt.Sprintf("%s/manga?title=%s&limit=20&includes[]=cover_art", baseURL, url.QueryEscape(query))
+	params := url.Values{}
+	params.Set("title", query)
+	params.Set("limit", "20")
+	params.Add("includes[]", "cover_art")
+	m.addContentRatings(params)
+
+	endpoint := fmt.Sprintf("%s/manga?%s", baseURL, params.Encode())
 
 	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
 	if err != nil {
@@ -64,6 +112,10 @@ func (m *MangaDex) Search(ctx context.Context, query string) ([]scraper.MangaRes
 		return nil, scraper.ErrRateLimited
 	}
 
+	if resp.StatusCode >= http.StatusInternalServerError {
+		return nil, fmt.Errorf("%w: status %d", scraper.ErrSourceUnavailable, resp.StatusCode)
+	}
+
 	if resp.StatusCode != http.StatusOK {
 		return nil, fmt.Errorf("unexpected status: %d", resp.StatusCode)
 	}
@@ -100,6 +152,10 @@ func (m *MangaDex) GetManga(ctx context.Context, id string) (*scraper.Manga, err
 		return nil, scraper.ErrRateLimited
 	}
 
+	if resp.StatusCode >= http.StatusInternalServerError {
+		return nil, fmt.Errorf("%w: status %d", scraper.ErrSourceUnavailable, resp.StatusCode)
+	}
+
 	if resp.StatusCode != http.StatusOK {
 		return nil, fmt.Errorf("unexpected status: %d", resp.StatusCode)
 	}
@@ -112,19 +168,26 @@ func (m *MangaDex) GetManga(ctx context.Context, id string) (*scraper.Manga, err
 	return m.convertManga(response.Data), nil
 }
 
-// GetChapters fetches all chapters for a manga.
+// GetChapters fetches all chapters for a manga in the configured languages.
 func (m *MangaDex) GetChapters(ctx context.Context, mangaID string) ([]scraper.Chapter, error) {
-	var allChapters []scraper.Chapter
+	return m.GetChaptersInLanguages(ctx, mangaID, m.languages)
+}
+
+// GetChaptersInLanguages fetches all chapters translated into any of the given
+// languages. When a chapter number is available in several of them, only the
+// releases in the most preferred language are returned.
+func (m *MangaDex) GetChaptersInLanguages(ctx context.Context, mangaID string, languages []string) ([]scraper.Chapter, error) {
+	var allData []chapterData
 	offset := 0
 	limit := 100
 
 	for {
-		chapters, total, err := m.fetchChapterPage(ctx, mangaID, offset, limit)
+		data, total, err := m.fetchChapterPage(ctx, mangaID, languages, offset, limit)
 		if err != nil {
 			return nil, err
 		}
 
-		allChapters = append(allChapters, chapters...)
+		allData = append(allData, data...)
 		offset += limit
 
 		if offset >= total {
@@ -138,7 +201,7 @@ func (m *MangaDex) GetChapters(ctx context.Context, mangaID string) ([]scraper.C
 		}
 	}
 
-	return allChapters, nil
+	return m.convertChapters(preferLanguages(allData, languages)), nil
 }
 
 // GetPages fetches all page URLs for a chapter.
@@ -165,6 +228,10 @@ func (m *MangaDex) GetPages(ctx context.Context, chapterID string) ([]scraper.Pa
 		return nil, scraper.ErrRateLimited
 	}
 
+	if resp.StatusCode >= http.StatusInternalServerError {
+		return nil, fmt.Errorf("%w: status %d", scraper.ErrSourceUnavailable, resp.StatusCode)
+	}
+
 	if resp.StatusCode != http.StatusOK {
 		return nil, fmt.Errorf("unexpected status: %d", resp.StatusCode)
 	}
@@ -177,12 +244,173 @@ func (m *MangaDex) GetPages(ctx context.Context, chapterID string) ([]scraper.Pa
 	return m.convertPages(response), nil
 }
 
-// fetchChapterPage fetches a single page of chapters.
-func (m *MangaDex) fetchChapterPage(ctx context.Context, mangaID string, offset, limit int) ([]scraper.Chapter, int, error) {
-	endpoint := fmt.Sprintf(
-		"%s/manga/%s/feed?translatedLanguage[]=%s&order[chapter]=asc&limit=%d&offset=%d&includes[]=scanlation_group",
-		baseURL, url.PathEscape(mangaID), m.language, limit, offset,
-	)
+// GetCovers lists the cover art uploaded for a manga, one per volume and
+// locale, ordered by volume.
+func (m *MangaDex) GetCovers(ctx context.Context, mangaID string) ([]scraper.Cover, error) {
+	params := url.Values{}
+	params.Add("manga[]", mangaID)
+	params.Set("limit", "100")
+	params.Set("order[volume]", "asc")
+
+	endpoint := fmt.Sprintf("%s/cover?%s", baseURL, params.Encode())
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+	if err != nil {
+		return nil, fmt.Errorf("create request: %w", err)
+	}
+	req.Header.Set("User-Agent", userAgent)
+
+	resp, err := m.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("execute request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusTooManyRequests {
+		return nil, scraper.ErrRateLimited
+	}
+
+	if resp.StatusCode >= http.StatusInternalServerError {
+		return nil, fmt.Errorf("%w: status %d", scraper.ErrSourceUnavailable, resp.StatusCode)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status: %d", resp.StatusCode)
+	}
+
+	var response coverListResponse
+	if err := json.NewDecoder(resp.Body).Decode(&response); err != nil {
+		return nil, fmt.Errorf("decode response: %w", err)
+	}
+
+	covers := make([]scraper.Cover, 0, len(response.Data))
+	for _, c := range response.Data {
+		if c.Attributes.FileName == "" {
+			continue
+		}
+		covers = append(covers, scraper.Cover{
+			URL:         fmt.Sprintf("%s/%s/%s%s", coversURL, mangaID, c.Attributes.FileName, m.coverSuffix),
+			Volume:      c.Attributes.Volume,
+			Language:    c.Attributes.Locale,
+			Description: c.Attributes.Description,
+		})
+	}
+
+	return covers, nil
+}
+
+// HealthCheck pings the MangaDex API.
+func (m *MangaDex) HealthCheck(ctx context.Context) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, baseURL+"/ping", nil)
+	if err != nil {
+		return fmt.Errorf("create request: %w", err)
+	}
+	req.Header.Set("User-Agent", userAgent)
+
+	resp, err := m.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("%w: %v", scraper.ErrSourceUnavailable, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("%w: status %d", scraper.ErrSourceUnavailable, resp.StatusCode)
+	}
+
+	return nil
+}
+
+// ResolveURL resolves mangadex.org/title/<id> and mangadex.org/chapter/<id> links.
+func (m *MangaDex) ResolveURL(ctx context.Context, u *url.URL) (*scraper.ResolvedURL, error) {
+	segments := strings.Split(strings.Trim(u.Path, "/"), "/")
+	if len(segments) < 2 || !uuidPattern.MatchString(segments[1]) {
+		return nil, fmt.Errorf("%w: %s", scraper.ErrUnsupportedURL, u)
+	}
+	id := strings.ToLower(segments[1])
+
+	switch segments[0] {
+	case "title", "manga":
+		return &scraper.ResolvedURL{MangaID: id}, nil
+	case "chapter":
+		mangaID, err := m.getChapterMangaID(ctx, id)
+		if err != nil {
+			return nil, err
+		}
+		return &scraper.ResolvedURL{MangaID: mangaID, ChapterID: id}, nil
+	}
+
+	return nil, fmt.Errorf("%w: %s", scraper.ErrUnsupportedURL, u)
+}
+
+// getChapterMangaID looks up the manga a chapter belongs to.
+func (m *MangaDex) getChapterMangaID(ctx context.Context, chapterID string) (string, error) {
+	endpoint := fmt.Sprintf("%s/chapter/%s", baseURL, url.PathEscape(chapterID))
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+	if err != nil {
+		return "", fmt.Errorf("create request: %w", err)
+	}
+	req.Header.Set("User-Agent", userAgent)
+
+	resp, err := m.client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("execute request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return "", scraper.ErrChapterNotFound
+	}
+
+	if resp.StatusCode == http.StatusTooManyRequests {
+		return "", scraper.ErrRateLimited
+	}
+
+	if resp.StatusCode >= http.StatusInternalServerError {
+		return "", fmt.Errorf("%w: status %d", scraper.ErrSourceUnavailable, resp.StatusCode)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("unexpected status: %d", resp.StatusCode)
+	}
+
+	var response chapterResponse
+	if err := json.NewDecoder(resp.Body).Decode(&response); err != nil {
+		return "", fmt.Errorf("decode response: %w", err)
+	}
+
+	for _, rel := range response.Data.Relationships {
+		if rel.Type == "manga" {
+			return rel.ID, nil
+		}
+	}
+
+	return "", scraper.ErrMangaNotFound
+}
+
+// fetchChapterPage fetches a single page of the chapter feed.
+func (m *MangaDex) fetchChapterPage(ctx context.Context, mangaID string, languages []string, offset, limit int) ([]chapterData, int, error) {
+	params := url.Values{}
+	for _, lang := range languages {
+		params.Add("translatedLanguage[]", lang)
+	}
+	params.Set("order[chapter]", "asc")
+	params.Set("limit", strconv.Itoa(limit))
+	params.Set("offset", strconv.Itoa(offset))
+	params.Add("includes[]", "scanlation_group")
+	m.addContentRatings(params)
+
+	if m.showUnavailable {
+		params.Set("includeExternalUrl", "1")
+		params.Set("includeEmptyPages", "1")
+		params.Set("includeUnavailable", "1")
+	} else {
+		params.Set("includeExternalUrl", "0")
+		params.Set("includeEmptyPages", "0")
+		params.Set("includeUnavailable", "0")
+	}
+
+	endpoint := fmt.Sprintf("%s/manga/%s/feed?%s", baseURL, url.PathEscape(mangaID), params.Encode())
 
 	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
 	if err != nil {
@@ -200,6 +428,10 @@ func (m *MangaDex) fetchChapterPage(ctx context.Context, mangaID string, offset,
 		return nil, 0, scraper.ErrRateLimited
 	}
 
+	if resp.StatusCode >= http.StatusInternalServerError {
+		return nil, 0, fmt.Errorf("%w: status %d", scraper.ErrSourceUnavailable, resp.StatusCode)
+	}
+
 	if resp.StatusCode != http.StatusOK {
 		return nil, 0, fmt.Errorf("unexpected status: %d", resp.StatusCode)
 	}
@@ -209,8 +441,50 @@ func (m *MangaDex) fetchChapterPage(ctx context.Context, mangaID string, offset,
 		return nil, 0, fmt.Errorf("decode response: %w", err)
 	}
 
-	chapters := m.convertChapters(response.Data)
-	return chapters, response.Total, nil
+	return response.Data, response.Total, nil
+}
+
+// addContentRatings restricts a query to the content ratings allowed by the NSFW setting.
+func (m *MangaDex) addContentRatings(params url.Values) {
+	params.Add("contentRating[]", "safe")
+	params.Add("contentRating[]", "suggestive")
+
+	if m.nsfw {
+		params.Add("contentRating[]", "erotica")
+		params.Add("contentRating[]", "pornographic")
+	}
+}
+
+// preferLanguages keeps, for every chapter number, only the releases in the
+// most preferred language available. Chapters without a number are kept.
+func preferLanguages(data []chapterData, languages []string) []chapterData {
+	rank := make(map[string]int, len(languages))
+	for i, lang := range languages {
+		if _, ok := rank[lang]; !ok {
+			rank[lang] = i
+		}
+	}
+
+	best := make(map[string]int)
+	for _, ch := range data {
+		r, ok := rank[ch.Attributes.TranslatedLanguage]
+		if !ok || ch.Attributes.Chapter == "" {
+			continue
+		}
+		if current, seen := best[ch.Attributes.Chapter]; !seen || r < current {
+			best[ch.Attributes.Chapter] = r
+		}
+	}
+
+	filtered := make([]chapterData, 0, len(data))
+	for _, ch := range data {
+		if r, ok := rank[ch.Attributes.TranslatedLanguage]; ok && ch.Attributes.Chapter != "" && r != best[ch.Attributes.Chapter] {
+			continue
+		}
+		filtered = append(filtered, ch)
+	}
+
+	return filtered
 }
 
 // convertChapters transforms API chapter data to scraper.Chapter slice.
@@ -230,20 +504,46 @@ func (m *MangaDex) convertChapters(data []chapterData) []scraper.Chapter {
 			publishedAt, _ = time.Parse(time.RFC3339, ch.Attributes.PublishAt)
 		}
 
+		chapterURL := fmt.Sprintf("https://mangadex.org/chapter/%s", ch.ID)
+		if ch.Attributes.ExternalURL != "" {
+			chapterURL = ch.Attributes.ExternalURL
+		}
+
 		chapters = append(chapters, scraper.Chapter{
 			ID:          ch.ID,
 			Title:       title,
 			Number:      number,
 			Volume:      ch.Attributes.Volume,
-			URL:         fmt.Sprintf("https://mangadex.org/chapter/%s", ch.ID),
+			Language:    ch.Attributes.TranslatedLanguage,
+			Groups:      m.getGroups(ch.Relationships),
+			URL:         chapterURL,
 			PublishedAt: publishedAt,
 			PageCount:   ch.Attributes.Pages,
+			Unavailable: ch.Attributes.IsUnavailable || ch.Attributes.ExternalURL != "" || ch.Attributes.Pages == 0,
 		})
 	}
 
 	return chapters
 }
 
+// getGroups extracts scanlation groups from chapter relationships.
+func (m *MangaDex) getGroups(relationships []relationship) []scraper.ScanlationGroup {
+	var groups []scraper.ScanlationGroup
+	for _, rel := range relationships {
+		if rel.Type != "scanlation_group" {
+			continue
+		}
+
+		var name string
+		if rel.Attributes != nil {
+			name, _ = rel.Attributes["name"].(string)
+		}
+
+		groups = append(groups, scraper.ScanlationGroup{ID: rel.ID, Name: name})
+	}
+	return groups
+}
+
 // parseChapterNumber parses chapter number string to float64.
 func parseChapterNumber(s string) float64 {
 	if s == "" {
@@ -277,7 +577,7 @@ func (m *MangaDex) convertSearchResults(resp searchResponse) []scraper.MangaResu
 
 	for _, manga := range resp.Data {
 		title := m.getTitle(manga.Attributes.Title)
-		coverURL := m.getCoverURL(manga.ID, manga.Relationships)
+		coverURL := m.getCoverURL(manga.ID, manga.Relationships, ".256.jpg")
 
 		results = append(results, scraper.MangaResult{
 			ID:       manga.ID,
@@ -294,28 +594,31 @@ func (m *MangaDex) convertSearchResults(resp searchResponse) []scraper.MangaResu
 func (m *MangaDex) convertManga(data mangaData) *scraper.Manga {
 	title := m.getTitle(data.Attributes.Title)
 	description := m.getDescription(data.Attributes.Description)
-	coverURL := m.getCoverURL(data.ID, data.Relationships)
+	coverURL := m.getCoverURL(data.ID, data.Relationships, m.coverSuffix)
 	author, artist := m.getStaff(data.Relationships)
 	genres, tags := m.getTags(data.Attributes.Tags)
 
 	return &scraper.Manga{
-		ID:          data.ID,
-		Title:       title,
-		Description: description,
-		CoverURL:    coverURL,
-		Status:      data.Attributes.Status,
-		Author:      author,
-		Artist:      artist,
-		Genres:      genres,
-		Tags:        tags,
-		URL:         fmt.Sprintf("https://mangadex.org/title/%s", data.ID),
+		ID:            data.ID,
+		Title:         title,
+		Description:   description,
+		CoverURL:      coverURL,
+		Status:        data.Attributes.Status,
+		Author:        author,
+		Artist:        artist,
+		Genres:        genres,
+		Tags:          tags,
+		URL:           fmt.Sprintf("https://mangadex.org/title/%s", data.ID),
+		ContentRating: data.Attributes.ContentRating,
 	}
 }
 
 // getDescription extracts description based on language preference.
 func (m *MangaDex) getDescription(descriptions map[string]string) string {
-	if desc, ok := descriptions[m.language]; ok && desc != "" {
-		return desc
+	for _, lang := range m.languages {
+		if desc, ok := descriptions[lang]; ok && desc != "" {
+			return desc
+		}
 	}
 
 	if desc, ok := descriptions["en"]; ok && desc != "" {
@@ -383,8 +686,10 @@ func (m *MangaDex) getTags(tagList []tagData) (genres, tags []string) {
 
 // getTitle extracts the best title based on language preference.
 func (m *MangaDex) getTitle(titles map[string]string) string {
-	if title, ok := titles[m.language]; ok && title != "" {
-		return title
+	for _, lang := range m.languages {
+		if title, ok := titles[lang]; ok && title != "" {
+			return title
+		}
 	}
 
 	if title, ok := titles["en"]; ok && title != "" {
@@ -404,12 +709,13 @@ func (m *MangaDex) getTitle(titles map[string]string) string {
 	return "Unknown Title"
 }
 
-// getCoverURL extracts the cover image URL from relationships.
-func (m *MangaDex) getCoverURL(mangaID string, relationships []relationship) string {
+// getCoverURL extracts the cover image URL from relationships. The suffix
+// selects a thumbnail size; an empty suffix returns the original image.
+func (m *MangaDex) getCoverURL(mangaID string, relationships []relationship, suffix string) string {
 	for _, rel := range relationships {
 		if rel.Type == "cover_art" && rel.Attributes != nil {
 			if filename, ok := rel.Attributes["fileName"].(string); ok {
-				return fmt.Sprintf("%s/%s/%s.256.jpg", coversURL, mangaID, filename)
+				return fmt.Sprintf("%s/%s/%s%s", coversURL, mangaID, filename, suffix)
 			}
 		}
 	}
@@ -0,0 +1,13 @@
+package mangadex
+
+import (
+	"testing"
+
+	"github.com/mangashelf/mangashelf/internal/scraper/fixture"
+	"github.com/mangashelf/mangashelf/internal/scraper/scrapertest"
+)
+
+func TestMangaDex(t *testing.T) {
+	p := New(Options{})
+	scrapertest.Run(t, p, "testdata/mangadex.json", fixture.Scenario{Query: "one piece"})
+}
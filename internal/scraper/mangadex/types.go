@@ -64,10 +64,17 @@ type chapterFeedResponse struct {
 	Total    int           `json:"total"`
 }
 
+type chapterResponse struct {
+	Result   string      `json:"result"`
+	Response string      `json:"response"`
+	Data     chapterData `json:"data"`
+}
+
 type chapterData struct {
-	ID         string            `json:"id"`
-	Type       string            `json:"type"`
-	Attributes chapterAttributes `json:"attributes"`
+	ID            string            `json:"id"`
+	Type          string            `json:"type"`
+	Attributes    chapterAttributes `json:"attributes"`
+	Relationships []relationship    `json:"relationships"`
 }
 
 type chapterAttributes struct {
@@ -75,6 +82,8 @@ type chapterAttributes struct {
 	Chapter            string `json:"chapter"`
 	Title              string `json:"title"`
 	TranslatedLanguage string `json:"translatedLanguage"`
+	ExternalURL        string `json:"externalUrl"`
+	IsUnavailable      bool   `json:"isUnavailable"`
 	PublishAt          string `json:"publishAt"`
 	Pages              int    `json:"pages"`
 }
@@ -90,3 +99,23 @@ type atHomeChapter struct {
 	Data      []string `json:"data"`
 	DataSaver []string `json:"dataSaver"`
 }
+
+type coverListResponse struct {
+	Result string      `json:"result"`
+	Data   []coverData `json:"data"`
+	Limit  int         `json:"limit"`
+	Offset int         `json:"offset"`
+	Total  int         `json:"total"`
+}
+
+type coverData struct {
+	ID         string          `json:"id"`
+	Attributes coverAttributes `json:"attributes"`
+}
+
+type coverAttributes struct {
+	Volume      string `json:"volume"`
+	FileName    string `json:"fileName"`
+	Description string `json:"description"`
+	Locale      string `json:"locale"`
+}
@@ -0,0 +1,303 @@
+package plugin
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog"
+
+	"github.com/mangashelf/mangashelf/internal/scraper"
+)
+
+// Error codes plugins return to signal the scraper package's sentinel errors.
+const (
+	CodeMangaNotFound     = -32001
+	CodeChapterNotFound   = -32002
+	CodeRateLimited       = -32003
+	CodeSourceUnavailable = -32004
+)
+
+// restartWindow is the period over which restarts are counted against
+// Options.MaxRestarts.
+const restartWindow = time.Minute
+
+// nonExecutableExts lists files in the custom path that belong to other
+// scraper formats and are never started as plugins.
+var nonExecutableExts = map[string]bool{
+	".lua": true, ".yaml": true, ".yml": true, ".json": true, ".md": true, ".txt": true,
+}
+
+// Options configures plugin supervision.
+type Options struct {
+	// Timeout bounds every call, including startup. A plugin that does not
+	// answer in time is killed and restarted on the next call.
+	Timeout time.Duration
+	// MaxRestarts is the number of restarts allowed per minute before the
+	// plugin is reported unavailable.
+	MaxRestarts int
+	// Env is added to the environment of the plugin process, for example
+	// HTTPS_PROXY to route its traffic through the configured proxy.
+	Env []string
+	// EnvFor, when set, is asked for the plugin's own environment once its
+	// provider ID is known, such as a proxy configured for that provider.
+	// A non-nil result replaces Env and the process is restarted with it.
+	EnvFor func(id string) []string
+}
+
+// Plugin implements the scraper.Provider interface by talking JSON-RPC 2.0
+// over stdin and stdout to an external executable. The executable is started
+// on demand and restarted when it exits.
+type Plugin struct {
+	path string
+	opts Options
+	info scraper.ProviderInfo
+	log  zerolog.Logger
+
+	mu       sync.Mutex
+	proc     *process
+	restarts []time.Time
+}
+
+// Start launches the executable at path and asks it for its provider info.
+func Start(ctx context.Context, path string, opts Options, log zerolog.Logger) (*Plugin, error) {
+	if opts.Timeout <= 0 {
+		opts.Timeout = 30 * time.Second
+	}
+
+	p := &Plugin{
+		path: path,
+		opts: opts,
+		log:  log.With().Str("component", "plugin").Str("path", path).Logger(),
+	}
+
+	var info scraper.ProviderInfo
+	if err := p.call(ctx, "info", nil, &info); err != nil {
+		p.Close()
+		return nil, fmt.Errorf("info: %w", err)
+	}
+	if info.ID == "" || info.Name == "" {
+		p.Close()
+		return nil, errors.New("info: id and name are required")
+	}
+	if info.Languages == nil {
+		info.Languages = []string{}
+	}
+
+	p.info = info
+	p.log = p.log.With().Str("plugin", info.ID).Logger()
+
+	if opts.EnvFor != nil {
+		if env := opts.EnvFor(info.ID); env != nil {
+			// The next call starts the process again with its own
+			// environment; this is not counted as a restart.
+			p.mu.Lock()
+			p.opts.Env = env
+			proc := p.proc
+			p.proc = nil
+			p.mu.Unlock()
+			if proc != nil {
+				proc.kill()
+			}
+		}
+	}
+	return p, nil
+}
+
+// LoadDir starts every executable in dir. Plugins that fail to start are
+// skipped and their errors joined into the returned error. A missing
+// directory yields no plugins and no error.
+func LoadDir(ctx context.Context, dir string, opts Options, log zerolog.Logger) ([]*Plugin, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	sort.Slice(entries, func(i, j int) bool { return entries[i].Name() < entries[j].Name() })
+
+	var plugins []*Plugin
+	var errs []error
+
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || strings.HasPrefix(name, ".") || nonExecutableExts[strings.ToLower(filepath.Ext(name))] {
+			continue
+		}
+
+		info, err := entry.Info()
+		if err != nil || !info.Mode().IsRegular() || info.Mode().Perm()&0o111 == 0 {
+			continue
+		}
+
+		path := filepath.Join(dir, name)
+		plugin, err := Start(ctx, path, opts, log)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", path, err))
+			continue
+		}
+		plugins = append(plugins, plugin)
+	}
+
+	return plugins, errors.Join(errs...)
+}
+
+// Info returns provider metadata reported by the plugin.
+func (p *Plugin) Info() scraper.ProviderInfo {
+	return p.info
+}
+
+// Search finds manga matching the query.
+func (p *Plugin) Search(ctx context.Context, query string) ([]scraper.MangaResult, error) {
+	results := make([]scraper.MangaResult, 0)
+	if err := p.call(ctx, "search", map[string]string{"query": query}, &results); err != nil {
+		return nil, err
+	}
+	return results, nil
+}
+
+// GetManga fetches full details for a manga.
+func (p *Plugin) GetManga(ctx context.Context, id string) (*scraper.Manga, error) {
+	var manga *scraper.Manga
+	if err := p.call(ctx, "getManga", map[string]string{"id": id}, &manga); err != nil {
+		return nil, err
+	}
+	if manga == nil {
+		return nil, scraper.ErrMangaNotFound
+	}
+	return manga, nil
+}
+
+// GetChapters fetches all chapters for a manga.
+func (p *Plugin) GetChapters(ctx context.Context, mangaID string) ([]scraper.Chapter, error) {
+	var chapters []scraper.Chapter
+	if err := p.call(ctx, "getChapters", map[string]string{"mangaId": mangaID}, &chapters); err != nil {
+		return nil, err
+	}
+	return chapters, nil
+}
+
+// GetPages fetches all page URLs for a chapter.
+func (p *Plugin) GetPages(ctx context.Context, chapterID string) ([]scraper.Page, error) {
+	var pages []scraper.Page
+	if err := p.call(ctx, "getPages", map[string]string{"chapterId": chapterID}, &pages); err != nil {
+		return nil, err
+	}
+	return pages, nil
+}
+
+// HealthCheck asks the plugin for its info, restarting it if it has exited.
+func (p *Plugin) HealthCheck(ctx context.Context) error {
+	return p.call(ctx, "info", nil, nil)
+}
+
+// Close stops the plugin process.
+func (p *Plugin) Close() {
+	p.mu.Lock()
+	proc := p.proc
+	p.proc = nil
+	p.mu.Unlock()
+
+	if proc != nil {
+		proc.kill()
+	}
+}
+
+// call runs a method on the plugin within the configured timeout. A plugin
+// that times out is killed so the next call starts a fresh process.
+func (p *Plugin) call(ctx context.Context, method string, params, result any) error {
+	proc, err := p.process()
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, p.opts.Timeout)
+	defer cancel()
+
+	err = proc.call(ctx, method, params, result)
+	switch {
+	case err == nil:
+		return nil
+	case errors.Is(err, context.DeadlineExceeded):
+		p.log.Warn().Str("method", method).Dur("timeout", p.opts.Timeout).Msg("plugin timed out, stopping it")
+		p.discard(proc)
+		return fmt.Errorf("%w: %s timed out", scraper.ErrSourceUnavailable, method)
+	case errors.Is(err, errProcessExited):
+		p.log.Warn().Err(err).Str("method", method).Msg("plugin exited")
+		return fmt.Errorf("%w: %v", scraper.ErrSourceUnavailable, err)
+	}
+
+	var rpcErr *rpcError
+	if errors.As(err, &rpcErr) {
+		return mapError(rpcErr)
+	}
+	return err
+}
+
+// process returns the running plugin process, starting it when needed.
+func (p *Plugin) process() (*process, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.proc != nil && !p.proc.exited() {
+		return p.proc, nil
+	}
+
+	if p.proc != nil {
+		now := time.Now()
+		recent := p.restarts[:0]
+		for _, t := range p.restarts {
+			if now.Sub(t) < restartWindow {
+				recent = append(recent, t)
+			}
+		}
+		p.restarts = recent
+
+		if len(p.restarts) >= p.opts.MaxRestarts {
+			return nil, fmt.Errorf("%w: plugin restarted %d times in the last minute", scraper.ErrSourceUnavailable, len(p.restarts))
+		}
+		p.restarts = append(p.restarts, now)
+		p.log.Info().Int("restarts", len(p.restarts)).Msg("restarting plugin")
+	}
+
+	proc, err := startProcess(p.path, p.opts.Env, p.log)
+	if err != nil {
+		return nil, err
+	}
+	p.proc = proc
+	return proc, nil
+}
+
+// discard kills proc if it is still the current process.
+func (p *Plugin) discard(proc *process) {
+	p.mu.Lock()
+	current := p.proc == proc
+	p.mu.Unlock()
+
+	if current {
+		proc.kill()
+	}
+}
+
+// mapError converts plugin error codes into the scraper package's errors.
+func mapError(err *rpcError) error {
+	switch err.Code {
+	case CodeMangaNotFound:
+		return scraper.ErrMangaNotFound
+	case CodeChapterNotFound:
+		return scraper.ErrChapterNotFound
+	case CodeRateLimited:
+		return scraper.ErrRateLimited
+	case CodeSourceUnavailable:
+		return fmt.Errorf("%w: %s", scraper.ErrSourceUnavailable, err.Message)
+	}
+	return fmt.Errorf("plugin error: %w", err)
+}
@@ -0,0 +1,261 @@
+package plugin
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sync"
+
+	"github.com/rs/zerolog"
+)
+
+// errProcessExited is returned for calls still waiting when the plugin exits.
+var errProcessExited = errors.New("plugin process exited")
+
+// request is a JSON-RPC 2.0 request.
+type request struct {
+	JSONRPC string `json:"jsonrpc"`
+	ID      int64  `json:"id"`
+	Method  string `json:"method"`
+	Params  any    `json:"params,omitempty"`
+}
+
+// response is a JSON-RPC 2.0 response.
+type response struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      *int64          `json:"id"`
+	Result  json.RawMessage `json:"result"`
+	Error   *rpcError       `json:"error"`
+}
+
+// rpcError is a JSON-RPC 2.0 error object.
+type rpcError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+func (e *rpcError) Error() string {
+	return fmt.Sprintf("%s (code %d)", e.Message, e.Code)
+}
+
+// process is one running plugin executable. Requests are written to its
+// stdin one JSON document per line and responses are matched by ID, so
+// several calls may be in flight at once.
+type process struct {
+	cmd   *exec.Cmd
+	stdin io.WriteCloser
+
+	// writing holds a token while a request is being written, so requests
+	// do not interleave; waiting for it can be cancelled, unlike a mutex.
+	writing chan struct{}
+	mu      sync.Mutex
+	nextID  int64
+	pending map[int64]chan *response
+
+	stderrDone chan struct{}
+	done       chan struct{}
+	err        error
+}
+
+// startProcess launches the executable and starts reading its output.
+func startProcess(path string, env []string, log zerolog.Logger) (*process, error) {
+	cmd := exec.Command(path)
+	cmd.Dir = filepath.Dir(path)
+	if len(env) > 0 {
+		cmd.Env = append(os.Environ(), env...)
+	}
+
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return nil, fmt.Errorf("stdin pipe: %w", err)
+	}
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, fmt.Errorf("stdout pipe: %w", err)
+	}
+	stderr, err := cmd.StderrPipe()
+	if err != nil {
+		return nil, fmt.Errorf("stderr pipe: %w", err)
+	}
+
+	if err := cmd.Start(); err != nil {
+		return nil, fmt.Errorf("start plugin: %w", err)
+	}
+
+	p := &process{
+		cmd:        cmd,
+		stdin:      stdin,
+		writing:    make(chan struct{}, 1),
+		pending:    make(map[int64]chan *response),
+		stderrDone: make(chan struct{}),
+		done:       make(chan struct{}),
+	}
+
+	go p.logStderr(stderr, log)
+	go p.readLoop(stdout, log)
+
+	return p, nil
+}
+
+// call sends a request and waits for its response.
+func (p *process) call(ctx context.Context, method string, params, result any) error {
+	ch := make(chan *response, 1)
+
+	p.mu.Lock()
+	p.nextID++
+	id := p.nextID
+	p.pending[id] = ch
+	p.mu.Unlock()
+
+	defer func() {
+		p.mu.Lock()
+		delete(p.pending, id)
+		p.mu.Unlock()
+	}()
+
+	data, err := json.Marshal(request{JSONRPC: "2.0", ID: id, Method: method, Params: params})
+	if err != nil {
+		return fmt.Errorf("encode request: %w", err)
+	}
+
+	if err := p.write(ctx, append(data, '\n')); err != nil {
+		return err
+	}
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-p.done:
+		return p.err
+	case resp := <-ch:
+		if resp.Error != nil {
+			return resp.Error
+		}
+		if result == nil || len(resp.Result) == 0 {
+			return nil
+		}
+		if err := json.Unmarshal(resp.Result, result); err != nil {
+			return fmt.Errorf("decode result: %w", err)
+		}
+		return nil
+	}
+}
+
+// write sends one request line. A plugin that stops reading its stdin
+// blocks the write, so the caller only waits for it until ctx is done; the
+// write itself ends when the process is killed.
+func (p *process) write(ctx context.Context, line []byte) error {
+	select {
+	case p.writing <- struct{}{}:
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-p.done:
+		return p.err
+	}
+
+	written := make(chan error, 1)
+	go func() {
+		_, err := p.stdin.Write(line)
+		<-p.writing
+		written <- err
+	}()
+
+	select {
+	case err := <-written:
+		if err != nil {
+			return fmt.Errorf("%w: write request: %v", errProcessExited, err)
+		}
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-p.done:
+		return p.err
+	}
+}
+
+// readLoop dispatches responses until stdout closes or carries something
+// that is not JSON-RPC, then reaps the process.
+func (p *process) readLoop(stdout io.Reader, log zerolog.Logger) {
+	dec := json.NewDecoder(stdout)
+	var readErr error
+
+	for {
+		var resp response
+		if err := dec.Decode(&resp); err != nil {
+			if !errors.Is(err, io.EOF) {
+				readErr = fmt.Errorf("invalid output: %w", err)
+			}
+			break
+		}
+		if resp.ID == nil {
+			log.Debug().Msg("ignoring plugin message without id")
+			continue
+		}
+
+		// Each call takes one response, so the entry is removed before the
+		// send; a repeated id would otherwise block on the full channel.
+		p.mu.Lock()
+		ch, ok := p.pending[*resp.ID]
+		delete(p.pending, *resp.ID)
+		p.mu.Unlock()
+		if !ok {
+			log.Debug().Int64("id", *resp.ID).Msg("ignoring plugin response to no pending call")
+			continue
+		}
+		ch <- &resp
+	}
+
+	_ = p.cmd.Process.Kill()
+	<-p.stderrDone
+	waitErr := p.cmd.Wait()
+
+	switch {
+	case readErr != nil:
+		p.err = fmt.Errorf("%w: %v", errProcessExited, readErr)
+	case waitErr != nil:
+		p.err = fmt.Errorf("%w: %v", errProcessExited, waitErr)
+	default:
+		p.err = errProcessExited
+	}
+	close(p.done)
+}
+
+// logStderr forwards the plugin's stderr to the log line by line. After a
+// line too long to log the rest is discarded, so the plugin never blocks on
+// a full stderr pipe.
+func (p *process) logStderr(stderr io.Reader, log zerolog.Logger) {
+	defer close(p.stderrDone)
+
+	scanner := bufio.NewScanner(stderr)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		log.Info().Str("stream", "stderr").Msg(scanner.Text())
+	}
+	if err := scanner.Err(); err != nil {
+		log.Warn().Err(err).Msg("plugin stderr no longer logged")
+		_, _ = io.Copy(io.Discard, stderr)
+	}
+}
+
+// exited reports whether the process has terminated.
+func (p *process) exited() bool {
+	select {
+	case <-p.done:
+		return true
+	default:
+		return false
+	}
+}
+
+// kill terminates the process and waits for it to be reaped.
+func (p *process) kill() {
+	_ = p.stdin.Close()
+	_ = p.cmd.Process.Kill()
+	<-p.done
+}
@@ -2,6 +2,9 @@ package scraper
 
 import (
 	"context"
+	"io"
+	"net/url"
+	"strings"
 	"time"
 )
 
@@ -23,6 +26,40 @@ type Provider interface {
 	GetPages(ctx context.Context, chapterID string) ([]Page, error)
 }
 
+// LanguageProvider is implemented by providers that can restrict a chapter
+// listing to a caller-supplied set of languages, given in order of preference.
+type LanguageProvider interface {
+	GetChaptersInLanguages(ctx context.Context, mangaID string, languages []string) ([]Chapter, error)
+}
+
+// URLResolver is implemented by providers that recognise links to their own
+// site. The manager only offers URLs whose host matches ProviderInfo.BaseURL.
+type URLResolver interface {
+	// ResolveURL returns the manga a manga or chapter link points to, or
+	// ErrUnsupportedURL when the path is not recognised.
+	ResolveURL(ctx context.Context, u *url.URL) (*ResolvedURL, error)
+}
+
+// ResolvedURL identifies the manga, and optionally the chapter, a link points to.
+type ResolvedURL struct {
+	Provider  string `json:"provider"`
+	MangaID   string `json:"mangaId"`
+	ChapterID string `json:"chapterId,omitempty"`
+}
+
+// CoverProvider is implemented by providers offering more than one cover per
+// manga, such as one per volume.
+type CoverProvider interface {
+	GetCovers(ctx context.Context, mangaID string) ([]Cover, error)
+}
+
+// Opener is implemented by providers whose page and cover URLs are not plain
+// HTTP links, such as files on disk. Consumers open such URLs through the
+// provider instead of fetching them.
+type Opener interface {
+	Open(ctx context.Context, rawURL string) (io.ReadCloser, error)
+}
+
 // ProviderInfo contains metadata about a provider.
 type ProviderInfo struct {
 	ID        string   `json:"id"`
@@ -52,17 +89,56 @@ type Manga struct {
 	Genres      []string `json:"genres"`
 	Tags        []string `json:"tags"`
 	URL         string   `json:"url"`
+	// ContentRating is one of "safe", "suggestive", "erotica" or
+	// "pornographic", or empty when the source does not rate its series.
+	ContentRating string `json:"contentRating,omitempty"`
+}
+
+// NormalizeStatus maps a free-form publication status onto one of "ongoing",
+// "completed", "hiatus", "cancelled" or "unknown".
+func NormalizeStatus(status string) string {
+	switch strings.ToLower(strings.TrimSpace(status)) {
+	case "ongoing", "continuing", "publishing", "releasing":
+		return "ongoing"
+	case "completed", "complete", "ended", "finished":
+		return "completed"
+	case "hiatus", "on hiatus":
+		return "hiatus"
+	case "cancelled", "canceled", "dropped", "discontinued", "abandoned":
+		return "cancelled"
+	default:
+		return "unknown"
+	}
+}
+
+// Cover is an alternate cover image for a manga.
+type Cover struct {
+	URL         string `json:"url"`
+	Volume      string `json:"volume"`
+	Language    string `json:"language"`
+	Description string `json:"description"`
 }
 
 // Chapter represents a manga chapter.
 type Chapter struct {
-	ID          string    `json:"id"`
-	Title       string    `json:"title"`
-	Number      float64   `json:"number"`
-	Volume      string    `json:"volume"`
-	URL         string    `json:"url"`
-	PublishedAt time.Time `json:"publishedAt"`
-	PageCount   int       `json:"pageCount"`
+	ID          string            `json:"id"`
+	Title       string            `json:"title"`
+	Number      float64           `json:"number"`
+	Volume      string            `json:"volume"`
+	Language    string            `json:"language"`
+	Groups      []ScanlationGroup `json:"groups"`
+	URL         string            `json:"url"`
+	PublishedAt time.Time         `json:"publishedAt"`
+	PageCount   int               `json:"pageCount"`
+	// Unavailable marks chapters the source lists but cannot serve pages
+	// for, such as ones hosted on another site. They are never downloaded.
+	Unavailable bool `json:"unavailable,omitempty"`
+}
+
+// ScanlationGroup identifies the group that released a chapter.
+type ScanlationGroup struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
 }
 
 // Page represents a single page in a chapter.
@@ -0,0 +1,163 @@
+package scraper
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"golang.org/x/net/proxy"
+)
+
+// ProxyConfig describes the proxy a provider's requests are sent through.
+type ProxyConfig struct {
+	// URL is the proxy address: http://, https:// or socks5:// followed by
+	// host and port, optionally with credentials.
+	URL string `json:"url"`
+	// Username and Password override credentials given in the URL.
+	Username string `json:"username"`
+	Password string `json:"password"`
+	// NoProxy lists hosts reached directly. Entries are host names, domain
+	// suffixes (".example.com" or "*.example.com"), IP addresses or CIDR ranges.
+	NoProxy []string `json:"noProxy"`
+}
+
+// IsZero reports whether no proxy is configured.
+func (c ProxyConfig) IsZero() bool {
+	return c.URL == ""
+}
+
+// Env returns the proxy as HTTP_PROXY, HTTPS_PROXY, ALL_PROXY and NO_PROXY
+// variables for external processes.
+func (c ProxyConfig) Env() []string {
+	if c.IsZero() {
+		return nil
+	}
+
+	proxyURL := c.URL
+	if u, err := url.Parse(c.URL); err == nil && c.Username != "" {
+		u.User = url.UserPassword(c.Username, c.Password)
+		proxyURL = u.String()
+	}
+
+	env := []string{
+		"HTTP_PROXY=" + proxyURL,
+		"HTTPS_PROXY=" + proxyURL,
+		"ALL_PROXY=" + proxyURL,
+	}
+	if len(c.NoProxy) > 0 {
+		env = append(env, "NO_PROXY="+strings.Join(c.NoProxy, ","))
+	}
+	return env
+}
+
+// NewProxyTransport returns a transport sending requests through the proxy.
+// HTTP proxies tunnel HTTPS with CONNECT; SOCKS5 proxies are used for every
+// connection.
+func NewProxyTransport(c ProxyConfig) (http.RoundTripper, error) {
+	u, err := url.Parse(c.URL)
+	if err != nil || u.Host == "" {
+		return nil, fmt.Errorf("invalid proxy url %q", c.URL)
+	}
+	if c.Username != "" {
+		u.User = url.UserPassword(c.Username, c.Password)
+	}
+
+	bypass := newNoProxy(c.NoProxy)
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+
+	switch u.Scheme {
+	case "http", "https":
+		transport.Proxy = func(req *http.Request) (*url.URL, error) {
+			if bypass.match(req.URL.Hostname()) {
+				return nil, nil
+			}
+			return u, nil
+		}
+	case "socks5", "socks5h":
+		var auth *proxy.Auth
+		if u.User != nil {
+			password, _ := u.User.Password()
+			auth = &proxy.Auth{User: u.User.Username(), Password: password}
+		}
+
+		direct := &net.Dialer{}
+		socks, err := proxy.SOCKS5("tcp", u.Host, auth, direct)
+		if err != nil {
+			return nil, fmt.Errorf("create socks5 dialer: %w", err)
+		}
+		dialer, ok := socks.(proxy.ContextDialer)
+		if !ok {
+			return nil, errors.New("socks5 dialer does not support contexts")
+		}
+
+		transport.Proxy = nil
+		transport.DialContext = func(ctx context.Context, network, addr string) (net.Conn, error) {
+			host, _, err := net.SplitHostPort(addr)
+			if err == nil && bypass.match(host) {
+				return direct.DialContext(ctx, network, addr)
+			}
+			return dialer.DialContext(ctx, network, addr)
+		}
+	default:
+		return nil, fmt.Errorf("unsupported proxy scheme %q", u.Scheme)
+	}
+
+	return transport, nil
+}
+
+// noProxy matches hosts that bypass the proxy.
+type noProxy struct {
+	hosts    map[string]bool
+	suffixes []string
+	networks []*net.IPNet
+}
+
+func newNoProxy(entries []string) *noProxy {
+	n := &noProxy{hosts: make(map[string]bool)}
+
+	for _, entry := range entries {
+		entry = strings.ToLower(strings.TrimSpace(entry))
+		switch {
+		case entry == "":
+		case strings.HasPrefix(entry, "*."):
+			n.suffixes = append(n.suffixes, entry[1:])
+		case strings.HasPrefix(entry, "."):
+			n.suffixes = append(n.suffixes, entry)
+		case strings.Contains(entry, "/"):
+			if _, network, err := net.ParseCIDR(entry); err == nil {
+				n.networks = append(n.networks, network)
+			}
+		default:
+			if host, _, err := net.SplitHostPort(entry); err == nil {
+				entry = host
+			}
+			n.hosts[strings.Trim(entry, "[]")] = true
+		}
+	}
+	return n
+}
+
+// match reports whether host is reached without the proxy.
+func (n *noProxy) match(host string) bool {
+	host = strings.ToLower(strings.TrimSuffix(host, "."))
+	if n.hosts[host] || n.hosts["*"] {
+		return true
+	}
+	for _, suffix := range n.suffixes {
+		if strings.HasSuffix(host, suffix) || host == suffix[1:] {
+			return true
+		}
+	}
+	if ip := net.ParseIP(host); ip != nil {
+		for _, network := range n.networks {
+			if network.Contains(ip) {
+				return true
+			}
+		}
+	}
+	return false
+}
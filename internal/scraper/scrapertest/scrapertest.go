@@ -0,0 +1,58 @@
+package scrapertest
+
+import (
+	"context"
+	"errors"
+	"os"
+	"testing"
+
+	"github.com/mangashelf/mangashelf/internal/scraper"
+	"github.com/mangashelf/mangashelf/internal/scraper/fixture"
+)
+
+// RecordEnv names the environment variable that makes Run record fixtures
+// from the live source instead of replaying them.
+const RecordEnv = "MANGASHELF_RECORD"
+
+// Run checks a provider against the contract from a test. The provider's
+// traffic is replayed from the fixture at path, or recorded into it when
+// MANGASHELF_RECORD is set. Tests without a fixture are skipped.
+//
+//	func TestMangaDex(t *testing.T) {
+//		p := mangadex.New(mangadex.Options{})
+//		scrapertest.Run(t, p, "testdata/mangadex.json", fixture.Scenario{Query: "one piece"})
+//	}
+func Run(t testing.TB, p scraper.Provider, path string, s fixture.Scenario) {
+	t.Helper()
+
+	id := p.Info().ID
+
+	var session *fixture.Session
+	if os.Getenv(RecordEnv) != "" {
+		session = fixture.Record(id, path)
+	} else {
+		var err error
+		session, err = fixture.Replay(id, path)
+		if errors.Is(err, os.ErrNotExist) {
+			t.Skipf("no fixture at %s; set %s=1 to record it", path, RecordEnv)
+		}
+		if err != nil {
+			t.Fatalf("load fixture: %v", err)
+		}
+	}
+
+	report := fixture.Check(context.Background(), p, s)
+
+	if err := session.Stop(); err != nil {
+		t.Fatalf("save fixture: %v", err)
+	}
+
+	for _, step := range report.Steps {
+		if step.Error != "" {
+			t.Errorf("%s: %s", step.Name, step.Error)
+		}
+	}
+	for _, v := range report.Violations {
+		t.Error(v)
+	}
+}
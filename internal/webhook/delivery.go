@@ -0,0 +1,392 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/mangashelf/mangashelf/internal/database"
+	"github.com/mangashelf/mangashelf/internal/events"
+)
+
+const (
+	// pollInterval is how often the worker looks for deliveries due a retry.
+	pollInterval = 5 * time.Second
+
+	// pruneInterval is how often old deliveries are removed from the log.
+	pruneInterval = time.Hour
+
+	// batchSize is the most deliveries sent per poll.
+	batchSize = 50
+
+	// maxResponseBody is how much of a response is kept in the log.
+	maxResponseBody = 2048
+)
+
+// Delivery statuses.
+const (
+	StatusPending   = "pending"
+	StatusDelivered = "delivered"
+	StatusFailed    = "failed"
+)
+
+// PingEvent is the event type of test deliveries.
+const PingEvent events.Type = "ping"
+
+// Delivery is an event sent, or to be sent, to a webhook.
+type Delivery struct {
+	ID        int64           `json:"id"`
+	WebhookID int64           `json:"webhookId"`
+	Event     events.Type     `json:"event"`
+	Payload   json.RawMessage `json:"payload"`
+	Status    string          `json:"status"`
+	Attempts  int64           `json:"attempts"`
+	// ResponseStatus and ResponseBody are from the last attempt.
+	ResponseStatus int64  `json:"responseStatus,omitempty"`
+	ResponseBody   string `json:"responseBody,omitempty"`
+	LastError      string `json:"lastError,omitempty"`
+	CreatedAt      string `json:"createdAt"`
+	NextAttemptAt  string `json:"nextAttemptAt,omitempty"`
+	CompletedAt    string `json:"completedAt,omitempty"`
+}
+
+// Handle queues an event for every enabled webhook subscribed to it. It is
+// meant to be subscribed to the event bus.
+func (s *Service) Handle(e events.Event) {
+	ctx := context.Background()
+	rows, err := s.db.ListEnabledWebhooks(ctx)
+	if err != nil {
+		s.log.Error().Err(err).Msg("failed to list webhooks")
+		return
+	}
+
+	payload, err := json.Marshal(e)
+	if err != nil {
+		s.log.Error().Err(err).Str("event", string(e.Type)).Msg("failed to encode event")
+		return
+	}
+	for _, row := range rows {
+		if !toWebhook(row).subscribed(e.Type) {
+			continue
+		}
+		if _, err := s.enqueue(ctx, row.ID, e.Type, payload); err != nil {
+			s.log.Error().Err(err).Int64("webhook", row.ID).Msg("failed to queue delivery")
+		}
+	}
+}
+
+// Ping queues a test delivery to a webhook, whether or not it is enabled.
+func (s *Service) Ping(ctx context.Context, id int64) (*Delivery, error) {
+	row, err := s.getWebhook(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	payload, _ := json.Marshal(map[string]interface{}{
+		"event":     PingEvent,
+		"timestamp": time.Now().UTC(),
+		"webhook":   map[string]interface{}{"id": row.ID, "name": row.Name},
+	})
+	return s.enqueue(ctx, id, PingEvent, payload)
+}
+
+// ListDeliveries returns a webhook's most recent deliveries, newest first.
+func (s *Service) ListDeliveries(ctx context.Context, webhookID int64, limit int) ([]*Delivery, error) {
+	if _, err := s.getWebhook(ctx, webhookID); err != nil {
+		return nil, err
+	}
+
+	rows, err := s.db.ListWebhookDeliveries(ctx, database.ListWebhookDeliveriesParams{
+		WebhookID: webhookID,
+		Limit:     int64(limit),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("list deliveries: %w", err)
+	}
+	deliveries := make([]*Delivery, 0, len(rows))
+	for _, row := range rows {
+		deliveries = append(deliveries, toDelivery(row))
+	}
+	return deliveries, nil
+}
+
+// GetDelivery returns one of a webhook's deliveries.
+func (s *Service) GetDelivery(ctx context.Context, webhookID, id int64) (*Delivery, error) {
+	row, err := s.db.GetWebhookDelivery(ctx, database.GetWebhookDeliveryParams{ID: id, WebhookID: webhookID})
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrDeliveryNotFound
+		}
+		return nil, fmt.Errorf("get delivery: %w", err)
+	}
+	return toDelivery(row), nil
+}
+
+// Redeliver sends a delivery again, with a fresh set of attempts.
+func (s *Service) Redeliver(ctx context.Context, webhookID, id int64) (*Delivery, error) {
+	row, err := s.db.RedeliverWebhookDelivery(ctx, database.RedeliverWebhookDeliveryParams{
+		NextAttemptAt: nullTime(time.Now()),
+		ID:            id,
+		WebhookID:     webhookID,
+	})
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrDeliveryNotFound
+		}
+		return nil, fmt.Errorf("redeliver: %w", err)
+	}
+	s.notify()
+	return toDelivery(row), nil
+}
+
+// Run sends queued deliveries and retries failed ones until ctx is
+// cancelled. Each webhook's deliveries are sent one at a time, oldest first,
+// while different webhooks are served concurrently.
+func (s *Service) Run(ctx context.Context) {
+	ticker := time.NewTicker(pollInterval)
+	defer ticker.Stop()
+	s.prune(ctx)
+	lastPrune := time.Now()
+
+	for {
+		s.deliverDue(ctx)
+		if time.Since(lastPrune) >= pruneInterval {
+			s.prune(ctx)
+			lastPrune = time.Now()
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-s.wake:
+		case <-ticker.C:
+		}
+	}
+}
+
+func (s *Service) enqueue(ctx context.Context, webhookID int64, event events.Type, payload []byte) (*Delivery, error) {
+	row, err := s.db.InsertWebhookDelivery(ctx, database.InsertWebhookDeliveryParams{
+		WebhookID:     webhookID,
+		Event:         string(event),
+		Payload:       string(payload),
+		NextAttemptAt: nullTime(time.Now()),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("insert delivery: %w", err)
+	}
+	s.notify()
+	return toDelivery(row), nil
+}
+
+// notify wakes the worker without waiting for it.
+func (s *Service) notify() {
+	select {
+	case s.wake <- struct{}{}:
+	default:
+	}
+}
+
+// deliverDue sends every delivery whose next attempt is due, one goroutine
+// per webhook, so an endpoint that keeps timing out only delays itself.
+func (s *Service) deliverDue(ctx context.Context) {
+	ids, err := s.db.ListDueWebhookIDs(ctx, nullTime(time.Now()))
+	if err != nil {
+		if ctx.Err() == nil {
+			s.log.Error().Err(err).Msg("failed to list due deliveries")
+		}
+		return
+	}
+
+	var wg sync.WaitGroup
+	for _, id := range ids {
+		wg.Go(func() { s.deliverDueTo(ctx, id) })
+	}
+	wg.Wait()
+}
+
+// deliverDueTo sends a webhook's due deliveries in order. After a failed
+// attempt the rest wait for the next poll rather than hitting the failing
+// endpoint again right away.
+func (s *Service) deliverDueTo(ctx context.Context, webhookID int64) {
+	for ctx.Err() == nil {
+		due, err := s.db.ListDueWebhookDeliveries(ctx, database.ListDueWebhookDeliveriesParams{
+			WebhookID:     webhookID,
+			NextAttemptAt: nullTime(time.Now()),
+			Limit:         batchSize,
+		})
+		if err != nil {
+			if ctx.Err() == nil {
+				s.log.Error().Err(err).Int64("webhook", webhookID).Msg("failed to list due deliveries")
+			}
+			return
+		}
+		for _, d := range due {
+			if ctx.Err() != nil || !s.deliver(ctx, d) {
+				return
+			}
+		}
+		if len(due) < batchSize {
+			return
+		}
+	}
+}
+
+// deliver makes one attempt at a delivery and records the outcome. It
+// reports whether the webhook's endpoint can take further deliveries now.
+func (s *Service) deliver(ctx context.Context, d *database.WebhookDelivery) bool {
+	update := database.UpdateWebhookDeliveryParams{
+		Status:   StatusPending,
+		Attempts: d.Attempts + 1,
+		ID:       d.ID,
+	}
+
+	var sendErr error
+	hook, err := s.getWebhook(ctx, d.WebhookID)
+	switch {
+	case err != nil:
+		sendErr = err
+	case hook.Enabled == 0 && d.Event != string(PingEvent):
+		update.Status = StatusFailed
+		update.Attempts = d.Attempts
+		update.LastError = sql.NullString{String: "webhook is disabled", Valid: true}
+		update.CompletedAt = nullTime(time.Now())
+		if err := s.db.UpdateWebhookDelivery(ctx, update); err != nil {
+			s.log.Error().Err(err).Int64("delivery", d.ID).Msg("failed to record delivery")
+		}
+		return true
+	default:
+		var status int
+		var body string
+		status, body, sendErr = s.send(ctx, hook, d)
+		if status != 0 {
+			update.ResponseStatus = sql.NullInt64{Int64: int64(status), Valid: true}
+			update.ResponseBody = sql.NullString{String: body, Valid: body != ""}
+		}
+	}
+	if ctx.Err() != nil {
+		// Shutting down: leave the attempt for the next run.
+		return false
+	}
+
+	now := time.Now()
+	switch {
+	case sendErr == nil:
+		update.Status = StatusDelivered
+		update.CompletedAt = nullTime(now)
+		s.log.Debug().Int64("delivery", d.ID).Int64("webhook", d.WebhookID).Str("event", d.Event).Msg("webhook delivered")
+	case update.Attempts >= int64(s.opts.MaxAttempts):
+		update.Status = StatusFailed
+		update.LastError = sql.NullString{String: sendErr.Error(), Valid: true}
+		update.CompletedAt = nullTime(now)
+		s.log.Warn().Err(sendErr).Int64("delivery", d.ID).Int64("webhook", d.WebhookID).Str("event", d.Event).Msg("webhook delivery failed")
+	default:
+		update.LastError = sql.NullString{String: sendErr.Error(), Valid: true}
+		update.NextAttemptAt = nullTime(now.Add(s.retryDelay(update.Attempts)))
+		s.log.Debug().Err(sendErr).Int64("delivery", d.ID).Int64("attempt", update.Attempts).Msg("webhook delivery will be retried")
+	}
+
+	if err := s.db.UpdateWebhookDelivery(context.WithoutCancel(ctx), update); err != nil {
+		s.log.Error().Err(err).Int64("delivery", d.ID).Msg("failed to record delivery")
+	}
+	return sendErr == nil
+}
+
+// send posts a delivery's payload. A response outside 2xx is an error; its
+// status and the start of its body are returned either way.
+func (s *Service) send(ctx context.Context, hook *database.Webhook, d *database.WebhookDelivery) (int, string, error) {
+	payload := []byte(d.Payload)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, hook.Url, bytes.NewReader(payload))
+	if err != nil {
+		return 0, "", fmt.Errorf("create request: %w", err)
+	}
+
+	req.Header.Set("User-Agent", "MangaShelf-Webhook")
+	for key, value := range toWebhook(hook).Headers {
+		req.Header.Set(key, value)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-MangaShelf-Event", d.Event)
+	req.Header.Set("X-MangaShelf-Delivery", strconv.FormatInt(d.ID, 10))
+	if hook.Secret.Valid {
+		req.Header.Set("X-MangaShelf-Signature", "sha256="+Sign(hook.Secret.String, payload))
+	}
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return 0, "", fmt.Errorf("execute request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, maxResponseBody))
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return resp.StatusCode, string(body), fmt.Errorf("unexpected status: %d", resp.StatusCode)
+	}
+	return resp.StatusCode, string(body), nil
+}
+
+// retryDelay returns the wait after a failed attempt: RetryDelay, doubled
+// for each attempt before it, and never more than a day.
+func (s *Service) retryDelay(attempts int64) time.Duration {
+	delay := s.opts.RetryDelay
+	for i := int64(1); i < attempts && delay < 24*time.Hour; i++ {
+		delay *= 2
+	}
+	return min(delay, 24*time.Hour)
+}
+
+// prune removes finished deliveries older than the retention period.
+func (s *Service) prune(ctx context.Context) {
+	if s.opts.Retention <= 0 {
+		return
+	}
+	deleted, err := s.db.DeleteWebhookDeliveriesBefore(ctx, nullTime(time.Now().Add(-s.opts.Retention)))
+	if err != nil {
+		if ctx.Err() == nil {
+			s.log.Error().Err(err).Msg("failed to prune deliveries")
+		}
+		return
+	}
+	if deleted > 0 {
+		s.log.Debug().Int64("deleted", deleted).Msg("old deliveries pruned")
+	}
+}
+
+// Sign returns the hex HMAC-SHA256 of a payload, as sent in the
+// X-MangaShelf-Signature header after "sha256=".
+func Sign(secret string, payload []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(payload)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+func nullTime(t time.Time) sql.NullString {
+	return sql.NullString{String: t.UTC().Format(database.TimeFormat), Valid: true}
+}
+
+func toDelivery(d *database.WebhookDelivery) *Delivery {
+	return &Delivery{
+		ID:             d.ID,
+		WebhookID:      d.WebhookID,
+		Event:          events.Type(d.Event),
+		Payload:        json.RawMessage(d.Payload),
+		Status:         d.Status,
+		Attempts:       d.Attempts,
+		ResponseStatus: d.ResponseStatus.Int64,
+		ResponseBody:   d.ResponseBody.String,
+		LastError:      d.LastError.String,
+		CreatedAt:      d.CreatedAt.String,
+		NextAttemptAt:  d.NextAttemptAt.String,
+		CompletedAt:    d.CompletedAt.String,
+	}
+}
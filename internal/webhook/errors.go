@@ -0,0 +1,20 @@
+package webhook
+
+import "errors"
+
+var (
+	// ErrWebhookNotFound is returned when a webhook is not found.
+	ErrWebhookNotFound = errors.New("webhook not found")
+
+	// ErrDeliveryNotFound is returned when a delivery is not found.
+	ErrDeliveryNotFound = errors.New("delivery not found")
+
+	// ErrInvalidURL is returned for a webhook URL that is not an absolute http or https URL.
+	ErrInvalidURL = errors.New("invalid webhook url")
+
+	// ErrInvalidEvent is returned for an event type that does not exist.
+	ErrInvalidEvent = errors.New("invalid event type")
+
+	// ErrInvalidHeader is returned for a malformed or reserved custom header.
+	ErrInvalidHeader = errors.New("invalid header")
+)
@@ -0,0 +1,306 @@
+// Package webhook delivers library and download events to HTTP endpoints
+// subscribed through the API. Deliveries are stored before they are sent, so
+// they are retried across restarts and can be inspected afterwards.
+package webhook
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/rs/zerolog"
+
+	"github.com/mangashelf/mangashelf/internal/database"
+	"github.com/mangashelf/mangashelf/internal/events"
+)
+
+// Options configures delivery.
+type Options struct {
+	// MaxAttempts is how often a delivery is tried before it is marked failed.
+	MaxAttempts int
+	// RetryDelay is the wait before the first retry; it doubles for each
+	// retry after that.
+	RetryDelay time.Duration
+	// Timeout bounds each delivery request.
+	Timeout time.Duration
+	// Retention is how long finished deliveries are kept in the log. Zero
+	// keeps them forever.
+	Retention time.Duration
+}
+
+// Service manages webhook subscriptions and delivers events to them.
+type Service struct {
+	db     *database.Queries
+	client *http.Client
+	opts   Options
+	wake   chan struct{}
+	log    zerolog.Logger
+}
+
+// NewService creates a new webhook service. Call Run to start delivering.
+func NewService(db *database.Queries, opts Options, log zerolog.Logger) *Service {
+	opts.MaxAttempts = max(opts.MaxAttempts, 1)
+	return &Service{
+		db: db,
+		client: &http.Client{
+			Timeout: opts.Timeout,
+			// A redirect is reported as a failed delivery rather than
+			// followed, which would resend the payload as a GET.
+			CheckRedirect: func(*http.Request, []*http.Request) error {
+				return http.ErrUseLastResponse
+			},
+		},
+		opts: opts,
+		wake: make(chan struct{}, 1),
+		log:  log.With().Str("component", "webhook").Logger(),
+	}
+}
+
+// Webhook is a subscription to events. The signing secret is never
+// returned; HasSecret reports whether one is set.
+type Webhook struct {
+	ID   int64  `json:"id"`
+	Name string `json:"name"`
+	URL  string `json:"url"`
+	// Events are the event types delivered; empty for every type.
+	Events    []events.Type     `json:"events"`
+	Headers   map[string]string `json:"headers"`
+	HasSecret bool              `json:"hasSecret"`
+	Enabled   bool              `json:"enabled"`
+	CreatedAt string            `json:"createdAt"`
+	UpdatedAt string            `json:"updatedAt"`
+}
+
+// CreateWebhookRequest contains parameters for subscribing to events.
+// Enabled defaults to true and Name to the URL's host.
+type CreateWebhookRequest struct {
+	Name    string            `json:"name"`
+	URL     string            `json:"url"`
+	Events  []events.Type     `json:"events"`
+	Secret  string            `json:"secret"`
+	Headers map[string]string `json:"headers"`
+	Enabled *bool             `json:"enabled"`
+}
+
+// UpdateWebhookRequest changes a subscription. Nil fields are left
+// untouched; an empty Secret removes the secret.
+type UpdateWebhookRequest struct {
+	Name    *string            `json:"name"`
+	URL     *string            `json:"url"`
+	Events  *[]events.Type     `json:"events"`
+	Secret  *string            `json:"secret"`
+	Headers *map[string]string `json:"headers"`
+	Enabled *bool              `json:"enabled"`
+}
+
+// ListWebhooks returns every subscription.
+func (s *Service) ListWebhooks(ctx context.Context) ([]*Webhook, error) {
+	rows, err := s.db.ListWebhooks(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("list webhooks: %w", err)
+	}
+	hooks := make([]*Webhook, 0, len(rows))
+	for _, row := range rows {
+		hooks = append(hooks, toWebhook(row))
+	}
+	return hooks, nil
+}
+
+// GetWebhook returns a subscription by ID.
+func (s *Service) GetWebhook(ctx context.Context, id int64) (*Webhook, error) {
+	row, err := s.getWebhook(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	return toWebhook(row), nil
+}
+
+// CreateWebhook subscribes a URL to events.
+func (s *Service) CreateWebhook(ctx context.Context, req CreateWebhookRequest) (*Webhook, error) {
+	enabled := req.Enabled == nil || *req.Enabled
+	params, err := webhookParams(req.Name, req.URL, req.Events, req.Secret, req.Headers, enabled)
+	if err != nil {
+		return nil, err
+	}
+
+	row, err := s.db.InsertWebhook(ctx, params)
+	if err != nil {
+		return nil, fmt.Errorf("insert webhook: %w", err)
+	}
+	s.log.Info().Int64("id", row.ID).Str("name", row.Name).Msg("webhook created")
+	return toWebhook(row), nil
+}
+
+// UpdateWebhook changes a subscription.
+func (s *Service) UpdateWebhook(ctx context.Context, id int64, req UpdateWebhookRequest) (*Webhook, error) {
+	row, err := s.getWebhook(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	hook := toWebhook(row)
+	secret := row.Secret.String
+
+	if req.Name != nil {
+		hook.Name = *req.Name
+	}
+	if req.URL != nil {
+		hook.URL = *req.URL
+	}
+	if req.Events != nil {
+		hook.Events = *req.Events
+	}
+	if req.Secret != nil {
+		secret = *req.Secret
+	}
+	if req.Headers != nil {
+		hook.Headers = *req.Headers
+	}
+	if req.Enabled != nil {
+		hook.Enabled = *req.Enabled
+	}
+
+	params, err := webhookParams(hook.Name, hook.URL, hook.Events, secret, hook.Headers, hook.Enabled)
+	if err != nil {
+		return nil, err
+	}
+	row, err = s.db.UpdateWebhook(ctx, database.UpdateWebhookParams{
+		Name:    params.Name,
+		Url:     params.Url,
+		Events:  params.Events,
+		Secret:  params.Secret,
+		Headers: params.Headers,
+		Enabled: params.Enabled,
+		ID:      id,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("update webhook: %w", err)
+	}
+	return toWebhook(row), nil
+}
+
+// DeleteWebhook removes a subscription and its delivery log.
+func (s *Service) DeleteWebhook(ctx context.Context, id int64) error {
+	deleted, err := s.db.DeleteWebhook(ctx, id)
+	if err != nil {
+		return fmt.Errorf("delete webhook: %w", err)
+	}
+	if deleted == 0 {
+		return ErrWebhookNotFound
+	}
+	s.log.Info().Int64("id", id).Msg("webhook deleted")
+	return nil
+}
+
+func (s *Service) getWebhook(ctx context.Context, id int64) (*database.Webhook, error) {
+	row, err := s.db.GetWebhook(ctx, id)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrWebhookNotFound
+		}
+		return nil, fmt.Errorf("get webhook: %w", err)
+	}
+	return row, nil
+}
+
+// reservedHeaders are set on every delivery and cannot be overridden.
+var reservedHeaders = []string{"Content-Length", "Content-Type", "Host", "Transfer-Encoding"}
+
+// headerPrefix starts the names of the headers describing a delivery.
+const headerPrefix = "X-Mangashelf-"
+
+// webhookParams validates a subscription and encodes it for storage.
+func webhookParams(name, rawURL string, types []events.Type, secret string, headers map[string]string, enabled bool) (database.InsertWebhookParams, error) {
+	u, err := url.Parse(strings.TrimSpace(rawURL))
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return database.InsertWebhookParams{}, fmt.Errorf("%w: %s", ErrInvalidURL, rawURL)
+	}
+
+	var filter []string
+	for _, t := range types {
+		if !t.Valid() {
+			return database.InsertWebhookParams{}, fmt.Errorf("%w: %s", ErrInvalidEvent, t)
+		}
+		if !slices.Contains(filter, string(t)) {
+			filter = append(filter, string(t))
+		}
+	}
+
+	canonical := make(map[string]string, len(headers))
+	for key, value := range headers {
+		key = http.CanonicalHeaderKey(strings.TrimSpace(key))
+		if !validHeader(key, value) || slices.Contains(reservedHeaders, key) || strings.HasPrefix(key, headerPrefix) {
+			return database.InsertWebhookParams{}, fmt.Errorf("%w: %s", ErrInvalidHeader, key)
+		}
+		canonical[key] = value
+	}
+
+	name = strings.TrimSpace(name)
+	if name == "" {
+		name = u.Host
+	}
+
+	params := database.InsertWebhookParams{
+		Name:   name,
+		Url:    u.String(),
+		Secret: sql.NullString{String: secret, Valid: secret != ""},
+	}
+	if len(filter) > 0 {
+		data, _ := json.Marshal(filter)
+		params.Events = sql.NullString{String: string(data), Valid: true}
+	}
+	if len(canonical) > 0 {
+		data, _ := json.Marshal(canonical)
+		params.Headers = sql.NullString{String: string(data), Valid: true}
+	}
+	if enabled {
+		params.Enabled = 1
+	}
+	return params, nil
+}
+
+// validHeader reports whether a header name is an HTTP token and its value
+// holds no line breaks.
+func validHeader(name, value string) bool {
+	if name == "" || strings.ContainsAny(value, "\r\n\x00") {
+		return false
+	}
+	for _, c := range name {
+		if c <= ' ' || c >= 0x7f || strings.ContainsRune(`"(),/:;<=>?@[\]{}`, c) {
+			return false
+		}
+	}
+	return true
+}
+
+// subscribed reports whether a webhook receives an event type.
+func (h *Webhook) subscribed(t events.Type) bool {
+	return len(h.Events) == 0 || slices.Contains(h.Events, t)
+}
+
+func toWebhook(w *database.Webhook) *Webhook {
+	hook := &Webhook{
+		ID:        w.ID,
+		Name:      w.Name,
+		URL:       w.Url,
+		Events:    []events.Type{},
+		Headers:   map[string]string{},
+		HasSecret: w.Secret.Valid,
+		Enabled:   w.Enabled == 1,
+		CreatedAt: w.CreatedAt.String,
+		UpdatedAt: w.UpdatedAt.String,
+	}
+	if w.Events.Valid {
+		_ = json.Unmarshal([]byte(w.Events.String), &hook.Events)
+	}
+	if w.Headers.Valid {
+		_ = json.Unmarshal([]byte(w.Headers.String), &hook.Headers)
+	}
+	return hook
+}